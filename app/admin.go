@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/outputs"
+)
+
+// startAdmin starts the admin HTTP server if an admin-listen address is configured,
+// it serves the net/http/pprof endpoints, the outputs internal channel depths and last errors,
+// the targets assigned to the instance when running in a cluster, as well as the targets connections counters.
+func (a *App) startAdmin() {
+	if a.Config.AdminListen == "" {
+		return
+	}
+	s := &http.Server{
+		Addr:    a.Config.AdminListen,
+		Handler: a.adminHandler(),
+	}
+	go func() {
+		a.Logger.Printf("starting admin server on %s", s.Addr)
+		err := s.ListenAndServe()
+		if err != nil {
+			a.Logger.Printf("admin server err: %v", err)
+			return
+		}
+	}()
+}
+
+func (a *App) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/outputs", a.handleAdminOutputs)
+	mux.HandleFunc("/debug/outputs/errors", a.handleAdminOutputsErrors)
+	mux.HandleFunc("/debug/cluster/targets", a.handleAdminClusterTargets)
+	mux.HandleFunc("/debug/targets/connections", a.handleAdminTargetsConnections)
+	return mux
+}
+
+func (a *App) handleAdminOutputs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	depths := make(map[string]map[string]int)
+	if a.collector != nil {
+		depths = a.collector.OutputsDepths()
+	}
+	err := json.NewEncoder(w).Encode(depths)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+	}
+}
+
+func (a *App) handleAdminOutputsErrors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	errs := make(map[string]map[string]*outputs.TargetError)
+	if a.collector != nil {
+		errs = a.collector.OutputsErrors()
+	}
+	err := json.NewEncoder(w).Encode(errs)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+	}
+}
+
+type clusterAssignments struct {
+	ClusterName  string   `json:"cluster-name,omitempty"`
+	InstanceName string   `json:"instance-name,omitempty"`
+	Targets      []string `json:"targets"`
+}
+
+func (a *App) handleAdminClusterTargets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ca := clusterAssignments{Targets: make([]string, 0)}
+	if a.inCluster() {
+		ca.ClusterName = a.Config.Clustering.ClusterName
+		ca.InstanceName = a.Config.Clustering.InstanceName
+	}
+	if a.collector != nil {
+		ca.Targets = a.collector.AssignedTargets()
+	}
+	err := json.NewEncoder(w).Encode(ca)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+	}
+}
+
+// ClientPoolStats returns the counters of the connections used by the Capabilities, Get and Set RPCs,
+// they are kept open across the prompt mode commands.
+func (a *App) ClientPoolStats() collector.ClientPoolStats {
+	if a.collector == nil {
+		return collector.ClientPoolStats{}
+	}
+	return a.collector.ClientPoolStats()
+}
+
+func (a *App) handleAdminTargetsConnections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(a.ClientPoolStats())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+	}
+}
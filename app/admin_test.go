@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/outputs"
+)
+
+func TestAdminDisabledByDefault(t *testing.T) {
+	a := New()
+	a.InitGlobalFlags()
+	if a.Config.AdminListen != "" {
+		t.Errorf("expected admin server to be disabled by default, got admin-listen=%q", a.Config.AdminListen)
+	}
+}
+
+func TestAdminServer(t *testing.T) {
+	a := New()
+	a.collector = collector.NewCollector(&collector.Config{}, nil, collector.WithLogger(log.New(ioutil.Discard, "", 0)))
+	out := outputs.Outputs["prometheus"]()
+	err := out.Init(context.Background(), "prom", map[string]interface{}{"listen": "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("failed to init prometheus output: %v", err)
+	}
+	defer out.Close()
+	a.collector.Outputs["prom"] = out
+
+	s := httptest.NewServer(a.adminHandler())
+	defer s.Close()
+
+	rsp, err := http.Get(s.URL + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected /debug/pprof/ status code: %d", rsp.StatusCode)
+	}
+
+	rsp, err = http.Get(s.URL + "/debug/outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected /debug/outputs status code: %d", rsp.StatusCode)
+	}
+	depths := make(map[string]map[string]int)
+	err = json.NewDecoder(rsp.Body).Decode(&depths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := depths["prom"]["event-chan"]; !ok {
+		t.Errorf("missing prometheus output event-chan depth: %v", depths)
+	}
+	if _, ok := depths["prom"]["entries"]; !ok {
+		t.Errorf("missing prometheus output entries count: %v", depths)
+	}
+
+	rsp, err = http.Get(s.URL + "/debug/outputs/errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected /debug/outputs/errors status code: %d", rsp.StatusCode)
+	}
+	errs := make(map[string]map[string]*outputs.TargetError)
+	err = json.NewDecoder(rsp.Body).Decode(&errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te, ok := errs["prom"]; !ok || len(te) != 0 {
+		t.Errorf("expected an empty prometheus output errors map: %v", errs)
+	}
+
+	rsp, err = http.Get(s.URL + "/debug/cluster/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected /debug/cluster/targets status code: %d", rsp.StatusCode)
+	}
+	ca := new(clusterAssignments)
+	err = json.NewDecoder(rsp.Body).Decode(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.Targets == nil || len(ca.Targets) != 0 {
+		t.Errorf("expected an empty list of assigned targets outside of a cluster: %+v", ca)
+	}
+}
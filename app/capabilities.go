@@ -26,16 +26,19 @@ func (a *App) CapRun(cmd *cobra.Command, args []string) error {
 	}
 	if a.collector == nil {
 		cfg := &collector.Config{
+			PrometheusAddress:   a.Config.PrometheusAddress,
 			Debug:               a.Config.Debug,
 			Format:              a.Config.Format,
 			TargetReceiveBuffer: a.Config.TargetBufferSize,
 			RetryTimer:          a.Config.Retry,
+			ClientIdleTimeout:   a.Config.ClientIdleTimeout,
 		}
 
 		a.collector = collector.NewCollector(cfg, targetsConfig,
 			collector.WithDialOptions(a.createCollectorDialOpts()),
 			collector.WithLogger(a.Logger),
 		)
+		a.collector.StartMetricsServer()
 	} else {
 		// prompt mode
 		for _, tc := range targetsConfig {
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ConfigValidateRunE loads the config file and reports all its validation errors,
+// it fails if the file cannot be loaded or if any error is found.
+func (a *App) ConfigValidateRunE(cmd *cobra.Command, args []string) error {
+	err := a.Config.Load()
+	if err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			return fmt.Errorf("no config file found")
+		}
+		return fmt.Errorf("failed loading config file: %v", err)
+	}
+	errs := a.Config.Validate(cmd)
+	for _, err := range errs {
+		fmt.Fprintf(a.out, "error: %v\n", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("config file %q is invalid: %d error(s) found", a.Config.FileConfig.ConfigFileUsed(), len(errs))
+	}
+	fmt.Fprintf(a.out, "config file %q is valid\n", a.Config.FileConfig.ConfigFileUsed())
+	return nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const validTestConfig = `
+username: admin
+password: admin
+insecure: true
+targets:
+  router1:57400:
+    subscriptions:
+      - counters
+    outputs:
+      - prom
+subscriptions:
+  counters:
+    paths:
+      - /interfaces/interface/state/counters
+    mode: stream
+    stream-mode: sample
+    sample-interval: 10s
+outputs:
+  prom:
+    type: prometheus
+    listen: :9804
+    event-processors:
+      - drop-mgmt
+processors:
+  drop-mgmt:
+    event-drop:
+      condition: '.tags.interface_name == "mgmt0"'
+`
+
+const invalidTestConfig = `
+targets:
+  router1:57400:
+    outputs:
+      - out1
+      - out2
+outputs:
+  out1:
+    type: dummy
+  out2:
+    type: prometheus
+    listen: not-an-address
+    event-processors:
+      - missing
+processors:
+  bad-regex:
+    event-drop:
+      value-names:
+        - "["
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "gnmic-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	name := filepath.Join(dir, "gnmic.yaml")
+	err = ioutil.WriteFile(name, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  string
+		wantErr bool
+		outputs []string
+	}{
+		"valid": {
+			config:  validTestConfig,
+			outputs: []string{"is valid"},
+		},
+		"invalid": {
+			config:  invalidTestConfig,
+			wantErr: true,
+			outputs: []string{
+				`output "out1": unknown output type "dummy"`,
+				`output "out2": address not-an-address: missing port in address`,
+				`output "out2": unknown event processor "missing"`,
+				`processor "bad-regex"`,
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := New()
+			out := new(strings.Builder)
+			a.out = out
+			a.Config.GlobalFlags.CfgFile = writeTestConfig(t, tc.config)
+			err := a.ConfigValidateRunE(&cobra.Command{}, nil)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v, output: %s", err, out)
+			}
+			for _, s := range tc.outputs {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected %q in the output, got: %s", s, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// startControlSocket listens on the control-socket unix socket path if configured,
+// each connection sends a JSON encoded collector.TapFilter line,
+// then receives the matching exported events as JSON lines until it disconnects.
+func (a *App) startControlSocket() {
+	if a.Config.ControlSocket == "" {
+		return
+	}
+	// remove the socket left by a previous run
+	if err := os.Remove(a.Config.ControlSocket); err != nil && !os.IsNotExist(err) {
+		a.Logger.Printf("failed to remove control socket %q: %v", a.Config.ControlSocket, err)
+		return
+	}
+	l, err := net.Listen("unix", a.Config.ControlSocket)
+	if err != nil {
+		a.Logger.Printf("failed to start control socket: %v", err)
+		return
+	}
+	a.Logger.Printf("control socket listening on %s", a.Config.ControlSocket)
+	go func() {
+		<-a.ctx.Done()
+		l.Close()
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				select {
+				case <-a.ctx.Done():
+				default:
+					a.Logger.Printf("control socket accept error: %v", err)
+				}
+				return
+			}
+			go a.handleControlConn(conn)
+		}
+	}()
+}
+
+func (a *App) handleControlConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	line, err := r.ReadBytes('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		a.Logger.Printf("control socket: failed to read request: %v", err)
+		return
+	}
+	filter := collector.TapFilter{}
+	err = json.Unmarshal(line, &filter)
+	if err != nil {
+		a.Logger.Printf("control socket: invalid request %q: %v", line, err)
+		fmt.Fprintf(conn, "{\"error\":%q}\n", err.Error())
+		return
+	}
+	if a.collector == nil {
+		fmt.Fprintf(conn, "{\"error\":%q}\n", "collector not started")
+		return
+	}
+	tap, err := a.collector.AddTap(filter, 0)
+	if err != nil {
+		fmt.Fprintf(conn, "{\"error\":%q}\n", err.Error())
+		return
+	}
+	defer a.collector.RemoveTap(tap)
+	a.Logger.Printf("control socket: attached client with filter %+v", filter)
+	// the client does not send anything after the request,
+	// a read returns once it disconnects.
+	done := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, r)
+		close(done)
+	}()
+	enc := json.NewEncoder(conn)
+	for {
+		select {
+		case <-done:
+			a.Logger.Printf("control socket: client detached, %d event(s) dropped", tap.Dropped())
+			return
+		case <-a.ctx.Done():
+			return
+		case ev := <-tap.Events():
+			if err := enc.Encode(ev); err != nil {
+				a.Logger.Printf("control socket: failed to write event: %v", err)
+				return
+			}
+		}
+	}
+}
+
+func (a *App) AttachRunE(cmd *cobra.Command, args []string) error {
+	if a.Config.ControlSocket == "" {
+		return errors.New("missing control-socket path")
+	}
+	conn, err := net.Dial("unix", a.Config.ControlSocket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to control socket: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		<-a.ctx.Done()
+		conn.Close()
+	}()
+	return a.attach(conn)
+}
+
+// attach sends the events filter over conn and prints the received events until conn is closed.
+func (a *App) attach(conn net.Conn) error {
+	err := json.NewEncoder(conn).Encode(collector.TapFilter{
+		Target: a.Config.LocalFlags.AttachTarget,
+		Path:   a.Config.LocalFlags.AttachPath,
+	})
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		msg := make(map[string]interface{})
+		err = dec.Decode(&msg)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			select {
+			case <-a.ctx.Done():
+				return nil
+			default:
+			}
+			return err
+		}
+		if e, ok := msg["error"]; ok && len(msg) == 1 {
+			return fmt.Errorf("%v", e)
+		}
+		b, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return err
+		}
+		a.printLock.Lock()
+		fmt.Fprintln(a.out, string(b))
+		a.printLock.Unlock()
+	}
+}
+
+func (a *App) InitAttachFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.AttachTarget, "target", "", "", "only print the events of this target")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.AttachPath, "path", "", "", "only print the events with a value or a deleted path starting with this prefix")
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// syncWriter sends each write on a channel
+type syncWriter chan string
+
+func (w syncWriter) Write(b []byte) (int, error) {
+	w <- string(b)
+	return len(b), nil
+}
+
+func TestControlSocketAttach(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnmic-control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := New()
+	defer a.Cfn()
+	a.Config.ControlSocket = filepath.Join(dir, "gnmic.sock")
+	a.collector = collector.NewCollector(&collector.Config{EnableTaps: true}, nil, collector.WithLogger(log.New(ioutil.Discard, "", 0)))
+	a.startControlSocket()
+
+	client := New()
+	defer client.Cfn()
+	out := make(syncWriter, 10)
+	client.out = out
+	client.Config.LocalFlags.AttachTarget = "router1"
+	conn, err := net.Dial("unix", a.Config.ControlSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.attach(conn)
+	}()
+
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "counter"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+	// the events exported before the tap is attached are not received,
+	// export until the first one is printed.
+	timeout := time.After(5 * time.Second)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	var printed string
+WAIT:
+	for {
+		select {
+		case printed = <-out:
+			break WAIT
+		case <-ticker.C:
+			a.collector.Export(context.Background(), rsp, outputs.Meta{"source": "router2", "subscription-name": "sub1"})
+			a.collector.Export(context.Background(), rsp, outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+		case <-timeout:
+			t.Fatal("timeout waiting for an event")
+		}
+	}
+	if !strings.Contains(printed, `"source": "router1"`) || !strings.Contains(printed, `"/counter": 1`) {
+		t.Errorf("unexpected event printed: %s", printed)
+	}
+
+	conn.Close()
+	select {
+	case <-errCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("attach did not return after the connection was closed")
+	}
+}
+
+func TestControlSocketTapsDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnmic-control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := New()
+	defer a.Cfn()
+	a.Config.ControlSocket = filepath.Join(dir, "gnmic.sock")
+	a.collector = collector.NewCollector(&collector.Config{}, nil, collector.WithLogger(log.New(ioutil.Discard, "", 0)))
+	a.startControlSocket()
+
+	client := New()
+	defer client.Cfn()
+	client.out = ioutil.Discard
+	conn, err := net.Dial("unix", a.Config.ControlSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = client.attach(conn)
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected a taps disabled error, got %v", err)
+	}
+}
@@ -2,12 +2,18 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/karimra/gnmic/collector"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func (a *App) GetRun(cmd *cobra.Command, args []string) error {
@@ -16,6 +22,9 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 	if a.Config.Format == "event" {
 		return fmt.Errorf("format event not supported for Get RPC")
 	}
+	if a.Config.LocalFlags.GetValuesOnly && a.Config.LocalFlags.GetPathsOnly {
+		return fmt.Errorf("flags --values-only and --paths-only are mutually exclusive")
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	// setupCloseHandler(cancel)
@@ -26,16 +35,19 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 
 	if a.collector == nil {
 		cfg := &collector.Config{
+			PrometheusAddress:   a.Config.PrometheusAddress,
 			Debug:               a.Config.Debug,
 			Format:              a.Config.Format,
 			TargetReceiveBuffer: a.Config.TargetBufferSize,
 			RetryTimer:          a.Config.Retry,
+			ClientIdleTimeout:   a.Config.ClientIdleTimeout,
 		}
 
 		a.collector = collector.NewCollector(cfg, targetsConfig,
 			collector.WithDialOptions(a.createCollectorDialOpts()),
 			collector.WithLogger(a.Logger),
 		)
+		a.collector.StartMetricsServer()
 	} else {
 		// prompt mode
 		for _, tc := range targetsConfig {
@@ -58,7 +70,8 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 
 func (a *App) GetRequest(ctx context.Context, tName string, req *gnmi.GetRequest) {
 	defer a.wg.Done()
-	xreq := req
+	// the request is shared by the targets goroutines
+	xreq := proto.Clone(req).(*gnmi.GetRequest)
 	if len(a.Config.LocalFlags.GetModel) > 0 {
 		spModels, unspModels, err := a.filterModels(ctx, tName, a.Config.LocalFlags.GetModel)
 		if err != nil {
@@ -78,19 +91,210 @@ func (a *App) GetRequest(ctx context.Context, tName string, req *gnmi.GetRequest
 			a.logError(fmt.Errorf("target %q Get Request printing failed: %v", tName, err))
 		}
 	}
-	a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
-		xreq.Prefix, xreq.Path, xreq.Type, xreq.Encoding, xreq.UseModels, xreq.Extension, tName)
-	response, err := a.collector.Get(ctx, tName, xreq)
+	encodings, err := a.Config.GetEncodings()
+	if err != nil {
+		a.logError(fmt.Errorf("target %q: %v", tName, err))
+		return
+	}
+	response, err := a.getWithEncodings(ctx, tName, xreq, encodings)
 	if err != nil {
 		a.logError(fmt.Errorf("target %q get request failed: %v", tName, err))
 		return
 	}
+	if a.Config.LocalFlags.GetValuesOnly || a.Config.LocalFlags.GetPathsOnly {
+		err = a.printFlatResponse(tName, response)
+		if err != nil {
+			a.logError(fmt.Errorf("target %q: %v", tName, err))
+		}
+		return
+	}
 	err = a.PrintMsg(tName, "Get Response:", response)
 	if err != nil {
 		a.logError(fmt.Errorf("target %q: %v", tName, err))
 	}
 }
 
+// getWithEncodings sends the GetRequest using each of the encodings in order, until one succeeds.
+// If multiple encodings are given, the ones not listed in the target capabilities are skipped.
+func (a *App) getWithEncodings(ctx context.Context, tName string, req *gnmi.GetRequest, encodings []gnmi.Encoding) (*gnmi.GetResponse, error) {
+	if len(encodings) > 1 {
+		encodings = a.supportedEncodings(ctx, tName, encodings)
+	}
+	var err error
+	var response *gnmi.GetResponse
+	for i, enc := range encodings {
+		// req is not modified, it may be shared with other targets
+		ereq := proto.Clone(req).(*gnmi.GetRequest)
+		ereq.Encoding = enc
+		a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
+			ereq.Prefix, ereq.Path, ereq.Type, ereq.Encoding, ereq.UseModels, ereq.Extension, tName)
+		response, err = a.collector.Get(ctx, tName, ereq)
+		if isResourceExhausted(err) {
+			a.Logger.Printf("target %q: get response too large: %v, splitting the request paths", tName, err)
+			response, err = a.splitGet(ctx, tName, ereq)
+		}
+		if err == nil {
+			if len(encodings) > 1 {
+				a.Logger.Printf("target %q: get request succeeded using encoding %s", tName, enc)
+			}
+			return response, nil
+		}
+		if i < len(encodings)-1 {
+			a.Logger.Printf("target %q: get request using encoding %s failed: %v, trying encoding %s", tName, enc, err, encodings[i+1])
+		}
+	}
+	return nil, err
+}
+
+// splitGet sends one GetRequest per path in req, a path whose response exceeds the max message size
+// is split on its first list with keys into one GetRequest per list entry.
+// The responses notifications are merged into a single GetResponse.
+func (a *App) splitGet(ctx context.Context, tName string, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	response := new(gnmi.GetResponse)
+	for _, p := range req.GetPath() {
+		preq := proto.Clone(req).(*gnmi.GetRequest)
+		preq.Path = []*gnmi.Path{p}
+		rsp, err := a.collector.Get(ctx, tName, preq)
+		if isResourceExhausted(err) {
+			rsp, err = a.splitPathGet(ctx, tName, preq)
+		}
+		if err != nil {
+			return nil, err
+		}
+		response.Notification = append(response.Notification, rsp.GetNotification()...)
+		response.Extension = append(response.Extension, rsp.GetExtension()...)
+	}
+	return response, nil
+}
+
+// splitPathGet retrieves the entries of the first list with a wildcard key found in the single path of req,
+// by requesting its key leaves only, then sends one GetRequest per list entry.
+func (a *App) splitPathGet(ctx context.Context, tName string, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	p := req.GetPath()[0]
+	idx := splitListIndex(p)
+	if idx < 0 {
+		return nil, fmt.Errorf("get response too large and path '%v' has no list with a wildcard key to split on", p)
+	}
+	entries, err := a.getListEntries(ctx, tName, req, idx)
+	if err != nil {
+		return nil, err
+	}
+	a.Logger.Printf("target %q: splitting path '%v' into %d requests on list %q", tName, p, len(entries), p.GetElem()[idx].GetName())
+	response := new(gnmi.GetResponse)
+	for _, keys := range entries {
+		ereq := proto.Clone(req).(*gnmi.GetRequest)
+		ereq.Path[0].Elem[idx].Key = keys
+		rsp, err := a.collector.Get(ctx, tName, ereq)
+		if err != nil {
+			return nil, err
+		}
+		response.Notification = append(response.Notification, rsp.GetNotification()...)
+		response.Extension = append(response.Extension, rsp.GetExtension()...)
+	}
+	return response, nil
+}
+
+// splitListIndex returns the index of the first element of p with a wildcard key, -1 if there is none.
+// The lists with all their keys set select a single entry, they cannot be split.
+func splitListIndex(p *gnmi.Path) int {
+	for i, e := range p.GetElem() {
+		for _, v := range e.GetKey() {
+			if v == "*" {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// getListEntries returns the keys of the entries of the list at index idx of the single path of req,
+// by requesting one of the list key leaves.
+func (a *App) getListEntries(ctx context.Context, tName string, req *gnmi.GetRequest, idx int) ([]map[string]string, error) {
+	list := req.GetPath()[0].GetElem()[idx]
+	keyNames := make([]string, 0, len(list.GetKey()))
+	for k := range list.GetKey() {
+		keyNames = append(keyNames, k)
+	}
+	sort.Strings(keyNames)
+	kreq := proto.Clone(req).(*gnmi.GetRequest)
+	kreq.Path[0].Elem = append(kreq.Path[0].Elem[:idx+1], &gnmi.PathElem{Name: keyNames[0]})
+	rsp, err := a.collector.Get(ctx, tName, kreq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list %q keys: %v", list.GetName(), err)
+	}
+	// index of the list in the paths returned by the target, which include the prefix elements
+	offset := len(req.GetPrefix().GetElem()) + idx
+	entries := make([]map[string]string, 0)
+	seen := make(map[string]struct{})
+	for _, n := range rsp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			elems := append(append([]*gnmi.PathElem{}, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+			if len(elems) <= offset || elems[offset].GetName() != list.GetName() || len(elems[offset].GetKey()) == 0 {
+				continue
+			}
+			keys := elems[offset].GetKey()
+			entry := listEntryKey(keys)
+			if _, ok := seen[entry]; ok {
+				continue
+			}
+			seen[entry] = struct{}{}
+			entries = append(entries, keys)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries found for list %q", list.GetName())
+	}
+	return entries, nil
+}
+
+// isResourceExhausted returns true if err wraps a gRPC status error with code ResourceExhausted,
+// e.g when a response exceeds the max message size.
+func isResourceExhausted(err error) bool {
+	var se interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &se) && se.GRPCStatus().Code() == codes.ResourceExhausted
+}
+
+// listEntryKey returns a string identifying a list entry from its keys.
+func listEntryKey(keys map[string]string) string {
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	sb := strings.Builder{}
+	for _, k := range names {
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(keys[k])
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// supportedEncodings returns the encodings supported by the target, in the order they are listed in encodings.
+// encodings is returned as is if the target capabilities cannot be retrieved or if none of the encodings is supported.
+func (a *App) supportedEncodings(ctx context.Context, tName string, encodings []gnmi.Encoding) []gnmi.Encoding {
+	capRsp, err := a.collector.Capabilities(ctx, tName)
+	if err != nil {
+		a.Logger.Printf("target %q: failed to get capabilities, trying all encodings: %v", tName, err)
+		return encodings
+	}
+	supported := make([]gnmi.Encoding, 0, len(encodings))
+	for _, enc := range encodings {
+		for _, senc := range capRsp.GetSupportedEncodings() {
+			if enc == senc {
+				supported = append(supported, enc)
+				break
+			}
+		}
+	}
+	if len(supported) == 0 {
+		a.Logger.Printf("target %q: none of the encodings %v is listed in the target capabilities, trying all encodings", tName, encodings)
+		return encodings
+	}
+	return supported
+}
+
 func (a *App) filterModels(ctx context.Context, tName string, modelsNames []string) (map[string]*gnmi.ModelData, []string, error) {
 	supModels, err := a.collector.GetModels(ctx, tName)
 	if err != nil {
@@ -123,8 +327,11 @@ func (a *App) InitGetFlags(cmd *cobra.Command) {
 	cmd.MarkFlagRequired("path")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetPrefix, "prefix", "", "", "get request prefix")
 	cmd.Flags().StringSliceVarP(&a.Config.LocalFlags.GetModel, "model", "", []string{}, "get request models")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.GetUseModel, "use-model", "", []string{}, "get request use_models, in the format name[@version]")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetType, "type", "t", "ALL", "data type requested from the target. one of: ALL, CONFIG, STATE, OPERATIONAL")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetTarget, "target", "", "", "get request target")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.GetValuesOnly, "values-only", "", false, "print only the values of the response leaves, one per line")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.GetPathsOnly, "paths-only", "", false, "print only the paths of the response leaves, one per line")
 
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
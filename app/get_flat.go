@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	flattener "github.com/karimra/go-map-flattener"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/value"
+)
+
+// flatValue is a leaf of a GetResponse, identified by its xpath
+type flatValue struct {
+	path  string
+	value interface{}
+}
+
+// flattenGetResponse returns the leaves of a GetResponse in the order they were received.
+// JSON values are flattened into one leaf per JSON leaf, sorted by path.
+func flattenGetResponse(rsp *gnmi.GetResponse) ([]*flatValue, error) {
+	fvs := make([]*flatValue, 0)
+	for _, n := range rsp.GetNotification() {
+		prefix := xpathFromGNMIPath(n.GetPrefix())
+		for _, upd := range n.GetUpdate() {
+			p := strings.TrimRight(prefix, "/") + xpathFromGNMIPath(upd.GetPath())
+			if p == "" {
+				p = "/"
+			}
+			vs, err := flattenTypedValue(p, upd.GetVal())
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %v", p, err)
+			}
+			fvs = append(fvs, vs...)
+		}
+	}
+	return fvs, nil
+}
+
+func flattenTypedValue(p string, tv *gnmi.TypedValue) ([]*flatValue, error) {
+	var jsondata []byte
+	switch tv.GetValue().(type) {
+	case nil:
+		return nil, nil
+	case *gnmi.TypedValue_JsonIetfVal:
+		jsondata = tv.GetJsonIetfVal()
+	case *gnmi.TypedValue_JsonVal:
+		jsondata = tv.GetJsonVal()
+	default:
+		v, err := value.ToScalar(tv)
+		if err != nil {
+			return nil, err
+		}
+		return []*flatValue{{path: p, value: v}}, nil
+	}
+	var v interface{}
+	err := json.Unmarshal(jsondata, &v)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return []*flatValue{{path: p, value: v}}, nil
+	}
+	f := flattener.NewFlattener()
+	f.SetPrefix(strings.TrimRight(p, "/"))
+	values, err := f.Flatten(m)
+	if err != nil {
+		return nil, err
+	}
+	fvs := make([]*flatValue, 0, len(values))
+	for k, v := range values {
+		fvs = append(fvs, &flatValue{path: k, value: v})
+	}
+	sort.Slice(fvs, func(i, j int) bool {
+		return fvs[i].path < fvs[j].path
+	})
+	return fvs, nil
+}
+
+// xpathFromGNMIPath returns the xpath representation of p, with the keys sorted by name.
+func xpathFromGNMIPath(p *gnmi.Path) string {
+	sb := strings.Builder{}
+	if p.GetOrigin() != "" {
+		sb.WriteString(p.GetOrigin())
+		sb.WriteString(":")
+	}
+	for _, pe := range p.GetElem() {
+		sb.WriteString("/")
+		sb.WriteString(pe.GetName())
+		keys := make([]string, 0, len(pe.GetKey()))
+		for k := range pe.GetKey() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sb.WriteString("[")
+			sb.WriteString(k)
+			sb.WriteString("=")
+			sb.WriteString(pe.GetKey()[k])
+			sb.WriteString("]")
+		}
+	}
+	return sb.String()
+}
+
+// printFlatResponse prints the paths or the values of the GetResponse leaves, one per line.
+func (a *App) printFlatResponse(tName string, rsp *gnmi.GetResponse) error {
+	fvs, err := flattenGetResponse(rsp)
+	if err != nil {
+		return err
+	}
+	printPrefix := ""
+	// the targets were read by the command, reading them again from concurrent prints races
+	if len(a.Config.Targets) > 1 && !a.Config.NoPrefix {
+		printPrefix = fmt.Sprintf("[%s] ", tName)
+	}
+	sb := strings.Builder{}
+	for _, fv := range fvs {
+		sb.WriteString(printPrefix)
+		if a.Config.LocalFlags.GetPathsOnly {
+			sb.WriteString(fv.path)
+		} else {
+			sb.WriteString(flatValueString(fv.value))
+		}
+		sb.WriteString("\n")
+	}
+	a.printLock.Lock()
+	defer a.printLock.Unlock()
+	fmt.Fprint(a.out, sb.String())
+	return nil
+}
+
+func flatValueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// encodingGNMIServer rejects the Get requests using an unsupported encoding
+type encodingGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	capabilities []gnmi.Encoding
+	supported    gnmi.Encoding
+
+	m        *sync.Mutex
+	received []gnmi.Encoding
+}
+
+func (s *encodingGNMIServer) Capabilities(ctx context.Context, req *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	if s.capabilities == nil {
+		return nil, status.Error(codes.Unimplemented, "capabilities not implemented")
+	}
+	return &gnmi.CapabilityResponse{SupportedEncodings: s.capabilities}, nil
+}
+
+func (s *encodingGNMIServer) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	s.m.Lock()
+	s.received = append(s.received, req.GetEncoding())
+	s.m.Unlock()
+	if req.GetEncoding() != s.supported {
+		return nil, status.Errorf(codes.Unimplemented, "unsupported encoding %s", req.GetEncoding())
+	}
+	return &gnmi.GetResponse{
+		Notification: []*gnmi.Notification{{
+			Update: []*gnmi.Update{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "hostname"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`"router1"`)}},
+			}},
+		}},
+	}, nil
+}
+
+func TestGetWithEncodings(t *testing.T) {
+	tests := map[string]struct {
+		capabilities []gnmi.Encoding
+		want         []gnmi.Encoding
+	}{
+		"no_capabilities": {
+			want: []gnmi.Encoding{gnmi.Encoding_JSON_IETF, gnmi.Encoding_JSON},
+		},
+		"capabilities": {
+			capabilities: []gnmi.Encoding{gnmi.Encoding_JSON, gnmi.Encoding_PROTO},
+			want:         []gnmi.Encoding{gnmi.Encoding_JSON},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &encodingGNMIServer{
+				capabilities: tc.capabilities,
+				supported:    gnmi.Encoding_JSON,
+				m:            new(sync.Mutex),
+			}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gs := grpc.NewServer()
+			gnmi.RegisterGNMIServer(gs, s)
+			go gs.Serve(l)
+			defer gs.Stop()
+
+			insecure := true
+			a := New()
+			a.collector = collector.NewCollector(&collector.Config{},
+				map[string]*collector.TargetConfig{
+					"target1": {
+						Name:     "target1",
+						Address:  l.Addr().String(),
+						Timeout:  5 * time.Second,
+						Insecure: &insecure,
+					},
+				},
+				collector.WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+				collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+			)
+			req := &gnmi.GetRequest{Encoding: gnmi.Encoding_ASCII}
+			rsp, err := a.getWithEncodings(context.Background(), "target1", req,
+				[]gnmi.Encoding{gnmi.Encoding_JSON_IETF, gnmi.Encoding_JSON})
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			// the request can be shared by multiple targets, it must not be modified
+			if req.Encoding != gnmi.Encoding_ASCII {
+				t.Errorf("expected the request encoding to be unchanged, got %v", req.Encoding)
+			}
+			if v := rsp.GetNotification()[0].GetUpdate()[0].GetVal().GetJsonVal(); string(v) != `"router1"` {
+				t.Errorf("expected a JSON encoded value, got %v", rsp)
+			}
+			if len(s.received) != len(tc.want) {
+				t.Fatalf("expected requests with encodings %v, got %v", tc.want, s.received)
+			}
+			for i := range tc.want {
+				if s.received[i] != tc.want[i] {
+					t.Errorf("expected requests with encodings %v, got %v", tc.want, s.received)
+				}
+			}
+		})
+	}
+}
+
+// listGNMIServer serves a list of interfaces, each with a large description
+type listGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	names []string
+
+	m        *sync.Mutex
+	received []*gnmi.Path
+}
+
+func (s *listGNMIServer) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	p := req.GetPath()[0]
+	s.m.Lock()
+	s.received = append(s.received, p)
+	s.m.Unlock()
+	notif := &gnmi.Notification{}
+	for _, name := range s.names {
+		if k := p.GetElem()[1].GetKey()["name"]; k != "*" && k != name {
+			continue
+		}
+		elems := []*gnmi.PathElem{{Name: "interfaces"}, {Name: "interface", Key: map[string]string{"name": name}}}
+		if len(p.GetElem()) > 2 && p.GetElem()[2].GetName() == "name" {
+			notif.Update = append(notif.Update, &gnmi.Update{
+				Path: &gnmi.Path{Elem: append(elems, &gnmi.PathElem{Name: "name"})},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: name}},
+			})
+			continue
+		}
+		notif.Update = append(notif.Update, &gnmi.Update{
+			Path: &gnmi.Path{Elem: append(elems, &gnmi.PathElem{Name: "description"})},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: strings.Repeat("x", 1024)}},
+		})
+	}
+	return &gnmi.GetResponse{Notification: []*gnmi.Notification{notif}}, nil
+}
+
+func TestGetSplitPath(t *testing.T) {
+	s := &listGNMIServer{
+		names: []string{"eth1", "eth2", "eth3"},
+		m:     new(sync.Mutex),
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	defer gs.Stop()
+
+	insecure := true
+	a := New()
+	a.collector = collector.NewCollector(&collector.Config{},
+		map[string]*collector.TargetConfig{
+			"target1": {
+				Name:     "target1",
+				Address:  l.Addr().String(),
+				Timeout:  5 * time.Second,
+				Insecure: &insecure,
+			},
+		},
+		collector.WithDialOptions([]grpc.DialOption{
+			grpc.WithBlock(),
+			// a single interface fits in the max message size, the whole list does not
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(2048)),
+		}),
+		collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	p, err := collector.ParsePath("/interfaces/interface[name=*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := a.getWithEncodings(context.Background(), "target1", &gnmi.GetRequest{Path: []*gnmi.Path{p}},
+		[]gnmi.Encoding{gnmi.Encoding_JSON})
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	got := make([]string, 0)
+	for _, n := range rsp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			got = append(got, u.GetPath().GetElem()[1].GetKey()["name"])
+		}
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, s.names) {
+		t.Errorf("expected the updates of interfaces %v, got %v", s.names, got)
+	}
+	// the broad request, the same path alone, the list keys and one request per interface
+	if len(s.received) != 6 {
+		t.Errorf("expected 6 requests, got %d: %v", len(s.received), s.received)
+	}
+}
+
+func TestSplitListIndex(t *testing.T) {
+	tests := map[string]int{
+		"/interfaces":                              -1,
+		"/interfaces/interface[name=*]":            1,
+		"/interfaces/interface[name=ethernet-1/1]": -1,
+		"/interfaces/interface[name=ethernet-1/1]/subinterfaces/subinterface[index=*]":                 3,
+		"/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=*]": 3,
+	}
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			p, err := collector.ParsePath(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := splitListIndex(p); got != want {
+				t.Errorf("got index %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPrintFlatResponse(t *testing.T) {
+	rsp := &gnmi.GetResponse{
+		Notification: []*gnmi.Notification{{
+			Prefix: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+						{Name: "description"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "uplink"}},
+				},
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "ethernet-1/2"}},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{
+						JsonIetfVal: []byte(`{"mtu":9212,"admin-state":"enable","counters":{"in-octets":"42"}}`),
+					}},
+				},
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "ethernet-1/2"}},
+						{Name: "enabled"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: true}},
+				},
+			},
+		}},
+	}
+	tests := map[string]struct {
+		addresses  []string
+		valuesOnly bool
+		want       string
+	}{
+		"paths_only": {
+			addresses: []string{"router1"},
+			want: `/interfaces/interface[name=ethernet-1/1]/description
+/interfaces/interface[name=ethernet-1/2]/admin-state
+/interfaces/interface[name=ethernet-1/2]/counters/in-octets
+/interfaces/interface[name=ethernet-1/2]/mtu
+/interfaces/interface[name=ethernet-1/2]/enabled
+`,
+		},
+		"values_only": {
+			addresses:  []string{"router1"},
+			valuesOnly: true,
+			want: `uplink
+enable
+42
+9212
+true
+`,
+		},
+		"values_only_prefixed": {
+			addresses:  []string{"router1", "router2"},
+			valuesOnly: true,
+			want: `[router1] uplink
+[router1] enable
+[router1] 42
+[router1] 9212
+[router1] true
+`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := New()
+			out := new(strings.Builder)
+			a.out = out
+			a.Config.Address = tc.addresses
+			a.Config.Username = "admin"
+			a.Config.Password = "admin"
+			a.Config.LocalFlags.GetValuesOnly = tc.valuesOnly
+			a.Config.LocalFlags.GetPathsOnly = !tc.valuesOnly
+			// the command reads the targets before printing
+			if _, err := a.Config.GetTargets(); err != nil {
+				t.Fatal(err)
+			}
+			err := a.printFlatResponse("router1", rsp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestGetPrintRequest(t *testing.T) {
+	s := &encodingGNMIServer{supported: gnmi.Encoding_JSON, m: new(sync.Mutex)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	defer gs.Stop()
+
+	insecure := true
+	a := New()
+	out := new(strings.Builder)
+	a.out = out
+	a.errCh = make(chan error, 2)
+	a.Config.Encoding = "json"
+	a.Config.PrintRequest = true
+	a.collector = collector.NewCollector(&collector.Config{},
+		map[string]*collector.TargetConfig{
+			"target1": {
+				Name:     "target1",
+				Address:  l.Addr().String(),
+				Timeout:  5 * time.Second,
+				Insecure: &insecure,
+			},
+		},
+		collector.WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+		collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	req := &gnmi.GetRequest{
+		Path:     []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "name"}}}},
+		Type:     gnmi.GetRequest_STATE,
+		Encoding: gnmi.Encoding_JSON,
+	}
+	mo := formatters.MarshalOptions{Multiline: true, Indent: "  "}
+	want, err := mo.Marshal(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.wg.Add(1)
+	a.GetRequest(context.Background(), "target1", req)
+	if err := a.checkErrors(); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), string(want)+"\n") {
+		t.Errorf("expected the output to start with the GetRequest:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+)
+
+// socks5Server is a minimal SOCKS5 proxy supporting the CONNECT command,
+// with username/password authentication if username is set.
+type socks5Server struct {
+	username string
+	password string
+
+	m         *sync.Mutex
+	connected []string
+}
+
+func (s *socks5Server) start(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func (s *socks5Server) handle(conn net.Conn) {
+	defer conn.Close()
+	// greeting: version, number of methods, methods
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	methods := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	if s.username == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		// username/password sub negotiation: version, ulen, username, plen, password
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		user := make([]byte, buf[1])
+		if _, err := io.ReadFull(conn, user); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		pass := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, pass); err != nil {
+			return
+		}
+		if string(user) != s.username || string(pass) != s.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+	// request: version, command, reserved, address type, address, port
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		name := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf))))
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	s.m.Lock()
+	s.connected = append(s.connected, addr)
+	s.m.Unlock()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestProxyDialer(t *testing.T) {
+	tests := map[string]struct {
+		proxyUser string
+		user      string
+		wantErr   bool
+	}{
+		"no_auth": {},
+		"auth": {
+			proxyUser: "admin",
+			user:      "admin:secret@",
+		},
+		"wrong_credentials": {
+			proxyUser: "admin",
+			user:      "admin:wrong@",
+			wantErr:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gs := grpc.NewServer()
+			gnmi.RegisterGNMIServer(gs, &encodingGNMIServer{
+				capabilities: []gnmi.Encoding{gnmi.Encoding_JSON},
+				m:            new(sync.Mutex),
+			})
+			go gs.Serve(l)
+			defer gs.Stop()
+
+			s := &socks5Server{username: tc.proxyUser, password: "secret", m: new(sync.Mutex)}
+			proxyAddr := s.start(t)
+
+			a := New()
+			a.proxyDialer, err = newProxyDialer("socks5://" + tc.user + proxyAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			insecure := true
+			a.collector = collector.NewCollector(&collector.Config{},
+				map[string]*collector.TargetConfig{
+					"target1": {
+						Name:     "target1",
+						Address:  l.Addr().String(),
+						Timeout:  time.Second,
+						Insecure: &insecure,
+					},
+				},
+				collector.WithDialOptions(a.createCollectorDialOpts()),
+				collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+			)
+			rsp, err := a.collector.Capabilities(context.Background(), "target1")
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected the proxy to reject the connection, got %v", rsp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("capabilities request failed: %v", err)
+			}
+			if len(rsp.GetSupportedEncodings()) != 1 {
+				t.Errorf("unexpected capabilities response: %v", rsp)
+			}
+			s.m.Lock()
+			defer s.m.Unlock()
+			if len(s.connected) == 0 || s.connected[0] != l.Addr().String() {
+				t.Errorf("expected the proxy to connect to %s, got %v", l.Addr(), s.connected)
+			}
+		})
+	}
+}
+
+func TestNewProxyDialerInvalid(t *testing.T) {
+	for _, u := range []string{"http://proxy:3128", "socks5://", "://proxy"} {
+		if _, err := newProxyDialer(u); err == nil {
+			t.Errorf("expected an error for proxy URL %q", u)
+		}
+	}
+}
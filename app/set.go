@@ -3,20 +3,31 @@ package app
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/karimra/gnmic/collector"
 	"github.com/karimra/gnmic/config"
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
 )
 
+// maxSetRate is the highest --set-rate, one request per nanosecond
+const maxSetRate = float64(time.Second)
+
 func (a *App) SetRun(cmd *cobra.Command, args []string) error {
 	defer a.InitSetFlags(cmd)
 
 	if a.Config.Format == "event" {
 		return fmt.Errorf("format event not supported for Set RPC")
 	}
+	// the requests are paced by a ticker, its interval must be at least 1ns
+	if a.Config.SetRate < 0 || a.Config.SetRate > maxSetRate {
+		return fmt.Errorf("invalid set-rate %v, must be between 0 and %v", a.Config.SetRate, maxSetRate)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	// setupCloseHandler(cancel)
@@ -29,16 +40,19 @@ func (a *App) SetRun(cmd *cobra.Command, args []string) error {
 	}
 	if a.collector == nil {
 		cfg := &collector.Config{
+			PrometheusAddress:   a.Config.PrometheusAddress,
 			Debug:               a.Config.Debug,
 			Format:              a.Config.Format,
 			TargetReceiveBuffer: a.Config.TargetBufferSize,
 			RetryTimer:          a.Config.Retry,
+			ClientIdleTimeout:   a.Config.ClientIdleTimeout,
 		}
 
 		a.collector = collector.NewCollector(cfg, targetsConfig,
 			collector.WithDialOptions(a.createCollectorDialOpts()),
 			collector.WithLogger(a.Logger),
 		)
+		a.collector.StartMetricsServer()
 	} else {
 		// prompt mode
 		for _, tc := range targetsConfig {
@@ -49,14 +63,30 @@ func (a *App) SetRun(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	a.sendSetRequests(ctx, req)
+	return a.checkErrors()
+}
+
+// sendSetRequests sends req to all the targets and waits for the responses,
+// if --set-rate is set the requests are sent at a fixed interval of 1/set-rate seconds.
+func (a *App) sendSetRequests(ctx context.Context, req *gnmi.SetRequest) {
+	var limiter *time.Ticker
+	if a.Config.SetRate > 0 {
+		limiter = time.NewTicker(time.Duration(float64(time.Second) / a.Config.SetRate))
+		defer limiter.Stop()
+	}
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*2)
 	a.wg.Add(numTargets)
+	i := 0
 	for tName := range a.Config.Targets {
+		if limiter != nil && i > 0 {
+			<-limiter.C
+		}
+		i++
 		go a.SetRequest(ctx, tName, req)
 	}
 	a.wg.Wait()
-	return a.checkErrors()
 }
 
 func (a *App) SetRequest(ctx context.Context, tName string, req *gnmi.SetRequest) {
@@ -65,6 +95,10 @@ func (a *App) SetRequest(ctx context.Context, tName string, req *gnmi.SetRequest
 }
 
 func (a *App) setRequest(ctx context.Context, tName string, req *gnmi.SetRequest) {
+	if a.Config.LocalFlags.SetDryRun {
+		a.setDryRun(ctx, tName, req)
+		return
+	}
 	a.Logger.Printf("sending gNMI SetRequest: prefix='%v', delete='%v', replace='%v', update='%v', extension='%v' to %s",
 		req.Prefix, req.Delete, req.Replace, req.Update, req.Extension, tName)
 	if a.Config.PrintRequest {
@@ -84,6 +118,75 @@ func (a *App) setRequest(ctx context.Context, tName string, req *gnmi.SetRequest
 	}
 }
 
+// setDryRun prints the SetRequest that would be sent to the target.
+// If --validate-ext is set and the target advertises that registered extension in its capabilities,
+// the SetRequest is sent with the extension added, for the target to validate it without applying it.
+// Otherwise the request is not sent.
+func (a *App) setDryRun(ctx context.Context, tName string, req *gnmi.SetRequest) {
+	ext, err := a.validateExtension(ctx, tName)
+	if err != nil {
+		a.logError(fmt.Errorf("target %q: %v", tName, err))
+	}
+	if ext == nil {
+		a.Logger.Printf("dry-run: gNMI SetRequest not sent to %s: prefix='%v', delete='%v', replace='%v', update='%v', extension='%v'",
+			tName, req.Prefix, req.Delete, req.Replace, req.Update, req.Extension)
+		path := "SetRequest not sent"
+		if id := a.Config.LocalFlags.SetValidateExt; id != 0 && err == nil {
+			path = fmt.Sprintf("SetRequest not sent, extension %d is not advertised by the target", id)
+		}
+		a.printDryRunPath(tName, path)
+		err = a.PrintMsg(tName, "Set Request:", req)
+		if err != nil {
+			a.logError(fmt.Errorf("target %q: %v", tName, err))
+		}
+		return
+	}
+	vreq := proto.Clone(req).(*gnmi.SetRequest)
+	vreq.Extension = append(vreq.Extension, ext)
+	a.Logger.Printf("dry-run: sending gNMI SetRequest to %s for validation only: prefix='%v', delete='%v', replace='%v', update='%v', extension='%v'",
+		tName, vreq.Prefix, vreq.Delete, vreq.Replace, vreq.Update, vreq.Extension)
+	a.printDryRunPath(tName, fmt.Sprintf("SetRequest sent for validation only, with extension %d", a.Config.LocalFlags.SetValidateExt))
+	err = a.PrintMsg(tName, "Set Request:", vreq)
+	if err != nil {
+		a.logError(fmt.Errorf("target %q: %v", tName, err))
+	}
+	response, err := a.collector.Set(ctx, tName, vreq)
+	if err != nil {
+		a.logError(fmt.Errorf("target %q validate-only set request failed: %v", tName, err))
+		return
+	}
+	err = a.PrintMsg(tName, "Set Response:", response)
+	if err != nil {
+		a.logError(fmt.Errorf("target %q: %v", tName, err))
+	}
+}
+
+// validateExtension returns the registered extension with ID --validate-ext
+// advertised by the target in its capabilities, nil if --validate-ext is not set or the target does not advertise it.
+func (a *App) validateExtension(ctx context.Context, tName string) (*gnmi_ext.Extension, error) {
+	id := a.Config.LocalFlags.SetValidateExt
+	if id == 0 {
+		return nil, nil
+	}
+	rsp, err := a.collector.Capabilities(ctx, tName)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities request failed: %v", err)
+	}
+	for _, ext := range rsp.GetExtension() {
+		if rext := ext.GetRegisteredExt(); rext != nil && int32(rext.GetId()) == id {
+			return ext, nil
+		}
+	}
+	return nil, nil
+}
+
+// printDryRunPath prints to stderr whether the dry-run SetRequest was sent to target tName.
+func (a *App) printDryRunPath(tName, path string) {
+	a.printLock.Lock()
+	defer a.printLock.Unlock()
+	fmt.Fprintf(os.Stderr, "target %q: dry-run, %s\n", tName, path)
+}
+
 // InitSetFlags used to init or reset setCmd flags for gnmic-prompt mode
 func (a *App) InitSetFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
@@ -93,6 +196,7 @@ func (a *App) InitSetFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetDelete, "delete", "", []string{}, "set request path to be deleted")
 
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetReplace, "replace", "", []string{}, fmt.Sprintf("set request path:::type:::value to be replaced, type must be one of %v", config.ValueTypes))
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetUnionReplace, "union-replace", "", []string{}, fmt.Sprintf("set request path:::type:::value to be union replaced, type must be one of %v", config.ValueTypes))
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetUpdate, "update", "", []string{}, fmt.Sprintf("set request path:::type:::value to be updated, type must be one of %v", config.ValueTypes))
 
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetReplacePath, "replace-path", "", []string{}, "set request path to be replaced")
@@ -103,6 +207,8 @@ func (a *App) InitSetFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetReplaceValue, "replace-value", "", []string{}, "set replace request value")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SetDelimiter, "delimiter", "", ":::", "set update/replace delimiter between path, type, value")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SetTarget, "target", "", "", "set request target")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SetDryRun, "dry-run", "", false, "print the set request instead of sending it to the targets")
+	cmd.Flags().Int32VarP(&a.Config.LocalFlags.SetValidateExt, "validate-ext", "", 0, "with --dry-run, registered extension ID the targets advertise in their capabilities for validate-only set requests")
 
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
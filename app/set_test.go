@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// setGNMIServer counts the received Set requests,
+// it advertises exts in its capabilities
+type setGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	m    *sync.Mutex
+	sets int
+	exts []*gnmi_ext.Extension
+	// extensions of the last Set request
+	setExts []*gnmi_ext.Extension
+}
+
+func (s *setGNMIServer) Capabilities(ctx context.Context, req *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	return &gnmi.CapabilityResponse{Extension: s.exts}, nil
+}
+
+func (s *setGNMIServer) Set(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.sets++
+	s.setExts = req.GetExtension()
+	return &gnmi.SetResponse{
+		Response: []*gnmi.UpdateResult{{
+			Path: req.GetUpdate()[0].GetPath(),
+			Op:   gnmi.UpdateResult_UPDATE,
+		}},
+	}, nil
+}
+
+func TestSetDryRun(t *testing.T) {
+	req := &gnmi.SetRequest{
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "name"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "router1"}},
+		}},
+	}
+	validateExt := &gnmi_ext.Extension{
+		Ext: &gnmi_ext.Extension_RegisteredExt{
+			RegisteredExt: &gnmi_ext.RegisteredExtension{Id: gnmi_ext.ExtensionID_EID_EXPERIMENTAL, Msg: []byte("validate")},
+		},
+	}
+	tests := map[string]struct {
+		dryRun      bool
+		validateExt int32
+		wantSets    int
+		wantExts    int
+	}{
+		"dry_run": {
+			dryRun:   true,
+			wantSets: 0,
+		},
+		"dry_run_validate": {
+			dryRun:      true,
+			validateExt: 999,
+			wantSets:    1,
+			wantExts:    1,
+		},
+		"dry_run_validate_not_advertised": {
+			dryRun:      true,
+			validateExt: 1000,
+			wantSets:    0,
+		},
+		"set": {
+			dryRun:   false,
+			wantSets: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &setGNMIServer{m: new(sync.Mutex), exts: []*gnmi_ext.Extension{validateExt}}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gs := grpc.NewServer()
+			gnmi.RegisterGNMIServer(gs, s)
+			go gs.Serve(l)
+			defer gs.Stop()
+
+			insecure := true
+			a := New()
+			out := new(strings.Builder)
+			a.out = out
+			a.errCh = make(chan error, 1)
+			a.Config.LocalFlags.SetDryRun = tc.dryRun
+			a.Config.LocalFlags.SetValidateExt = tc.validateExt
+			a.collector = collector.NewCollector(&collector.Config{},
+				map[string]*collector.TargetConfig{
+					"target1": {
+						Name:     "target1",
+						Address:  l.Addr().String(),
+						Timeout:  5 * time.Second,
+						Insecure: &insecure,
+					},
+				},
+				collector.WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+				collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+			)
+			a.setRequest(context.Background(), "target1", req)
+			if err := a.checkErrors(); err != nil {
+				t.Fatalf("set failed: %v", err)
+			}
+			s.m.Lock()
+			defer s.m.Unlock()
+			if s.sets != tc.wantSets {
+				t.Errorf("expected %d Set RPC(s), got %d", tc.wantSets, s.sets)
+			}
+			// the validate-only request carries the extension advertised by the target
+			if len(s.setExts) != tc.wantExts {
+				t.Errorf("expected %d extension(s) in the SetRequest, got %v", tc.wantExts, s.setExts)
+			}
+			if tc.wantExts > 0 && !proto.Equal(s.setExts[0], validateExt) {
+				t.Errorf("expected extension %v, got %v", validateExt, s.setExts[0])
+			}
+			if tc.dryRun && !strings.Contains(out.String(), "router1") {
+				t.Errorf("expected the SetRequest to be printed, got: %s", out.String())
+			}
+		})
+	}
+}
+
+func TestSetPrintRequest(t *testing.T) {
+	s := &setGNMIServer{m: new(sync.Mutex)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	defer gs.Stop()
+
+	insecure := true
+	a := New()
+	out := new(strings.Builder)
+	a.out = out
+	a.errCh = make(chan error, 1)
+	a.Config.PrintRequest = true
+	a.collector = collector.NewCollector(&collector.Config{},
+		map[string]*collector.TargetConfig{
+			"target1": {
+				Name:     "target1",
+				Address:  l.Addr().String(),
+				Timeout:  5 * time.Second,
+				Insecure: &insecure,
+			},
+		},
+		collector.WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+		collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	req := &gnmi.SetRequest{
+		Delete: []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "location"}}}},
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "name"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "router1"}},
+		}},
+	}
+	mo := formatters.MarshalOptions{Multiline: true, Indent: "  "}
+	want, err := mo.Marshal(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.setRequest(context.Background(), "target1", req)
+	if err := a.checkErrors(); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), string(want)+"\n") {
+		t.Errorf("expected the output to start with the SetRequest:\n%s\ngot:\n%s", want, out.String())
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.sets != 1 {
+		t.Errorf("expected the SetRequest to be sent once, got %d", s.sets)
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	s := &setGNMIServer{m: new(sync.Mutex)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	defer gs.Stop()
+
+	req := &gnmi.SetRequest{
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "name"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "router1"}},
+		}},
+	}
+	insecure := true
+	numTargets := 4
+	targets := make(map[string]*collector.TargetConfig)
+	for i := 0; i < numTargets; i++ {
+		name := fmt.Sprintf("target%d", i)
+		targets[name] = &collector.TargetConfig{
+			Name:     name,
+			Address:  l.Addr().String(),
+			Timeout:  5 * time.Second,
+			Insecure: &insecure,
+		}
+	}
+	a := New()
+	a.out = ioutil.Discard
+	a.Config.SetRate = 20
+	a.Config.Targets = targets
+	a.collector = collector.NewCollector(&collector.Config{}, targets,
+		collector.WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+		collector.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	start := time.Now()
+	a.sendSetRequests(context.Background(), req)
+	elapsed := time.Since(start)
+	if err := a.checkErrors(); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.sets != numTargets {
+		t.Errorf("expected %d Set RPCs, got %d", numTargets, s.sets)
+	}
+	// the first request is sent right away, the others every 50ms
+	if min := time.Duration(numTargets-1) * 50 * time.Millisecond; elapsed < min {
+		t.Errorf("expected the Set RPCs to take at least %s, took %s", min, elapsed)
+	}
+}
+
+func TestSetRateInvalid(t *testing.T) {
+	for _, rate := range []float64{-1, 2e9} {
+		a := New()
+		a.Config.SetRate = rate
+		err := a.SetRun(&cobra.Command{Use: "set"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "invalid set-rate") {
+			t.Errorf("set-rate %v: expected an invalid set-rate error, got %v", rate, err)
+		}
+	}
+}
@@ -11,9 +11,7 @@ import (
 
 	"github.com/karimra/gnmic/collector"
 	"github.com/karimra/gnmic/config"
-	"github.com/karimra/gnmic/formatters"
 	"github.com/manifoldco/promptui"
-	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -77,6 +75,8 @@ func (a *App) SubscribeRun(cmd *cobra.Command, args []string) error {
 	a.collector = collector.NewCollector(a.collectorConfig(), targetsConfig, cOpts...)
 
 	a.startAPI()
+	a.startAdmin()
+	a.startControlSocket()
 	go a.startCluster()
 	a.startIO()
 
@@ -95,11 +95,13 @@ func (a *App) SubscribeRun(cmd *cobra.Command, args []string) error {
 
 func (a *App) subscribeStream(ctx context.Context, name string) {
 	defer a.wg.Done()
+	a.printSubscribeRequests(name)
 	a.collector.TargetSubscribeStream(ctx, name)
 }
 
 func (a *App) subscribeOnce(ctx context.Context, name string) {
 	defer a.wg.Done()
+	a.printSubscribeRequests(name)
 	err := a.collector.TargetSubscribeOnce(ctx, name)
 	if err != nil {
 		a.logError(err)
@@ -108,9 +110,34 @@ func (a *App) subscribeOnce(ctx context.Context, name string) {
 
 func (a *App) subscribePoll(ctx context.Context, name string) {
 	defer a.wg.Done()
+	a.printSubscribeRequests(name)
 	a.collector.TargetSubscribePoll(ctx, name)
 }
 
+// printSubscribeRequests prints the SubscribeRequests sent to the target if print-request is set.
+func (a *App) printSubscribeRequests(name string) {
+	if !a.Config.PrintRequest {
+		return
+	}
+	// the errors are only logged, the subscription itself reports them
+	reqs, err := a.collector.TargetSubscribeRequests(name)
+	if err != nil {
+		a.Logger.Printf("target %q: failed to create subscribe requests: %v", name, err)
+		return
+	}
+	subNames := make([]string, 0, len(reqs))
+	for sn := range reqs {
+		subNames = append(subNames, sn)
+	}
+	sort.Strings(subNames)
+	for _, sn := range subNames {
+		err = a.PrintMsg(name, fmt.Sprintf("Subscribe Request (subscription %q):", sn), reqs[sn])
+		if err != nil {
+			a.Logger.Printf("target %q: %v", name, err)
+		}
+	}
+}
+
 func (a *App) SubscribeRunPrompt(cmd *cobra.Command, args []string) error {
 	targetsConfig, err := a.Config.GetTargets()
 	if err != nil {
@@ -143,6 +170,8 @@ func (a *App) SubscribeRunPrompt(cmd *cobra.Command, args []string) error {
 		a.collector = collector.NewCollector(a.collectorConfig(), targetsConfig, cOpts...)
 		go a.collector.Start(a.ctx)
 		a.startAPI()
+		a.startAdmin()
+		a.startControlSocket()
 		go a.startCluster()
 	} else {
 		// prompt mode
@@ -205,6 +234,7 @@ func (a *App) InitSubscribeFlags(cmd *cobra.Command) {
 		"sample interval as a decimal number and a suffix unit, such as \"10s\" or \"1m30s\"")
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SubscribeSuppressRedundant, "suppress-redundant", "", false, "suppress redundant update if the subscribed value didn't not change")
 	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SubscribeHeartbearInterval, "heartbeat-interval", "", 0, "heartbeat interval in case suppress-redundant is enabled")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SubscribePollInterval, "poll-interval", "", 0, "interval between poll requests of poll mode subscriptions, if not set the polls are triggered interactively")
 	cmd.Flags().StringSliceVarP(&a.Config.LocalFlags.SubscribeModel, "model", "", []string{}, "subscribe request used model(s)")
 	cmd.Flags().BoolVar(&a.Config.LocalFlags.SubscribeQuiet, "quiet", false, "suppress stdout printing")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SubscribeTarget, "target", "", "", "subscribe request target")
@@ -213,6 +243,8 @@ func (a *App) InitSubscribeFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SubscribeWatchConfig, "watch-config", "", false, "watch configuration changes, add or delete subscribe targets accordingly")
 	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SubscribeBackoff, "backoff", "", 0, "backoff time between subscribe requests")
 	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SubscribeLockRetry, "lock-retry", "", 5*time.Second, "time to wait between target lock attempts")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SubscribeOnce, "once", "", false, "use ONCE mode subscriptions and exit once all the targets sent their sync response or closed the stream")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SubscribeOnceTimeout, "once-timeout", "", 0, "maximum time to wait for all the targets to complete the ONCE mode subscriptions, no limit if not set")
 	//
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
@@ -220,6 +252,9 @@ func (a *App) InitSubscribeFlags(cmd *cobra.Command) {
 }
 
 func (a *App) createCollectorOpts(cmd *cobra.Command) ([]collector.CollectorOption, error) {
+	if !collector.IsBufferOverflowPolicy(a.Config.TargetBufferOverflow) {
+		return nil, fmt.Errorf("unknown target-buffer-overflow %q, must be one of %q", a.Config.TargetBufferOverflow, collector.BufferOverflowPolicies)
+	}
 	inputsConfig, err := a.Config.GetInputs()
 	if err != nil {
 		return nil, fmt.Errorf("failed reading inputs config: %v", err)
@@ -254,12 +289,16 @@ func (a *App) createCollectorOpts(cmd *cobra.Command) ([]collector.CollectorOpti
 
 func (a *App) collectorConfig() *collector.Config {
 	cfg := &collector.Config{
-		PrometheusAddress:   a.Config.PrometheusAddress,
-		Debug:               a.Config.Debug,
-		Format:              a.Config.Format,
-		TargetReceiveBuffer: a.Config.TargetBufferSize,
-		RetryTimer:          a.Config.Retry,
-		LockRetryTimer:      a.Config.LocalFlags.SubscribeLockRetry,
+		PrometheusAddress:    a.Config.PrometheusAddress,
+		Debug:                a.Config.Debug,
+		Format:               a.Config.Format,
+		TargetReceiveBuffer:  a.Config.TargetBufferSize,
+		RetryTimer:           a.Config.Retry,
+		ClientIdleTimeout:    a.Config.ClientIdleTimeout,
+		EnableTaps:           a.Config.ControlSocket != "",
+		TargetBuffer:         a.Config.TargetBuffer,
+		TargetBufferOverflow: a.Config.TargetBufferOverflow,
+		LockRetryTimer:       a.Config.LocalFlags.SubscribeLockRetry,
 	}
 	if a.Config.Clustering != nil {
 		cfg.ClusterName = a.Config.Clustering.ClusterName
@@ -292,11 +331,6 @@ func (a *App) handlePolledSubscriptions() {
 		}
 		waitChan := make(chan struct{}, 1)
 		waitChan <- struct{}{}
-		mo := &formatters.MarshalOptions{
-			Multiline: true,
-			Indent:    "  ",
-			Format:    a.Config.Format,
-		}
 
 		for {
 			select {
@@ -316,29 +350,11 @@ func (a *App) handlePolledSubscriptions() {
 					fmt.Printf("failed selecting subscription to poll: %v\n", err)
 					continue
 				}
-				response, err := a.collector.TargetPoll(name, subName)
+				// the poll responses are written to the outputs
+				err = a.collector.TargetPoll(name, subName)
 				if err != nil && err != io.EOF {
 					fmt.Printf("target '%s', subscription '%s': poll response error:%v\n", name, subName, err)
-					continue
-				}
-				if response == nil {
-					fmt.Printf("received empty response from target '%s'\n", name)
-					continue
-				}
-				switch rsp := response.Response.(type) {
-				case *gnmi.SubscribeResponse_SyncResponse:
-					fmt.Printf("received sync response '%t' from '%s'\n", rsp.SyncResponse, name)
-					waitChan <- struct{}{}
-					continue
 				}
-				b, err := mo.Marshal(response, nil)
-				if err != nil {
-					fmt.Printf("target '%s', subscription '%s': poll response formatting error:%v\n", name, subName, err)
-					fmt.Println(string(b))
-					waitChan <- struct{}{}
-					continue
-				}
-				fmt.Println(string(b))
 				waitChan <- struct{}{}
 			case <-a.ctx.Done():
 				return
@@ -389,6 +405,12 @@ func (a *App) SubscribeRunONCE(cmd *cobra.Command, args []string) error {
 	a.collector = collector.NewCollector(a.collectorConfig(), targetsConfig, cOpts...)
 	a.collector.InitOutputs(a.ctx)
 
+	ctx := a.ctx
+	if a.Config.LocalFlags.SubscribeOnceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Config.LocalFlags.SubscribeOnceTimeout)
+		defer cancel()
+	}
 	var limiter *time.Ticker
 	if a.Config.LocalFlags.SubscribeBackoff > 0 {
 		limiter = time.NewTicker(a.Config.LocalFlags.SubscribeBackoff)
@@ -397,7 +419,7 @@ func (a *App) SubscribeRunONCE(cmd *cobra.Command, args []string) error {
 	a.errCh = make(chan error, numTargets)
 	a.wg.Add(numTargets)
 	for name := range a.Config.Targets {
-		go a.subscribeOnce(a.ctx, name)
+		go a.subscribeOnce(ctx, name)
 		if limiter != nil {
 			<-limiter.C
 		}
@@ -406,6 +428,10 @@ func (a *App) SubscribeRunONCE(cmd *cobra.Command, args []string) error {
 		limiter.Stop()
 	}
 	a.wg.Wait()
+	// flush the outputs before exiting, the prompt mode keeps using them
+	if !a.PromptMode {
+		a.collector.CloseOutputs()
+	}
 	return a.checkErrors()
 }
 
@@ -423,18 +449,32 @@ func (a *App) SubscribeRunPoll(cmd *cobra.Command, args []string) error {
 	a.collector = collector.NewCollector(a.collectorConfig(), targetsConfig, cOpts...)
 
 	go a.collector.Start(a.ctx)
-	// a.collector.InitOutputs(a.ctx)
-	// a.collector.InitTargets()
+	a.collector.InitOutputs(a.ctx)
 
 	a.wg.Add(len(a.Config.Targets))
 	for name := range a.Config.Targets {
 		go a.subscribePoll(a.ctx, name)
 	}
 	a.wg.Wait()
+	// the subscriptions are polled periodically
+	if allSubscriptionsPollInterval(a.collector.Subscriptions) {
+		<-a.ctx.Done()
+		return a.ctx.Err()
+	}
 	a.handlePolledSubscriptions()
 	return nil
 }
 
+// allSubscriptionsPollInterval returns true if all the subscriptions have a poll interval.
+func allSubscriptionsPollInterval(sc map[string]*collector.SubscriptionConfig) bool {
+	for _, s := range sc {
+		if s.PollInterval <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func allSubscriptionsModeOnce(sc map[string]*collector.SubscriptionConfig) bool {
 	for _, sub := range sc {
 		if strings.ToUpper(sub.Mode) != "ONCE" {
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// onceGNMIServer answers ONCE subscriptions with an update and a sync response, then closes the stream.
+// If hang is true, it never answers.
+type onceGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	hang bool
+
+	m     *sync.Mutex
+	modes []gnmi.SubscriptionList_Mode
+}
+
+func (s *onceGNMIServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	s.modes = append(s.modes, req.GetSubscribe().GetMode())
+	s.m.Unlock()
+	if s.hang {
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	}
+	if req.GetSubscribe().GetMode() != gnmi.SubscriptionList_ONCE {
+		return status.Errorf(codes.InvalidArgument, "unexpected mode %s", req.GetSubscribe().GetMode())
+	}
+	err = stream.Send(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: time.Now().UnixNano(),
+				Update: []*gnmi.Update{{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "hostname"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "router1"}},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return stream.Send(&gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true},
+	})
+}
+
+func startOnceGNMIServer(t *testing.T, s *onceGNMIServer) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	t.Cleanup(gs.Stop)
+	return l.Addr().String()
+}
+
+func TestSubscribeOnceFlag(t *testing.T) {
+	tests := map[string]struct {
+		hang    bool
+		wantErr bool
+	}{
+		"all_targets_complete": {},
+		"target_timeout": {
+			hang:    true,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			servers := []*onceGNMIServer{
+				{m: new(sync.Mutex)},
+				{m: new(sync.Mutex), hang: tc.hang},
+			}
+			a := New()
+			for _, s := range servers {
+				a.Config.Address = append(a.Config.Address, startOnceGNMIServer(t, s))
+			}
+			a.Config.Username = "admin"
+			a.Config.Password = "admin"
+			a.Config.Insecure = true
+			a.Config.Timeout = 5 * time.Second
+			cmd := &cobra.Command{Use: "subscribe"}
+			a.InitSubscribeFlags(cmd)
+			for flag, v := range map[string]string{
+				"path":         "/hostname",
+				"once":         "true",
+				"once-timeout": "1s",
+				"quiet":        "true",
+			} {
+				if err := cmd.Flags().Set(flag, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			done := make(chan error, 1)
+			go func() {
+				done <- a.SubscribeRun(cmd, nil)
+			}()
+			select {
+			case err := <-done:
+				if tc.wantErr && err == nil {
+					t.Errorf("expected an error")
+				}
+				if !tc.wantErr && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("timeout waiting for the subscribe command to exit")
+			}
+			for i, s := range servers {
+				s.m.Lock()
+				if len(s.modes) != 1 || s.modes[0] != gnmi.SubscriptionList_ONCE {
+					t.Errorf("target %d: expected a single ONCE subscription, got %v", i, s.modes)
+				}
+				s.m.Unlock()
+			}
+		})
+	}
+}
+
+func TestSubscribePrintRequest(t *testing.T) {
+	s := &onceGNMIServer{m: new(sync.Mutex)}
+	a := New()
+	out := new(bytes.Buffer)
+	a.out = out
+	a.Config.Address = []string{startOnceGNMIServer(t, s)}
+	a.Config.Username = "admin"
+	a.Config.Password = "admin"
+	a.Config.Insecure = true
+	a.Config.Timeout = 5 * time.Second
+	a.Config.PrintRequest = true
+	cmd := &cobra.Command{Use: "subscribe"}
+	a.InitSubscribeFlags(cmd)
+	for flag, v := range map[string]string{
+		"path":  "/hostname",
+		"once":  "true",
+		"quiet": "true",
+	} {
+		if err := cmd.Flags().Set(flag, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- a.SubscribeRun(cmd, nil)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the subscribe command to exit")
+	}
+	printed := out.String()
+	for _, want := range []string{`"mode": "ONCE"`, `"path": "hostname"`} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("expected %s in the printed subscribe request, got:\n%s", want, printed)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright © 2020 Karim Radhouani <medkarimrdi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// attachCmd represents the attach command
+func newAttachCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attach",
+		Short: "print the events exported by a running gnmic through its control socket",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			gApp.Config.SetLocalFlagsFromFile(cmd)
+		},
+		RunE:         gApp.AttachRunE,
+		SilenceUsage: true,
+	}
+	gApp.InitAttachFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "manage gnmic configuration",
+	}
+	cmd.AddCommand(newConfigValidateCmd())
+	return cmd
+}
+
+// configValidateCmd represents the config validate command
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "validate",
+		Short:        "validate the config file without connecting to the targets or starting the outputs",
+		RunE:         gApp.ConfigValidateRunE,
+		SilenceUsage: true,
+	}
+	return cmd
+}
@@ -0,0 +1,180 @@
+// Copyright © 2020 Karim Radhouani <medkarimrdi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/config"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+var listKeysRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// generatedSubscription is the subscription config written by the generate command
+type generatedSubscription struct {
+	Paths          []string `yaml:"paths,omitempty"`
+	Mode           string   `yaml:"mode,omitempty"`
+	StreamMode     string   `yaml:"stream-mode,omitempty"`
+	SampleInterval string   `yaml:"sample-interval,omitempty"`
+	Encoding       string   `yaml:"encoding,omitempty"`
+}
+
+type generatedConfig struct {
+	Subscriptions map[string]*generatedSubscription `yaml:"subscriptions,omitempty"`
+}
+
+func generateCmdRun(d, f, e []string) error {
+	err := generateYangSchema(d, f, e)
+	if err != nil {
+		return err
+	}
+	b, err := generateSubscriptionConfig(gApp.SchemaTree, &gApp.Config.LocalFlags)
+	if err != nil {
+		return err
+	}
+	if gApp.Config.LocalFlags.GenerateOutput == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(gApp.Config.LocalFlags.GenerateOutput, b, 0644)
+}
+
+// generateSubscriptionConfig returns a YAML subscription config, named after the --name flag,
+// subscribing to the schema leaves matching the --path prefix and the --match regular expression.
+func generateSubscriptionConfig(root *yang.Entry, lf *config.LocalFlags) ([]byte, error) {
+	var re *regexp.Regexp
+	var err error
+	if lf.GenerateMatch != "" {
+		re, err = regexp.Compile(lf.GenerateMatch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match regex: %v", err)
+		}
+	}
+	paths := matchingLeafPaths(root, lf.GeneratePath, re)
+	if len(paths) == 0 {
+		return nil, errors.New("no schema leaf matches the path prefix and the match regex")
+	}
+	sub := &generatedSubscription{
+		Paths:    paths,
+		Mode:     strings.ToLower(lf.GenerateMode),
+		Encoding: strings.ToLower(lf.GenerateEncoding),
+	}
+	switch sub.Mode {
+	case "once", "poll":
+	case "stream":
+		sub.StreamMode = strings.ToLower(strings.Replace(lf.GenerateStreamMode, "-", "_", -1))
+		switch sub.StreamMode {
+		case "sample":
+			if lf.GenerateSampleInterval > 0 {
+				sub.SampleInterval = lf.GenerateSampleInterval.String()
+			}
+		case "on_change", "target_defined":
+		default:
+			return nil, fmt.Errorf("unknown stream mode %q, must be one of 'sample', 'on_change' or 'target_defined'", lf.GenerateStreamMode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown subscription mode %q, must be one of 'stream', 'once' or 'poll'", lf.GenerateMode)
+	}
+	return yaml.Marshal(&generatedConfig{
+		Subscriptions: map[string]*generatedSubscription{lf.GenerateName: sub},
+	})
+}
+
+// matchingLeafPaths returns the sorted XPATH style paths of the schema leaves,
+// whose path without list keys starts with prefix and matches re (if not nil).
+func matchingLeafPaths(root *yang.Entry, prefix string, re *regexp.Regexp) []string {
+	if root == nil {
+		return nil
+	}
+	prefix = listKeysRegex.ReplaceAllString(prefix, "")
+	paths := make([]string, 0)
+	for _, entry := range root.Dir {
+		for _, leaf := range collectSchemaNodes(entry, true) {
+			p := xpathFromEntry(leaf, false)
+			keyless := listKeysRegex.ReplaceAllString(p, "")
+			if !strings.HasPrefix(keyless, prefix) {
+				continue
+			}
+			if re != nil && !re.MatchString(keyless) {
+				continue
+			}
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// generateCmd represents the generate command
+func newGenerateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "generate a subscription config from yang files",
+		Annotations: map[string]string{
+			"--file": "YANG",
+			"--dir":  "DIR",
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			gApp.Config.SetLocalFlagsFromFile(cmd)
+			gApp.Config.LocalFlags.GenerateDir = config.SanitizeArrayFlagValue(gApp.Config.LocalFlags.GenerateDir)
+			gApp.Config.LocalFlags.GenerateFile = config.SanitizeArrayFlagValue(gApp.Config.LocalFlags.GenerateFile)
+			gApp.Config.LocalFlags.GenerateExclude = config.SanitizeArrayFlagValue(gApp.Config.LocalFlags.GenerateExclude)
+
+			var err error
+			gApp.Config.LocalFlags.GenerateDir, gApp.Config.LocalFlags.GenerateFile, err = resolveYangPaths(gApp.Config.LocalFlags.GenerateDir, gApp.Config.LocalFlags.GenerateFile)
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateCmdRun(gApp.Config.LocalFlags.GenerateDir, gApp.Config.LocalFlags.GenerateFile, gApp.Config.LocalFlags.GenerateExclude)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			cmd.ResetFlags()
+			initGenerateFlags(cmd)
+		},
+		SilenceUsage: true,
+	}
+	initGenerateFlags(cmd)
+	return cmd
+}
+
+// used to init or reset generateCmd flags for gnmic-prompt mode
+func initGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&gApp.Config.LocalFlags.GenerateFile, "file", "", []string{}, "yang files to generate the subscription paths from")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringArrayVarP(&gApp.Config.LocalFlags.GenerateExclude, "exclude", "", []string{}, "yang modules to be excluded from path generation")
+	cmd.Flags().StringArrayVarP(&gApp.Config.LocalFlags.GenerateDir, "dir", "", []string{}, "directories to search yang includes and imports")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GeneratePath, "path", "", "/", "generate paths starting with this path prefix")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateMatch, "match", "", "", "generate paths matching this regular expression")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateName, "name", "", "sub1", "generated subscription name")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateMode, "mode", "", "stream", "generated subscription mode, one of stream, once or poll")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateStreamMode, "stream-mode", "", "sample", "generated subscription stream mode, one of sample, on_change or target_defined")
+	cmd.Flags().DurationVarP(&gApp.Config.LocalFlags.GenerateSampleInterval, "sample-interval", "", 10*time.Second, "generated subscription sample interval")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateEncoding, "encoding", "", "json", "generated subscription encoding")
+	cmd.Flags().StringVarP(&gApp.Config.LocalFlags.GenerateOutput, "output", "o", "", "file to write the generated config to, defaults to stdout")
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		gApp.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
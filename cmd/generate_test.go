@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/config"
+)
+
+const testGenerateModule = `module test-interfaces {
+  namespace "urn:test-interfaces";
+  prefix ti;
+  container interfaces {
+    list interface {
+      key name;
+      leaf name { type string; }
+      container state {
+        leaf mtu { type uint16; }
+        container counters {
+          leaf in-octets { type uint64; }
+          leaf out-octets { type uint64; }
+        }
+      }
+    }
+  }
+  container system {
+    leaf hostname { type string; }
+  }
+}
+`
+
+// loadTestSchema loads testGenerateModule into gApp.SchemaTree
+func loadTestSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	yangFile := filepath.Join(dir, "test-interfaces.yang")
+	err = ioutil.WriteFile(yangFile, []byte(testGenerateModule), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generateYangSchema(nil, []string{yangFile}, nil)
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+}
+
+func TestGenerateSubscriptionConfig(t *testing.T) {
+	loadTestSchema(t)
+	b, err := generateSubscriptionConfig(gApp.SchemaTree, &config.LocalFlags{
+		GeneratePath:           "/interfaces/interface[name=*]/state",
+		GenerateMatch:          "octets$",
+		GenerateName:           "counters",
+		GenerateMode:           "stream",
+		GenerateStreamMode:     "sample",
+		GenerateSampleInterval: 30 * time.Second,
+		GenerateEncoding:       "json_ietf",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate config: %v", err)
+	}
+	t.Logf("generated config:\n%s", b)
+
+	cfg := config.New()
+	cfg.SetLogger()
+	cfg.FileConfig.SetConfigType("yaml")
+	err = cfg.FileConfig.ReadConfig(bytes.NewBuffer(b))
+	if err != nil {
+		t.Fatalf("failed to read generated config: %v", err)
+	}
+	subs, err := cfg.GetSubscriptions(nil)
+	if err != nil {
+		t.Fatalf("failed to get subscriptions: %v", err)
+	}
+	sub, ok := subs["counters"]
+	if !ok || len(subs) != 1 {
+		t.Fatalf("expected a single subscription named 'counters', got %v", subs)
+	}
+	wantPaths := []string{
+		"/interfaces/interface[name=*]/state/counters/in-octets",
+		"/interfaces/interface[name=*]/state/counters/out-octets",
+	}
+	if !reflect.DeepEqual(sub.Paths, wantPaths) {
+		t.Errorf("expected paths %v, got %v", wantPaths, sub.Paths)
+	}
+	if sub.Mode != "stream" || sub.StreamMode != "sample" || sub.Encoding != "json_ietf" {
+		t.Errorf("unexpected subscription mode or encoding: %s", sub)
+	}
+	if sub.SampleInterval == nil || *sub.SampleInterval != 30*time.Second {
+		t.Errorf("expected sample interval 30s, got %v", sub.SampleInterval)
+	}
+	_, err = sub.CreateSubscribeRequest()
+	if err != nil {
+		t.Errorf("generated subscription is not valid: %v", err)
+	}
+}
+
+func TestGenerateSubscriptionConfigErrors(t *testing.T) {
+	loadTestSchema(t)
+	for name, lf := range map[string]*config.LocalFlags{
+		"no_match":       {GeneratePath: "/unknown", GenerateMode: "stream", GenerateStreamMode: "sample"},
+		"invalid_regex":  {GenerateMatch: "(", GenerateMode: "stream", GenerateStreamMode: "sample"},
+		"invalid_mode":   {GenerateMode: "sometimes"},
+		"invalid_stream": {GenerateMode: "stream", GenerateStreamMode: "often"},
+	} {
+		if _, err := generateSubscriptionConfig(gApp.SchemaTree, lf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -163,38 +163,8 @@ func newPathCmd() *cobra.Command {
 			gApp.Config.LocalFlags.PathExclude = config.SanitizeArrayFlagValue(gApp.Config.LocalFlags.PathExclude)
 
 			var err error
-			gApp.Config.LocalFlags.PathDir, err = resolveGlobs(gApp.Config.LocalFlags.PathDir)
-			if err != nil {
-				return err
-			}
-			gApp.Config.LocalFlags.PathFile, err = resolveGlobs(gApp.Config.LocalFlags.PathFile)
-			if err != nil {
-				return err
-			}
-			for _, dirpath := range gApp.Config.LocalFlags.PathDir {
-				expanded, err := yang.PathsWithModules(dirpath)
-				if err != nil {
-					return err
-				}
-				if gApp.Config.Debug {
-					for _, fdir := range expanded {
-						gApp.Logger.Printf("adding %s to YANG paths", fdir)
-					}
-				}
-				yang.AddPath(expanded...)
-			}
-			yfiles, err := findYangFiles(gApp.Config.LocalFlags.PathFile)
-			if err != nil {
-				return err
-			}
-			gApp.Config.LocalFlags.PathFile = make([]string, 0, len(yfiles))
-			gApp.Config.LocalFlags.PathFile = append(gApp.Config.LocalFlags.PathFile, yfiles...)
-			if gApp.Config.Debug {
-				for _, file := range gApp.Config.LocalFlags.PathFile {
-					gApp.Logger.Printf("loading %s file", file)
-				}
-			}
-			return nil
+			gApp.Config.LocalFlags.PathDir, gApp.Config.LocalFlags.PathFile, err = resolveYangPaths(gApp.Config.LocalFlags.PathDir, gApp.Config.LocalFlags.PathFile)
+			return err
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return pathCmdRun(gApp.Config.LocalFlags.PathDir, gApp.Config.LocalFlags.PathFile, gApp.Config.LocalFlags.PathExclude)
@@ -252,6 +222,22 @@ func collectSchemaNodes(e *yang.Entry, leafOnly bool) []*yang.Entry {
 }
 
 func generatePath(entry *yang.Entry, prefixTagging bool) string {
+	path := xpathFromEntry(entry, prefixTagging)
+	if gApp.Config.LocalFlags.PathPathType == "gnmi" {
+		gnmiPath, err := xpath.ToGNMIPath(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "path: %s could not be changed to gnmi: %v\n", path, err)
+		}
+		path = gnmiPath.String()
+	}
+	if gApp.Config.LocalFlags.PathTypes {
+		path = fmt.Sprintf("%s (type=%s)", path, entry.Type.Name)
+	}
+	return path
+}
+
+// xpathFromEntry returns the XPATH style path of a schema entry, with a wildcard value for each list key
+func xpathFromEntry(entry *yang.Entry, prefixTagging bool) string {
 	path := ""
 	for e := entry; e != nil && e.Parent != nil; e = e.Parent {
 		if e.IsCase() || e.IsChoice() {
@@ -272,16 +258,6 @@ func generatePath(entry *yang.Entry, prefixTagging bool) string {
 		}
 		path = fmt.Sprintf("/%s%s", elementName, path)
 	}
-	if gApp.Config.LocalFlags.PathPathType == "gnmi" {
-		gnmiPath, err := xpath.ToGNMIPath(path)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "path: %s could not be changed to gnmi: %v\n", path, err)
-		}
-		path = gnmiPath.String()
-	}
-	if gApp.Config.LocalFlags.PathTypes {
-		path = fmt.Sprintf("%s (type=%s)", path, entry.Type.Name)
-	}
 	return path
 }
 
@@ -448,6 +424,42 @@ func walkDir(path, ext string) ([]string, error) {
 	return fs, nil
 }
 
+// resolveYangPaths resolves the globs in the YANG directories and files lists,
+// adds the directories to the YANG search paths and returns the resolved directories and the YANG files found.
+func resolveYangPaths(dirs, files []string) ([]string, []string, error) {
+	var err error
+	dirs, err = resolveGlobs(dirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err = resolveGlobs(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, dirpath := range dirs {
+		expanded, err := yang.PathsWithModules(dirpath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if gApp.Config.Debug {
+			for _, fdir := range expanded {
+				gApp.Logger.Printf("adding %s to YANG paths", fdir)
+			}
+		}
+		yang.AddPath(expanded...)
+	}
+	yfiles, err := findYangFiles(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gApp.Config.Debug {
+		for _, file := range yfiles {
+			gApp.Logger.Printf("loading %s file", file)
+		}
+	}
+	return dirs, yfiles, nil
+}
+
 func findYangFiles(files []string) ([]string, error) {
 	yfiles := make([]string, 0, len(files))
 	for _, file := range files {
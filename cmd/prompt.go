@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -254,6 +255,23 @@ var targetShowCmd = &cobra.Command{
 	},
 }
 
+var targetConnectionsCmd = &cobra.Command{
+	Use:   "connections",
+	Short: "show the targets connections counters",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stats := gApp.ClientPoolStats()
+		tabData := [][]string{
+			{"Active", strconv.Itoa(stats.Active)},
+			{"Dials", strconv.FormatUint(stats.Dials, 10)},
+			{"Reuses", strconv.FormatUint(stats.Reuses, 10)},
+			{"Idle Evictions", strconv.FormatUint(stats.IdleEvictions, 10)},
+			{"Failed Evictions", strconv.FormatUint(stats.FailedEvictions, 10)},
+		}
+		renderTable(tabData, []string{"Param", "Value"})
+		return nil
+	},
+}
+
 var subscriptionCmd = &cobra.Command{
 	Use:   "subscription",
 	Short: "manipulate configured subscriptions",
@@ -952,6 +970,7 @@ func initPromptCmds() {
 
 	targetCmd.AddCommand(targetListCmd)
 	targetCmd.AddCommand(targetShowCmd)
+	targetCmd.AddCommand(targetConnectionsCmd)
 	targetShowCmd.Flags().StringVarP(&name, "name", "", "", "target name")
 
 	subscriptionCmd.AddCommand(subscriptionListCmd)
@@ -60,10 +60,13 @@ func newRootCmd() *cobra.Command {
 		PersistentPreRunE: gApp.PreRun,
 	}
 	gApp.InitGlobalFlags()
+	gApp.RootCmd.AddCommand(newAttachCmd())
 	gApp.RootCmd.AddCommand(newCompletionCmd())
 	gApp.RootCmd.AddCommand(newCapabilitiesCmd())
+	gApp.RootCmd.AddCommand(newConfigCmd())
 	gApp.RootCmd.AddCommand(newGetCmd())
 	gApp.RootCmd.AddCommand(newGetSetCmd())
+	gApp.RootCmd.AddCommand(newGenerateCmd())
 	gApp.RootCmd.AddCommand(newListenCmd())
 	gApp.RootCmd.AddCommand(newPathCmd())
 	gApp.RootCmd.AddCommand(newPromptCmd())
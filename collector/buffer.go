@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// overflow policies of the targets buffers
+const (
+	BufferOverflowBlock      = "block"
+	BufferOverflowDropOldest = "drop-oldest"
+	BufferOverflowDropNewest = "drop-newest"
+)
+
+// BufferOverflowPolicies lists the valid target buffer overflow policies
+var BufferOverflowPolicies = []string{
+	BufferOverflowBlock,
+	BufferOverflowDropOldest,
+	BufferOverflowDropNewest,
+}
+
+// IsBufferOverflowPolicy returns true if s is a valid target buffer overflow policy,
+// the empty string defaults to block.
+func IsBufferOverflowPolicy(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, p := range BufferOverflowPolicies {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse is a subscribe response waiting in a target buffer to be exported
+type bufferedResponse struct {
+	rsp  *gnmi.SubscribeResponse
+	meta outputs.Meta
+	outs []string
+}
+
+// targetBuffer is a bounded queue of a target's responses, between the target listener and the outputs.
+// The responses are exported in order by a single goroutine, a burst from one target
+// fills its own buffer instead of competing with the other targets for the outputs.
+type targetBuffer struct {
+	policy  string
+	ch      chan *bufferedResponse
+	dropped prometheus.Counter
+	// closed once the buffer is removed, stops run
+	done chan struct{}
+}
+
+func newTargetBuffer(size uint, policy string, dropped prometheus.Counter) *targetBuffer {
+	if policy == "" {
+		policy = BufferOverflowBlock
+	}
+	return &targetBuffer{
+		policy:  policy,
+		ch:      make(chan *bufferedResponse, size),
+		dropped: dropped,
+		done:    make(chan struct{}),
+	}
+}
+
+// push adds br to the buffer, if the buffer is full it blocks or drops a response depending on the overflow policy.
+// It returns false if a response was dropped.
+func (b *targetBuffer) push(ctx context.Context, br *bufferedResponse) bool {
+	switch b.policy {
+	case BufferOverflowDropNewest:
+		select {
+		case b.ch <- br:
+			return true
+		default:
+			b.drop()
+			return false
+		}
+	case BufferOverflowDropOldest:
+		// the listener is the only producer, the loop ends
+		// once the consumer or a drop made room for br
+		ok := true
+		for {
+			select {
+			case b.ch <- br:
+				return ok
+			default:
+			}
+			select {
+			case <-b.ch:
+				b.drop()
+				ok = false
+			default:
+			}
+		}
+	default:
+		select {
+		case b.ch <- br:
+		case <-ctx.Done():
+		}
+		return true
+	}
+}
+
+func (b *targetBuffer) drop() {
+	if b.dropped != nil {
+		b.dropped.Inc()
+	}
+}
+
+// run exports the buffered responses until ctx is done or the buffer is removed,
+// the responses still buffered at that time are discarded.
+func (b *targetBuffer) run(ctx context.Context, export func(*bufferedResponse)) {
+	for {
+		select {
+		case br := <-b.ch:
+			export(br)
+		case <-b.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// targetBuffersCollector implements prometheus.Collector,
+// it exports the number of responses waiting in each target buffer.
+type targetBuffersCollector struct {
+	desc    *prometheus.Desc
+	dropped *prometheus.CounterVec
+
+	m       *sync.Mutex
+	buffers map[string]*targetBuffer
+}
+
+func newTargetBuffersCollector() *targetBuffersCollector {
+	return &targetBuffersCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("gnmic", "target", "buffer_occupancy"),
+			"Number of subscribe responses waiting in the target buffer to be exported",
+			[]string{"target"},
+			nil,
+		),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gnmic",
+			Subsystem: "target",
+			Name:      "buffer_dropped_total",
+			Help:      "Number of subscribe responses dropped because the target buffer was full",
+		}, []string{"target"}),
+		m:       new(sync.Mutex),
+		buffers: make(map[string]*targetBuffer),
+	}
+}
+
+// add creates the buffer of target name, replacing any previous one.
+func (c *targetBuffersCollector) add(name string, size uint, policy string) *targetBuffer {
+	c.m.Lock()
+	defer c.m.Unlock()
+	b := newTargetBuffer(size, policy, c.dropped.WithLabelValues(name))
+	c.buffers[name] = b
+	return b
+}
+
+// remove stops the buffer b of target name and deletes it, unless it was already replaced.
+func (c *targetBuffersCollector) remove(name string, b *targetBuffer) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	close(b.done)
+	if c.buffers[name] == b {
+		delete(c.buffers, name)
+	}
+}
+
+// occupancy returns the number of responses in each target buffer.
+func (c *targetBuffersCollector) occupancy() map[string]int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	res := make(map[string]int, len(c.buffers))
+	for name, b := range c.buffers {
+		res[name] = len(b.ch)
+	}
+	return res
+}
+
+// Describe implements prometheus.Collector
+func (c *targetBuffersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+	c.dropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *targetBuffersCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, n := range c.occupancy() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), name)
+	}
+	c.dropped.Collect(ch)
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/outputs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func bufferTestResponse(i int) *bufferedResponse {
+	return &bufferedResponse{
+		rsp:  tapTestResponse("interface"),
+		meta: outputs.Meta{"source": "router1", "id": fmt.Sprint(i)},
+	}
+}
+
+// bufferedIDs returns the ids of the responses waiting in b.
+func bufferedIDs(b *targetBuffer) []string {
+	ids := make([]string, 0, len(b.ch))
+	for len(b.ch) > 0 {
+		ids = append(ids, (<-b.ch).meta["id"])
+	}
+	return ids
+}
+
+func TestTargetBufferDrop(t *testing.T) {
+	tests := map[string]struct {
+		policy      string
+		wantIDs     []string
+		wantDropped float64
+	}{
+		"drop-newest": {
+			policy:      BufferOverflowDropNewest,
+			wantIDs:     []string{"0", "1", "2"},
+			wantDropped: 2,
+		},
+		"drop-oldest": {
+			policy:      BufferOverflowDropOldest,
+			wantIDs:     []string{"2", "3", "4"},
+			wantDropped: 2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			bc := newTargetBuffersCollector()
+			b := bc.add("router1", 3, tc.policy)
+			// burst of 5 responses while the outputs are not consuming
+			dropped := 0
+			for i := 0; i < 5; i++ {
+				if !b.push(context.Background(), bufferTestResponse(i)) {
+					dropped++
+				}
+			}
+			if dropped != int(tc.wantDropped) {
+				t.Errorf("expected push to report %v drops, got %d", tc.wantDropped, dropped)
+			}
+			if occ := bc.occupancy(); occ["router1"] != 3 {
+				t.Errorf("expected an occupancy of 3, got %v", occ)
+			}
+			if v := testutil.ToFloat64(bc.dropped.WithLabelValues("router1")); v != tc.wantDropped {
+				t.Errorf("expected %v dropped responses, got %v", tc.wantDropped, v)
+			}
+			if ids := bufferedIDs(b); !reflect.DeepEqual(ids, tc.wantIDs) {
+				t.Errorf("expected buffered responses %v, got %v", tc.wantIDs, ids)
+			}
+		})
+	}
+}
+
+func TestTargetBufferBlock(t *testing.T) {
+	bc := newTargetBuffersCollector()
+	b := bc.add("router1", 3, "")
+	pushed := make(chan struct{})
+	go func() {
+		defer close(pushed)
+		for i := 0; i < 5; i++ {
+			b.push(context.Background(), bufferTestResponse(i))
+		}
+	}()
+	select {
+	case <-pushed:
+		t.Fatalf("expected the burst to block on the full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if occ := bc.occupancy(); occ["router1"] != 3 {
+		t.Errorf("expected an occupancy of 3, got %v", occ)
+	}
+	// the outputs start consuming, the blocked responses are exported in order
+	exported := make(chan string, 5)
+	go b.run(context.Background(), func(br *bufferedResponse) {
+		exported <- br.meta["id"]
+	})
+	ids := make([]string, 0, 5)
+	for len(ids) < 5 {
+		select {
+		case id := <-exported:
+			ids = append(ids, id)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for the buffered responses, got %v", ids)
+		}
+	}
+	<-pushed
+	if want := []string{"0", "1", "2", "3", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected exported responses %v, got %v", want, ids)
+	}
+	if v := testutil.ToFloat64(bc.dropped.WithLabelValues("router1")); v != 0 {
+		t.Errorf("expected no dropped responses, got %v", v)
+	}
+	bc.remove("router1", b)
+	if occ := bc.occupancy(); len(occ) != 0 {
+		t.Errorf("expected the removed buffer to not be reported, got %v", occ)
+	}
+}
+
+func TestTargetBufferBlockCanceled(t *testing.T) {
+	b := newTargetBuffer(1, BufferOverflowBlock, nil)
+	b.push(context.Background(), bufferTestResponse(0))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// a full buffer does not block once the context is done
+	b.push(ctx, bufferTestResponse(1))
+}
+
+func TestIsBufferOverflowPolicy(t *testing.T) {
+	for _, p := range append([]string{""}, BufferOverflowPolicies...) {
+		if !IsBufferOverflowPolicy(p) {
+			t.Errorf("expected %q to be a valid policy", p)
+		}
+	}
+	if IsBufferOverflowPolicy("drop") {
+		t.Errorf("expected %q to be an invalid policy", "drop")
+	}
+}
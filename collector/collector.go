@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -41,6 +42,15 @@ type Config struct {
 	RetryTimer          time.Duration
 	ClusterName         string
 	LockRetryTimer      time.Duration
+	// time after which an unused Capabilities, Get or Set client connection is closed,
+	// a negative value disables the idle eviction
+	ClientIdleTimeout time.Duration
+	// allows attaching Taps receiving a copy of the exported events
+	EnableTaps bool
+	// size of the per target buffer between the subscriptions and the outputs, 0 disables it
+	TargetBuffer uint
+	// what to do when a target buffer is full, one of BufferOverflowPolicies
+	TargetBufferOverflow string
 }
 
 // Collector //
@@ -58,6 +68,8 @@ type Collector struct {
 	Inputs       map[string]inputs.Input
 
 	locker lockers.Locker
+	// targets locked by this collector
+	assignments *assignmentsCollector
 
 	targetsConfig map[string]*TargetConfig
 	Targets       map[string]*Target
@@ -66,12 +78,20 @@ type Collector struct {
 	logger                *log.Logger
 	httpServer            *http.Server
 	reg                   *prometheus.Registry
+	reconnects            *prometheus.CounterVec
+	rpcMetrics            *rpcMetrics
 
 	targetsChan    chan *Target
 	activeTargets  map[string]struct{}
 	targetsLocksFn map[string]context.CancelFunc
 
 	rootDesc desc.Descriptor
+	// unary RPCs clients counters
+	clientPool ClientPoolStats
+	// attached events Taps
+	taps taps
+	// per target buffers, used if TargetBuffer is set
+	buffers *targetBuffersCollector
 }
 
 type CollectorOption func(c *Collector)
@@ -130,6 +150,9 @@ func NewCollector(config *Config, targetConfigs map[string]*TargetConfig, opts .
 	if config.LockRetryTimer <= 0 {
 		config.LockRetryTimer = defaultLockRetry
 	}
+	if config.ClientIdleTimeout == 0 {
+		config.ClientIdleTimeout = defaultClientIdleTimeout
+	}
 	c := &Collector{
 		Config:         config,
 		m:              new(sync.Mutex),
@@ -141,6 +164,8 @@ func NewCollector(config *Config, targetConfigs map[string]*TargetConfig, opts .
 		targetsChan:    make(chan *Target),
 		activeTargets:  make(map[string]struct{}),
 		targetsLocksFn: make(map[string]context.CancelFunc),
+		assignments:    newAssignmentsCollector(config.Name),
+		buffers:        newTargetBuffersCollector(),
 	}
 	for _, op := range opts {
 		op(c)
@@ -155,6 +180,21 @@ func NewCollector(config *Config, targetConfigs map[string]*TargetConfig, opts .
 		c.reg.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 		grpcMetrics.EnableClientHandlingTimeHistogram()
 		c.reg.MustRegister(grpcMetrics)
+		c.reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gnmic",
+			Subsystem: "subscribe",
+			Name:      "reconnects_total",
+			Help:      "Number of re-subscribe attempts after a subscription failure",
+		}, []string{"target", "subscription"})
+		c.reg.MustRegister(c.reconnects)
+		c.rpcMetrics = newRPCMetrics()
+		c.rpcMetrics.register(c.reg)
+		if c.locker != nil {
+			c.reg.MustRegister(c.assignments)
+		}
+		if config.TargetBuffer > 0 {
+			c.reg.MustRegister(c.buffers)
+		}
 		handler := http.NewServeMux()
 		handler.Handle("/metrics", promhttp.HandlerFor(c.reg, promhttp.HandlerOpts{}))
 		c.httpServer = &http.Server{
@@ -191,24 +231,20 @@ func (c *Collector) AddTarget(tc *TargetConfig) error {
 	return nil
 }
 
+// initTarget creates the target name from its config, it is a no-op if the target exists.
 func (c *Collector) initTarget(name string) error {
 	c.m.Lock()
 	defer c.m.Unlock()
+	if _, ok := c.Targets[name]; ok {
+		return nil
+	}
 	if tc, ok := c.targetsConfig[name]; ok {
 		if _, ok := c.Targets[name]; !ok {
 			t := NewTarget(tc)
+			t.reconnects = c.reconnects
+			t.rpcMetrics = c.rpcMetrics
 			//
-			t.Subscriptions = make(map[string]*SubscriptionConfig)
-			for _, subName := range tc.Subscriptions {
-				if sub, ok := c.Subscriptions[subName]; ok {
-					t.Subscriptions[subName] = sub
-				}
-			}
-			if len(t.Subscriptions) == 0 {
-				for _, sub := range c.Subscriptions {
-					t.Subscriptions[sub.Name] = sub
-				}
-			}
+			t.Subscriptions = c.targetSubscriptions(tc)
 			err := c.parseProtoFiles(t)
 			if err != nil {
 				return err
@@ -220,6 +256,23 @@ func (c *Collector) initTarget(name string) error {
 	return fmt.Errorf("unknown target")
 }
 
+// targetSubscriptions returns the subscriptions bound to the target config tc,
+// or all the subscriptions if none is bound to it. It must be called with c.m locked.
+func (c *Collector) targetSubscriptions(tc *TargetConfig) map[string]*SubscriptionConfig {
+	subs := make(map[string]*SubscriptionConfig)
+	for _, subName := range tc.Subscriptions {
+		if sub, ok := c.Subscriptions[subName]; ok {
+			subs[subName] = sub
+		}
+	}
+	if len(subs) == 0 {
+		for _, sub := range c.Subscriptions {
+			subs[sub.Name] = sub
+		}
+	}
+	return subs
+}
+
 func (c *Collector) TargetSubscribeStream(ctx context.Context, name string) {
 	lockKey := c.lockKey(name)
 START:
@@ -261,6 +314,7 @@ START:
 					goto START
 				}
 				c.logger.Printf("acquired lock for target %q", name)
+				c.assignments.assign(name)
 			}
 			select {
 			case <-nctx.Done():
@@ -282,9 +336,11 @@ START:
 					select {
 					case <-nctx.Done():
 						c.logger.Printf("target %q stopped: %v", name, ctx.Err())
+						c.assignments.unassign(name)
 						return
 					case <-doneChan:
 						c.logger.Printf("target lock %q removed", name)
+						c.assignments.unassign(name)
 						return
 					case err := <-errChan:
 						c.logger.Printf("failed to maintain target %q lock: %v", name, err)
@@ -367,6 +423,7 @@ func (c *Collector) DeleteTarget(ctx context.Context, name string) error {
 	if c.locker == nil {
 		return nil
 	}
+	c.assignments.unassign(name)
 	if cfn, ok := c.targetsLocksFn[name]; ok {
 		cfn()
 	}
@@ -389,6 +446,7 @@ func (c *Collector) StopTarget(ctx context.Context, name string) error {
 	if c.locker == nil {
 		return nil
 	}
+	c.assignments.unassign(name)
 	return c.locker.Unlock(ctx, c.lockKey(name))
 }
 
@@ -459,6 +517,43 @@ func (c *Collector) DeleteOutput(name string) error {
 	return nil
 }
 
+// CloseOutputs closes all the outputs, flushing their buffered messages
+func (c *Collector) CloseOutputs() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for _, o := range c.Outputs {
+		o.Close()
+	}
+}
+
+// OutputsDepths returns the depths of the internal channels and caches
+// of the outputs implementing outputs.DepthReporter, keyed by output name.
+func (c *Collector) OutputsDepths() map[string]map[string]int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	depths := make(map[string]map[string]int)
+	for name, o := range c.Outputs {
+		if dr, ok := o.(outputs.DepthReporter); ok {
+			depths[name] = dr.Depths()
+		}
+	}
+	return depths
+}
+
+// OutputsErrors returns the last error per target
+// of the outputs implementing outputs.ErrorReporter, keyed by output name.
+func (c *Collector) OutputsErrors() map[string]map[string]*outputs.TargetError {
+	c.m.Lock()
+	defer c.m.Unlock()
+	errs := make(map[string]map[string]*outputs.TargetError)
+	for name, o := range c.Outputs {
+		if er, ok := o.(outputs.ErrorReporter); ok {
+			errs[name] = er.LastErrors()
+		}
+	}
+	return errs
+}
+
 // AddSubscriptionConfig adds a subscriptionConfig sc to Collector's map if it does not already exists
 func (c *Collector) AddSubscriptionConfig(sc *SubscriptionConfig) error {
 	if c.Subscriptions == nil {
@@ -494,32 +589,79 @@ func (c *Collector) DeleteSubscription(name string) error {
 }
 
 // Subscribe //
-func (c *Collector) Subscribe(ctx context.Context, tName string) error {
-	if t, ok := c.Targets[tName]; ok {
-		subscriptionsConfigs := t.Subscriptions
-		if len(subscriptionsConfigs) == 0 {
-			subscriptionsConfigs = c.Subscriptions
-		}
-		if len(subscriptionsConfigs) == 0 {
-			return fmt.Errorf("target '%s' has no subscriptions defined", tName)
+// subscriptionRequests creates the SubscribeRequests of the subscriptions bound to the target,
+// or of all the subscriptions if none is bound to it.
+func (c *Collector) subscriptionRequests(t *Target) ([]subscriptionRequest, error) {
+	// the subscriptions configs are shared by the targets and get their defaults set
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.createSubscriptionRequests(t.Config.Name, t.Subscriptions)
+}
+
+// createSubscriptionRequests creates the SubscribeRequests of the subscriptions configs of target name,
+// or of all the subscriptions if subscriptionsConfigs is empty. It must be called with c.m locked.
+func (c *Collector) createSubscriptionRequests(name string, subscriptionsConfigs map[string]*SubscriptionConfig) ([]subscriptionRequest, error) {
+	if len(subscriptionsConfigs) == 0 {
+		subscriptionsConfigs = c.Subscriptions
+	}
+	if len(subscriptionsConfigs) == 0 {
+		return nil, fmt.Errorf("target '%s' has no subscriptions defined", name)
+	}
+	subRequests := make([]subscriptionRequest, 0, len(subscriptionsConfigs))
+	for _, sc := range subscriptionsConfigs {
+		req, err := sc.CreateSubscribeRequest()
+		if err != nil {
+			return nil, err
 		}
-		subRequests := make([]subscriptionRequest, 0)
-		for _, sc := range subscriptionsConfigs {
-			req, err := sc.CreateSubscribeRequest()
-			if err != nil {
-				return err
-			}
-			subRequests = append(subRequests, subscriptionRequest{name: sc.Name, req: req})
+		subRequests = append(subRequests, subscriptionRequest{name: sc.Name, req: req})
+	}
+	sort.Slice(subRequests, func(i, j int) bool {
+		return subRequests[i].name < subRequests[j].name
+	})
+	return subRequests, nil
+}
+
+// TargetSubscribeRequests returns the SubscribeRequests sent to a target, keyed by subscription name.
+// The target does not need to be initialized, the requests are built from its configuration.
+func (c *Collector) TargetSubscribeRequests(tName string) (map[string]*gnmi.SubscribeRequest, error) {
+	c.m.Lock()
+	var subRequests []subscriptionRequest
+	var err error
+	if t, ok := c.Targets[tName]; ok {
+		subRequests, err = c.createSubscriptionRequests(tName, t.Subscriptions)
+	} else if tc, ok := c.targetsConfig[tName]; ok {
+		subRequests, err = c.createSubscriptionRequests(tName, c.targetSubscriptions(tc))
+	} else {
+		err = fmt.Errorf("unknown target name: %s", tName)
+	}
+	c.m.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	reqs := make(map[string]*gnmi.SubscribeRequest, len(subRequests))
+	for _, sreq := range subRequests {
+		reqs[sreq.name] = sreq.req
+	}
+	return reqs, nil
+}
+
+func (c *Collector) Subscribe(ctx context.Context, tName string) error {
+	c.m.Lock()
+	t, ok := c.Targets[tName]
+	c.m.Unlock()
+	if ok {
+		subRequests, err := c.subscriptionRequests(t)
+		if err != nil {
+			return err
 		}
 		gnmiCtx, cancel := context.WithCancel(ctx)
 		t.cfn = cancel
 	CRCLIENT:
-		if err := t.CreateGNMIClient(gnmiCtx, c.dialOpts...); err != nil {
-			if errors.Is(err, context.DeadlineExceeded) {
-				c.logger.Printf("failed to initialize target %q timeout (%s) reached", tName, t.Config.Timeout)
-			} else {
-				c.logger.Printf("failed to initialize target %q: %v", tName, err)
-			}
+		c.m.Lock()
+		err = c.targetClient(gnmiCtx, t)
+		c.m.Unlock()
+		if err != nil {
+			c.logger.Printf("failed to initialize target %q: %v", tName, err)
 			c.logger.Printf("retrying target %q in %s", tName, t.Config.RetryTimer)
 			time.Sleep(t.Config.RetryTimer)
 			goto CRCLIENT
@@ -538,62 +680,38 @@ func (c *Collector) Subscribe(ctx context.Context, tName string) error {
 }
 
 func (c *Collector) SubscribeOnce(ctx context.Context, tName string) error {
-	if t, ok := c.Targets[tName]; ok {
-		subscriptionsConfigs := t.Subscriptions
-		if len(subscriptionsConfigs) == 0 {
-			subscriptionsConfigs = c.Subscriptions
-		}
-		if len(subscriptionsConfigs) == 0 {
-			return fmt.Errorf("target '%s' has no subscriptions defined", tName)
-		}
-		subRequests := make([]subscriptionRequest, 0)
-		for _, sc := range subscriptionsConfigs {
-			req, err := sc.CreateSubscribeRequest()
-			if err != nil {
-				return err
-			}
-			subRequests = append(subRequests, subscriptionRequest{name: sc.Name, req: req})
+	c.m.Lock()
+	t, ok := c.Targets[tName]
+	c.m.Unlock()
+	if ok {
+		subRequests, err := c.subscriptionRequests(t)
+		if err != nil {
+			return err
 		}
 		gnmiCtx, cancel := context.WithCancel(ctx)
 		t.cfn = cancel
 	CRCLIENT:
-		if err := t.CreateGNMIClient(gnmiCtx, c.dialOpts...); err != nil {
-			if errors.Is(err, context.DeadlineExceeded) {
-				c.logger.Printf("failed to initialize target %q timeout (%s) reached", tName, t.Config.Timeout)
-			} else {
-				c.logger.Printf("failed to initialize target %q: %v", tName, err)
-			}
+		c.m.Lock()
+		err = c.targetClient(gnmiCtx, t)
+		c.m.Unlock()
+		if err != nil {
+			c.logger.Printf("failed to initialize target %q: %v", tName, err)
 			c.logger.Printf("retrying target %q in %s", tName, t.Config.RetryTimer)
-			time.Sleep(t.Config.RetryTimer)
+			select {
+			case <-gnmiCtx.Done():
+				return fmt.Errorf("target %q: %v", tName, gnmiCtx.Err())
+			case <-time.After(t.Config.RetryTimer):
+			}
 			goto CRCLIENT
 
 		}
 		c.logger.Printf("target '%s' gNMI client created", t.Config.Name)
-	OUTER:
 		for _, sreq := range subRequests {
 			c.logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
 				sreq.req, sreq.req.GetSubscribe().GetMode(), sreq.req.GetSubscribe().GetEncoding(), t.Config.Name)
-			rspCh, errCh := t.SubscribeOnce(gnmiCtx, sreq.req, sreq.name)
-			for {
-				select {
-				case err := <-errCh:
-					if errors.Is(err, io.EOF) {
-						c.logger.Printf("target %q, subscription %q closed stream(EOF)", t.Config.Name, sreq.name)
-						close(rspCh)
-						// next subscription or end
-						continue OUTER
-					}
-					return err
-				case rsp := <-rspCh:
-					switch rsp.Response.(type) {
-					case *gnmi.SubscribeResponse_SyncResponse:
-						c.logger.Printf("target %q, subscription %q received sync response", t.Config.Name, sreq.name)
-						return nil
-					default:
-						m := outputs.Meta{"source": t.Config.Name, "format": c.Config.Format, "subscription-name": sreq.name}
-						c.Export(ctx, rsp, m, t.Config.Outputs...)
-					}
-				}
+			err = c.subscribeOnce(gnmiCtx, t, sreq)
+			if err != nil {
+				return err
 			}
 		}
 		return nil
@@ -601,18 +719,53 @@ func (c *Collector) SubscribeOnce(ctx context.Context, tName string) error {
 	return fmt.Errorf("unknown target name: %s", tName)
 }
 
-// Start start the prometheus server as well as a goroutine per target selecting on the response chan, the error chan and the ctx.Done() chan
-func (c *Collector) Start(ctx context.Context) {
-	if c.httpServer != nil {
-		go func() {
-			c.logger.Printf("starting prometheus server on %s", c.httpServer.Addr)
-			err := c.httpServer.ListenAndServe()
-			if err != nil {
-				c.logger.Printf("Unable to start prometheus http server: %v", err)
-				return
+// subscribeOnce sends a ONCE subscribe request to the target and exports the responses,
+// it returns once the sync response is received or the target closes the stream.
+func (c *Collector) subscribeOnce(ctx context.Context, t *Target, sreq subscriptionRequest) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rspCh, errCh := t.SubscribeOnce(ctx, sreq.req, sreq.name)
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("target %q, subscription %q: %v", t.Config.Name, sreq.name, ctx.Err())
+		case err := <-errCh:
+			if errors.Is(err, io.EOF) {
+				c.logger.Printf("target %q, subscription %q closed stream(EOF)", t.Config.Name, sreq.name)
+				return nil
+			}
+			return fmt.Errorf("target %q, subscription %q: %v", t.Config.Name, sreq.name, err)
+		case rsp := <-rspCh:
+			switch rsp.Response.(type) {
+			case *gnmi.SubscribeResponse_SyncResponse:
+				c.logger.Printf("target %q, subscription %q received sync response", t.Config.Name, sreq.name)
+				return nil
+			default:
+				m := t.outputsMeta(sreq.name, c.Config.Format)
+				c.Export(ctx, rsp, m, t.subscriptionOutputs(sreq.name)...)
 			}
-		}()
+		}
+	}
+}
+
+// StartMetricsServer starts the prometheus server exposing the collector metrics, if a prometheus address is configured
+func (c *Collector) StartMetricsServer() {
+	if c.httpServer == nil {
+		return
 	}
+	go func() {
+		c.logger.Printf("starting prometheus server on %s", c.httpServer.Addr)
+		err := c.httpServer.ListenAndServe()
+		if err != nil {
+			c.logger.Printf("Unable to start prometheus http server: %v", err)
+			return
+		}
+	}()
+}
+
+// Start start the prometheus server as well as a goroutine per target selecting on the response chan, the error chan and the ctx.Done() chan
+func (c *Collector) Start(ctx context.Context) {
+	c.StartMetricsServer()
 	defer func() {
 		for _, o := range c.Outputs {
 			o.Close()
@@ -635,6 +788,10 @@ func (c *Collector) Start(ctx context.Context) {
 		c.activeTargets[t.Config.Name] = struct{}{}
 		c.logger.Printf("starting target %q listener", t.Config.Name)
 		go func(t *Target) {
+			buf := c.startTargetBuffer(ctx, t.Config.Name)
+			if buf != nil {
+				defer c.buffers.remove(t.Config.Name, buf)
+			}
 			numOnceSubscriptions := t.numberOfOnceSubscriptions()
 			remainingOnceSubscriptions := numOnceSubscriptions
 			numSubscriptions := len(t.Subscriptions)
@@ -649,11 +806,21 @@ func (c *Collector) Start(ctx context.Context) {
 						c.logger.Printf("target %q, failed to decode proto bytes: %v", t.Config.Name, err)
 						continue
 					}
-					m := outputs.Meta{"source": t.Config.Name, "format": c.Config.Format, "subscription-name": rsp.SubscriptionName}
-					if c.subscriptionMode(rsp.SubscriptionName) == "ONCE" {
-						c.Export(ctx, rsp.Response, m, t.Config.Outputs...)
-					} else {
-						go c.Export(ctx, rsp.Response, m, t.Config.Outputs...)
+					m := t.outputsMeta(rsp.SubscriptionName, c.Config.Format)
+					switch {
+					case c.subscriptionMode(rsp.SubscriptionName) == "ONCE":
+						c.Export(ctx, rsp.Response, m, t.subscriptionOutputs(rsp.SubscriptionName)...)
+					case buf != nil:
+						ok := buf.push(ctx, &bufferedResponse{
+							rsp:  rsp.Response,
+							meta: m,
+							outs: t.subscriptionOutputs(rsp.SubscriptionName),
+						})
+						if !ok && c.Config.Debug {
+							c.logger.Printf("target %q buffer full, dropped a response", t.Config.Name)
+						}
+					default:
+						go c.Export(ctx, rsp.Response, m, t.subscriptionOutputs(rsp.SubscriptionName)...)
 					}
 					if remainingOnceSubscriptions > 0 {
 						if c.subscriptionMode(rsp.SubscriptionName) == "ONCE" {
@@ -675,6 +842,9 @@ func (c *Collector) Start(ctx context.Context) {
 					} else {
 						c.logger.Printf("target %q, subscription %s rcv error: %v", t.Config.Name, tErr.SubscriptionName, tErr.Err)
 					}
+					if c.subscriptionMode(tErr.SubscriptionName) != "ONCE" {
+						c.targetDown(t.Config.Name, t.subscriptionOutputs(tErr.SubscriptionName)...)
+					}
 					if remainingOnceSubscriptions > 0 {
 						if c.subscriptionMode(tErr.SubscriptionName) == "ONCE" {
 							remainingOnceSubscriptions--
@@ -706,30 +876,35 @@ func (c *Collector) Start(ctx context.Context) {
 	}
 }
 
-// TargetPoll sends a gnmi.SubscribeRequest_Poll to targetName and returns the response and an error,
-// it uses the targetName and the subscriptionName strings to find the gnmi.GNMI_SubscribeClient
-func (c *Collector) TargetPoll(targetName, subscriptionName string) (*gnmi.SubscribeResponse, error) {
+// startTargetBuffer creates the buffer of target name and starts exporting its responses,
+// it returns nil if the target buffer is disabled.
+// The buffered responses are exported until ctx is done or the buffer is removed.
+func (c *Collector) startTargetBuffer(ctx context.Context, name string) *targetBuffer {
+	if c.Config.TargetBuffer == 0 {
+		return nil
+	}
+	buf := c.buffers.add(name, c.Config.TargetBuffer, c.Config.TargetBufferOverflow)
+	go buf.run(ctx, func(br *bufferedResponse) {
+		c.Export(ctx, br.rsp, br.meta, br.outs...)
+	})
+	return buf
+}
+
+// TargetPoll sends a gnmi.SubscribeRequest_Poll to targetName on the subscription subscriptionName,
+// the poll responses are exported to the outputs. It returns once the sync response is received, or after the target timeout.
+func (c *Collector) TargetPoll(targetName, subscriptionName string) error {
 	if t, ok := c.Targets[targetName]; ok {
 		if sub, ok := t.Subscriptions[subscriptionName]; ok {
 			if strings.ToUpper(sub.Mode) != "POLL" {
-				return nil, fmt.Errorf("subscription '%s' is not a POLL subscription", subscriptionName)
-			}
-			if subClient, ok := t.SubscribeClients[subscriptionName]; ok {
-				err := subClient.Send(&gnmi.SubscribeRequest{
-					Request: &gnmi.SubscribeRequest_Poll{
-						Poll: &gnmi.Poll{},
-					},
-				})
-				if err != nil {
-					return nil, err
-				}
-				return subClient.Recv()
+				return fmt.Errorf("subscription '%s' is not a POLL subscription", subscriptionName)
 			}
-			return nil, fmt.Errorf("subscribe-client not found '%s'", subscriptionName)
+			ctx, cancel := context.WithTimeout(context.Background(), t.Config.Timeout)
+			defer cancel()
+			return t.Poll(ctx, subscriptionName)
 		}
-		return nil, fmt.Errorf("unknown subscription name '%s'", subscriptionName)
+		return fmt.Errorf("unknown subscription name '%s'", subscriptionName)
 	}
-	return nil, fmt.Errorf("unknown target name '%s'", targetName)
+	return fmt.Errorf("unknown target name '%s'", targetName)
 }
 
 // PolledSubscriptionsTargets returns a map of target name to a list of subscription names that have Mode == POLL
@@ -755,10 +930,26 @@ func (c *Collector) subscriptionMode(name string) string {
 	return ""
 }
 
+// targetDown notifies the outputs implementing outputs.TargetStateHandler
+// that a subscription of target name failed
+func (c *Collector) targetDown(name string, outs ...string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for oName, o := range c.Outputs {
+		if len(outs) > 0 && !contains(outs, oName) {
+			continue
+		}
+		if h, ok := o.(outputs.TargetStateHandler); ok {
+			h.TargetDown(name)
+		}
+	}
+}
+
 func (c *Collector) Export(ctx context.Context, rsp *gnmi.SubscribeResponse, m outputs.Meta, outs ...string) {
 	if rsp == nil {
 		return
 	}
+	c.tap(rsp, m)
 	wg := new(sync.WaitGroup)
 	if len(outs) == 0 {
 		wg.Add(len(c.Outputs))
@@ -784,24 +975,17 @@ func (c *Collector) Export(ctx context.Context, rsp *gnmi.SubscribeResponse, m o
 }
 
 func (c *Collector) Capabilities(ctx context.Context, tName string, ext ...*gnmi_ext.Extension) (*gnmi.CapabilityResponse, error) {
-	if _, ok := c.Targets[tName]; !ok {
-		err := c.initTarget(tName)
-		if err != nil {
-			return nil, err
-		}
+	err := c.initTarget(tName)
+	if err != nil {
+		return nil, err
 	}
 	c.m.Lock()
 	defer c.m.Unlock()
 	if t, ok := c.Targets[tName]; ok {
 		ctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
 		defer cancel()
-		if t.Client == nil {
-			if err := t.CreateGNMIClient(ctx, c.dialOpts...); err != nil {
-				if errors.Is(err, context.DeadlineExceeded) {
-					return nil, fmt.Errorf("failed to create a gRPC client for target '%s', timeout (%s) reached", t.Config.Name, t.Config.Timeout)
-				}
-				return nil, fmt.Errorf("failed to create a gRPC client for target '%s' : %v", t.Config.Name, err)
-			}
+		if err := c.targetClient(ctx, t); err != nil {
+			return nil, err
 		}
 		return t.Capabilities(ctx, ext...)
 	}
@@ -809,24 +993,17 @@ func (c *Collector) Capabilities(ctx context.Context, tName string, ext ...*gnmi
 }
 
 func (c *Collector) Get(ctx context.Context, tName string, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
-	if _, ok := c.Targets[tName]; !ok {
-		err := c.initTarget(tName)
-		if err != nil {
-			return nil, err
-		}
+	err := c.initTarget(tName)
+	if err != nil {
+		return nil, err
 	}
 	c.m.Lock()
 	defer c.m.Unlock()
 	if t, ok := c.Targets[tName]; ok {
 		ctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
 		defer cancel()
-		if t.Client == nil {
-			if err := t.CreateGNMIClient(ctx, c.dialOpts...); err != nil {
-				if errors.Is(err, context.DeadlineExceeded) {
-					return nil, fmt.Errorf("failed to create a gRPC client for target '%s', timeout (%s) reached", t.Config.Name, t.Config.Timeout)
-				}
-				return nil, fmt.Errorf("failed to create a gRPC client for target '%s' : %v", t.Config.Name, err)
-			}
+		if err := c.targetClient(ctx, t); err != nil {
+			return nil, err
 		}
 		return t.Get(ctx, req)
 	}
@@ -834,24 +1011,17 @@ func (c *Collector) Get(ctx context.Context, tName string, req *gnmi.GetRequest)
 }
 
 func (c *Collector) Set(ctx context.Context, tName string, req *gnmi.SetRequest) (*gnmi.SetResponse, error) {
-	if _, ok := c.Targets[tName]; !ok {
-		err := c.initTarget(tName)
-		if err != nil {
-			return nil, err
-		}
+	err := c.initTarget(tName)
+	if err != nil {
+		return nil, err
 	}
 	c.m.Lock()
 	defer c.m.Unlock()
 	if t, ok := c.Targets[tName]; ok {
 		ctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
 		defer cancel()
-		if t.Client == nil {
-			if err := t.CreateGNMIClient(ctx, c.dialOpts...); err != nil {
-				if errors.Is(err, context.DeadlineExceeded) {
-					return nil, fmt.Errorf("failed to create a gRPC client for target '%s', timeout (%s) reached", t.Config.Name, t.Config.Timeout)
-				}
-				return nil, fmt.Errorf("failed to create a gRPC client for target '%s' : %v", t.Config.Name, err)
-			}
+		if err := c.targetClient(ctx, t); err != nil {
+			return nil, err
 		}
 		return t.Set(ctx, req)
 	}
@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeOutput records the subscription names of the written messages
+type fakeOutput struct {
+	m             *sync.Mutex
+	subscriptions []string
+}
+
+func newFakeOutput() *fakeOutput { return &fakeOutput{m: new(sync.Mutex)} }
+
+func (o *fakeOutput) Init(context.Context, string, map[string]interface{}, ...outputs.Option) error {
+	return nil
+}
+func (o *fakeOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.subscriptions = append(o.subscriptions, meta["subscription-name"])
+}
+func (o *fakeOutput) WriteEvent(context.Context, *formatters.EventMsg) {}
+func (o *fakeOutput) Close() error                                     { return nil }
+func (o *fakeOutput) RegisterMetrics(*prometheus.Registry)             {}
+func (o *fakeOutput) String() string                                   { return "fake" }
+func (o *fakeOutput) SetLogger(*log.Logger)                            {}
+func (o *fakeOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]interface{}) {
+}
+func (o *fakeOutput) SetName(string)        {}
+func (o *fakeOutput) SetClusterName(string) {}
+
+func TestSubscriptionOutputs(t *testing.T) {
+	tests := map[string]struct {
+		targetOutputs []string
+		// expected subscriptions written to each output
+		want map[string][]string
+	}{
+		"no_target_outputs": {
+			want: map[string][]string{
+				"prom": {"subA", "subC"},
+				"file": {"subB", "subC"},
+			},
+		},
+		"target_outputs": {
+			targetOutputs: []string{"file"},
+			want: map[string][]string{
+				"prom": {"subA"},
+				"file": {"subB", "subC"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := NewCollector(&Config{}, nil, WithLogger(log.New(ioutil.Discard, "", 0)))
+			outs := map[string]*fakeOutput{
+				"prom": newFakeOutput(),
+				"file": newFakeOutput(),
+			}
+			for n, o := range outs {
+				c.Outputs[n] = o
+			}
+			tg := NewTarget(&TargetConfig{Name: "target1", Outputs: tc.targetOutputs})
+			tg.Subscriptions = map[string]*SubscriptionConfig{
+				"subA": {Name: "subA", Outputs: []string{"prom"}},
+				"subB": {Name: "subB", Outputs: []string{"file"}},
+				"subC": {Name: "subC"},
+			}
+			for _, sub := range []string{"subA", "subB", "subC"} {
+				rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{}}}
+				m := outputs.Meta{"source": tg.Config.Name, "subscription-name": sub}
+				c.Export(context.Background(), rsp, m, tg.subscriptionOutputs(sub)...)
+			}
+			for n, o := range outs {
+				if len(o.subscriptions) != len(tc.want[n]) {
+					t.Fatalf("output %q: expected subscriptions %v, got %v", n, tc.want[n], o.subscriptions)
+				}
+				for i := range o.subscriptions {
+					if o.subscriptions[i] != tc.want[n][i] {
+						t.Errorf("output %q: expected subscriptions %v, got %v", n, tc.want[n], o.subscriptions)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTargetEventTags(t *testing.T) {
+	tg := NewTarget(&TargetConfig{
+		Name:      "router1",
+		EventTags: map[string]string{"site": "paris", "interface_name": "mgmt0"},
+	})
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+				{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+				{Name: "in-octets"},
+			}},
+			Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1}},
+		}},
+	}}}
+	evs, err := formatters.ResponseToEventMsgs("sub1", rsp, tg.outputsMeta("sub1", "event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(evs))
+	}
+	want := map[string]string{
+		"source":            "router1",
+		"subscription-name": "sub1",
+		"site":              "paris",
+		// the path keys take precedence over the target event tags
+		"interface_name":      "ethernet-1/1",
+		"meta:interface_name": "mgmt0",
+	}
+	if !reflect.DeepEqual(evs[0].Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, evs[0].Tags)
+	}
+}
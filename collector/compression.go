@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// isCompressionUnsupported returns true if err is the error returned by a gRPC server
+// that does not have a decompressor for the request compression.
+func isCompressionUnsupported(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	return st.Code() == codes.Unimplemented && strings.Contains(st.Message(), "grpc-encoding")
+}
+
+// compressionFallback marks the target as not supporting the configured compression,
+// the following RPCs are sent uncompressed.
+func (t *Target) compressionFallback(err error) bool {
+	if !isCompressionUnsupported(err) {
+		return false
+	}
+	atomic.StoreInt32(&t.compressionUnsupported, 1)
+	return true
+}
+
+func (t *Target) compressionCallOptions(opts []grpc.CallOption) []grpc.CallOption {
+	if atomic.LoadInt32(&t.compressionUnsupported) == 1 {
+		return append(opts, grpc.UseCompressor(encoding.Identity))
+	}
+	return opts
+}
+
+// compressionUnaryInterceptor retries a unary RPC uncompressed if the target
+// does not support the request compression.
+func (t *Target) compressionUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, t.compressionCallOptions(opts)...)
+	if err != nil && atomic.LoadInt32(&t.compressionUnsupported) == 0 && t.compressionFallback(err) {
+		return invoker(ctx, method, req, reply, cc, t.compressionCallOptions(opts)...)
+	}
+	return err
+}
+
+// compressionStreamInterceptor opens streams uncompressed once the target is known
+// to not support the request compression.
+// The error of a stream rejected because of its compression is only returned by RecvMsg,
+// the stream is not retried, the next one is opened uncompressed.
+func (t *Target) compressionStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, t.compressionCallOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return &compressionStream{ClientStream: s, t: t}, nil
+}
+
+type compressionStream struct {
+	grpc.ClientStream
+	t *Target
+}
+
+func (s *compressionStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.t.compressionFallback(err)
+	}
+	return err
+}
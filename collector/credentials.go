@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCredentialsTTL = time.Minute
+
+// commandCredentials holds the credentials printed by a target credentials-command
+type commandCredentials struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// credentialsCache holds the last credentials returned by the target credentials-command
+type credentialsCache struct {
+	m       sync.Mutex
+	creds   *commandCredentials
+	expires time.Time
+}
+
+// loadCredentials runs the target credentials-command if the cached credentials are missing or expired,
+// it is a no-op if the target does not have a credentials-command.
+func (t *Target) loadCredentials(ctx context.Context) error {
+	if t.Config.CredentialsCommand == "" {
+		return nil
+	}
+	t.credentials.m.Lock()
+	defer t.credentials.m.Unlock()
+	if t.credentials.creds != nil && time.Now().Before(t.credentials.expires) {
+		return nil
+	}
+	creds, err := runCredentialsCommand(ctx, t.Config.CredentialsCommand, t.Config.Timeout)
+	if err != nil {
+		return fmt.Errorf("credentials-command failed: %v", err)
+	}
+	ttl := t.Config.CredentialsTTL
+	if ttl <= 0 {
+		ttl = defaultCredentialsTTL
+	}
+	t.credentials.creds = creds
+	t.credentials.expires = time.Now().Add(ttl)
+	return nil
+}
+
+// commandCredentials returns the cached credentials-command credentials, nil if there are none.
+func (t *Target) commandCredentials() *commandCredentials {
+	if t.Config.CredentialsCommand == "" {
+		return nil
+	}
+	t.credentials.m.Lock()
+	defer t.credentials.m.Unlock()
+	return t.credentials.creds
+}
+
+// runCredentialsCommand executes command using the system shell,
+// and decodes the JSON object it prints on its stdout.
+func runCredentialsCommand(ctx context.Context, command string, timeout time.Duration) (*commandCredentials, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	creds := new(commandCredentials)
+	err = json.Unmarshal(stdout.Bytes(), creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode output: %v", err)
+	}
+	if creds.Username == "" && creds.Password == "" && creds.Token == "" {
+		return nil, fmt.Errorf("output does not contain a username, a password or a token")
+	}
+	return creds, nil
+}
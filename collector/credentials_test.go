@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// writeCredentialsHelper writes a shell script running body to dir and returns its path
+func writeCredentialsHelper(t *testing.T, dir, body string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the credentials helpers are shell scripts")
+	}
+	path := filepath.Join(dir, "helper.sh")
+	err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTargetCredentialsCommand(t *testing.T) {
+	dir := tempDir(t)
+	calls := filepath.Join(dir, "calls")
+	helper := writeCredentialsHelper(t, dir,
+		`echo call >> `+calls+`
+echo '{"username": "user1", "password": "short-lived", "token": "abc"}'`)
+
+	s := newFakeGNMIServer()
+	tg := NewTarget(&TargetConfig{
+		Name:               "router1",
+		Address:            startFakeGNMIServer(t, s),
+		Username:           strPtr("admin"),
+		Timeout:            5 * time.Second,
+		Insecure:           boolPtr(true),
+		CredentialsCommand: helper,
+		CredentialsTTL:     time.Minute,
+	})
+	for i := 0; i < 2; i++ {
+		if err := tg.CreateGNMIClient(context.Background(), grpc.WithBlock()); err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		if _, err := tg.Capabilities(context.Background()); err != nil {
+			t.Fatalf("capabilities failed: %v", err)
+		}
+		tg.closeClient()
+	}
+	// the credentials are cached for the TTL
+	b, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "call"); n != 1 {
+		t.Errorf("expected the credentials-command to run once, ran %d times", n)
+	}
+	if len(s.metadata) != 2 {
+		t.Fatalf("expected the metadata of 2 requests, got %d", len(s.metadata))
+	}
+	want := map[string]string{"username": "user1", "password": "short-lived", "authorization": "Bearer abc"}
+	for k, v := range want {
+		if got := s.metadata[1].Get(k); len(got) != 1 || got[0] != v {
+			t.Errorf("expected metadata %s=%s, got %v", k, v, got)
+		}
+	}
+
+	// the command runs again once the credentials expire
+	tg.credentials.expires = time.Now().Add(-time.Second)
+	if err := tg.loadCredentials(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "call"); n != 2 {
+		t.Errorf("expected the credentials-command to run twice, ran %d times", n)
+	}
+}
+
+// passwordGNMIServer is a flakyGNMIServer recording the password sent on each subscribe stream
+type passwordGNMIServer struct {
+	flakyGNMIServer
+
+	m         sync.Mutex
+	passwords []string
+}
+
+func (s *passwordGNMIServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	s.m.Lock()
+	s.passwords = append(s.passwords, strings.Join(md.Get("password"), ","))
+	s.m.Unlock()
+	return s.flakyGNMIServer.Subscribe(stream)
+}
+
+func TestTargetCredentialsCommandResubscribe(t *testing.T) {
+	dir := tempDir(t)
+	calls := filepath.Join(dir, "calls")
+	// each run prints a new password
+	helper := writeCredentialsHelper(t, dir,
+		`echo call >> `+calls+`
+echo "{\"password\": \"secret$(wc -l < `+calls+` | tr -d ' ')\"}"`)
+
+	s := &passwordGNMIServer{}
+	sc := &SubscriptionConfig{
+		Name:  "sub1",
+		Paths: []string{"/counter"},
+	}
+	req, err := sc.CreateSubscribeRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the credentials expire before the re-subscribe attempt
+	tg := NewTarget(&TargetConfig{
+		Name:               "router1",
+		Address:            startFakeGNMIServer(t, s),
+		Timeout:            5 * time.Second,
+		Insecure:           boolPtr(true),
+		RetryTimer:         500 * time.Millisecond,
+		CredentialsCommand: helper,
+		CredentialsTTL:     100 * time.Millisecond,
+	})
+	tg.Subscriptions[sc.Name] = sc
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := tg.CreateGNMIClient(ctx); err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	go tg.Subscribe(ctx, req, sc.Name)
+
+	rspCh, errCh := tg.ReadSubscriptions()
+	for _, want := range []int64{1, 2} {
+	WAIT:
+		select {
+		case rsp := <-rspCh:
+			if got := rsp.Response.GetUpdate().GetUpdate()[0].GetVal().GetIntVal(); got != want {
+				t.Fatalf("expected counter %d, got response %v", want, rsp.Response)
+			}
+		case <-errCh:
+			goto WAIT
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for counter %d", want)
+		}
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if len(s.passwords) != 2 {
+		t.Fatalf("expected 2 subscribe streams, got %d", len(s.passwords))
+	}
+	if s.passwords[0] == "" || s.passwords[1] == "" {
+		t.Fatalf("expected a password on each stream, got %q", s.passwords)
+	}
+	if s.passwords[0] == s.passwords[1] {
+		t.Errorf("expected the re-subscribe to send refreshed credentials, both streams sent %q", s.passwords[0])
+	}
+}
+
+func TestTargetCredentialsCommandFailure(t *testing.T) {
+	tests := map[string]struct {
+		body    string
+		wantErr string
+	}{
+		"exit_error": {
+			body:    `echo "vault is sealed" >&2; exit 1`,
+			wantErr: "vault is sealed",
+		},
+		"invalid_output": {
+			body:    `echo not-json`,
+			wantErr: "failed to decode output",
+		},
+		"empty_credentials": {
+			body:    `echo '{}'`,
+			wantErr: "does not contain",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newFakeGNMIServer()
+			tg := NewTarget(&TargetConfig{
+				Name:               "router1",
+				Address:            startFakeGNMIServer(t, s),
+				Timeout:            5 * time.Second,
+				Insecure:           boolPtr(true),
+				CredentialsCommand: writeCredentialsHelper(t, tempDir(t), tc.body),
+			})
+			err := tg.CreateGNMIClient(context.Background(), grpc.WithBlock())
+			if err == nil {
+				t.Fatal("expected the dial to fail")
+			}
+			if !strings.Contains(err.Error(), "credentials-command failed") || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tg.Client != nil {
+				t.Errorf("expected no client to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+// maximum number of updates remembered within a sync window,
+// the remembered updates are forgotten when reached.
+const dedupMaxEntries = 100000
+
+// dedup drops the exact duplicate updates (same path, value and timestamp)
+// received within a sync window, i.e from the (re-)subscription or the poll request until the SyncResponse.
+// The updates received after the SyncResponse are not filtered.
+//
+// It filters the responses in the collector, before they are written to the outputs,
+// so that the duplicates are dropped once for all the outputs, whatever their format.
+type dedup struct {
+	// nil outside of a sync window
+	seen map[string]struct{}
+}
+
+func newDedup() *dedup {
+	return new(dedup)
+}
+
+// start starts a sync window, it is called on (re-)subscription and before a poll request.
+func (d *dedup) start() {
+	d.seen = make(map[string]struct{})
+}
+
+// filter removes the already seen updates from rsp,
+// it returns false if rsp does not carry anything new and should be dropped.
+func (d *dedup) filter(rsp *gnmi.SubscribeResponse) bool {
+	switch rsp := rsp.Response.(type) {
+	case *gnmi.SubscribeResponse_SyncResponse:
+		d.seen = nil
+	case *gnmi.SubscribeResponse_Update:
+		if d.seen == nil || rsp.Update == nil || len(rsp.Update.Update) == 0 {
+			return true
+		}
+		prefix, err := proto.MarshalOptions{Deterministic: true}.Marshal(rsp.Update.Prefix)
+		if err != nil {
+			return true
+		}
+		keyPrefix := strconv.FormatInt(rsp.Update.Timestamp, 10) + "/" + strconv.Itoa(len(prefix)) + "/" + string(prefix)
+		updates := make([]*gnmi.Update, 0, len(rsp.Update.Update))
+		for _, upd := range rsp.Update.Update {
+			b, err := proto.MarshalOptions{Deterministic: true}.Marshal(upd)
+			if err != nil {
+				updates = append(updates, upd)
+				continue
+			}
+			key := keyPrefix + string(b)
+			if _, ok := d.seen[key]; ok {
+				continue
+			}
+			if len(d.seen) >= dedupMaxEntries {
+				d.start()
+			}
+			d.seen[key] = struct{}{}
+			updates = append(updates, upd)
+		}
+		rsp.Update.Update = updates
+		return len(updates) > 0 || len(rsp.Update.Delete) > 0
+	}
+	return true
+}
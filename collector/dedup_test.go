@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func testUpdate(elem string, value int64) *gnmi.Update {
+	return &gnmi.Update{
+		Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: elem}}},
+		Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: value}},
+	}
+}
+
+func testNotification(ts int64, upds ...*gnmi.Update) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: ts,
+				Update:    upds,
+			},
+		},
+	}
+}
+
+var syncResponse = &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}
+
+func TestDedupFilter(t *testing.T) {
+	tests := map[string]struct {
+		in         []*gnmi.SubscribeResponse
+		wantEvents int
+	}{
+		"duplicates_in_same_response": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1), testUpdate("a", 1), testUpdate("b", 1)),
+			},
+			wantEvents: 2,
+		},
+		"duplicates_across_responses": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1)),
+				testNotification(1, testUpdate("a", 1)),
+			},
+			wantEvents: 1,
+		},
+		"different_values": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1)),
+				testNotification(1, testUpdate("a", 2)),
+			},
+			wantEvents: 2,
+		},
+		"different_timestamps": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1)),
+				testNotification(2, testUpdate("a", 1)),
+			},
+			wantEvents: 2,
+		},
+		"sync_response_ends_window": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1)),
+				syncResponse,
+				testNotification(1, testUpdate("a", 1)),
+				testNotification(1, testUpdate("a", 1)),
+			},
+			wantEvents: 3,
+		},
+		"poll_starts_window": {
+			in: []*gnmi.SubscribeResponse{
+				testNotification(1, testUpdate("a", 1)),
+				syncResponse,
+				nil, // poll request
+				testNotification(1, testUpdate("a", 1)),
+				testNotification(1, testUpdate("a", 1)),
+				syncResponse,
+			},
+			wantEvents: 2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dd := newDedup()
+			dd.start()
+			numEvents := 0
+			for _, rsp := range tc.in {
+				if rsp == nil {
+					dd.start()
+					continue
+				}
+				if !dd.filter(rsp) {
+					continue
+				}
+				evs, err := formatters.ResponseToEventMsgs("sub1", rsp, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				numEvents += len(evs)
+			}
+			if numEvents != tc.wantEvents {
+				t.Errorf("expected %d events, got %d", tc.wantEvents, numEvents)
+			}
+		})
+	}
+}
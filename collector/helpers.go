@@ -178,3 +178,12 @@ func parseXPathKeys(s string) (map[string]string, error) {
 	}
 	return kvs, nil
 }
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,11 @@ package collector
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/karimra/gnmic/lockers"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func WithLocker(locker lockers.Locker) CollectorOption {
@@ -18,3 +21,66 @@ func (c *Collector) lockKey(s string) string {
 	}
 	return fmt.Sprintf("gnmic/%s/targets/%s", c.Config.ClusterName, s)
 }
+
+// assignmentsCollector implements prometheus.Collector,
+// it keeps the targets locked by the local instance and exports a gauge per target set to 1.
+type assignmentsCollector struct {
+	desc *prometheus.Desc
+
+	m       *sync.Mutex
+	targets map[string]struct{}
+}
+
+func newAssignmentsCollector(instance string) *assignmentsCollector {
+	return &assignmentsCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("gnmic", "cluster", "target_assignment"),
+			"Targets assigned to the gnmic instance, i.e whose lock it holds",
+			[]string{"target"},
+			prometheus.Labels{"gnmic_instance": instance},
+		),
+		m:       new(sync.Mutex),
+		targets: make(map[string]struct{}),
+	}
+}
+
+func (a *assignmentsCollector) assign(target string) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.targets[target] = struct{}{}
+}
+
+func (a *assignmentsCollector) unassign(target string) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	delete(a.targets, target)
+}
+
+func (a *assignmentsCollector) list() []string {
+	a.m.Lock()
+	defer a.m.Unlock()
+	targets := make([]string, 0, len(a.targets))
+	for t := range a.targets {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Describe implements prometheus.Collector
+func (a *assignmentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.desc
+}
+
+// Collect implements prometheus.Collector
+func (a *assignmentsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, t := range a.list() {
+		ch <- prometheus.MustNewConstMetric(a.desc, prometheus.GaugeValue, 1, t)
+	}
+}
+
+// AssignedTargets returns the sorted names of the targets whose lock is held by the collector,
+// it is empty if the collector does not run in a cluster.
+func (c *Collector) AssignedTargets() []string {
+	return c.assignments.list()
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/lockers"
+)
+
+// fakeLocker grants all the locks and keeps them until done is closed
+type fakeLocker struct {
+	lockers.Locker
+	done chan struct{}
+}
+
+func (l *fakeLocker) Lock(context.Context, string, []byte) (bool, error) { return true, nil }
+func (l *fakeLocker) KeepLock(context.Context, string) (chan struct{}, chan error) {
+	return l.done, make(chan error)
+}
+func (l *fakeLocker) Unlock(context.Context, string) error { return nil }
+
+func TestTargetAssignments(t *testing.T) {
+	l := &fakeLocker{done: make(chan struct{})}
+	c := NewCollector(
+		&Config{Name: "gnmic1", ClusterName: "cluster1", PrometheusAddress: "127.0.0.1:0"},
+		map[string]*TargetConfig{"router1": {Name: "router1", Address: "127.0.0.1:57400"}},
+		WithLogger(log.New(ioutil.Discard, "", 0)),
+		WithLocker(l),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// assignments returns the targets label of the gnmic_cluster_target_assignment series
+	// once they match the assigned targets
+	assignments := func(want []string) []string {
+		deadline := time.Now().Add(time.Second)
+		for !reflect.DeepEqual(c.AssignedTargets(), want) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mfs, err := c.reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets := make([]string, 0)
+		for _, mf := range mfs {
+			if mf.GetName() != "gnmic_cluster_target_assignment" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := make(map[string]string)
+				for _, lb := range m.GetLabel() {
+					labels[lb.GetName()] = lb.GetValue()
+				}
+				if labels["gnmic_instance"] != "gnmic1" || m.GetGauge().GetValue() != 1 {
+					t.Errorf("unexpected assignment series: %v", m)
+				}
+				targets = append(targets, labels["target"])
+			}
+		}
+		return targets
+	}
+
+	if got := assignments([]string{}); len(got) != 0 {
+		t.Fatalf("expected no assigned targets, got %v", got)
+	}
+	go c.TargetSubscribeStream(ctx, "router1")
+	<-c.targetsChan
+	if got := assignments([]string{"router1"}); !reflect.DeepEqual(got, []string{"router1"}) {
+		t.Fatalf("expected router1 to be assigned, got %v", got)
+	}
+	// the target lock is removed
+	close(l.done)
+	if got := assignments([]string{}); len(got) != 0 {
+		t.Fatalf("expected no assigned targets after the lock removal, got %v", got)
+	}
+	if got := c.AssignedTargets(); len(got) != 0 {
+		t.Fatalf("expected no assigned targets, got %v", got)
+	}
+}
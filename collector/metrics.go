@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	rpcCapabilities = "capabilities"
+	rpcGet          = "get"
+	rpcSet          = "set"
+	rpcSubscribe    = "subscribe"
+)
+
+// rpcMetrics holds the gNMI RPCs latency and errors metrics,
+// exported by the collector prometheus server.
+type rpcMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func newRPCMetrics() *rpcMetrics {
+	return &rpcMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gnmic",
+			Subsystem: "rpc",
+			Name:      "duration_seconds",
+			Help:      "Duration of the gNMI RPCs, up to the first sync response for the subscriptions",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"rpc", "target"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gnmic",
+			Subsystem: "rpc",
+			Name:      "errors_total",
+			Help:      "Number of failed gNMI RPCs by gRPC status code",
+		}, []string{"rpc", "target", "code"}),
+	}
+}
+
+func (m *rpcMetrics) register(reg *prometheus.Registry) {
+	reg.MustRegister(m.duration)
+	reg.MustRegister(m.errors)
+}
+
+// observe records the duration of an RPC started at start, and its error if not nil.
+// It is a no-op if the collector metrics are disabled.
+func (m *rpcMetrics) observe(rpc, target string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.duration.WithLabelValues(rpc, target).Observe(time.Since(start).Seconds())
+	m.failed(rpc, target, err)
+}
+
+// failed counts err as an RPC error if not nil.
+func (m *rpcMetrics) failed(rpc, target string, err error) {
+	if m == nil || err == nil {
+		return
+	}
+	m.errors.WithLabelValues(rpc, target, status.Code(err).String()).Inc()
+}
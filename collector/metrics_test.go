@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// findMetric returns the metric of the family name having all the labels, nil if not found
+func findMetric(t *testing.T, c *Collector, name string, labels map[string]string) *dto.Metric {
+	mfs, err := c.reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather the metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+	METRICS:
+		for _, m := range mf.GetMetric() {
+			for k, v := range labels {
+				found := false
+				for _, lp := range m.GetLabel() {
+					if lp.GetName() == k && lp.GetValue() == v {
+						found = true
+						break
+					}
+				}
+				if !found {
+					continue METRICS
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
+
+func TestRPCMetrics(t *testing.T) {
+	// the metrics server is not started, the metrics are gathered from the registry
+	c, _ := newPoolTestCollector(t, &Config{PrometheusAddress: "127.0.0.1:0", ClientIdleTimeout: time.Minute})
+	if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	m := findMetric(t, c, "gnmic_rpc_duration_seconds", map[string]string{"rpc": "get", "target": "router1"})
+	if m == nil || m.GetHistogram().GetSampleCount() != 1 {
+		t.Fatalf("expected a get duration observation, got %v", m)
+	}
+	// the fake server does not implement Set
+	if _, err := c.Set(context.Background(), "router1", &gnmi.SetRequest{}); err == nil {
+		t.Fatal("expected the set to fail")
+	}
+	m = findMetric(t, c, "gnmic_rpc_errors_total", map[string]string{"rpc": "set", "target": "router1", "code": "Unimplemented"})
+	if m == nil || m.GetCounter().GetValue() != 1 {
+		t.Errorf("expected a set error with code Unimplemented, got %v", m)
+	}
+	if m := findMetric(t, c, "gnmic_rpc_errors_total", map[string]string{"rpc": "get"}); m != nil {
+		t.Errorf("expected no get error, got %v", m)
+	}
+}
+
+func TestRPCMetricsDisabled(t *testing.T) {
+	c, _ := newPoolTestCollector(t, &Config{ClientIdleTimeout: time.Minute})
+	if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if c.rpcMetrics != nil || c.Targets["router1"].rpcMetrics != nil {
+		t.Error("expected the RPC metrics to be disabled without a prometheus address")
+	}
+}
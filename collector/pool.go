@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+const defaultClientIdleTimeout = 5 * time.Minute
+
+// ClientPoolStats holds the counters of the targets gNMI clients
+// used for the Capabilities, Get and Set RPCs.
+type ClientPoolStats struct {
+	// number of targets with an open gRPC connection
+	Active int `json:"active"`
+	// number of gRPC connections dialed
+	Dials uint64 `json:"dials"`
+	// number of RPCs sent over an already open connection
+	Reuses uint64 `json:"reuses"`
+	// number of connections closed after being unused for the client-idle-timeout
+	IdleEvictions uint64 `json:"idle-evictions"`
+	// number of connections closed because they were in a failed state
+	FailedEvictions uint64 `json:"failed-evictions"`
+}
+
+// ClientPoolStats returns the targets gNMI clients counters
+func (c *Collector) ClientPoolStats() ClientPoolStats {
+	c.m.Lock()
+	defer c.m.Unlock()
+	stats := c.clientPool
+	for _, t := range c.Targets {
+		if t.Client != nil {
+			stats.Active++
+		}
+	}
+	return stats
+}
+
+// targetClient makes sure the target has a gNMI client, it must be called with c.m locked.
+// An existing client is reused unless its connection is in a failed state, in which case a new one is dialed.
+// A failed connection used by running subscriptions is kept unless it is shut down, gRPC reconnects it.
+// The connection is closed once unused for the client-idle-timeout.
+func (c *Collector) targetClient(ctx context.Context, t *Target) error {
+	if t.Client != nil {
+		if t.healthy() || (t.subscribed() && t.conn.GetState() != connectivity.Shutdown) {
+			// the credentials are sent with each RPC, they are refreshed without dialing again
+			if err := t.loadCredentials(ctx); err != nil {
+				return fmt.Errorf("failed to get the credentials of target '%s': %v", t.Config.Name, err)
+			}
+			c.clientPool.Reuses++
+			c.touchClient(t)
+			return nil
+		}
+		c.logger.Printf("target %q connection is in a failed state, reconnecting", t.Config.Name)
+		t.closeClient()
+		c.clientPool.FailedEvictions++
+	}
+	if err := t.CreateGNMIClient(ctx, c.dialOpts...); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to create a gRPC client for target '%s', timeout (%s) reached", t.Config.Name, t.Config.Timeout)
+		}
+		return fmt.Errorf("failed to create a gRPC client for target '%s' : %v", t.Config.Name, err)
+	}
+	c.clientPool.Dials++
+	c.touchClient(t)
+	return nil
+}
+
+// touchClient records the target client use and (re)starts its idle timer.
+func (c *Collector) touchClient(t *Target) {
+	if c.Config.ClientIdleTimeout <= 0 {
+		return
+	}
+	t.lastUsed = time.Now()
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+	}
+	t.idleTimer = time.AfterFunc(c.Config.ClientIdleTimeout, func() {
+		c.evictIdleClient(t)
+	})
+}
+
+// evictIdleClient closes the target connection if it was not used for the client-idle-timeout,
+// the connections used by subscriptions are kept.
+func (c *Collector) evictIdleClient(t *Target) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if t.Client == nil || time.Since(t.lastUsed) < c.Config.ClientIdleTimeout {
+		return
+	}
+	if t.subscribed() {
+		return
+	}
+	c.logger.Printf("closing target %q idle connection", t.Config.Name)
+	t.closeClient()
+	c.clientPool.IdleEvictions++
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+)
+
+// countingListener counts the accepted connections
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+func newPoolTestCollector(t *testing.T, cfg *Config, opts ...CollectorOption) (*Collector, *countingListener) {
+	return newPoolTestCollectorWithServer(t, cfg, newFakeGNMIServer(), opts...)
+}
+
+func newPoolTestCollectorWithServer(t *testing.T, cfg *Config, srv gnmi.GNMIServer, opts ...CollectorOption) (*Collector, *countingListener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	cl := &countingListener{Listener: l}
+	gs := grpc.NewServer()
+	gnmi.RegisterGNMIServer(gs, srv)
+	go gs.Serve(cl)
+	t.Cleanup(gs.Stop)
+
+	c := NewCollector(cfg,
+		map[string]*TargetConfig{
+			"router1": {
+				Name:     "router1",
+				Address:  l.Addr().String(),
+				Timeout:  5 * time.Second,
+				Insecure: boolPtr(true),
+			},
+		},
+		append([]CollectorOption{
+			WithDialOptions([]grpc.DialOption{grpc.WithBlock()}),
+			WithLogger(log.New(ioutil.Discard, "", 0)),
+		}, opts...)...,
+	)
+	return c, cl
+}
+
+func TestCollectorGetReusesConnection(t *testing.T) {
+	c, cl := newPoolTestCollector(t, &Config{ClientIdleTimeout: time.Minute})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+			t.Fatalf("get %d failed: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&cl.accepted); n != 1 {
+		t.Errorf("expected the 2 Gets to use a single connection, got %d", n)
+	}
+	want := ClientPoolStats{Active: 1, Dials: 1, Reuses: 1}
+	if got := c.ClientPoolStats(); got != want {
+		t.Errorf("expected stats %+v, got %+v", want, got)
+	}
+	// a failed connection is not reused
+	c.m.Lock()
+	c.Targets["router1"].conn.Close()
+	c.m.Unlock()
+	if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get after connection failure failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&cl.accepted); n != 2 {
+		t.Errorf("expected a new connection after the failure, got %d connections", n)
+	}
+	want = ClientPoolStats{Active: 1, Dials: 2, Reuses: 1, FailedEvictions: 1}
+	if got := c.ClientPoolStats(); got != want {
+		t.Errorf("expected stats %+v, got %+v", want, got)
+	}
+}
+
+func TestCollectorIdleClientEviction(t *testing.T) {
+	c, cl := newPoolTestCollector(t, &Config{ClientIdleTimeout: 50 * time.Millisecond})
+	if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.ClientPoolStats().IdleEvictions == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the idle connection was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := c.Get(context.Background(), "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get after eviction failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&cl.accepted); n != 2 {
+		t.Errorf("expected a new connection after the eviction, got %d connections", n)
+	}
+	if got := c.ClientPoolStats(); got.Dials != 2 || got.Reuses != 0 || got.IdleEvictions < 1 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
+
+// getStreamGNMIServer is a streamGNMIServer answering the Gets with an empty response
+type getStreamGNMIServer struct {
+	streamGNMIServer
+}
+
+func (s *getStreamGNMIServer) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	return &gnmi.GetResponse{}, nil
+}
+
+func TestCollectorGetFailedConnectionWithSubscription(t *testing.T) {
+	c, cl := newPoolTestCollectorWithServer(t, &Config{ClientIdleTimeout: time.Minute}, new(getStreamGNMIServer),
+		WithSubscriptions(map[string]*SubscriptionConfig{
+			"sub1": {Name: "sub1", Paths: []string{"/counter"}},
+		}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.initTarget("router1"); err != nil {
+		t.Fatal(err)
+	}
+	tg := c.Targets["router1"]
+	tg.Config.RetryTimer = 10 * time.Millisecond
+	rsps := make(chan *SubscribeResponse, 100)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tg.errors:
+			case rsp := <-tg.subscribeResponses:
+				rsps <- rsp
+			}
+		}
+	}()
+	// the subscription and the Gets share the pooled connection
+	if err := c.Subscribe(ctx, "router1"); err != nil {
+		t.Fatal(err)
+	}
+	waitResponse := func() {
+		select {
+		case <-rsps:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a subscribe response")
+		}
+	}
+	waitResponse()
+	if _, err := c.Get(ctx, "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	// drop the responses of the first stream
+	time.Sleep(50 * time.Millisecond)
+	for len(rsps) > 0 {
+		<-rsps
+	}
+	if n := atomic.LoadInt32(&cl.accepted); n != 1 {
+		t.Errorf("expected the subscription and the get to share a connection, got %d connections", n)
+	}
+	// the connection fails while the subscription is running,
+	// the get dials a new one and the subscription re-subscribes over it
+	c.m.Lock()
+	tg.conn.Close()
+	c.m.Unlock()
+	if _, err := c.Get(ctx, "router1", &gnmi.GetRequest{}); err != nil {
+		t.Fatalf("get after connection failure failed: %v", err)
+	}
+	waitResponse()
+	if n := atomic.LoadInt32(&cl.accepted); n != 2 {
+		t.Errorf("expected a single new connection, got %d connections", n)
+	}
+}
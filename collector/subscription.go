@@ -13,6 +13,11 @@ const (
 	subscriptionDefaultMode       = "STREAM"
 	subscriptionDefaultStreamMode = "TARGET_DEFINED"
 	subscriptionDefaultEncoding   = "JSON"
+
+	// the prefix is sent as the SubscriptionList prefix
+	prefixModePrefix = "prefix"
+	// the prefix is prepended to each subscription path
+	prefixModeConcat = "concat"
 )
 
 // SubscriptionConfig //
@@ -20,6 +25,7 @@ type SubscriptionConfig struct {
 	Name              string         `mapstructure:"name,omitempty" json:"name,omitempty"`
 	Models            []string       `mapstructure:"models,omitempty" json:"models,omitempty"`
 	Prefix            string         `mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	PrefixMode        string         `mapstructure:"prefix-mode,omitempty" json:"prefix-mode,omitempty"`
 	Target            string         `mapstructure:"target,omitempty" json:"target,omitempty"`
 	Paths             []string       `mapstructure:"paths,omitempty" json:"paths,omitempty"`
 	Mode              string         `mapstructure:"mode,omitempty" json:"mode,omitempty"`
@@ -28,8 +34,11 @@ type SubscriptionConfig struct {
 	Qos               *uint32        `mapstructure:"qos,omitempty" json:"qos,omitempty"`
 	SampleInterval    *time.Duration `mapstructure:"sample-interval,omitempty" json:"sample-interval,omitempty"`
 	HeartbeatInterval *time.Duration `mapstructure:"heartbeat-interval,omitempty" json:"heartbeat-interval,omitempty"`
+	PollInterval      time.Duration  `mapstructure:"poll-interval,omitempty" json:"poll-interval,omitempty"`
 	SuppressRedundant bool           `mapstructure:"suppress-redundant,omitempty" json:"suppress-redundant,omitempty"`
 	UpdatesOnly       bool           `mapstructure:"updates-only,omitempty" json:"updates-only,omitempty"`
+	DedupSync         bool           `mapstructure:"dedup-sync,omitempty" json:"dedup-sync,omitempty"`
+	Outputs           []string       `mapstructure:"outputs,omitempty" json:"outputs,omitempty"`
 }
 type subscriptionRequest struct {
 	name string
@@ -58,6 +67,14 @@ func (sc *SubscriptionConfig) setDefaults() error {
 	if sc.Encoding == "" {
 		sc.Encoding = subscriptionDefaultEncoding
 	}
+	sc.PrefixMode = strings.ToLower(sc.PrefixMode)
+	switch sc.PrefixMode {
+	case "":
+		sc.PrefixMode = prefixModePrefix
+	case prefixModePrefix, prefixModeConcat:
+	default:
+		return fmt.Errorf("subscription '%s' invalid prefix-mode '%s', must be one of %s or %s", sc.Name, sc.PrefixMode, prefixModePrefix, prefixModeConcat)
+	}
 	return nil
 }
 
@@ -70,6 +87,13 @@ func (sc *SubscriptionConfig) CreateSubscribeRequest() (*gnmi.SubscribeRequest,
 	if err != nil {
 		return nil, fmt.Errorf("prefix parse error: %v", err)
 	}
+	// the prefix elements are prepended to the paths,
+	// only the target is sent as prefix
+	var pathsPrefix *gnmi.Path
+	if sc.PrefixMode == prefixModeConcat && sc.Prefix != "" {
+		pathsPrefix = gnmiPrefix
+		gnmiPrefix, _ = CreatePrefix("", sc.Target)
+	}
 	encodingVal, ok := gnmi.Encoding_value[strings.Replace(strings.ToUpper(sc.Encoding), "-", "_", -1)]
 	if !ok {
 		return nil, fmt.Errorf("subscription '%s' invalid encoding type '%s'", sc.Name, sc.Encoding)
@@ -78,6 +102,9 @@ func (sc *SubscriptionConfig) CreateSubscribeRequest() (*gnmi.SubscribeRequest,
 	if !ok {
 		return nil, fmt.Errorf("subscription '%s' invalid subscription list type '%s'", sc.Name, sc.Mode)
 	}
+	if sc.UpdatesOnly && gnmi.SubscriptionList_Mode(modeVal) != gnmi.SubscriptionList_STREAM {
+		return nil, fmt.Errorf("subscription '%s' updates-only is only supported with mode STREAM, got '%s'", sc.Name, sc.Mode)
+	}
 	var qos *gnmi.QOSMarking
 	if sc.Qos != nil {
 		qos = &gnmi.QOSMarking{Marking: *sc.Qos}
@@ -89,6 +116,15 @@ func (sc *SubscriptionConfig) CreateSubscribeRequest() (*gnmi.SubscribeRequest,
 		if err != nil {
 			return nil, fmt.Errorf("path '%s' parse error: %v", p, err)
 		}
+		if gnmiPrefix.GetOrigin() != "" && gnmiPath.GetOrigin() != "" && gnmiPrefix.GetOrigin() != gnmiPath.GetOrigin() {
+			return nil, fmt.Errorf("path '%s' origin '%s' does not match the prefix origin '%s'", p, gnmiPath.GetOrigin(), gnmiPrefix.GetOrigin())
+		}
+		if pathsPrefix != nil {
+			gnmiPath, err = joinPaths(pathsPrefix, gnmiPath)
+			if err != nil {
+				return nil, fmt.Errorf("path '%s': %v", p, err)
+			}
+		}
 		subscriptions[i] = &gnmi.Subscription{Path: gnmiPath}
 		switch gnmi.SubscriptionList_Mode(modeVal) {
 		case gnmi.SubscriptionList_STREAM:
@@ -132,6 +168,25 @@ func (sc *SubscriptionConfig) CreateSubscribeRequest() (*gnmi.SubscribeRequest,
 	}, nil
 }
 
+// joinPaths returns a path made of the prefix elements followed by the path p elements.
+// The origin is taken from either of them, it must be the same if both are set.
+func joinPaths(prefix, p *gnmi.Path) (*gnmi.Path, error) {
+	origin := prefix.GetOrigin()
+	if p.GetOrigin() != "" {
+		if origin != "" && origin != p.GetOrigin() {
+			return nil, fmt.Errorf("origin '%s' does not match the prefix origin '%s'", p.GetOrigin(), origin)
+		}
+		origin = p.GetOrigin()
+	}
+	elems := make([]*gnmi.PathElem, 0, len(prefix.GetElem())+len(p.GetElem()))
+	elems = append(elems, prefix.GetElem()...)
+	elems = append(elems, p.GetElem()...)
+	return &gnmi.Path{
+		Origin: origin,
+		Elem:   elems,
+	}, nil
+}
+
 // SubscribeResponse //
 type SubscribeResponse struct {
 	SubscriptionName string
@@ -0,0 +1,495 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/protobuf/proto"
+)
+
+// streamGNMIServer sends the initial state of a single leaf followed by a sync response
+// then a single change, the initial state is not sent if the request sets updates_only.
+type streamGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+}
+
+func (s *streamGNMIServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	rsps := make([]*gnmi.SubscribeResponse, 0, 3)
+	if !req.GetSubscribe().GetUpdatesOnly() {
+		rsps = append(rsps, testNotification(1, testUpdate("counter", 1)))
+	}
+	rsps = append(rsps, syncResponse, testNotification(2, testUpdate("counter", 2)))
+	for _, rsp := range rsps {
+		if err := stream.Send(rsp); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestCreateSubscribeRequestUpdatesOnly(t *testing.T) {
+	tests := map[string]struct {
+		mode    string
+		wantErr bool
+	}{
+		"stream": {
+			mode: "stream",
+		},
+		"default_mode": {
+			mode: "",
+		},
+		"once": {
+			mode:    "once",
+			wantErr: true,
+		},
+		"poll": {
+			mode:    "poll",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sc := &SubscriptionConfig{
+				Name:        "sub1",
+				Paths:       []string{"/counter"},
+				Mode:        tc.mode,
+				UpdatesOnly: true,
+			}
+			req, err := sc.CreateSubscribeRequest()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got request: %v", req)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !req.GetSubscribe().GetUpdatesOnly() {
+				t.Errorf("expected updates_only to be set: %v", req)
+			}
+		})
+	}
+}
+
+func TestCreateSubscribeRequestPrefix(t *testing.T) {
+	elems := func(names ...string) []*gnmi.PathElem {
+		pes := make([]*gnmi.PathElem, 0, len(names))
+		for _, n := range names {
+			pes = append(pes, &gnmi.PathElem{Name: n})
+		}
+		return pes
+	}
+	tests := map[string]struct {
+		sc         *SubscriptionConfig
+		wantPrefix *gnmi.Path
+		wantPaths  []*gnmi.Path
+		wantErr    bool
+	}{
+		"no_prefix": {
+			sc:        &SubscriptionConfig{Paths: []string{"/interfaces/interface"}},
+			wantPaths: []*gnmi.Path{{Elem: elems("interfaces", "interface")}},
+		},
+		"prefix": {
+			sc: &SubscriptionConfig{
+				Prefix: "openconfig:/interfaces/interface",
+				Paths:  []string{"state/counters", "config"},
+			},
+			wantPrefix: &gnmi.Path{Origin: "openconfig", Elem: elems("interfaces", "interface")},
+			wantPaths: []*gnmi.Path{
+				{Elem: elems("state", "counters")},
+				{Elem: elems("config")},
+			},
+		},
+		"concat": {
+			sc: &SubscriptionConfig{
+				Prefix:     "/interfaces/interface",
+				PrefixMode: "concat",
+				Target:     "router1",
+				Paths:      []string{"state/counters", "/config"},
+			},
+			wantPrefix: &gnmi.Path{Target: "router1"},
+			wantPaths: []*gnmi.Path{
+				{Elem: elems("interfaces", "interface", "state", "counters")},
+				{Elem: elems("interfaces", "interface", "config")},
+			},
+		},
+		"concat_prefix_origin": {
+			sc: &SubscriptionConfig{
+				Prefix:     "openconfig:/interfaces",
+				PrefixMode: "concat",
+				Paths:      []string{"interface", "openconfig:/interface/config"},
+			},
+			wantPaths: []*gnmi.Path{
+				{Origin: "openconfig", Elem: elems("interfaces", "interface")},
+				{Origin: "openconfig", Elem: elems("interfaces", "interface", "config")},
+			},
+		},
+		"concat_path_origin": {
+			sc: &SubscriptionConfig{
+				Prefix:     "/interfaces",
+				PrefixMode: "concat",
+				Paths:      []string{"openconfig:/interface"},
+			},
+			wantPaths: []*gnmi.Path{
+				{Origin: "openconfig", Elem: elems("interfaces", "interface")},
+			},
+		},
+		"prefix_origin_mismatch": {
+			sc: &SubscriptionConfig{
+				Prefix: "openconfig:/interfaces",
+				Paths:  []string{"cli:/interface"},
+			},
+			wantErr: true,
+		},
+		"concat_origin_mismatch": {
+			sc: &SubscriptionConfig{
+				Prefix:     "openconfig:/interfaces",
+				PrefixMode: "concat",
+				Paths:      []string{"cli:/interface"},
+			},
+			wantErr: true,
+		},
+		"invalid_prefix_mode": {
+			sc: &SubscriptionConfig{
+				Prefix:     "/interfaces",
+				PrefixMode: "append",
+				Paths:      []string{"interface"},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.sc.Name = "sub1"
+			req, err := tc.sc.CreateSubscribeRequest()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got request: %v", req)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prefix := req.GetSubscribe().GetPrefix(); !proto.Equal(prefix, tc.wantPrefix) {
+				t.Errorf("unexpected prefix: got %v, want %v", prefix, tc.wantPrefix)
+			}
+			subs := req.GetSubscribe().GetSubscription()
+			if len(subs) != len(tc.wantPaths) {
+				t.Fatalf("expected %d subscriptions, got %d", len(tc.wantPaths), len(subs))
+			}
+			for i, sub := range subs {
+				if !proto.Equal(sub.GetPath(), tc.wantPaths[i]) {
+					t.Errorf("unexpected path %d: got %v, want %v", i, sub.GetPath(), tc.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeUpdatesOnly(t *testing.T) {
+	tests := map[string]struct {
+		updatesOnly bool
+		// expected counter values received before and after the sync response
+		want []int64
+	}{
+		"full_sync": {
+			updatesOnly: false,
+			want:        []int64{1, 0, 2},
+		},
+		"updates_only": {
+			updatesOnly: true,
+			want:        []int64{0, 2},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			addr := startFakeGNMIServer(t, &streamGNMIServer{})
+			sc := &SubscriptionConfig{
+				Name:        "sub1",
+				Paths:       []string{"/counter"},
+				UpdatesOnly: tc.updatesOnly,
+			}
+			req, err := sc.CreateSubscribeRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tg := NewTarget(&TargetConfig{
+				Name:       name,
+				Address:    addr,
+				Timeout:    5 * time.Second,
+				Insecure:   boolPtr(true),
+				BufferSize: 10,
+			})
+			tg.Subscriptions[sc.Name] = sc
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err = tg.CreateGNMIClient(ctx)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			go tg.Subscribe(ctx, req, sc.Name)
+
+			rspCh, errCh := tg.ReadSubscriptions()
+			for _, want := range tc.want {
+				select {
+				case rsp := <-rspCh:
+					got := int64(0) // sync response
+					if upd := rsp.Response.GetUpdate(); upd != nil {
+						got = upd.GetUpdate()[0].GetVal().GetIntVal()
+					}
+					if got != want {
+						t.Fatalf("expected counter %d, got response %v", want, rsp.Response)
+					}
+				case err := <-errCh:
+					t.Fatalf("subscription failed: %v", err.Err)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timeout waiting for counter %d", want)
+				}
+			}
+		})
+	}
+}
+
+// flakyGNMIServer closes the first subscribe stream after sending a single notification,
+// the following streams send a second notification and stay open.
+type flakyGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	streams int32
+}
+
+func (s *flakyGNMIServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	if atomic.AddInt32(&s.streams, 1) == 1 {
+		return stream.Send(testNotification(1, testUpdate("counter", 1)))
+	}
+	if err := stream.Send(testNotification(2, testUpdate("counter", 2))); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestSubscribeReconnect(t *testing.T) {
+	s := &flakyGNMIServer{}
+	addr := startFakeGNMIServer(t, s)
+	sc := &SubscriptionConfig{
+		Name:  "sub1",
+		Paths: []string{"/counter"},
+	}
+	req, err := sc.CreateSubscribeRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := NewTarget(&TargetConfig{
+		Name:       "target1",
+		Address:    addr,
+		Timeout:    5 * time.Second,
+		Insecure:   boolPtr(true),
+		BufferSize: 10,
+		RetryTimer: 10 * time.Millisecond,
+	})
+	tg.reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "reconnects_total"}, []string{"target", "subscription"})
+	tg.Subscriptions[sc.Name] = sc
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = tg.CreateGNMIClient(ctx)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	go tg.Subscribe(ctx, req, sc.Name)
+
+	rspCh, errCh := tg.ReadSubscriptions()
+	numErrs := 0
+	for _, want := range []int64{1, 2} {
+	WAIT:
+		select {
+		case rsp := <-rspCh:
+			if got := rsp.Response.GetUpdate().GetUpdate()[0].GetVal().GetIntVal(); got != want {
+				t.Fatalf("expected counter %d, got response %v", want, rsp.Response)
+			}
+		case <-errCh:
+			// the stream closed error followed by the retry message
+			numErrs++
+			goto WAIT
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for counter %d", want)
+		}
+	}
+	if numErrs != 2 {
+		t.Errorf("expected 2 errors reporting the reconnect, got %d", numErrs)
+	}
+	if n := atomic.LoadInt32(&s.streams); n != 2 {
+		t.Errorf("expected 2 subscribe streams, got %d", n)
+	}
+	if v := testutil.ToFloat64(tg.reconnects.WithLabelValues("target1", "sub1")); v != 1 {
+		t.Errorf("expected a single reconnect, got %v", v)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[string]struct {
+		max  time.Duration
+		want []time.Duration
+	}{
+		"no_backoff": {
+			want: []time.Duration{time.Second, time.Second, time.Second, time.Second},
+		},
+		"backoff": {
+			max:  5 * time.Second,
+			want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tg := NewTarget(&TargetConfig{RetryTimer: time.Second, MaxRetryTimer: tc.max})
+			for i, want := range tc.want {
+				if got := tg.retryDelay(i + 1); got != want {
+					t.Errorf("attempt %d: expected %s, got %s", i+1, want, got)
+				}
+			}
+		})
+	}
+}
+
+// pollGNMIServer sends the current counter value followed by a sync response
+// after the subscribe request and after each poll request.
+type pollGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	m     *sync.Mutex
+	polls []time.Time
+}
+
+func (s *pollGNMIServer) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if req.GetSubscribe().GetMode() != gnmi.SubscriptionList_POLL {
+		return fmt.Errorf("unexpected subscription mode %s", req.GetSubscribe().GetMode())
+	}
+	for i := int64(1); ; i++ {
+		for _, rsp := range []*gnmi.SubscribeResponse{testNotification(i, testUpdate("counter", i)), syncResponse} {
+			if err := stream.Send(rsp); err != nil {
+				return err
+			}
+		}
+		req, err = stream.Recv()
+		if err != nil {
+			return err
+		}
+		if req.GetPoll() == nil {
+			return fmt.Errorf("expected a poll request, got %v", req)
+		}
+		s.m.Lock()
+		s.polls = append(s.polls, time.Now())
+		s.m.Unlock()
+	}
+}
+
+func TestSubscribePoll(t *testing.T) {
+	tests := map[string]struct {
+		pollInterval time.Duration
+	}{
+		"interval": {
+			pollInterval: 100 * time.Millisecond,
+		},
+		"on_demand": {},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &pollGNMIServer{m: new(sync.Mutex)}
+			addr := startFakeGNMIServer(t, s)
+			sc := &SubscriptionConfig{
+				Name:         "sub1",
+				Paths:        []string{"/counter"},
+				Mode:         "poll",
+				PollInterval: tc.pollInterval,
+			}
+			req, err := sc.CreateSubscribeRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tg := NewTarget(&TargetConfig{
+				Name:       name,
+				Address:    addr,
+				Timeout:    5 * time.Second,
+				Insecure:   boolPtr(true),
+				BufferSize: 10,
+			})
+			tg.Subscriptions[sc.Name] = sc
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err = tg.CreateGNMIClient(ctx)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			go tg.Subscribe(ctx, req, sc.Name)
+
+			rspCh, errCh := tg.ReadSubscriptions()
+			// the initial state then 3 polls
+			for want := int64(1); want <= 4; want++ {
+				if tc.pollInterval == 0 && want > 1 {
+					go func() {
+						if err := tg.Poll(ctx, sc.Name); err != nil {
+							t.Errorf("poll failed: %v", err)
+						}
+					}()
+				}
+				for _, sync := range []bool{false, true} {
+					select {
+					case rsp := <-rspCh:
+						if sync {
+							if !rsp.Response.GetSyncResponse() {
+								t.Fatalf("expected a sync response, got %v", rsp.Response)
+							}
+							continue
+						}
+						if got := rsp.Response.GetUpdate().GetUpdate()[0].GetVal().GetIntVal(); got != want {
+							t.Fatalf("expected counter %d, got response %v", want, rsp.Response)
+						}
+					case err := <-errCh:
+						t.Fatalf("subscription failed: %v", err.Err)
+					case <-time.After(5 * time.Second):
+						t.Fatalf("timeout waiting for counter %d", want)
+					}
+				}
+			}
+			s.m.Lock()
+			defer s.m.Unlock()
+			if tc.pollInterval == 0 {
+				if len(s.polls) != 3 {
+					t.Fatalf("expected 3 poll requests, got %d", len(s.polls))
+				}
+				return
+			}
+			// the ticker may have fired again since the last response was read
+			if len(s.polls) < 3 {
+				t.Fatalf("expected at least 3 poll requests, got %d", len(s.polls))
+			}
+			for i := 1; i < len(s.polls); i++ {
+				// allow some scheduling delay
+				if d := s.polls[i].Sub(s.polls[i-1]); d < tc.pollInterval/2 || d > 3*tc.pollInterval {
+					t.Errorf("expected poll requests every %s, got %s between poll %d and %d", tc.pollInterval, d, i, i+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const defaultTapBufferSize = 1000
+
+var errTapsDisabled = errors.New("event taps are disabled")
+
+// TapFilter selects the events delivered to a Tap,
+// an empty field matches all the events.
+type TapFilter struct {
+	// target name
+	Target string `json:"target,omitempty"`
+	// prefix of at least one of the event values or deleted paths
+	Path string `json:"path,omitempty"`
+}
+
+// Tap receives a copy of the events exported to the outputs.
+// Events are dropped if the Tap consumer does not keep up, the outputs are never blocked.
+type Tap struct {
+	// accessed atomically, first to be 64-bit aligned
+	dropped uint64
+	id      uint64
+	filter  TapFilter
+	events  chan *formatters.EventMsg
+}
+
+// Events returns the channel the tapped events are sent on,
+// it is closed once the Tap is removed.
+// The events are shared between the Taps and must not be modified.
+func (t *Tap) Events() <-chan *formatters.EventMsg {
+	return t.events
+}
+
+// Dropped returns the number of events not delivered because the Tap buffer was full.
+func (t *Tap) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+func (t *Tap) match(target string, ev *formatters.EventMsg) bool {
+	if t.filter.Target != "" && t.filter.Target != target {
+		return false
+	}
+	if t.filter.Path == "" {
+		return true
+	}
+	for name := range ev.Values {
+		if strings.HasPrefix(name, t.filter.Path) {
+			return true
+		}
+	}
+	for _, p := range ev.Deletes {
+		if strings.HasPrefix(p, t.filter.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// taps holds the Taps attached to a collector
+type taps struct {
+	m      sync.RWMutex
+	lastID uint64
+	taps   map[uint64]*Tap
+}
+
+// AddTap attaches a new Tap to the collector, it receives the events matching filter until it is removed.
+// bufferSize is the number of events buffered before dropping, it defaults to 1000.
+// It fails if the collector is not configured with EnableTaps.
+func (c *Collector) AddTap(filter TapFilter, bufferSize int) (*Tap, error) {
+	if !c.Config.EnableTaps {
+		return nil, errTapsDisabled
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultTapBufferSize
+	}
+	c.taps.m.Lock()
+	defer c.taps.m.Unlock()
+	if c.taps.taps == nil {
+		c.taps.taps = make(map[uint64]*Tap)
+	}
+	c.taps.lastID++
+	t := &Tap{
+		id:     c.taps.lastID,
+		filter: filter,
+		events: make(chan *formatters.EventMsg, bufferSize),
+	}
+	c.taps.taps[t.id] = t
+	return t, nil
+}
+
+// RemoveTap detaches t from the collector and closes its events channel.
+func (c *Collector) RemoveTap(t *Tap) {
+	c.taps.m.Lock()
+	defer c.taps.m.Unlock()
+	if _, ok := c.taps.taps[t.id]; !ok {
+		return
+	}
+	delete(c.taps.taps, t.id)
+	close(t.events)
+}
+
+// tap sends the events built from rsp to the attached Taps,
+// the response is only converted if at least one Tap is attached.
+func (c *Collector) tap(rsp *gnmi.SubscribeResponse, m outputs.Meta) {
+	if !c.Config.EnableTaps {
+		return
+	}
+	c.taps.m.RLock()
+	defer c.taps.m.RUnlock()
+	if len(c.taps.taps) == 0 {
+		return
+	}
+	events, err := formatters.ResponseToEventMsgs(m["subscription-name"], rsp, m)
+	if err != nil {
+		if c.Config.Debug {
+			c.logger.Printf("failed to convert response to events for the taps: %v", err)
+		}
+		return
+	}
+	for _, t := range c.taps.taps {
+		for _, ev := range events {
+			if !t.match(m["source"], ev) {
+				continue
+			}
+			select {
+			case t.events <- ev:
+			default:
+				atomic.AddUint64(&t.dropped, 1)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func tapTestResponse(elem string) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: elem}, {Name: "counter"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestTap(t *testing.T) {
+	c := NewCollector(&Config{EnableTaps: true}, nil, WithLogger(log.New(ioutil.Discard, "", 0)))
+	all, err := c.AddTap(TapFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := c.AddTap(TapFilter{Target: "router1", Path: "/interface"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Export(context.Background(), tapTestResponse("interface"), outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+	c.Export(context.Background(), tapTestResponse("interface"), outputs.Meta{"source": "router2", "subscription-name": "sub1"})
+	c.Export(context.Background(), tapTestResponse("system"), outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+
+	if n := len(all.Events()); n != 3 {
+		t.Errorf("expected 3 events on the unfiltered tap, got %d", n)
+	}
+	if n := len(filtered.Events()); n != 1 {
+		t.Fatalf("expected 1 event on the filtered tap, got %d", n)
+	}
+	ev := <-filtered.Events()
+	if ev.Tags["source"] != "router1" {
+		t.Errorf("unexpected event source: %q", ev.Tags["source"])
+	}
+	if _, ok := ev.Values["/interface/counter"]; !ok {
+		t.Errorf("unexpected event values: %v", ev.Values)
+	}
+
+	// a removed tap channel is closed and no longer receives events
+	c.RemoveTap(filtered)
+	c.Export(context.Background(), tapTestResponse("interface"), outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+	if _, ok := <-filtered.Events(); ok {
+		t.Errorf("expected the removed tap channel to be closed")
+	}
+	if n := len(all.Events()); n != 4 {
+		t.Errorf("expected 4 events on the remaining tap, got %d", n)
+	}
+	// removing a tap twice is a no-op
+	c.RemoveTap(filtered)
+}
+
+func TestTapDropped(t *testing.T) {
+	c := NewCollector(&Config{EnableTaps: true}, nil, WithLogger(log.New(ioutil.Discard, "", 0)))
+	tap, err := c.AddTap(TapFilter{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		c.Export(context.Background(), tapTestResponse("interface"), outputs.Meta{"source": "router1"})
+	}
+	if d := tap.Dropped(); d != 2 {
+		t.Errorf("expected 2 dropped events, got %d", d)
+	}
+}
+
+func TestTapDisabled(t *testing.T) {
+	c := NewCollector(&Config{}, nil, WithLogger(log.New(ioutil.Discard, "", 0)))
+	if _, err := c.AddTap(TapFilter{}, 0); err == nil {
+		t.Errorf("expected an error adding a tap without EnableTaps")
+	}
+}
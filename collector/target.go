@@ -15,16 +15,22 @@ import (
 
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
+	"github.com/karimra/gnmic/outputs"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/openconfig/gnmi/proto/gnmi_ext"
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 )
 
 const (
-	defaultRetryTimer = 10 * time.Second
+	defaultRetryTimer       = 10 * time.Second
+	defaultKeepaliveTime    = 10 * time.Minute
+	defaultKeepaliveTimeout = 20 * time.Second
 )
 
 type TargetError struct {
@@ -38,10 +44,11 @@ type Target struct {
 	Subscriptions map[string]*SubscriptionConfig `json:"subscriptions,omitempty"`
 
 	m                  *sync.Mutex
+	conn               *grpc.ClientConn
 	Client             gnmi.GNMIClient                      `json:"-"`
 	SubscribeClients   map[string]gnmi.GNMI_SubscribeClient `json:"-"` // subscription name to subscribeClient
 	subscribeCancelFn  map[string]context.CancelFunc
-	pollChan           chan string // subscription name to be polled
+	pollChans          map[string]chan chan error // subscription name to poll trigger channel
 	subscribeResponses chan *SubscribeResponse
 	errors             chan *TargetError
 	stopped            bool
@@ -49,6 +56,20 @@ type Target struct {
 	cfn                context.CancelFunc
 
 	rootDesc desc.Descriptor
+
+	// set to 1 if the target does not support the configured gRPC compression
+	compressionUnsupported int32
+	// counts the subscriptions re-subscribe attempts, nil if the collector metrics are disabled
+	reconnects *prometheus.CounterVec
+	// RPCs latency and errors, nil if the collector metrics are disabled
+	rpcMetrics *rpcMetrics
+	// last use of the client for a unary RPC and the timer closing it once idle
+	lastUsed  time.Time
+	idleTimer *time.Timer
+	// number of running subscriptions, their connection is not closed by the client pool
+	subscriptions int
+	// credentials returned by the credentials-command
+	credentials credentialsCache
 }
 
 // TargetConfig //
@@ -67,6 +88,7 @@ type TargetConfig struct {
 	Outputs       []string      `mapstructure:"outputs,omitempty" json:"outputs,omitempty"`
 	BufferSize    uint          `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
 	RetryTimer    time.Duration `mapstructure:"retry,omitempty" json:"retry-timer,omitempty"`
+	MaxRetryTimer time.Duration `mapstructure:"max-retry,omitempty" json:"max-retry-timer,omitempty"`
 	TLSMinVersion string        `mapstructure:"tls-min-version,omitempty" json:"tls-min-version,omitempty"`
 	TLSMaxVersion string        `mapstructure:"tls-max-version,omitempty" json:"tls-max-version,omitempty"`
 	TLSVersion    string        `mapstructure:"tls-version,omitempty" json:"tls-version,omitempty"`
@@ -74,6 +96,22 @@ type TargetConfig struct {
 	ProtoDirs     []string      `mapstructure:"proto-dirs,omitempty" json:"proto-dirs,omitempty"`
 	Tags          []string      `mapstructure:"tags,omitempty" json:"tags,omitempty"`
 	Gzip          *bool         `mapstructure:"gzip,omitempty" json:"gzip,omitempty"`
+	// gRPC keepalive
+	KeepaliveTime       time.Duration `mapstructure:"keepalive-time,omitempty" json:"keepalive-time,omitempty"`
+	KeepaliveTimeout    time.Duration `mapstructure:"keepalive-timeout,omitempty" json:"keepalive-timeout,omitempty"`
+	PermitWithoutStream *bool         `mapstructure:"permit-without-stream,omitempty" json:"permit-without-stream,omitempty"`
+	// static tags added to all the events of the target
+	EventTags map[string]string `mapstructure:"event-tags,omitempty" json:"event-tags,omitempty"`
+	// gRPC metadata sent with all the RPCs to the target
+	GRPCMetadata map[string]string `mapstructure:"grpc-metadata,omitempty" json:"grpc-metadata,omitempty"`
+	// gRPC maximum received and sent message sizes in bytes, the global max-msg-size applies to the received messages if not set
+	MaxRecvMsgSize int `mapstructure:"max-recv-msg-size,omitempty" json:"max-recv-msg-size,omitempty"`
+	MaxSendMsgSize int `mapstructure:"max-send-msg-size,omitempty" json:"max-send-msg-size,omitempty"`
+	// command printing the target credentials as a JSON object, executed at dial time,
+	// its username and password take precedence over the configured ones
+	CredentialsCommand string `mapstructure:"credentials-command,omitempty" json:"credentials-command,omitempty"`
+	// duration the credentials-command output is cached for, defaults to 1m
+	CredentialsTTL time.Duration `mapstructure:"credentials-ttl,omitempty" json:"credentials-ttl,omitempty"`
 }
 
 func (tc *TargetConfig) String() string {
@@ -92,7 +130,7 @@ func NewTarget(c *TargetConfig) *Target {
 		m:                  new(sync.Mutex),
 		SubscribeClients:   make(map[string]gnmi.GNMI_SubscribeClient),
 		subscribeCancelFn:  make(map[string]context.CancelFunc),
-		pollChan:           make(chan string),
+		pollChans:          make(map[string]chan chan error),
 		subscribeResponses: make(chan *SubscribeResponse, c.BufferSize),
 		errors:             make(chan *TargetError),
 		stopChan:           make(chan struct{}),
@@ -104,7 +142,7 @@ func NewTarget(c *TargetConfig) *Target {
 func (tc *TargetConfig) newTLS() (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		Renegotiation:      tls.RenegotiateNever,
-		InsecureSkipVerify: *tc.SkipVerify,
+		InsecureSkipVerify: tc.SkipVerify != nil && *tc.SkipVerify,
 		MaxVersion:         tc.getTLSMaxVersion(),
 		MinVersion:         tc.getTLSMinVersion(),
 	}
@@ -117,6 +155,9 @@ func (tc *TargetConfig) newTLS() (*tls.Config, error) {
 
 // CreateGNMIClient //
 func (t *Target) CreateGNMIClient(ctx context.Context, opts ...grpc.DialOption) error {
+	if err := t.loadCredentials(ctx); err != nil {
+		return err
+	}
 	tOpts := make([]grpc.DialOption, 0, len(opts)+1)
 	tOpts = append(tOpts, opts...)
 
@@ -129,8 +170,16 @@ func (t *Target) CreateGNMIClient(ctx context.Context, opts ...grpc.DialOption)
 		}
 		tOpts = append(tOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	}
-	if *t.Config.Gzip {
-		tOpts = append(tOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	if t.Config.Gzip != nil && *t.Config.Gzip {
+		tOpts = append(tOpts,
+			grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+			grpc.WithChainUnaryInterceptor(t.compressionUnaryInterceptor),
+			grpc.WithChainStreamInterceptor(t.compressionStreamInterceptor),
+		)
+	}
+	tOpts = append(tOpts, grpc.WithKeepaliveParams(t.Config.keepaliveParams()))
+	if cOpts := t.Config.msgSizeCallOptions(); len(cOpts) > 0 {
+		tOpts = append(tOpts, grpc.WithDefaultCallOptions(cOpts...))
 	}
 	timeoutCtx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
 	defer cancel()
@@ -138,14 +187,151 @@ func (t *Target) CreateGNMIClient(ctx context.Context, opts ...grpc.DialOption)
 	if err != nil {
 		return err
 	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.conn = conn
 	t.Client = gnmi.NewGNMIClient(conn)
 	return nil
 }
 
+// subscribeClient returns the target gNMI client for a subscription,
+// it is read under t.m since the client pool can replace it while the subscription retries.
+func (t *Target) subscribeClient() (gnmi.GNMIClient, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.Client == nil {
+		return nil, fmt.Errorf("target '%s' has no gNMI client", t.Config.Name)
+	}
+	return t.Client, nil
+}
+
+// startSubscription records a running subscription, the returned func is called once it returns.
+func (t *Target) startSubscription() func() {
+	t.m.Lock()
+	t.subscriptions++
+	t.m.Unlock()
+	return func() {
+		t.m.Lock()
+		t.subscriptions--
+		t.m.Unlock()
+	}
+}
+
+// subscribed reports whether the target has running subscriptions.
+func (t *Target) subscribed() bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.subscriptions > 0
+}
+
+// healthy reports whether the target gRPC connection can be reused
+func (t *Target) healthy() bool {
+	if t.conn == nil {
+		return true
+	}
+	switch t.conn.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	}
+	return true
+}
+
+// closeClient closes the target gRPC connection
+func (t *Target) closeClient() {
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+		t.idleTimer = nil
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+	t.Client = nil
+}
+
+// keepaliveParams returns the gRPC keepalive parameters of the target connection.
+// The default ping interval is larger than the 5 minutes grpc servers enforce by default,
+// so that the pings do not get the connection closed with a too_many_pings error.
+func (tc *TargetConfig) keepaliveParams() keepalive.ClientParameters {
+	kp := keepalive.ClientParameters{
+		Time:    tc.KeepaliveTime,
+		Timeout: tc.KeepaliveTimeout,
+	}
+	if kp.Time <= 0 {
+		kp.Time = defaultKeepaliveTime
+	}
+	if kp.Timeout <= 0 {
+		kp.Timeout = defaultKeepaliveTimeout
+	}
+	if tc.PermitWithoutStream != nil {
+		kp.PermitWithoutStream = *tc.PermitWithoutStream
+	}
+	return kp
+}
+
+// msgSizeCallOptions returns the call options setting the target maximum message sizes,
+// they are added after the global ones and take precedence.
+func (tc *TargetConfig) msgSizeCallOptions() []grpc.CallOption {
+	opts := make([]grpc.CallOption, 0, 2)
+	if tc.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallRecvMsgSize(tc.MaxRecvMsgSize))
+	}
+	if tc.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxCallSendMsgSize(tc.MaxSendMsgSize))
+	}
+	return opts
+}
+
+// appendMetadata adds the target grpc-metadata, username and password to the outgoing context metadata,
+// targets authenticating with a client certificate only can leave the credentials empty.
+// The credentials-command credentials take precedence, its token is sent as a bearer authorization.
+func (t *Target) appendMetadata(ctx context.Context) context.Context {
+	if len(t.Config.GRPCMetadata) > 0 {
+		md := metadata.New(t.Config.GRPCMetadata)
+		if omd, ok := metadata.FromOutgoingContext(ctx); ok {
+			md = metadata.Join(omd, md)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	var username, password string
+	if t.Config.Username != nil {
+		username = *t.Config.Username
+	}
+	if t.Config.Password != nil {
+		password = *t.Config.Password
+	}
+	kv := make([]string, 0, 6)
+	if creds := t.commandCredentials(); creds != nil {
+		if creds.Username != "" {
+			username = creds.Username
+		}
+		if creds.Password != "" {
+			password = creds.Password
+		}
+		if creds.Token != "" {
+			kv = append(kv, "authorization", "Bearer "+creds.Token)
+		}
+	}
+	if username != "" {
+		kv = append(kv, "username", username)
+	}
+	if password != "" {
+		kv = append(kv, "password", password)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
 // Capabilities sends a gnmi.CapabilitiesRequest to the target *t and returns a gnmi.CapabilitiesResponse and an error
 func (t *Target) Capabilities(ctx context.Context, ext ...*gnmi_ext.Extension) (*gnmi.CapabilityResponse, error) {
-	ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	ctx = t.appendMetadata(ctx)
+	start := time.Now()
 	response, err := t.Client.Capabilities(ctx, &gnmi.CapabilityRequest{Extension: ext})
+	t.rpcMetrics.observe(rpcCapabilities, t.Config.Name, start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed sending capabilities request: %v", err)
 	}
@@ -154,18 +340,22 @@ func (t *Target) Capabilities(ctx context.Context, ext ...*gnmi_ext.Extension) (
 
 // Get sends a gnmi.GetRequest to the target *t and returns a gnmi.GetResponse and an error
 func (t *Target) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
-	ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	ctx = t.appendMetadata(ctx)
+	start := time.Now()
 	response, err := t.Client.Get(ctx, req)
+	t.rpcMetrics.observe(rpcGet, t.Config.Name, start, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed sending GetRequest to '%s': %v", t.Config.Address, err)
+		return nil, fmt.Errorf("failed sending GetRequest to '%s': %w", t.Config.Address, err)
 	}
 	return response, nil
 }
 
 // Set sends a gnmi.SetRequest to the target *t and returns a gnmi.SetResponse and an error
 func (t *Target) Set(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetResponse, error) {
-	ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	ctx = t.appendMetadata(ctx)
+	start := time.Now()
 	response, err := t.Client.Set(ctx, req)
+	t.rpcMetrics.observe(rpcSet, t.Config.Name, start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed sending SetRequest to '%s': %v", t.Config.Address, err)
 	}
@@ -174,18 +364,56 @@ func (t *Target) Set(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetRespon
 
 // Subscribe sends a gnmi.SubscribeRequest to the target *t, responses and error are sent to the target channels
 func (t *Target) Subscribe(ctx context.Context, req *gnmi.SubscribeRequest, subscriptionName string) {
+	defer t.startSubscription()()
+	var dd *dedup
+	if sc, ok := t.Subscriptions[subscriptionName]; ok && sc.DedupSync {
+		dd = newDedup()
+	}
+	// number of consecutive failed attempts, reset when a response is received
+	attempt := 0
 SUBSC:
+	if attempt > 0 && t.reconnects != nil {
+		t.reconnects.WithLabelValues(t.Config.Name, subscriptionName).Inc()
+	}
 	nctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	nctx = metadata.AppendToOutgoingContext(nctx, "username", *t.Config.Username, "password", *t.Config.Password)
-	subscribeClient, err := t.Client.Subscribe(nctx)
+	// refresh the credentials-command credentials if they expired since the last attempt
+	err := t.loadCredentials(nctx)
+	if err != nil {
+		attempt++
+		t.errors <- &TargetError{
+			SubscriptionName: subscriptionName,
+			Err:              fmt.Errorf("failed to load the credentials of target '%s', retry in %s. err=%v", t.Config.Name, t.retryDelay(attempt), err),
+		}
+		cancel()
+		if !t.waitRetry(ctx, attempt) {
+			return
+		}
+		goto SUBSC
+	}
+	nctx = t.appendMetadata(nctx)
+	// the subscription duration is observed on the first sync response
+	start := time.Now()
+	synced := false
+	if dd != nil {
+		dd.start()
+	}
+	var subscribeClient gnmi.GNMI_SubscribeClient
+	client, err := t.subscribeClient()
+	if err == nil {
+		subscribeClient, err = client.Subscribe(nctx)
+	}
 	if err != nil {
+		t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+		attempt++
 		t.errors <- &TargetError{
 			SubscriptionName: subscriptionName,
-			Err:              fmt.Errorf("failed to create a subscribe client, target='%s', retry in %d. err=%v", t.Config.Name, t.Config.RetryTimer, err),
+			Err:              fmt.Errorf("failed to create a subscribe client, target='%s', retry in %s. err=%v", t.Config.Name, t.retryDelay(attempt), err),
 		}
 		cancel()
-		time.Sleep(t.Config.RetryTimer)
+		if !t.waitRetry(ctx, attempt) {
+			return
+		}
 		goto SUBSC
 	}
 	t.m.Lock()
@@ -194,12 +422,16 @@ SUBSC:
 	t.m.Unlock()
 	err = subscribeClient.Send(req)
 	if err != nil {
+		t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+		attempt++
 		t.errors <- &TargetError{
 			SubscriptionName: subscriptionName,
-			Err:              fmt.Errorf("target '%s' send error, retry in %d. err=%v", t.Config.Name, t.Config.RetryTimer, err),
+			Err:              fmt.Errorf("target '%s' send error, retry in %s. err=%v", t.Config.Name, t.retryDelay(attempt), err),
 		}
 		cancel()
-		time.Sleep(t.Config.RetryTimer)
+		if !t.waitRetry(ctx, attempt) {
+			return
+		}
 		goto SUBSC
 	}
 	switch req.GetSubscribe().Mode {
@@ -210,18 +442,33 @@ SUBSC:
 			}
 			response, err := subscribeClient.Recv()
 			if err != nil {
+				if nctx.Err() != nil {
+					return
+				}
+				t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+				attempt++
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
 					Err:              err,
 				}
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
-					Err:              fmt.Errorf("retrying in %s", t.Config.RetryTimer),
+					Err:              fmt.Errorf("retrying in %s (attempt %d)", t.retryDelay(attempt), attempt),
 				}
 				cancel()
-				time.Sleep(t.Config.RetryTimer)
+				if !t.waitRetry(ctx, attempt) {
+					return
+				}
 				goto SUBSC
 			}
+			attempt = 0
+			if !synced && response.GetSyncResponse() {
+				synced = true
+				t.rpcMetrics.observe(rpcSubscribe, t.Config.Name, start, nil)
+			}
+			if dd != nil && !dd.filter(response) {
+				continue
+			}
 			t.subscribeResponses <- &SubscribeResponse{
 				SubscriptionName: subscriptionName,
 				Response:         response,
@@ -238,55 +485,164 @@ SUBSC:
 				if errors.Is(err, io.EOF) {
 					return
 				}
+				t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+				attempt++
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
-					Err:              fmt.Errorf("retrying in %d", t.Config.RetryTimer),
+					Err:              fmt.Errorf("retrying in %s (attempt %d)", t.retryDelay(attempt), attempt),
 				}
 				cancel()
-				time.Sleep(t.Config.RetryTimer)
+				if !t.waitRetry(ctx, attempt) {
+					return
+				}
 				goto SUBSC
 			}
+			if dd != nil && !dd.filter(response) {
+				continue
+			}
 			t.subscribeResponses <- &SubscribeResponse{
 				SubscriptionName: subscriptionName,
 				Response:         response,
 			}
 			switch response.Response.(type) {
 			case *gnmi.SubscribeResponse_SyncResponse:
+				t.rpcMetrics.observe(rpcSubscribe, t.Config.Name, start, nil)
 				return
 			}
 		}
 	case gnmi.SubscriptionList_POLL:
-		for {
+		pollCh := t.pollChannel(subscriptionName)
+		var tickCh <-chan time.Time
+		if sc, ok := t.Subscriptions[subscriptionName]; ok && sc.PollInterval > 0 {
+			ticker := time.NewTicker(sc.PollInterval)
+			defer ticker.Stop()
+			tickCh = ticker.C
+		}
+		// the target sends the initial state followed by a sync response
+		err = t.receivePoll(subscriptionName, subscribeClient, dd)
+		if err == nil {
+			t.rpcMetrics.observe(rpcSubscribe, t.Config.Name, start, nil)
+		}
+		for err == nil {
 			select {
-			case subName := <-t.pollChan:
-				err = t.SubscribeClients[subName].Send(&gnmi.SubscribeRequest{
-					Request: &gnmi.SubscribeRequest_Poll{
-						Poll: &gnmi.Poll{},
-					},
-				})
-				if err != nil {
-					t.errors <- &TargetError{
-						SubscriptionName: subscriptionName,
-						Err:              fmt.Errorf("failed to send PollRequest: %v", err),
-					}
-					continue
-				}
-				response, err := subscribeClient.Recv()
-				if err != nil {
-					t.errors <- &TargetError{
-						SubscriptionName: subscriptionName,
-						Err:              err,
-					}
-					continue
-				}
-				t.subscribeResponses <- &SubscribeResponse{
-					SubscriptionName: subscriptionName,
-					Response:         response,
-				}
 			case <-nctx.Done():
 				return
+			case <-tickCh:
+				err = t.poll(subscriptionName, subscribeClient, dd)
+			case errCh := <-pollCh:
+				err = t.poll(subscriptionName, subscribeClient, dd)
+				errCh <- err
 			}
 		}
+		if nctx.Err() != nil {
+			return
+		}
+		attempt++
+		t.errors <- &TargetError{
+			SubscriptionName: subscriptionName,
+			Err:              err,
+		}
+		t.errors <- &TargetError{
+			SubscriptionName: subscriptionName,
+			Err:              fmt.Errorf("retrying in %s (attempt %d)", t.retryDelay(attempt), attempt),
+		}
+		cancel()
+		if !t.waitRetry(ctx, attempt) {
+			return
+		}
+		goto SUBSC
+	}
+}
+
+// Poll triggers a poll of the POLL mode subscription subscriptionName,
+// it returns once the poll responses are received up to the sync response.
+func (t *Target) Poll(ctx context.Context, subscriptionName string) error {
+	errCh := make(chan error, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case t.pollChannel(subscriptionName) <- errCh:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// pollChannel returns the channel used to trigger polls of the subscription subscriptionName.
+func (t *Target) pollChannel(subscriptionName string) chan chan error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if _, ok := t.pollChans[subscriptionName]; !ok {
+		t.pollChans[subscriptionName] = make(chan chan error)
+	}
+	return t.pollChans[subscriptionName]
+}
+
+// poll sends a Poll request on the subscribeClient stream and forwards the responses up to the sync response.
+func (t *Target) poll(subscriptionName string, subscribeClient gnmi.GNMI_SubscribeClient, dd *dedup) error {
+	if dd != nil {
+		dd.start()
+	}
+	err := subscribeClient.Send(&gnmi.SubscribeRequest{
+		Request: &gnmi.SubscribeRequest_Poll{
+			Poll: &gnmi.Poll{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send PollRequest: %v", err)
+	}
+	return t.receivePoll(subscriptionName, subscribeClient, dd)
+}
+
+// receivePoll forwards the responses received on the subscribeClient stream up to the sync response.
+func (t *Target) receivePoll(subscriptionName string, subscribeClient gnmi.GNMI_SubscribeClient, dd *dedup) error {
+	for {
+		response, err := subscribeClient.Recv()
+		if err != nil {
+			return err
+		}
+		if dd == nil || dd.filter(response) {
+			t.subscribeResponses <- &SubscribeResponse{
+				SubscriptionName: subscriptionName,
+				Response:         response,
+			}
+		}
+		if _, ok := response.Response.(*gnmi.SubscribeResponse_SyncResponse); ok {
+			return nil
+		}
+	}
+}
+
+// retryDelay returns the delay before the re-subscribe attempt number attempt.
+// The retry timer is doubled after each failed attempt, up to the max retry timer if it is set,
+// otherwise the retry timer is used for all attempts.
+func (t *Target) retryDelay(attempt int) time.Duration {
+	d := t.Config.RetryTimer
+	if t.Config.MaxRetryTimer <= d {
+		return d
+	}
+	for i := 1; i < attempt && d < t.Config.MaxRetryTimer; i++ {
+		d *= 2
+	}
+	if d > t.Config.MaxRetryTimer {
+		d = t.Config.MaxRetryTimer
+	}
+	return d
+}
+
+// waitRetry waits for the delay before the re-subscribe attempt number attempt,
+// it returns false if ctx is done before the delay expires.
+func (t *Target) waitRetry(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(t.retryDelay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
 
@@ -294,27 +650,58 @@ func (t *Target) SubscribeOnce(ctx context.Context, req *gnmi.SubscribeRequest,
 	responseCh := make(chan *gnmi.SubscribeResponse)
 	errCh := make(chan error)
 	go func() {
+		defer t.startSubscription()()
 		nctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
-		nctx = metadata.AppendToOutgoingContext(nctx, "username", *t.Config.Username, "password", *t.Config.Password)
-		subscribeClient, err := t.Client.Subscribe(nctx)
+		// the sends are abandoned once ctx is done, the receiver may have returned
+		sendErr := func(err error) {
+			select {
+			case errCh <- err:
+			case <-nctx.Done():
+			}
+		}
+		err := t.loadCredentials(nctx)
 		if err != nil {
-			errCh <- err
+			sendErr(err)
+			return
+		}
+		nctx = t.appendMetadata(nctx)
+		start := time.Now()
+		client, err := t.subscribeClient()
+		if err != nil {
+			sendErr(err)
+			return
+		}
+		subscribeClient, err := client.Subscribe(nctx)
+		if err != nil {
+			t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+			sendErr(err)
 			return
 		}
 		err = subscribeClient.Send(req)
 		if err != nil {
-			errCh <- err
+			t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+			sendErr(err)
 			return
 		}
 		for {
 			response, err := subscribeClient.Recv()
 			if err != nil {
-				errCh <- err
+				if !errors.Is(err, io.EOF) {
+					t.rpcMetrics.failed(rpcSubscribe, t.Config.Name, err)
+				}
+				sendErr(err)
+				return
+			}
+			if response.GetSyncResponse() {
+				t.rpcMetrics.observe(rpcSubscribe, t.Config.Name, start, nil)
+			}
+			select {
+			case responseCh <- response:
+			case <-nctx.Done():
 				return
 			}
-			responseCh <- response
 		}
 	}()
 
@@ -341,7 +728,7 @@ func (t *Target) ReadSubscriptions() (chan *SubscribeResponse, chan *TargetError
 }
 
 func loadCerts(tlscfg *tls.Config, c *TargetConfig) error {
-	if *c.TLSCert != "" && *c.TLSKey != "" {
+	if c.TLSCert != nil && c.TLSKey != nil && *c.TLSCert != "" && *c.TLSKey != "" {
 		certificate, err := tls.LoadX509KeyPair(*c.TLSCert, *c.TLSKey)
 		if err != nil {
 			return err
@@ -373,6 +760,28 @@ func (t *Target) numberOfOnceSubscriptions() int {
 	return num
 }
 
+// outputsMeta returns the metadata passed to the outputs along with the target responses,
+// it carries the target event tags, which cannot override the source, format and subscription-name keys.
+func (t *Target) outputsMeta(subscriptionName, format string) outputs.Meta {
+	m := make(outputs.Meta, len(t.Config.EventTags)+3)
+	for k, v := range t.Config.EventTags {
+		m[k] = v
+	}
+	m["source"] = t.Config.Name
+	m["format"] = format
+	m["subscription-name"] = subscriptionName
+	return m
+}
+
+// subscriptionOutputs returns the names of the outputs the responses of subscription subscriptionName are written to.
+// The subscription outputs take precedence over the target outputs, an empty list means all outputs.
+func (t *Target) subscriptionOutputs(subscriptionName string) []string {
+	if sub, ok := t.Subscriptions[subscriptionName]; ok && len(sub.Outputs) > 0 {
+		return sub.Outputs
+	}
+	return t.Config.Outputs
+}
+
 func (tc *TargetConfig) UsernameString() string {
 	if tc.Username == nil {
 		return "NA"
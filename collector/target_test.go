@@ -0,0 +1,382 @@
+package collector
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeGNMIServer records some attributes of the received RPCs
+type fakeGNMIServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	m           *sync.Mutex
+	clientCerts []string
+	metadata    []metadata.MD
+	// grpc-encoding of the received requests
+	compressions []string
+}
+
+func newFakeGNMIServer() *fakeGNMIServer {
+	return &fakeGNMIServer{m: new(sync.Mutex)}
+}
+
+func (s *fakeGNMIServer) Capabilities(ctx context.Context, req *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	s.record(ctx)
+	return &gnmi.CapabilityResponse{GNMIVersion: "0.7.0"}, nil
+}
+
+func (s *fakeGNMIServer) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	s.record(ctx)
+	return &gnmi.GetResponse{}, nil
+}
+
+func (s *fakeGNMIServer) record(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.metadata = append(s.metadata, md)
+	}
+	s.compressions = append(s.compressions, recvCompress(ctx))
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		for _, c := range tlsInfo.State.PeerCertificates {
+			s.clientCerts = append(s.clientCerts, c.Subject.CommonName)
+		}
+	}
+}
+
+func recvCompress(ctx context.Context) string {
+	if st, ok := grpc.ServerTransportStreamFromContext(ctx).(interface{ RecvCompress() string }); ok {
+		return st.RecvCompress()
+	}
+	return ""
+}
+
+// rejectCompression mimics a server without any decompressor installed
+func rejectCompression(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if rc := recvCompress(ctx); rc != "" && rc != encoding.Identity {
+		return nil, status.Errorf(codes.Unimplemented, "grpc: Decompressor is not installed for grpc-encoding %q", rc)
+	}
+	return handler(ctx, req)
+}
+
+// startFakeGNMIServer starts a gNMI server on a random local port and returns its address
+func startFakeGNMIServer(t *testing.T, s gnmi.GNMIServer, opts ...grpc.ServerOption) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	gs := grpc.NewServer(opts...)
+	gnmi.RegisterGNMIServer(gs, s)
+	go gs.Serve(l)
+	t.Cleanup(gs.Stop)
+	return l.Addr().String()
+}
+
+// writeTestCert generates a self signed certificate with common name cn,
+// writes it and its key to dir and returns their paths.
+func writeTestCert(t *testing.T, dir, cn string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	certFile := filepath.Join(dir, cn+".crt")
+	keyFile := filepath.Join(dir, cn+".key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gnmic-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func strPtr(s string) *string { return &s }
+func boolPtr(b bool) *bool    { return &b }
+
+func TestTargetClientCertificates(t *testing.T) {
+	dir := tempDir(t)
+	srvCert, srvKey := writeTestCert(t, dir, "server")
+	cert, err := tls.LoadX509KeyPair(srvCert, srvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newFakeGNMIServer()
+	addr := startFakeGNMIServer(t, s, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	})))
+
+	targets := []string{"target1", "target2"}
+	for _, name := range targets {
+		certFile, keyFile := writeTestCert(t, dir, name)
+		tg := NewTarget(&TargetConfig{
+			Name:       name,
+			Address:    addr,
+			Timeout:    5 * time.Second,
+			Insecure:   boolPtr(false),
+			SkipVerify: boolPtr(true),
+			TLSCert:    strPtr(certFile),
+			TLSKey:     strPtr(keyFile),
+			Gzip:       boolPtr(false),
+		})
+		err = tg.CreateGNMIClient(context.Background(), grpc.WithBlock())
+		if err != nil {
+			t.Fatalf("target %q failed to create client: %v", name, err)
+		}
+		_, err = tg.Capabilities(context.Background())
+		if err != nil {
+			t.Fatalf("target %q capabilities failed: %v", name, err)
+		}
+	}
+	if len(s.clientCerts) != len(targets) {
+		t.Fatalf("expected %d client certificates, got %v", len(targets), s.clientCerts)
+	}
+	for i := range targets {
+		if s.clientCerts[i] != targets[i] {
+			t.Errorf("expected client certificate %q, got %q", targets[i], s.clientCerts[i])
+		}
+	}
+	// certificate only targets do not send credentials
+	for _, md := range s.metadata {
+		if len(md.Get("username")) > 0 || len(md.Get("password")) > 0 {
+			t.Errorf("unexpected credentials in metadata: %v", md)
+		}
+	}
+}
+
+func TestTargetGzip(t *testing.T) {
+	tests := map[string]struct {
+		gzip      bool
+		serverOpt []grpc.ServerOption
+		// expected compression of the requests received by the server handler
+		want []string
+	}{
+		"gzip": {
+			gzip: true,
+			want: []string{"gzip", "gzip"},
+		},
+		"no_gzip": {
+			gzip: false,
+			want: []string{"", ""},
+		},
+		"gzip_unsupported": {
+			gzip:      true,
+			serverOpt: []grpc.ServerOption{grpc.UnaryInterceptor(rejectCompression)},
+			want:      []string{encoding.Identity, encoding.Identity},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newFakeGNMIServer()
+			addr := startFakeGNMIServer(t, s, tc.serverOpt...)
+			tg := NewTarget(&TargetConfig{
+				Name:     name,
+				Address:  addr,
+				Timeout:  5 * time.Second,
+				Insecure: boolPtr(true),
+				Gzip:     boolPtr(tc.gzip),
+			})
+			err := tg.CreateGNMIClient(context.Background(), grpc.WithBlock())
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			for range tc.want {
+				rsp, err := tg.Capabilities(context.Background())
+				if err != nil {
+					t.Fatalf("capabilities failed: %v", err)
+				}
+				if rsp.GetGNMIVersion() != "0.7.0" {
+					t.Errorf("unexpected capabilities response: %v", rsp)
+				}
+			}
+			if len(s.compressions) != len(tc.want) {
+				t.Fatalf("expected requests compression %v, got %v", tc.want, s.compressions)
+			}
+			for i := range tc.want {
+				if s.compressions[i] != tc.want[i] {
+					t.Errorf("expected requests compression %v, got %v", tc.want, s.compressions)
+				}
+			}
+		})
+	}
+}
+
+func TestTargetKeepaliveParams(t *testing.T) {
+	tests := map[string]struct {
+		tc   *TargetConfig
+		want keepalive.ClientParameters
+	}{
+		"defaults": {
+			tc: &TargetConfig{},
+			want: keepalive.ClientParameters{
+				Time:    defaultKeepaliveTime,
+				Timeout: defaultKeepaliveTimeout,
+			},
+		},
+		"configured": {
+			tc: &TargetConfig{
+				KeepaliveTime:       time.Minute,
+				KeepaliveTimeout:    5 * time.Second,
+				PermitWithoutStream: boolPtr(true),
+			},
+			want: keepalive.ClientParameters{
+				Time:                time.Minute,
+				Timeout:             5 * time.Second,
+				PermitWithoutStream: true,
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.tc.keepaliveParams()
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTargetGRPCMetadata(t *testing.T) {
+	tg := NewTarget(&TargetConfig{
+		Name:         "router1",
+		Username:     strPtr("admin"),
+		GRPCMetadata: map[string]string{"X-Tenant": "tenant1"},
+	})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "request-id", "1")
+	md, ok := metadata.FromOutgoingContext(tg.appendMetadata(ctx))
+	if !ok {
+		t.Fatal("expected an outgoing context metadata")
+	}
+	want := map[string]string{"x-tenant": "tenant1", "username": "admin", "request-id": "1"}
+	for k, v := range want {
+		if got := md.Get(k); len(got) != 1 || got[0] != v {
+			t.Errorf("expected metadata %s=%s, got %v", k, v, got)
+		}
+	}
+	// the metadata is received by the target
+	s := newFakeGNMIServer()
+	tg.Config.Address = startFakeGNMIServer(t, s)
+	tg.Config.Timeout = 5 * time.Second
+	tg.Config.Insecure = boolPtr(true)
+	err := tg.CreateGNMIClient(context.Background(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	_, err = tg.Capabilities(context.Background())
+	if err != nil {
+		t.Fatalf("capabilities failed: %v", err)
+	}
+	if len(s.metadata) != 1 {
+		t.Fatalf("expected the metadata of 1 request, got %d", len(s.metadata))
+	}
+	if got := s.metadata[0].Get("x-tenant"); len(got) != 1 || got[0] != "tenant1" {
+		t.Errorf("expected the target to receive x-tenant=tenant1, got %v", got)
+	}
+}
+
+func TestTargetMsgSizeCallOptions(t *testing.T) {
+	tests := map[string]struct {
+		tc   *TargetConfig
+		want []grpc.CallOption
+	}{
+		"not_set": {
+			tc:   &TargetConfig{},
+			want: []grpc.CallOption{},
+		},
+		"recv_and_send": {
+			tc: &TargetConfig{MaxRecvMsgSize: 1 << 30, MaxSendMsgSize: 1 << 20},
+			want: []grpc.CallOption{
+				grpc.MaxRecvMsgSizeCallOption{MaxRecvMsgSize: 1 << 30},
+				grpc.MaxSendMsgSizeCallOption{MaxSendMsgSize: 1 << 20},
+			},
+		},
+		"recv_only": {
+			tc: &TargetConfig{MaxRecvMsgSize: 1 << 30},
+			want: []grpc.CallOption{
+				grpc.MaxRecvMsgSizeCallOption{MaxRecvMsgSize: 1 << 30},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.tc.msgSizeCallOptions()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTargetMaxRecvMsgSize(t *testing.T) {
+	s := newFakeGNMIServer()
+	tg := NewTarget(&TargetConfig{
+		Name:           "router1",
+		Address:        startFakeGNMIServer(t, s),
+		Timeout:        5 * time.Second,
+		Insecure:       boolPtr(true),
+		MaxRecvMsgSize: 4,
+	})
+	// the target limit takes precedence over the global one
+	err := tg.CreateGNMIClient(context.Background(), grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024)))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	_, err = tg.Capabilities(context.Background())
+	if err == nil || !strings.Contains(err.Error(), codes.ResourceExhausted.String()) {
+		t.Errorf("expected a ResourceExhausted error, got %v", err)
+	}
+}
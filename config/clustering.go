@@ -50,6 +50,11 @@ func (c *Config) setClusteringDefaults() {
 	if c.Clustering.ClusterName == "" {
 		c.Clustering.ClusterName = c.GlobalFlags.ClusterName
 	}
+	// the --cluster-instance-name flag overrides the instance name set in the config file,
+	// --instance-name is only used if the config file does not set one.
+	if c.GlobalFlags.ClusterInstanceName != "" {
+		c.Clustering.InstanceName = c.GlobalFlags.ClusterInstanceName
+	}
 	if c.Clustering.InstanceName == "" {
 		if c.GlobalFlags.InstanceName != "" {
 			c.Clustering.InstanceName = c.GlobalFlags.InstanceName
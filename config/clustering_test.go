@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClusteringInstanceName(t *testing.T) {
+	tests := map[string]struct {
+		fileName            string
+		instanceName        string
+		clusterInstanceName string
+		want                string
+	}{
+		"from_file":                  {fileName: "gnmic1", instanceName: "gnmic2", want: "gnmic1"},
+		"from_instance_name":         {instanceName: "gnmic2", want: "gnmic2"},
+		"cluster_instance_name":      {fileName: "gnmic1", instanceName: "gnmic2", clusterInstanceName: "gnmic3", want: "gnmic3"},
+		"cluster_instance_name_only": {clusterInstanceName: "gnmic3", want: "gnmic3"},
+		"generated":                  {want: "gnmic-"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.GlobalFlags.InstanceName = tt.instanceName
+			cfg.GlobalFlags.ClusterInstanceName = tt.clusterInstanceName
+			cfg.Clustering = &clustering{InstanceName: tt.fileName}
+			cfg.setClusteringDefaults()
+			got := cfg.Clustering.InstanceName
+			if tt.want == "gnmic-" {
+				if !strings.HasPrefix(got, tt.want) || got == tt.want {
+					t.Errorf("expected a generated instance name, got %q", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got instance name %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/adrg/xdg"
 	"github.com/itchyny/gojq"
 	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/logging"
 	"github.com/mitchellh/go-homedir"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/spf13/cobra"
@@ -70,9 +71,11 @@ type GlobalFlags struct {
 	SkipVerify        bool          `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty" yaml:"skip-verify,omitempty"`
 	NoPrefix          bool          `mapstructure:"no-prefix,omitempty" json:"no-prefix,omitempty" yaml:"no-prefix,omitempty"`
 	ProxyFromEnv      bool          `mapstructure:"proxy-from-env,omitempty" json:"proxy-from-env,omitempty" yaml:"proxy-from-env,omitempty"`
+	Proxy             string        `mapstructure:"proxy,omitempty" json:"proxy,omitempty" yaml:"proxy,omitempty"`
 	Format            string        `mapstructure:"format,omitempty" json:"format,omitempty" yaml:"format,omitempty"`
 	LogFile           string        `mapstructure:"log-file,omitempty" json:"log-file,omitempty" yaml:"log-file,omitempty"`
 	Log               bool          `mapstructure:"log,omitempty" json:"log,omitempty" yaml:"log,omitempty"`
+	LogFormat         string        `mapstructure:"log-format,omitempty" json:"log-format,omitempty" yaml:"log-format,omitempty"`
 	MaxMsgSize        int           `mapstructure:"max-msg-size,omitempty" json:"max-msg-size,omitempty" yaml:"max-msg-size,omitempty"`
 	PrometheusAddress string        `mapstructure:"prometheus-address,omitempty" json:"prometheus-address,omitempty" yaml:"prometheus-address,omitempty"`
 	PrintRequest      bool          `mapstructure:"print-request,omitempty" json:"print-request,omitempty" yaml:"print-request,omitempty"`
@@ -81,21 +84,35 @@ type GlobalFlags struct {
 	ClusterName       string        `mapstructure:"cluster-name,omitempty" json:"cluster-name,omitempty" yaml:"cluster-name,omitempty"`
 	InstanceName      string        `mapstructure:"instance-name,omitempty" json:"instance-name,omitempty" yaml:"instance-name,omitempty"`
 	API               string        `mapstructure:"api,omitempty" json:"api,omitempty" yaml:"api,omitempty"`
+	AdminListen       string        `mapstructure:"admin-listen,omitempty" json:"admin-listen,omitempty" yaml:"admin-listen,omitempty"`
 	ProtoFile         []string      `mapstructure:"proto-file,omitempty" json:"proto-file,omitempty" yaml:"proto-file,omitempty"`
 	ProtoDir          []string      `mapstructure:"proto-dir,omitempty" json:"proto-dir,omitempty" yaml:"proto-dir,omitempty"`
 	TargetsFile       string        `mapstructure:"targets-file,omitempty" json:"targets-file,omitempty" yaml:"targets-file,omitempty"`
 	Gzip              bool          `mapstructure:"gzip,omitempty" json:"gzip,omitempty" yaml:"gzip,omitempty"`
+
+	KeepaliveTime        time.Duration `mapstructure:"keepalive-time,omitempty" json:"keepalive-time,omitempty" yaml:"keepalive-time,omitempty"`
+	KeepaliveTimeout     time.Duration `mapstructure:"keepalive-timeout,omitempty" json:"keepalive-timeout,omitempty" yaml:"keepalive-timeout,omitempty"`
+	PermitWithoutStream  bool          `mapstructure:"permit-without-stream,omitempty" json:"permit-without-stream,omitempty" yaml:"permit-without-stream,omitempty"`
+	SetRate              float64       `mapstructure:"set-rate,omitempty" json:"set-rate,omitempty" yaml:"set-rate,omitempty"`
+	ClientIdleTimeout    time.Duration `mapstructure:"client-idle-timeout,omitempty" json:"client-idle-timeout,omitempty" yaml:"client-idle-timeout,omitempty"`
+	ControlSocket        string        `mapstructure:"control-socket,omitempty" json:"control-socket,omitempty" yaml:"control-socket,omitempty"`
+	TargetBuffer         uint          `mapstructure:"target-buffer,omitempty" json:"target-buffer,omitempty" yaml:"target-buffer,omitempty"`
+	TargetBufferOverflow string        `mapstructure:"target-buffer-overflow,omitempty" json:"target-buffer-overflow,omitempty" yaml:"target-buffer-overflow,omitempty"`
+	ClusterInstanceName  string        `mapstructure:"cluster-instance-name,omitempty" json:"cluster-instance-name,omitempty" yaml:"cluster-instance-name,omitempty"`
 }
 
 type LocalFlags struct {
 	// Capabilities
 	CapabilitiesVersion bool `mapstructure:"capabilities-version,omitempty" json:"capabilities-version,omitempty" yaml:"capabilities-version,omitempty"`
 	// Get
-	GetPath   []string `mapstructure:"get-path,omitempty" json:"get-path,omitempty" yaml:"get-path,omitempty"`
-	GetPrefix string   `mapstructure:"get-prefix,omitempty" json:"get-prefix,omitempty" yaml:"get-prefix,omitempty"`
-	GetModel  []string `mapstructure:"get-model,omitempty" json:"get-model,omitempty" yaml:"get-model,omitempty"`
-	GetType   string   `mapstructure:"get-type,omitempty" json:"get-type,omitempty" yaml:"get-type,omitempty"`
-	GetTarget string   `mapstructure:"get-target,omitempty" json:"get-target,omitempty" yaml:"get-target,omitempty"`
+	GetPath       []string `mapstructure:"get-path,omitempty" json:"get-path,omitempty" yaml:"get-path,omitempty"`
+	GetPrefix     string   `mapstructure:"get-prefix,omitempty" json:"get-prefix,omitempty" yaml:"get-prefix,omitempty"`
+	GetModel      []string `mapstructure:"get-model,omitempty" json:"get-model,omitempty" yaml:"get-model,omitempty"`
+	GetUseModel   []string `mapstructure:"get-use-model,omitempty" json:"get-use-model,omitempty" yaml:"get-use-model,omitempty"`
+	GetType       string   `mapstructure:"get-type,omitempty" json:"get-type,omitempty" yaml:"get-type,omitempty"`
+	GetTarget     string   `mapstructure:"get-target,omitempty" json:"get-target,omitempty" yaml:"get-target,omitempty"`
+	GetValuesOnly bool     `mapstructure:"get-values-only,omitempty" json:"get-values-only,omitempty" yaml:"get-values-only,omitempty"`
+	GetPathsOnly  bool     `mapstructure:"get-paths-only,omitempty" json:"get-paths-only,omitempty" yaml:"get-paths-only,omitempty"`
 	// Set
 	SetPrefix       string   `mapstructure:"set-prefix,omitempty" json:"set-prefix,omitempty" yaml:"set-prefix,omitempty"`
 	SetDelete       []string `mapstructure:"set-delete,omitempty" json:"set-delete,omitempty" yaml:"set-delete,omitempty"`
@@ -107,8 +124,11 @@ type LocalFlags struct {
 	SetUpdateFile   []string `mapstructure:"set-update-file,omitempty" json:"set-update-file,omitempty" yaml:"set-update-file,omitempty"`
 	SetReplaceValue []string `mapstructure:"set-replace-value,omitempty" json:"set-replace-value,omitempty" yaml:"set-replace-value,omitempty"`
 	SetUpdateValue  []string `mapstructure:"set-update-value,omitempty" json:"set-update-value,omitempty" yaml:"set-update-value,omitempty"`
+	SetUnionReplace []string `mapstructure:"set-union-replace,omitempty" json:"set-union-replace,omitempty" yaml:"set-union-replace,omitempty"`
 	SetDelimiter    string   `mapstructure:"set-delimiter,omitempty" json:"set-delimiter,omitempty" yaml:"set-delimiter,omitempty"`
 	SetTarget       string   `mapstructure:"set-target,omitempty" json:"set-target,omitempty" yaml:"set-target,omitempty"`
+	SetDryRun       bool     `mapstructure:"set-dry-run,omitempty" json:"set-dry-run,omitempty" yaml:"set-dry-run,omitempty"`
+	SetValidateExt  int32    `mapstructure:"set-validate-ext,omitempty" json:"set-validate-ext,omitempty" yaml:"set-validate-ext,omitempty"`
 	// Sub
 	SubscribePrefix            string        `mapstructure:"subscribe-prefix,omitempty" json:"subscribe-prefix,omitempty" yaml:"subscribe-prefix,omitempty"`
 	SubscribePath              []string      `mapstructure:"subscribe-path,omitempty" json:"subscribe-path,omitempty" yaml:"subscribe-path,omitempty"`
@@ -119,6 +139,7 @@ type LocalFlags struct {
 	SubscribeSampleInterval    time.Duration `mapstructure:"subscribe-sample-interval,omitempty" json:"subscribe-sample-interval,omitempty" yaml:"subscribe-sample-interval,omitempty"`
 	SubscribeSuppressRedundant bool          `mapstructure:"subscribe-suppress-redundant,omitempty" json:"subscribe-suppress-redundant,omitempty" yaml:"subscribe-suppress-redundant,omitempty"`
 	SubscribeHeartbearInterval time.Duration `mapstructure:"subscribe-heartbear-interval,omitempty" json:"subscribe-heartbear-interval,omitempty" yaml:"subscribe-heartbear-interval,omitempty"`
+	SubscribePollInterval      time.Duration `mapstructure:"subscribe-poll-interval,omitempty" json:"subscribe-poll-interval,omitempty" yaml:"subscribe-poll-interval,omitempty"`
 	SubscribeModel             []string      `mapstructure:"subscribe-model,omitempty" json:"subscribe-model,omitempty" yaml:"subscribe-model,omitempty"`
 	SubscribeQuiet             bool          `mapstructure:"subscribe-quiet,omitempty" json:"subscribe-quiet,omitempty" yaml:"subscribe-quiet,omitempty"`
 	SubscribeTarget            string        `mapstructure:"subscribe-target,omitempty" json:"subscribe-target,omitempty" yaml:"subscribe-target,omitempty"`
@@ -126,6 +147,8 @@ type LocalFlags struct {
 	SubscribeOutput            []string      `mapstructure:"subscribe-output,omitempty" json:"subscribe-output,omitempty" yaml:"subscribe-output,omitempty"`
 	SubscribeWatchConfig       bool          `mapstructure:"subscribe-watch-config,omitempty" json:"subscribe-watch-config,omitempty" yaml:"subscribe-watch-config,omitempty"`
 	SubscribeBackoff           time.Duration `mapstructure:"subscribe-backoff,omitempty" json:"subscribe-backoff,omitempty" yaml:"subscribe-backoff,omitempty"`
+	SubscribeOnce              bool          `mapstructure:"subscribe-once,omitempty" json:"subscribe-once,omitempty" yaml:"subscribe-once,omitempty"`
+	SubscribeOnceTimeout       time.Duration `mapstructure:"subscribe-once-timeout,omitempty" json:"subscribe-once-timeout,omitempty" yaml:"subscribe-once-timeout,omitempty"`
 
 	SubscribeLockRetry time.Duration `mapstructure:"subscribe-lock-retry,omitempty" json:"subscribe-lock-retry,omitempty" yaml:"subscribe-lock-retry,omitempty"`
 	// Path
@@ -149,6 +172,9 @@ type LocalFlags struct {
 	PromptDescriptionWithPrefix bool     `mapstructure:"prompt-description-with-prefix,omitempty" json:"prompt-description-with-prefix,omitempty" yaml:"prompt-description-with-prefix,omitempty"`
 	PromptDescriptionWithTypes  bool     `mapstructure:"prompt-description-with-types,omitempty" json:"prompt-description-with-types,omitempty" yaml:"prompt-description-with-types,omitempty"`
 	PromptSuggestWithOrigin     bool     `mapstructure:"prompt-suggest-with-origin,omitempty" json:"prompt-suggest-with-origin,omitempty" yaml:"prompt-suggest-with-origin,omitempty"`
+	// Attach
+	AttachTarget string `mapstructure:"attach-target,omitempty" json:"attach-target,omitempty" yaml:"attach-target,omitempty"`
+	AttachPath   string `mapstructure:"attach-path,omitempty" json:"attach-path,omitempty" yaml:"attach-path,omitempty"`
 	// Listen
 	ListenMaxConcurrentStreams uint32 `mapstructure:"listen-max-concurrent-streams,omitempty" json:"listen-max-concurrent-streams,omitempty" yaml:"listen-max-concurrent-streams,omitempty"`
 	// VersionUpgrade
@@ -164,6 +190,18 @@ type LocalFlags struct {
 	GetSetReplace   string `mapstructure:"getset-replace,omitempty" json:"getset-replace,omitempty" yaml:"getset-replace,omitempty"`
 	GetSetDelete    string `mapstructure:"getset-delete,omitempty" json:"getset-delete,omitempty" yaml:"getset-delete,omitempty"`
 	GetSetValue     string `mapstructure:"getset-value,omitempty" json:"getset-value,omitempty" yaml:"getset-value,omitempty"`
+	// Generate
+	GenerateFile           []string      `mapstructure:"generate-file,omitempty" json:"generate-file,omitempty" yaml:"generate-file,omitempty"`
+	GenerateExclude        []string      `mapstructure:"generate-exclude,omitempty" json:"generate-exclude,omitempty" yaml:"generate-exclude,omitempty"`
+	GenerateDir            []string      `mapstructure:"generate-dir,omitempty" json:"generate-dir,omitempty" yaml:"generate-dir,omitempty"`
+	GeneratePath           string        `mapstructure:"generate-path,omitempty" json:"generate-path,omitempty" yaml:"generate-path,omitempty"`
+	GenerateMatch          string        `mapstructure:"generate-match,omitempty" json:"generate-match,omitempty" yaml:"generate-match,omitempty"`
+	GenerateName           string        `mapstructure:"generate-name,omitempty" json:"generate-name,omitempty" yaml:"generate-name,omitempty"`
+	GenerateMode           string        `mapstructure:"generate-mode,omitempty" json:"generate-mode,omitempty" yaml:"generate-mode,omitempty"`
+	GenerateStreamMode     string        `mapstructure:"generate-stream-mode,omitempty" json:"generate-stream-mode,omitempty" yaml:"generate-stream-mode,omitempty"`
+	GenerateSampleInterval time.Duration `mapstructure:"generate-sample-interval,omitempty" json:"generate-sample-interval,omitempty" yaml:"generate-sample-interval,omitempty"`
+	GenerateEncoding       string        `mapstructure:"generate-encoding,omitempty" json:"generate-encoding,omitempty" yaml:"generate-encoding,omitempty"`
+	GenerateOutput         string        `mapstructure:"generate-output,omitempty" json:"generate-output,omitempty" yaml:"generate-output,omitempty"`
 }
 
 func New() *Config {
@@ -231,6 +269,7 @@ func (c *Config) SetLogger() {
 		loggingFlags := c.logger.Flags() | log.Llongfile
 		c.logger.SetFlags(loggingFlags)
 	}
+	logging.SetFormat(c.logger, c.LogFormat)
 }
 
 func (c *Config) SetPersistantFlagsFromFile(cmd *cobra.Command) {
@@ -278,18 +317,33 @@ func flagIsSet(cmd *cobra.Command, name string) bool {
 	return isSet
 }
 
+// GetEncodings parses the encoding flag as a comma separated list of encodings,
+// the Get command tries them in order until one succeeds.
+func (c *Config) GetEncodings() ([]gnmi.Encoding, error) {
+	encodings := make([]gnmi.Encoding, 0)
+	for _, enc := range strings.Split(c.Encoding, ",") {
+		enc = strings.TrimSpace(enc)
+		encodingVal, ok := gnmi.Encoding_value[strings.Replace(strings.ToUpper(enc), "-", "_", -1)]
+		if !ok {
+			return nil, fmt.Errorf("invalid encoding type '%s'", enc)
+		}
+		encodings = append(encodings, gnmi.Encoding(encodingVal))
+	}
+	return encodings, nil
+}
+
 func (c *Config) CreateGetRequest() (*gnmi.GetRequest, error) {
 	if c == nil {
 		return nil, errors.New("invalid configuration")
 	}
-	encodingVal, ok := gnmi.Encoding_value[strings.Replace(strings.ToUpper(c.Encoding), "-", "_", -1)]
-	if !ok {
-		return nil, fmt.Errorf("invalid encoding type '%s'", c.Encoding)
+	encodings, err := c.GetEncodings()
+	if err != nil {
+		return nil, err
 	}
 	req := &gnmi.GetRequest{
 		UseModels: make([]*gnmi.ModelData, 0),
 		Path:      make([]*gnmi.Path, 0, len(c.LocalFlags.GetPath)),
-		Encoding:  gnmi.Encoding(encodingVal),
+		Encoding:  encodings[0],
 	}
 	if c.LocalFlags.GetPrefix != "" {
 		gnmiPrefix, err := collector.ParsePath(c.LocalFlags.GetPrefix)
@@ -311,6 +365,13 @@ func (c *Config) CreateGetRequest() (*gnmi.GetRequest, error) {
 		}
 		req.Type = gnmi.GetRequest_DataType(dti)
 	}
+	for _, m := range c.LocalFlags.GetUseModel {
+		md, err := parseModelData(m)
+		if err != nil {
+			return nil, err
+		}
+		req.UseModels = append(req.UseModels, md)
+	}
 	for _, p := range c.LocalFlags.GetPath {
 		gnmiPath, err := collector.ParsePath(strings.TrimSpace(p))
 		if err != nil {
@@ -321,6 +382,22 @@ func (c *Config) CreateGetRequest() (*gnmi.GetRequest, error) {
 	return req, nil
 }
 
+// parseModelData parses a model in the format name[@version]
+func parseModelData(m string) (*gnmi.ModelData, error) {
+	m = strings.TrimSpace(m)
+	name, version := m, ""
+	if i := strings.Index(m, "@"); i >= 0 {
+		name, version = m[:i], m[i+1:]
+		if version == "" || strings.Contains(version, "@") {
+			return nil, fmt.Errorf("invalid model %q: expected format name[@version]", m)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid model %q: expected format name[@version]", m)
+	}
+	return &gnmi.ModelData{Name: name, Version: version}, nil
+}
+
 func (c *Config) CreateGASGetRequest() (*gnmi.GetRequest, error) {
 	if c == nil {
 		return nil, errors.New("invalid configuration")
@@ -506,6 +583,8 @@ func (c *Config) CreateSetRequest() (*gnmi.SetRequest, error) {
 		c.logger.Printf("Set input replace path(s): %+v", &c.LocalFlags.SetReplacePath)
 		c.logger.Printf("Set input replace value(s): %+v", &c.LocalFlags.SetReplaceValue)
 		c.logger.Printf("Set input replace file(s): %+v", &c.LocalFlags.SetReplaceFile)
+
+		c.logger.Printf("Set input union replace: %+v", &c.LocalFlags.SetUnionReplace)
 	}
 
 	//
@@ -634,6 +713,42 @@ func (c *Config) CreateSetRequest() (*gnmi.SetRequest, error) {
 			Val:  value,
 		})
 	}
+	unionReplace := make([]*gnmi.Update, 0, len(c.LocalFlags.SetUnionReplace))
+	for _, r := range c.LocalFlags.SetUnionReplace {
+		singleReplace := strings.Split(r, c.LocalFlags.SetDelimiter)
+		if len(singleReplace) < 3 {
+			return nil, fmt.Errorf("invalid inline union replace format: %s", c.LocalFlags.SetUnionReplace)
+		}
+		gnmiPath, err := collector.ParsePath(strings.TrimSpace(singleReplace[0]))
+		if err != nil {
+			return nil, err
+		}
+		value := new(gnmi.TypedValue)
+		err = setValue(value, singleReplace[1], singleReplace[2])
+		if err != nil {
+			return nil, err
+		}
+		unionReplace = append(unionReplace, &gnmi.Update{
+			Path: gnmiPath,
+			Val:  value,
+		})
+	}
+	paths := append([]*gnmi.Path{req.Prefix}, req.Delete...)
+	for _, upds := range [][]*gnmi.Update{req.Replace, req.Update, unionReplace} {
+		for _, upd := range upds {
+			paths = append(paths, upd.Path)
+		}
+	}
+	err = validateOrigins(paths...)
+	if err != nil {
+		return nil, err
+	}
+	if len(unionReplace) > 0 {
+		err = SetUnionReplace(req, unionReplace)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
@@ -802,6 +917,7 @@ func (c *Config) ValidateSetInput() error {
 	c.LocalFlags.SetDelete = SanitizeArrayFlagValue(c.LocalFlags.SetDelete)
 	c.LocalFlags.SetUpdate = SanitizeArrayFlagValue(c.LocalFlags.SetUpdate)
 	c.LocalFlags.SetReplace = SanitizeArrayFlagValue(c.LocalFlags.SetReplace)
+	c.LocalFlags.SetUnionReplace = SanitizeArrayFlagValue(c.LocalFlags.SetUnionReplace)
 	c.LocalFlags.SetUpdatePath = SanitizeArrayFlagValue(c.LocalFlags.SetUpdatePath)
 	c.LocalFlags.SetReplacePath = SanitizeArrayFlagValue(c.LocalFlags.SetReplacePath)
 	c.LocalFlags.SetUpdateValue = SanitizeArrayFlagValue(c.LocalFlags.SetUpdateValue)
@@ -817,7 +933,7 @@ func (c *Config) ValidateSetInput() error {
 	if err != nil {
 		return err
 	}
-	if (len(c.LocalFlags.SetDelete)+len(c.LocalFlags.SetUpdate)+len(c.LocalFlags.SetReplace)) == 0 && (len(c.LocalFlags.SetUpdatePath)+len(c.LocalFlags.SetReplacePath)) == 0 {
+	if (len(c.LocalFlags.SetDelete)+len(c.LocalFlags.SetUpdate)+len(c.LocalFlags.SetReplace)+len(c.LocalFlags.SetUnionReplace)) == 0 && (len(c.LocalFlags.SetUpdatePath)+len(c.LocalFlags.SetReplacePath)) == 0 {
 		return errors.New("no paths provided")
 	}
 	if len(c.LocalFlags.SetUpdateFile) > 0 && len(c.LocalFlags.SetUpdateValue) > 0 {
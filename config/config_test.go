@@ -9,6 +9,7 @@ import (
 
 	"github.com/karimra/gnmic/testutils"
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
 )
 
 var createGetRequestTestSet = map[string]struct {
@@ -94,6 +95,109 @@ var createGetRequestTestSet = map[string]struct {
 		},
 		err: nil,
 	},
+	"get_request_with_use_models": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json",
+			},
+			LocalFlags{
+				GetPath:     []string{"/valid/path"},
+				GetUseModel: []string{"openconfig-interfaces@2.4.3", "nokia-conf"},
+			},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: &gnmi.GetRequest{
+			Path: []*gnmi.Path{
+				{
+					Elem: []*gnmi.PathElem{
+						{Name: "valid"},
+						{Name: "path"},
+					},
+				},
+			},
+			UseModels: []*gnmi.ModelData{
+				{Name: "openconfig-interfaces", Version: "2.4.3"},
+				{Name: "nokia-conf"},
+			},
+		},
+		err: nil,
+	},
+	"invalid_use_model_empty_version": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json",
+			},
+			LocalFlags{
+				GetPath:     []string{"/valid/path"},
+				GetUseModel: []string{"openconfig-interfaces@"},
+			},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: nil,
+		err: errors.New("invalid model"),
+	},
+	"invalid_use_model_empty_name": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json",
+			},
+			LocalFlags{
+				GetPath:     []string{"/valid/path"},
+				GetUseModel: []string{"@2.4.3"},
+			},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: nil,
+		err: errors.New("invalid model"),
+	},
+	"invalid_use_model_multiple_versions": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json",
+			},
+			LocalFlags{
+				GetPath:     []string{"/valid/path"},
+				GetUseModel: []string{"openconfig-interfaces@2.4.3@1.0.0"},
+			},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: nil,
+		err: errors.New("invalid model"),
+	},
+	"get_request_with_encodings_list": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json_ietf, json",
+			},
+			LocalFlags{
+				GetPath: []string{"/valid/path"},
+			},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: &gnmi.GetRequest{
+			Path: []*gnmi.Path{
+				{
+					Elem: []*gnmi.PathElem{
+						{Name: "valid"},
+						{Name: "path"},
+					},
+				},
+			},
+			Encoding: gnmi.Encoding_JSON_IETF,
+		},
+		err: nil,
+	},
+	"invalid_encoding_in_list": {
+		in: &Config{
+			GlobalFlags{
+				Encoding: "json_ietf,dummy",
+			},
+			LocalFlags{},
+			nil, nil, nil, nil, nil, nil, nil, nil,
+		},
+		out: nil,
+		err: errors.New("invalid encoding type"),
+	},
 	"get_request_with_type": {
 		in: &Config{
 			GlobalFlags{
@@ -621,3 +725,59 @@ func TestExecPathTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSetRequestUnionReplace(t *testing.T) {
+	c := &Config{
+		GlobalFlags: GlobalFlags{},
+		LocalFlags: LocalFlags{
+			SetDelimiter:    ":::",
+			SetUpdate:       []string{"openconfig:/system/config/hostname:::json:::router1"},
+			SetUnionReplace: []string{"openconfig:/interfaces:::json_ietf:::{}", "cli:/:::ascii:::hostname router1"},
+		},
+	}
+	req, err := c.CreateSetRequest()
+	if err != nil {
+		t.Fatalf("failed to create set request: %v", err)
+	}
+	if len(req.Update) != 1 || req.Update[0].Path.Origin != "openconfig" {
+		t.Errorf("unexpected updates: %v", req.Update)
+	}
+	// the union replace field survives a marshal/unmarshal round trip
+	b, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = new(gnmi.SetRequest)
+	err = proto.Unmarshal(b, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upds, err := GetUnionReplace(req)
+	if err != nil {
+		t.Fatalf("failed to get union replace updates: %v", err)
+	}
+	if len(upds) != 2 {
+		t.Fatalf("expected 2 union replace updates, got %d: %v", len(upds), upds)
+	}
+	for i, origin := range []string{"openconfig", "cli"} {
+		if upds[i].Path.Origin != origin {
+			t.Errorf("union replace %d: expected origin %q, got %q", i, origin, upds[i].Path.Origin)
+		}
+	}
+	if upds[1].Val.GetAsciiVal() != "hostname router1" {
+		t.Errorf("unexpected union replace value: %v", upds[1].Val)
+	}
+}
+
+func TestCreateSetRequestInvalidOrigin(t *testing.T) {
+	c := &Config{
+		LocalFlags: LocalFlags{
+			SetDelimiter: ":::",
+			SetReplace:   []string{"open config:/system:::json:::{}"},
+		},
+	}
+	_, err := c.CreateSetRequest()
+	if err == nil {
+		t.Errorf("expected an invalid origin error")
+	}
+}
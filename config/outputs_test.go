@@ -60,6 +60,38 @@ outputs:
 			},
 		},
 	},
+	"per_output_format": {
+		in: []byte(`
+format: protojson
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+    format: event
+  output2:
+    type: file
+    file-type: stdout
+  output3:
+    type: nats
+    format: ""
+`),
+		out: map[string]map[string]interface{}{
+			"output1": {
+				"type":      "file",
+				"file-type": "stdout",
+				"format":    "event",
+			},
+			"output2": {
+				"type":      "file",
+				"file-type": "stdout",
+				"format":    "protojson",
+			},
+			"output3": {
+				"type":   "nats",
+				"format": "protojson",
+			},
+		},
+	},
 }
 
 func TestGetOutputs(t *testing.T) {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/karimra/gnmic/collector"
 	"github.com/mitchellh/mapstructure"
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +18,9 @@ func (c *Config) GetSubscriptions(cmd *cobra.Command) (map[string]*collector.Sub
 	if len(c.LocalFlags.SubscribePath) > 0 && len(c.LocalFlags.SubscribeName) > 0 {
 		return nil, fmt.Errorf("flags --path and --name cannot be mixed")
 	}
+	if c.LocalFlags.SubscribeOnce && flagIsSet(cmd, "mode") && strings.ToUpper(c.LocalFlags.SubscribeMode) != "ONCE" {
+		return nil, fmt.Errorf("flag --once cannot be used with --mode %s", c.LocalFlags.SubscribeMode)
+	}
 	if len(c.LocalFlags.SubscribePath) > 0 {
 		sub := new(collector.SubscriptionConfig)
 		sub.Name = fmt.Sprintf("default-%d", time.Now().Unix())
@@ -24,7 +28,10 @@ func (c *Config) GetSubscriptions(cmd *cobra.Command) (map[string]*collector.Sub
 		sub.Prefix = c.LocalFlags.SubscribePrefix
 		sub.Target = c.LocalFlags.SubscribeTarget
 		sub.Mode = c.LocalFlags.SubscribeMode
-		sub.Encoding = c.Encoding
+		if c.LocalFlags.SubscribeOnce {
+			sub.Mode = "once"
+		}
+		sub.Encoding = c.subscriptionEncoding()
 		if flagIsSet(cmd, "qos") {
 			sub.Qos = &c.LocalFlags.SubscribeQos
 		}
@@ -35,6 +42,7 @@ func (c *Config) GetSubscriptions(cmd *cobra.Command) (map[string]*collector.Sub
 		if flagIsSet(cmd, "sample-interval") {
 			sub.SampleInterval = &c.LocalFlags.SubscribeSampleInterval
 		}
+		sub.PollInterval = c.LocalFlags.SubscribePollInterval
 		sub.SuppressRedundant = c.LocalFlags.SubscribeSuppressRedundant
 		sub.UpdatesOnly = c.LocalFlags.SubscribeUpdatesOnly
 		sub.Models = c.LocalFlags.SubscribeModel
@@ -112,8 +120,15 @@ func (c *Config) setSubscriptionDefaults(sub *collector.SubscriptionConfig, cmd
 			sub.HeartbeatInterval = &c.LocalFlags.SubscribeHeartbearInterval
 		}
 	}
+	if sub.PollInterval == 0 {
+		sub.PollInterval = c.LocalFlags.SubscribePollInterval
+	}
 	if sub.Encoding == "" {
-		sub.Encoding = c.Encoding
+		sub.Encoding = c.subscriptionEncoding()
+	}
+	// --once overrides the mode of the subscriptions defined in the config file
+	if c.LocalFlags.SubscribeOnce {
+		sub.Mode = "once"
 	}
 	if sub.Mode == "" {
 		sub.Mode = c.LocalFlags.SubscribeMode
@@ -143,11 +158,22 @@ func (c *Config) GetSubscriptionsFromFile() []*collector.SubscriptionConfig {
 	return subscriptions
 }
 
+// subscriptionEncoding returns the encoding inherited by the subscriptions not setting one,
+// the first one if the global encoding is a list.
+func (c *Config) subscriptionEncoding() string {
+	return strings.TrimSpace(strings.Split(c.Encoding, ",")[0])
+}
+
 func validateSubscriptionsConfig(subs map[string]*collector.SubscriptionConfig) error {
 	var hasPoll bool
 	var hasOnce bool
 	var hasStream bool
 	for _, sc := range subs {
+		if sc.Encoding != "" {
+			if _, ok := gnmi.Encoding_value[strings.Replace(strings.ToUpper(sc.Encoding), "-", "_", -1)]; !ok {
+				return fmt.Errorf("subscription %q: invalid encoding %q", sc.Name, sc.Encoding)
+			}
+		}
 		switch strings.ToUpper(sc.Mode) {
 		case "POLL":
 			hasPoll = true
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/karimra/gnmic/collector"
+	"github.com/openconfig/gnmi/proto/gnmi"
 )
 
 var getSubscriptionsTestSet = map[string]struct {
@@ -175,3 +176,78 @@ func TestGetSubscriptions(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscriptionsEncoding(t *testing.T) {
+	tests := map[string]struct {
+		in     []byte
+		out    map[string]gnmi.Encoding
+		outErr bool
+	}{
+		"per_subscription": {
+			in: []byte(`
+encoding: json_ietf, json
+subscriptions:
+  sub1:
+    paths: 
+      - /valid/path
+  sub2:
+    paths: 
+      - /valid/path
+    encoding: proto
+  sub3:
+    paths: 
+      - /valid/path
+    encoding: ascii
+`),
+			out: map[string]gnmi.Encoding{
+				"sub1": gnmi.Encoding_JSON_IETF,
+				"sub2": gnmi.Encoding_PROTO,
+				"sub3": gnmi.Encoding_ASCII,
+			},
+		},
+		"invalid_encoding": {
+			in: []byte(`
+subscriptions:
+  sub1:
+    paths: 
+      - /valid/path
+    encoding: xml
+`),
+			outErr: true,
+		},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.SetLogger()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBuffer(data.in))
+			if err != nil {
+				t.Fatalf("failed reading config: %v", err)
+			}
+			err = cfg.FileConfig.Unmarshal(cfg)
+			if err != nil {
+				t.Fatalf("failed fileConfig.Unmarshal: %v", err)
+			}
+			subs, err := cfg.GetSubscriptions(nil)
+			if data.outErr {
+				if err == nil {
+					t.Fatalf("expected an error, got subscriptions: %v", subs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed getting subscriptions: %v", err)
+			}
+			for sn, enc := range data.out {
+				req, err := subs[sn].CreateSubscribeRequest()
+				if err != nil {
+					t.Fatalf("failed creating subscribe request for %q: %v", sn, err)
+				}
+				if got := req.GetSubscribe().GetEncoding(); got != enc {
+					t.Errorf("subscription %q: expected encoding %s, got %s", sn, enc, got)
+				}
+			}
+		})
+	}
+}
@@ -181,12 +181,15 @@ func (c *Config) SetTargetConfigDefaults(tc *collector.TargetConfig) error {
 				tc.TLSCA = &c.TLSCa
 			}
 		}
-		if tc.TLSCert == nil {
+		// the client certificate and key fall back to the global ones
+		// only if the target does not define its own
+		if tc.TLSCert == nil && tc.TLSKey == nil {
 			tc.TLSCert = &c.TLSCert
-		}
-		if tc.TLSKey == nil {
 			tc.TLSKey = &c.TLSKey
 		}
+		if tc.TLSCert == nil || tc.TLSKey == nil {
+			return fmt.Errorf("target %q: tls-cert and tls-key must be set together", tc.Name)
+		}
 	}
 	if tc.RetryTimer == 0 {
 		tc.RetryTimer = c.Retry
@@ -203,6 +206,32 @@ func (c *Config) SetTargetConfigDefaults(tc *collector.TargetConfig) error {
 	if tc.Gzip == nil {
 		tc.Gzip = &c.Gzip
 	}
+	if tc.KeepaliveTime == 0 {
+		tc.KeepaliveTime = c.KeepaliveTime
+	}
+	if tc.KeepaliveTimeout == 0 {
+		tc.KeepaliveTimeout = c.KeepaliveTimeout
+	}
+	if tc.PermitWithoutStream == nil {
+		tc.PermitWithoutStream = &c.PermitWithoutStream
+	}
+	for k := range tc.EventTags {
+		switch k {
+		case "source", "format", "subscription-name":
+			return fmt.Errorf("target %q: event-tags cannot set the reserved tag %q", tc.Name, k)
+		}
+	}
+	if tc.MaxRecvMsgSize < 0 {
+		return fmt.Errorf("target %q: invalid max-recv-msg-size %d, must be a positive number of bytes", tc.Name, tc.MaxRecvMsgSize)
+	}
+	if tc.MaxSendMsgSize < 0 {
+		return fmt.Errorf("target %q: invalid max-send-msg-size %d, must be a positive number of bytes", tc.Name, tc.MaxSendMsgSize)
+	}
+	for k := range tc.GRPCMetadata {
+		if k == "" || strings.HasPrefix(strings.ToLower(k), "grpc-") {
+			return fmt.Errorf("target %q: invalid grpc-metadata key %q", tc.Name, k)
+		}
+	}
 	return nil
 }
 
@@ -25,15 +25,16 @@ address: 10.1.1.1
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &falseBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &falseBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 		},
 		outErr: nil,
@@ -47,15 +48,16 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &falseBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &falseBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 		},
 		outErr: nil,
@@ -70,15 +72,16 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 		},
 		outErr: nil,
@@ -95,26 +98,28 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &falseBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &falseBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 			"10.1.1.2:57400": {
-				Address:    "10.1.1.2:57400",
-				Name:       "10.1.1.2:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &falseBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.2:57400",
+				Name:                "10.1.1.2:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &falseBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 		},
 		outErr: nil,
@@ -132,26 +137,28 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 			"10.1.1.2:57400": {
-				Address:    "10.1.1.2:57400",
-				Name:       "10.1.1.2:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.2:57400",
+				Name:                "10.1.1.2:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 			},
 		},
 		outErr: nil,
@@ -170,26 +177,56 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &trueBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &trueBool,
+				PermitWithoutStream: &falseBool,
 			},
 			"10.1.1.2:57400": {
-				Address:    "10.1.1.2:57400",
-				Name:       "10.1.1.2:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.2:57400",
+				Name:                "10.1.1.2:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
+			},
+		},
+		outErr: nil,
+	},
+	"target_with_event_tags": {
+		in: []byte(`
+skip-verify: true
+targets:
+  10.1.1.1:57400:
+    username: admin
+    password: admin
+    event-tags:
+      site: paris
+      role: spine
+`),
+		out: map[string]*collector.TargetConfig{
+			"10.1.1.1:57400": {
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
+				EventTags:           map[string]string{"site": "paris", "role": "spine"},
 			},
 		},
 		outErr: nil,
@@ -212,15 +249,16 @@ targets:
 `),
 		out: map[string]*collector.TargetConfig{
 			"10.1.1.1:57400": {
-				Address:    "10.1.1.1:57400",
-				Name:       "10.1.1.1:57400",
-				Password:   &adminStr,
-				Username:   &adminStr,
-				TLSCert:    &emptyStr,
-				TLSKey:     &emptyStr,
-				Insecure:   &falseBool,
-				SkipVerify: &trueBool,
-				Gzip:       &falseBool,
+				Address:             "10.1.1.1:57400",
+				Name:                "10.1.1.1:57400",
+				Password:            &adminStr,
+				Username:            &adminStr,
+				TLSCert:             &emptyStr,
+				TLSKey:              &emptyStr,
+				Insecure:            &falseBool,
+				SkipVerify:          &trueBool,
+				Gzip:                &falseBool,
+				PermitWithoutStream: &falseBool,
 				Subscriptions: []string{
 					"sub1",
 				},
@@ -270,3 +308,62 @@ func TestGetTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTargetsReservedEventTags(t *testing.T) {
+	cfg := New()
+	cfg.FileConfig.SetConfigType("yaml")
+	err := cfg.FileConfig.ReadConfig(bytes.NewBuffer([]byte(`
+insecure: true
+targets:
+  10.1.1.1:57400:
+    event-tags:
+      source: router1
+`)))
+	if err != nil {
+		t.Fatalf("failed reading config: %v", err)
+	}
+	err = cfg.FileConfig.Unmarshal(cfg)
+	if err != nil {
+		t.Fatalf("failed fileConfig.Unmarshal: %v", err)
+	}
+	_, err = cfg.GetTargets()
+	if err == nil || !strings.Contains(err.Error(), "reserved tag") {
+		t.Errorf("expected a reserved tag error, got %v", err)
+	}
+}
+
+func TestGetTargetsInvalidGRPCOptions(t *testing.T) {
+	tests := map[string]string{
+		"negative_max_recv_msg_size": `
+    max-recv-msg-size: -1
+`,
+		"negative_max_send_msg_size": `
+    max-send-msg-size: -1
+`,
+		"reserved_metadata_key": `
+    grpc-metadata:
+      grpc-timeout: 1s
+`,
+	}
+	for name, targetCfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBuffer([]byte(`
+insecure: true
+targets:
+  10.1.1.1:57400:` + targetCfg)))
+			if err != nil {
+				t.Fatalf("failed reading config: %v", err)
+			}
+			err = cfg.FileConfig.Unmarshal(cfg)
+			if err != nil {
+				t.Fatalf("failed fileConfig.Unmarshal: %v", err)
+			}
+			_, err = cfg.GetTargets()
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
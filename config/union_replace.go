@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// SetRequest union_replace field number.
+// The field was added to the gNMI specification (v0.10.0) after the version of the gnmi proto
+// used by gnmic, it is encoded directly in the message unknown fields.
+const setRequestUnionReplaceField protowire.Number = 6
+
+var originRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// SetUnionReplace sets the union_replace updates of a SetRequest
+func SetUnionReplace(req *gnmi.SetRequest, upds []*gnmi.Update) error {
+	b := make([]byte, 0)
+	for _, upd := range upds {
+		ub, err := proto.Marshal(upd)
+		if err != nil {
+			return err
+		}
+		b = protowire.AppendTag(b, setRequestUnionReplaceField, protowire.BytesType)
+		b = protowire.AppendBytes(b, ub)
+	}
+	req.ProtoReflect().SetUnknown(b)
+	return nil
+}
+
+// GetUnionReplace returns the union_replace updates of a SetRequest
+func GetUnionReplace(req *gnmi.SetRequest) ([]*gnmi.Update, error) {
+	upds := make([]*gnmi.Update, 0)
+	b := req.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num != setRequestUnionReplaceField || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		ub, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		upd := new(gnmi.Update)
+		err := proto.Unmarshal(ub, upd)
+		if err != nil {
+			return nil, err
+		}
+		upds = append(upds, upd)
+	}
+	return upds, nil
+}
+
+// validateOrigins checks that the origins set in the paths are valid origin names
+func validateOrigins(ps ...*gnmi.Path) error {
+	for _, p := range ps {
+		if p == nil || p.Origin == "" {
+			continue
+		}
+		if !originRegex.MatchString(p.Origin) {
+			return fmt.Errorf("invalid path origin %q", p.Origin)
+		}
+	}
+	return nil
+}
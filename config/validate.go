@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/karimra/gnmic/collector"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/spf13/cobra"
+)
+
+// Validate decodes the targets, subscriptions, outputs and processors sections of the config file
+// and returns all the errors found.
+// The outputs and processors are instantiated from their registries
+// without connecting to the targets or starting the outputs.
+func (c *Config) Validate(cmd *cobra.Command) []error {
+	errs := make([]error, 0)
+	processors, perrs := c.validateProcessors()
+	errs = append(errs, perrs...)
+	outs, oerrs := c.validateOutputs(processors)
+	errs = append(errs, oerrs...)
+
+	subs, subsErr := c.GetSubscriptions(cmd)
+	if subsErr != nil {
+		errs = append(errs, fmt.Errorf("subscriptions: %v", subsErr))
+	}
+	targets, err := c.validateTargets()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("targets: %v", err))
+	}
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tc := targets[name]
+		for _, sn := range tc.Subscriptions {
+			if _, ok := subs[sn]; !ok && subsErr == nil {
+				errs = append(errs, fmt.Errorf("target %q: unknown subscription %q", name, sn))
+			}
+		}
+		for _, on := range tc.Outputs {
+			if _, ok := outs[on]; !ok {
+				errs = append(errs, fmt.Errorf("target %q: unknown output %q", name, on))
+			}
+		}
+	}
+	return errs
+}
+
+// validateProcessors validates each configured event processor config,
+// it returns the configured processors names.
+func (c *Config) validateProcessors() (map[string]struct{}, []error) {
+	names := make(map[string]struct{})
+	errs := make([]error, 0)
+	eps := c.FileConfig.GetStringMap("processors")
+	for _, name := range sortedKeys(eps) {
+		names[name] = struct{}{}
+		var epc map[string]interface{}
+		switch cfg := eps[name].(type) {
+		case map[string]interface{}:
+			epc = cfg
+		case nil:
+		default:
+			errs = append(errs, fmt.Errorf("processor %q: malformed config, got %T", name, cfg))
+			continue
+		}
+		if len(epc) == 0 {
+			errs = append(errs, fmt.Errorf("processor %q: empty config", name))
+			continue
+		}
+		for epType, cfg := range epc {
+			if _, ok := formatters.EventProcessors[epType]; !ok {
+				errs = append(errs, fmt.Errorf("processor %q: unknown processor type %q", name, epType))
+				continue
+			}
+			cfg = convert(cfg)
+			if m, ok := cfg.(map[string]interface{}); ok {
+				expandMapEnv(m)
+			}
+			err := formatters.ValidateEventProcessor(epType, cfg)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("processor %q: %v", name, err))
+			}
+		}
+	}
+	return names, errs
+}
+
+// validateOutputs validates each configured output config and the event processors it references,
+// it returns the set of configured outputs names.
+func (c *Config) validateOutputs(processors map[string]struct{}) (map[string]struct{}, []error) {
+	outs := make(map[string]struct{})
+	errs := make([]error, 0)
+	outDef := c.FileConfig.GetStringMap("outputs")
+	for _, name := range sortedKeys(outDef) {
+		outs[name] = struct{}{}
+		outCfg, ok := convert(outDef[name]).(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("output %q: malformed config, got %T", name, outDef[name]))
+			continue
+		}
+		outType, ok := outCfg["type"].(string)
+		if !ok || outType == "" {
+			errs = append(errs, fmt.Errorf("output %q: missing output type", name))
+			continue
+		}
+		initFn, ok := outputs.Outputs[outType]
+		if !ok {
+			errs = append(errs, fmt.Errorf("output %q: unknown output type %q, must be one of %q", name, outType, outputs.OutputTypes))
+			continue
+		}
+		expandMapEnv(outCfg)
+		if v, ok := initFn().(outputs.ConfigValidator); ok {
+			if err := v.ValidateConfig(outCfg); err != nil {
+				errs = append(errs, fmt.Errorf("output %q: %v", name, err))
+			}
+		}
+		epNames := make([]string, 0)
+		err := outputs.DecodeConfig(outCfg["event-processors"], &epNames)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("output %q: invalid event-processors: %v", name, err))
+			continue
+		}
+		for _, ep := range epNames {
+			if _, ok := processors[ep]; !ok {
+				errs = append(errs, fmt.Errorf("output %q: unknown event processor %q", name, ep))
+			}
+		}
+	}
+	return outs, errs
+}
+
+// validateTargets returns the targets configured in the config file or with the --address flag,
+// without prompting for missing credentials.
+func (c *Config) validateTargets() (map[string]*collector.TargetConfig, error) {
+	if len(c.Address) == 0 {
+		targets, err := c.GetTargets()
+		if err == ErrNoTargetsFound {
+			return nil, nil
+		}
+		return targets, err
+	}
+	targets := make(map[string]*collector.TargetConfig)
+	for _, addr := range c.Address {
+		tc := &collector.TargetConfig{Address: addr}
+		err := c.SetTargetConfigDefaults(tc)
+		if err != nil {
+			return nil, err
+		}
+		targets[tc.Name] = tc
+	}
+	return targets, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,14 +3,38 @@ package all
 import (
 	_ "github.com/karimra/gnmic/formatters/event_add_tag"
 	_ "github.com/karimra/gnmic/formatters/event_allow"
+	_ "github.com/karimra/gnmic/formatters/event_base64_decode"
+	_ "github.com/karimra/gnmic/formatters/event_bit_decode"
+	_ "github.com/karimra/gnmic/formatters/event_coalesce_tags"
+	_ "github.com/karimra/gnmic/formatters/event_combine"
+	_ "github.com/karimra/gnmic/formatters/event_conditional"
 	_ "github.com/karimra/gnmic/formatters/event_convert"
+	_ "github.com/karimra/gnmic/formatters/event_data_convert"
 	_ "github.com/karimra/gnmic/formatters/event_date_string"
 	_ "github.com/karimra/gnmic/formatters/event_delete"
 	_ "github.com/karimra/gnmic/formatters/event_drop"
+	_ "github.com/karimra/gnmic/formatters/event_duration_convert"
 	_ "github.com/karimra/gnmic/formatters/event_extract_tags"
+	_ "github.com/karimra/gnmic/formatters/event_flatten"
+	_ "github.com/karimra/gnmic/formatters/event_hash"
+	_ "github.com/karimra/gnmic/formatters/event_index_tag"
+	_ "github.com/karimra/gnmic/formatters/event_ip_enrich"
 	_ "github.com/karimra/gnmic/formatters/event_jq"
+	_ "github.com/karimra/gnmic/formatters/event_jsonpath"
+	_ "github.com/karimra/gnmic/formatters/event_keep"
+	_ "github.com/karimra/gnmic/formatters/event_mac_normalize"
 	_ "github.com/karimra/gnmic/formatters/event_merge"
+	_ "github.com/karimra/gnmic/formatters/event_normalize_path"
+	_ "github.com/karimra/gnmic/formatters/event_outlier"
+	_ "github.com/karimra/gnmic/formatters/event_percentage"
+	_ "github.com/karimra/gnmic/formatters/event_reduce"
+	_ "github.com/karimra/gnmic/formatters/event_regex_replace"
+	_ "github.com/karimra/gnmic/formatters/event_rename_value"
+	_ "github.com/karimra/gnmic/formatters/event_round"
+	_ "github.com/karimra/gnmic/formatters/event_static"
 	_ "github.com/karimra/gnmic/formatters/event_strings"
+	_ "github.com/karimra/gnmic/formatters/event_time_bucket"
+	_ "github.com/karimra/gnmic/formatters/event_time_since"
 	_ "github.com/karimra/gnmic/formatters/event_to_tag"
 	_ "github.com/karimra/gnmic/formatters/event_trigger"
 	_ "github.com/karimra/gnmic/formatters/event_write"
@@ -3,6 +3,7 @@ package formatters
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	flattener "github.com/karimra/go-map-flattener"
@@ -20,6 +21,19 @@ type EventMsg struct {
 
 // ResponseToEventMsgs //
 func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[string]string, eps ...EventProcessor) ([]*EventMsg, error) {
+	return responseToEventMsgs(name, rsp, meta, false, eps...)
+}
+
+// ResponseToEventMsgsSplitDeletes is like ResponseToEventMsgs, except that each deleted path
+// is converted to its own event, holding the path name without the keys in .Deletes
+// and its keys as tags, the same way the updated paths are.
+// The delete events go through the processors with the update events, so that
+// the renamed values and tags can still be matched by the outputs deleting them.
+func ResponseToEventMsgsSplitDeletes(name string, rsp *gnmi.SubscribeResponse, meta map[string]string, eps ...EventProcessor) ([]*EventMsg, error) {
+	return responseToEventMsgs(name, rsp, meta, true, eps...)
+}
+
+func responseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[string]string, splitDeletes bool, eps ...EventProcessor) ([]*EventMsg, error) {
 	if rsp == nil {
 		return nil, nil
 	}
@@ -67,11 +81,47 @@ func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[stri
 				evs = append(evs, e)
 			}
 		}
+		if splitDeletes {
+			for _, del := range rsp.Update.Delete {
+				e := &EventMsg{
+					Tags:   make(map[string]string),
+					Values: make(map[string]interface{}),
+				}
+				e.Timestamp = rsp.Update.Timestamp
+				e.Name = name
+				for k, v := range prefixTags {
+					e.Tags[k] = v
+				}
+				pathName, pTags := TagsFromGNMIPath(del)
+				pathName = strings.TrimRight(namePrefix, "/") + "/" + strings.TrimLeft(pathName, "/")
+				for k, v := range pTags {
+					if vv, ok := e.Tags[k]; ok {
+						if v != vv {
+							e.Tags[pathName+":::"+k] = v
+						}
+						continue
+					}
+					e.Tags[k] = v
+				}
+				e.Deletes = []string{pathName}
+				for k, v := range meta {
+					if k == "format" {
+						continue
+					}
+					if _, ok := e.Tags[k]; ok {
+						e.Tags["meta:"+k] = v
+						continue
+					}
+					e.Tags[k] = v
+				}
+				evs = append(evs, e)
+			}
+		}
 		for _, ep := range eps {
 			evs = ep.Apply(evs...)
 		}
 
-		if len(rsp.Update.Delete) > 0 {
+		if len(rsp.Update.Delete) > 0 && !splitDeletes {
 			e := &EventMsg{
 				Tags:    make(map[string]string),
 				Deletes: make([]string, 0, len(rsp.Update.Delete)),
@@ -308,3 +358,37 @@ func num64(n interface{}) interface{} {
 	}
 	return nil
 }
+
+// ToFloat converts an event value of a numeric type, or a string holding a number, to a float64.
+func ToFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
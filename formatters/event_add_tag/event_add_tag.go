@@ -1,12 +1,14 @@
 package event_add_tag
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"log"
 	"os"
 	"regexp"
 	"strings"
+	"text/template"
 
 	"github.com/itchyny/gojq"
 	"github.com/karimra/gnmic/formatters"
@@ -17,9 +19,12 @@ const (
 	loggingPrefix = "[" + processorType + "] "
 )
 
-// AddTag adds a set of tags to the event message if tag
+// AddTag adds a set of tags to the event message if the condition or one of the regexes match,
+// if neither a condition nor regexes are configured, the tags are added to all event messages.
+// The added tags values can be Go templates executed against the event message.
 type AddTag struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 	Condition  string            `mapstructure:"condition,omitempty"`
 	Tags       []string          `mapstructure:"tags,omitempty" json:"tags,omitempty"`
 	Values     []string          `mapstructure:"values,omitempty" json:"values,omitempty"`
@@ -34,6 +39,7 @@ type AddTag struct {
 	tagNames   []*regexp.Regexp
 	valueNames []*regexp.Regexp
 	code       *gojq.Code
+	templates  map[string]*template.Template
 	logger     *log.Logger
 }
 
@@ -100,6 +106,18 @@ func (p *AddTag) Init(cfg interface{}, opts ...formatters.Option) error {
 		}
 		p.valueNames = append(p.valueNames, re)
 	}
+	// init added tags values templates
+	p.templates = make(map[string]*template.Template)
+	for k, v := range p.Add {
+		if !strings.Contains(v, "{{") {
+			continue
+		}
+		tpl, err := template.New(k).Parse(v)
+		if err != nil {
+			return err
+		}
+		p.templates[k] = tpl
+	}
 
 	if p.logger.Writer() != ioutil.Discard {
 		b, err := json.Marshal(p)
@@ -122,12 +140,18 @@ func (p *AddTag) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 			ok, err := formatters.CheckCondition(p.code, e)
 			if err != nil {
 				p.logger.Printf("condition check failed: %v", err)
+				p.ReportError(err)
 			}
 			if ok {
 				p.addTags(e)
 			}
 			continue
 		}
+		// no condition and no regexes, add tags unconditionally
+		if len(p.tags)+len(p.tagNames)+len(p.values)+len(p.valueNames) == 0 {
+			p.addTags(e)
+			continue
+		}
 		// no condition, check regexes
 		for k, v := range e.Values {
 			for _, re := range p.valueNames {
@@ -176,12 +200,21 @@ func (p *AddTag) addTags(e *formatters.EventMsg) {
 		e.Tags = make(map[string]string)
 	}
 	for nk, nv := range p.Add {
-		if p.Overwrite {
-			e.Tags[nk] = nv
-			continue
+		if !p.Overwrite {
+			if _, ok := e.Tags[nk]; ok {
+				continue
+			}
 		}
-		if _, ok := e.Tags[nk]; !ok {
-			e.Tags[nk] = nv
+		if tpl, ok := p.templates[nk]; ok {
+			b := new(bytes.Buffer)
+			err := tpl.Execute(b, e)
+			if err != nil {
+				p.logger.Printf("failed to execute tag %q value template: %v", nk, err)
+				p.ReportError(err)
+				continue
+			}
+			nv = b.String()
 		}
+		e.Tags[nk] = nv
 	}
 }
@@ -842,6 +842,102 @@ var testset = map[string]struct {
 			},
 		},
 	},
+	"constant_add": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"add": map[string]string{"collector": "gnmic-1"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 1},
+					},
+					{
+						Values: map[string]interface{}{"value": 2},
+						Tags:   map[string]string{"collector": "other"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 1},
+						Tags:   map[string]string{"collector": "gnmic-1"},
+					},
+					{
+						Values: map[string]interface{}{"value": 2},
+						Tags:   map[string]string{"collector": "other"},
+					},
+				},
+			},
+		},
+	},
+	"templated_value": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"add": map[string]string{
+				"device": `{{ index .Tags "source" }}-{{ .Name }}`,
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"value": 1},
+						Tags:   map[string]string{"source": "router1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"value": 1},
+						Tags: map[string]string{
+							"source": "router1",
+							"device": "router1-sub1",
+						},
+					},
+				},
+			},
+		},
+	},
+	"condition_not_matching": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"condition": `.values.value > 100`,
+			"add":       map[string]string{"severity": "high"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 101},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 101},
+						Tags:   map[string]string{"severity": "high"},
+					},
+				},
+			},
+		},
+	},
 }
 
 func TestEventAddTag(t *testing.T) {
@@ -20,6 +20,7 @@ const (
 // Allow Allows the msg if ANY of the Tags or Values regexes are matched
 type Allow struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 	Condition  string   `mapstructure:"condition,omitempty"`
 	TagNames   []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
 	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
@@ -115,6 +116,7 @@ func (d *Allow) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 			ok, err := formatters.CheckCondition(d.code, e)
 			if err != nil {
 				d.logger.Printf("condition check failed: %v", err)
+				d.ReportError(err)
 				continue
 			}
 			if !ok {
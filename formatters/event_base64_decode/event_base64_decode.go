@@ -0,0 +1,124 @@
+package event_base64_decode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-base64-decode"
+	loggingPrefix = "[" + processorType + "] "
+	defaultAs     = "string"
+)
+
+// Base64Decode decodes the base64 encoded values with key matching one of regexes,
+// the decoded value is stored as a string or as a hex string
+type Base64Decode struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	Values []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	As     string   `mapstructure:"as,omitempty" json:"as,omitempty"`
+	Debug  bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values []*regexp.Regexp
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Base64Decode{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *Base64Decode) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	switch p.As {
+	case "":
+		p.As = defaultAs
+	case "string", "hex":
+	default:
+		return fmt.Errorf("%s: unknown 'as' value %q, must be one of 'string' or 'hex'", processorType, p.As)
+	}
+	p.values = make([]*regexp.Regexp, 0, len(p.Values))
+	for _, reg := range p.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.values = append(p.values, re)
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *Base64Decode) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			for _, re := range p.values {
+				if !re.MatchString(k) {
+					continue
+				}
+				s, ok := v.(string)
+				if !ok {
+					p.logger.Printf("key '%s' value is not a string: %T", k, v)
+					break
+				}
+				b, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					p.logger.Printf("key '%s' failed to decode value %q: %v", k, s, err)
+					p.ReportError(err)
+					break
+				}
+				switch p.As {
+				case "hex":
+					e.Values[k] = hex.EncodeToString(b)
+				default:
+					if !utf8.Valid(b) {
+						p.logger.Printf("key '%s' decoded value is not a valid UTF-8 string", k)
+						break
+					}
+					e.Values[k] = string(b)
+				}
+				p.logger.Printf("key '%s', value %q decoded to %v", k, s, e.Values[k])
+				break
+			}
+		}
+	}
+	return es
+}
+
+func (p *Base64Decode) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
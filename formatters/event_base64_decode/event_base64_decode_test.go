@@ -0,0 +1,143 @@
+package event_base64_decode
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"decode_to_string": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"description$"},
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/description": "dXBsaW5rIHRvIGNvcmU=",
+							"/interface/name":        "dXBsaW5rIHRvIGNvcmU=",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/description": "uplink to core",
+							"/interface/name":        "dXBsaW5rIHRvIGNvcmU=",
+						},
+					},
+				},
+			},
+		},
+	},
+	"decode_to_hex": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"mac$"},
+			"as":          "hex",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/mac": "ABEiM0RV"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/mac": "001122334455"},
+					},
+				},
+			},
+		},
+	},
+	"invalid_input": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{".*"},
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/a": "not base64!",
+							"/b": 42,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/a": "not base64!",
+							"/b": 42,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventBase64Decode(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.Fail()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package event_bit_decode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-bit-decode"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	modeValues = "values"
+	modeTags   = "tags"
+)
+
+// BitDecode expands the integer values matching one of the regexes in .ValueNames
+// into a flag per bit defined in .Bits, named <value-name>/<flag-name>.
+// The flags are added as values (1 or 0) or as tags ("1" or "0") depending on .Mode.
+// Only the set bits are added, unless .IncludeUnset is true.
+// If .Keep is true, the decoded value is not deleted.
+type BitDecode struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	ValueNames   []string          `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Bits         map[string]string `mapstructure:"bits,omitempty" json:"bits,omitempty"`
+	Mode         string            `mapstructure:"mode,omitempty" json:"mode,omitempty"`
+	IncludeUnset bool              `mapstructure:"include-unset,omitempty" json:"include-unset,omitempty"`
+	Keep         bool              `mapstructure:"keep,omitempty" json:"keep,omitempty"`
+	Debug        bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	bits       []bitFlag
+
+	logger *log.Logger
+}
+
+type bitFlag struct {
+	bit  uint
+	name string
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &BitDecode{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (b *BitDecode) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, b)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if len(b.ValueNames) == 0 {
+		return errors.New(processorType + ": missing value-names")
+	}
+	if len(b.Bits) == 0 {
+		return errors.New(processorType + ": missing bits")
+	}
+	b.Mode = strings.ToLower(b.Mode)
+	switch b.Mode {
+	case "":
+		b.Mode = modeValues
+	case modeValues, modeTags:
+	default:
+		return fmt.Errorf("%s: unknown mode %q, must be one of %s or %s", processorType, b.Mode, modeValues, modeTags)
+	}
+	b.bits = make([]bitFlag, 0, len(b.Bits))
+	for k, name := range b.Bits {
+		bit, err := strconv.ParseUint(k, 10, 8)
+		if err != nil || bit > 63 {
+			return fmt.Errorf("%s: invalid bit %q, must be an integer between 0 and 63", processorType, k)
+		}
+		if name == "" {
+			return fmt.Errorf("%s: missing name for bit %d", processorType, bit)
+		}
+		b.bits = append(b.bits, bitFlag{bit: uint(bit), name: name})
+	}
+	sort.Slice(b.bits, func(i, j int) bool {
+		return b.bits[i].bit < b.bits[j].bit
+	})
+	// init value names regex
+	b.valueNames = make([]*regexp.Regexp, 0, len(b.ValueNames))
+	for _, reg := range b.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		b.valueNames = append(b.valueNames, re)
+	}
+	if b.logger.Writer() != ioutil.Discard {
+		js, err := json.Marshal(b)
+		if err != nil {
+			b.logger.Printf("initialized processor '%s': %+v", processorType, b)
+			return nil
+		}
+		b.logger.Printf("initialized processor '%s': %s", processorType, string(js))
+	}
+	return nil
+}
+
+func (b *BitDecode) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		// the matching names are collected first, the added flags are not decoded
+		names := make([]string, 0, len(e.Values))
+		for k := range e.Values {
+			if b.match(k) {
+				names = append(names, k)
+			}
+		}
+		for _, k := range names {
+			field, err := toUint(e.Values[k])
+			if err != nil {
+				b.logger.Printf("failed to decode value %q: %v", k, err)
+				b.ReportError(err)
+				continue
+			}
+			if !b.Keep {
+				delete(e.Values, k)
+			}
+			for _, bf := range b.bits {
+				set := field&(1<<bf.bit) != 0
+				if !set && !b.IncludeUnset {
+					continue
+				}
+				name := k + "/" + bf.name
+				switch b.Mode {
+				case modeValues:
+					if set {
+						e.Values[name] = 1
+					} else {
+						e.Values[name] = 0
+					}
+				case modeTags:
+					if e.Tags == nil {
+						e.Tags = make(map[string]string)
+					}
+					if set {
+						e.Tags[name] = "1"
+					} else {
+						e.Tags[name] = "0"
+					}
+				}
+			}
+		}
+	}
+	return es
+}
+
+func (b *BitDecode) WithLogger(l *log.Logger) {
+	if b.Debug && l != nil {
+		b.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if b.Debug {
+		b.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (b *BitDecode) match(name string) bool {
+	for _, re := range b.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toUint returns the value of v as a bitfield,
+// strings are parsed as decimal, hexadecimal (0x), octal (0o) or binary (0b) integers.
+func toUint(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case int:
+		return signedToUint(int64(v))
+	case int8:
+		return signedToUint(int64(v))
+	case int16:
+		return signedToUint(int64(v))
+	case int32:
+		return signedToUint(int64(v))
+	case int64:
+		return signedToUint(v)
+	case uint:
+		return uint64(v), nil
+	case uint8:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float32:
+		return floatToUint(float64(v))
+	case float64:
+		return floatToUint(v)
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(v), 0, 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func signedToUint(i int64) (uint64, error) {
+	if i < 0 {
+		return 0, fmt.Errorf("negative value %d", i)
+	}
+	return uint64(i), nil
+}
+
+// floatToUint converts the whole numbers, e.g decoded from JSON
+func floatToUint(f float64) (uint64, error) {
+	if f < 0 || f != math.Trunc(f) || f > math.MaxUint64 {
+		return 0, fmt.Errorf("value %v is not an unsigned integer", f)
+	}
+	return uint64(f), nil
+}
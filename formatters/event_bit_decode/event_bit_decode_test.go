@@ -0,0 +1,223 @@
+package event_bit_decode
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testBits = map[string]string{
+	"0": "up",
+	"1": "admin-down",
+	"2": "running",
+	"7": "loopback",
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"values_set_bits": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"/flags$"},
+			"bits":        testBits,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/flags": 133,
+							"/interface/mtu":   1500,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/flags/up":       1,
+							"/interface/flags/running":  1,
+							"/interface/flags/loopback": 1,
+							"/interface/mtu":            1500,
+						},
+					},
+				},
+			},
+			{
+				// non integer values are left unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/flags": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/flags": "up"},
+					},
+				},
+			},
+		},
+	},
+	"values_all_bits": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names":   []string{"/flags"},
+			"bits":          testBits,
+			"include-unset": true,
+			"keep":          true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/flags": "0x85"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/flags":            "0x85",
+							"/interface/flags/up":         1,
+							"/interface/flags/admin-down": 0,
+							"/interface/flags/running":    1,
+							"/interface/flags/loopback":   1,
+						},
+					},
+				},
+			},
+		},
+	},
+	"tags_set_bits": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"/flags"},
+			"bits":        testBits,
+			"mode":        "tags",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1"},
+						Values: map[string]interface{}{
+							"/interface/flags": float64(6),
+							"/interface/mtu":   1500,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":                      "router1",
+							"/interface/flags/admin-down": "1",
+							"/interface/flags/running":    "1",
+						},
+						Values: map[string]interface{}{
+							"/interface/mtu": 1500,
+						},
+					},
+				},
+			},
+		},
+	},
+	"tags_all_bits": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names":   []string{"/flags$"},
+			"bits":          testBits,
+			"mode":          "tags",
+			"include-unset": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/flags": uint32(1)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"/interface/flags/up":         "1",
+							"/interface/flags/admin-down": "0",
+							"/interface/flags/running":    "0",
+							"/interface/flags/loopback":   "0",
+						},
+						Values: map[string]interface{}{},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventBitDecode(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventBitDecodeInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_value_names": {"bits": testBits},
+		"missing_bits":        {"value-names": []string{"flags"}},
+		"bit_out_of_range":    {"value-names": []string{"flags"}, "bits": map[string]string{"64": "up"}},
+		"bit_not_a_number":    {"value-names": []string{"flags"}, "bits": map[string]string{"up": "up"}},
+		"empty_flag_name":     {"value-names": []string{"flags"}, "bits": map[string]string{"0": ""}},
+		"unknown_mode":        {"value-names": []string{"flags"}, "bits": testBits, "mode": "labels"},
+		"bad_regex":           {"value-names": []string{"("}, "bits": testBits},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &BitDecode{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
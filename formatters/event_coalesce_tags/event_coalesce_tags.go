@@ -0,0 +1,132 @@
+package event_coalesce_tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-coalesce-tags"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	missingSkip  = "skip"
+	missingEmpty = "empty"
+	missingOmit  = "omit"
+)
+
+// CoalesceTags joins the tags listed in sources, in order, using a separator.
+// The result is stored as a new tag named target.
+// Unlike event-combine, the sources are only looked up in the event tags and the missing
+// tags are left out of the result by default. .Missing has the same meaning in both processors.
+type CoalesceTags struct {
+	formatters.EventProcessor
+
+	Sources       []string `mapstructure:"sources,omitempty" json:"sources,omitempty"`
+	Separator     string   `mapstructure:"separator,omitempty" json:"separator,omitempty"`
+	Target        string   `mapstructure:"target,omitempty" json:"target,omitempty"`
+	Missing       string   `mapstructure:"missing,omitempty" json:"missing,omitempty"`
+	DeleteSources bool     `mapstructure:"delete-sources,omitempty" json:"delete-sources,omitempty"`
+	Debug         bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &CoalesceTags{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (c *CoalesceTags) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, c)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.Sources) == 0 {
+		return errors.New(processorType + ": missing sources")
+	}
+	if c.Target == "" {
+		return errors.New(processorType + ": missing target")
+	}
+	c.Missing = strings.ToLower(c.Missing)
+	switch c.Missing {
+	case "":
+		c.Missing = missingOmit
+	case missingSkip, missingEmpty, missingOmit:
+	default:
+		return fmt.Errorf("%s: unknown missing %q, must be one of skip, empty or omit", processorType, c.Missing)
+	}
+	if c.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(c)
+		if err != nil {
+			c.logger.Printf("initialized processor '%s': %+v", processorType, c)
+			return nil
+		}
+		c.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (c *CoalesceTags) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		parts := make([]string, 0, len(c.Sources))
+		found := 0
+		skip := false
+		for _, src := range c.Sources {
+			v, ok := e.Tags[src]
+			if ok {
+				found++
+				parts = append(parts, v)
+				continue
+			}
+			switch c.Missing {
+			case missingSkip:
+				c.logger.Printf("tag '%s' not found, skipping event", src)
+				skip = true
+			case missingEmpty:
+				parts = append(parts, "")
+			}
+			if skip {
+				break
+			}
+		}
+		// a source tag is missing, or none of the source tags is present
+		if skip || found == 0 {
+			continue
+		}
+		if c.DeleteSources {
+			for _, src := range c.Sources {
+				delete(e.Tags, src)
+			}
+		}
+		res := strings.Join(parts, c.Separator)
+		e.Tags[c.Target] = res
+		c.logger.Printf("coalesced tags %v into tag '%s': %q", c.Sources, c.Target, res)
+	}
+	return es
+}
+
+func (c *CoalesceTags) WithLogger(l *log.Logger) {
+	if c.Debug && l != nil {
+		c.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if c.Debug {
+		c.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
@@ -0,0 +1,226 @@
+package event_coalesce_tags
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"coalesce": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"interface_name", "subinterface_index"},
+			"separator": "/",
+			"target":    "id",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"interface_name": "ethernet-1/1", "subinterface_index": "0"},
+						Values: map[string]interface{}{"in_octets": 10},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name":     "ethernet-1/1",
+							"subinterface_index": "0",
+							"id":                 "ethernet-1/1/0",
+						},
+						Values: map[string]interface{}{"in_octets": 10},
+					},
+				},
+			},
+			{
+				// values are not used as sources
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"interface_name": "ethernet-1/1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"interface_name": "ethernet-1/1"},
+					},
+				},
+			},
+		},
+	},
+	"missing_skip": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"network_instance", "interface_name", "subinterface_index"},
+			"separator": "/",
+			"target":    "id",
+			"missing":   "skip",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"network_instance": "default", "subinterface_index": "0"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"network_instance": "default", "subinterface_index": "0"},
+					},
+				},
+			},
+		},
+	},
+	"missing_omit": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"network_instance", "interface_name", "subinterface_index"},
+			"separator": "/",
+			"target":    "id",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"network_instance": "default", "subinterface_index": "0"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"network_instance":   "default",
+							"subinterface_index": "0",
+							"id":                 "default/0",
+						},
+					},
+				},
+			},
+		},
+	},
+	"missing_empty": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"network_instance", "interface_name", "subinterface_index"},
+			"separator": "/",
+			"target":    "id",
+			"missing":   "empty",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"network_instance": "default", "subinterface_index": "0"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"network_instance":   "default",
+							"subinterface_index": "0",
+							"id":                 "default//0",
+						},
+					},
+				},
+			},
+		},
+	},
+	"delete_sources": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":        []string{"interface_name", "subinterface_index"},
+			"separator":      ".",
+			"target":         "id",
+			"delete-sources": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":             "router1",
+							"interface_name":     "ethernet-1/1",
+							"subinterface_index": "0",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source": "router1",
+							"id":     "ethernet-1/1.0",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventCoalesceTags(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventCoalesceTagsInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_sources": {"target": "id"},
+		"missing_target":  {"sources": []string{"interface_name"}},
+		"unknown_missing": {"sources": []string{"interface_name"}, "target": "id", "missing": "drop"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &CoalesceTags{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package event_combine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-combine"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	targetTypeValue = "value"
+	targetTypeTag   = "tag"
+
+	missingSkip  = "skip"
+	missingEmpty = "empty"
+	missingOmit  = "omit"
+)
+
+// Combine joins the values (or tags) listed in sources, in order, using a separator.
+// The result is stored as a new value or tag named target.
+type Combine struct {
+	formatters.EventProcessor
+
+	Sources       []string `mapstructure:"sources,omitempty" json:"sources,omitempty"`
+	Separator     string   `mapstructure:"separator,omitempty" json:"separator,omitempty"`
+	Target        string   `mapstructure:"target,omitempty" json:"target,omitempty"`
+	TargetType    string   `mapstructure:"target-type,omitempty" json:"target-type,omitempty"`
+	Missing       string   `mapstructure:"missing,omitempty" json:"missing,omitempty"`
+	DeleteSources bool     `mapstructure:"delete-sources,omitempty" json:"delete-sources,omitempty"`
+	Debug         bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Combine{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (c *Combine) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, c)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.Sources) == 0 {
+		return errors.New(processorType + ": missing sources")
+	}
+	if c.Target == "" {
+		return errors.New(processorType + ": missing target")
+	}
+	c.TargetType = strings.ToLower(c.TargetType)
+	switch c.TargetType {
+	case "":
+		c.TargetType = targetTypeValue
+	case targetTypeValue, targetTypeTag:
+	default:
+		return fmt.Errorf("%s: unknown target-type %q, must be one of value or tag", processorType, c.TargetType)
+	}
+	c.Missing = strings.ToLower(c.Missing)
+	switch c.Missing {
+	case "":
+		c.Missing = missingSkip
+	case missingSkip, missingEmpty, missingOmit:
+	default:
+		return fmt.Errorf("%s: unknown missing %q, must be one of skip, empty or omit", processorType, c.Missing)
+	}
+	if c.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(c)
+		if err != nil {
+			c.logger.Printf("initialized processor '%s': %+v", processorType, c)
+			return nil
+		}
+		c.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (c *Combine) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		parts := make([]string, 0, len(c.Sources))
+		found := make([]string, 0, len(c.Sources))
+		skip := false
+		for _, src := range c.Sources {
+			v, ok := c.lookup(e, src)
+			if ok {
+				found = append(found, src)
+				parts = append(parts, v)
+				continue
+			}
+			switch c.Missing {
+			case missingSkip:
+				c.logger.Printf("source '%s' not found, skipping event", src)
+				skip = true
+			case missingEmpty:
+				c.logger.Printf("source '%s' not found, using an empty string", src)
+				parts = append(parts, "")
+			case missingOmit:
+				c.logger.Printf("source '%s' not found, leaving it out", src)
+			}
+			if skip {
+				break
+			}
+		}
+		// a source is missing, or none of the sources is present
+		if skip || len(found) == 0 {
+			continue
+		}
+		if c.DeleteSources {
+			for _, src := range found {
+				delete(e.Values, src)
+				delete(e.Tags, src)
+			}
+		}
+		res := strings.Join(parts, c.Separator)
+		switch c.TargetType {
+		case targetTypeValue:
+			if e.Values == nil {
+				e.Values = make(map[string]interface{})
+			}
+			e.Values[c.Target] = res
+		case targetTypeTag:
+			if e.Tags == nil {
+				e.Tags = make(map[string]string)
+			}
+			e.Tags[c.Target] = res
+		}
+		c.logger.Printf("combined sources %v into %s '%s': %q", c.Sources, c.TargetType, c.Target, res)
+	}
+	return es
+}
+
+func (c *Combine) WithLogger(l *log.Logger) {
+	if c.Debug && l != nil {
+		c.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if c.Debug {
+		c.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// lookup returns the string representation of the value named name,
+// or of the tag with that name if there is no such value.
+func (c *Combine) lookup(e *formatters.EventMsg, name string) (string, bool) {
+	if v, ok := e.Values[name]; ok {
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(v), true
+	}
+	v, ok := e.Tags[name]
+	return v, ok
+}
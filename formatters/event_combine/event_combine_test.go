@@ -0,0 +1,261 @@
+package event_combine
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"two_fields": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"vendor", "model"},
+			"separator": "-",
+			"target":    "platform",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "model": "7750"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "model": "7750", "platform": "nokia-7750"},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"vendor": "nokia"},
+						Values: map[string]interface{}{"model": 7750},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"vendor": "nokia"},
+						Values: map[string]interface{}{"model": 7750, "platform": "nokia-7750"},
+					},
+				},
+			},
+		},
+	},
+	"to_tag": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":     []string{"vendor", "model"},
+			"separator":   "/",
+			"target":      "platform",
+			"target-type": "tag",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "model": "7750"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"platform": "nokia/7750"},
+						Values: map[string]interface{}{"vendor": "nokia", "model": "7750"},
+					},
+				},
+			},
+		},
+	},
+	"missing_source_skip": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"vendor", "model"},
+			"separator": "-",
+			"target":    "platform",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia"},
+					},
+				},
+			},
+		},
+	},
+	"missing_source_empty": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"vendor", "model"},
+			"separator": "-",
+			"target":    "platform",
+			"missing":   "empty",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "platform": "nokia-"},
+					},
+				},
+			},
+		},
+	},
+	"missing_source_omit": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":   []string{"vendor", "model", "version"},
+			"separator": "-",
+			"target":    "platform",
+			"missing":   "omit",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "version": "21.10"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"vendor": "nokia", "version": "21.10", "platform": "nokia-21.10"},
+					},
+				},
+			},
+			{
+				// none of the sources is present
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"cpu": 42},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"cpu": 42},
+					},
+				},
+			},
+		},
+	},
+	"delete_sources": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"sources":        []string{"vendor", "model"},
+			"separator":      "_",
+			"target":         "platform",
+			"target-type":    "tag",
+			"delete-sources": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"vendor": "nokia", "source": "router1"},
+						Values: map[string]interface{}{"model": "7750", "cpu": 42},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"platform": "nokia_7750", "source": "router1"},
+						Values: map[string]interface{}{"cpu": 42},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"vendor": "nokia"},
+						Values: map[string]interface{}{"cpu": 42},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"vendor": "nokia"},
+						Values: map[string]interface{}{"cpu": 42},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventCombine(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventCombineInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_sources":     {"target": "platform"},
+		"missing_target":      {"sources": []string{"vendor"}},
+		"unknown_target_type": {"sources": []string{"vendor"}, "target": "platform", "target-type": "label"},
+		"unknown_missing":     {"sources": []string{"vendor"}, "target": "platform", "missing": "drop"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Combine{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
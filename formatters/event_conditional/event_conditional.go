@@ -0,0 +1,191 @@
+package event_conditional
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-conditional"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// Conditional applies the nested Processors, in order, to the events matching Condition.
+// The other events are left unchanged.
+type Conditional struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	Condition  string                   `mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	Processors []map[string]interface{} `mapstructure:"processors,omitempty" json:"processors,omitempty"`
+	Debug      bool                     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	code       *gojq.Code
+	processors []formatters.EventProcessor
+	logger     *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Conditional{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *Conditional) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	err = p.compile()
+	if err != nil {
+		return err
+	}
+	// the nested processors get the same logger, targets and error handler
+	p.processors = make([]formatters.EventProcessor, 0, len(p.Processors))
+	for i, epCfg := range p.Processors {
+		ep, err := formatters.NewEventProcessor(epCfg, opts...)
+		if err != nil {
+			return fmt.Errorf("%s: processor %d: %v", processorType, i, err)
+		}
+		p.processors = append(p.processors, ep)
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+// ValidateConfig implements formatters.ConfigValidator, the nested processors configs are validated without initializing them.
+func (p *Conditional) ValidateConfig(cfg interface{}) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	err = p.compile()
+	if err != nil {
+		return err
+	}
+	for i, epCfg := range p.Processors {
+		if len(epCfg) != 1 {
+			return fmt.Errorf("%s: processor %d: a processor config must have a single type, got %d", processorType, i, len(epCfg))
+		}
+		for epType, c := range epCfg {
+			err = formatters.ValidateEventProcessor(epType, c)
+			if err != nil {
+				return fmt.Errorf("%s: processor %d: %v", processorType, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compile compiles the condition and checks that there are nested processors.
+func (p *Conditional) compile() error {
+	p.Condition = strings.TrimSpace(p.Condition)
+	if p.Condition == "" {
+		return errors.New(processorType + ": missing condition")
+	}
+	q, err := gojq.Parse(p.Condition)
+	if err != nil {
+		return err
+	}
+	p.code, err = gojq.Compile(q)
+	if err != nil {
+		return err
+	}
+	if len(p.Processors) == 0 {
+		return errors.New(processorType + ": missing processors")
+	}
+	return nil
+}
+
+// Apply runs the nested processors on the matching events, the events order is kept.
+// If the nested processors drop or add events, e.g event-drop or event-merge,
+// the processed events take the place of the first matching event.
+func (p *Conditional) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	matched := make([]*formatters.EventMsg, 0, len(es))
+	// positions of the matching events in es
+	pos := make([]int, 0, len(es))
+	for i, e := range es {
+		if e == nil {
+			continue
+		}
+		ok, err := formatters.CheckCondition(p.code, e)
+		if err != nil {
+			p.logger.Printf("condition check failed: %v", err)
+			p.ReportError(err)
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, e)
+		pos = append(pos, i)
+	}
+	if len(matched) == 0 {
+		return es
+	}
+	p.logger.Printf("%d/%d event(s) matched condition %q", len(matched), len(es), p.Condition)
+	processed := matched
+	for _, ep := range p.processors {
+		processed = ep.Apply(processed...)
+	}
+	res := make([]*formatters.EventMsg, 0, len(es)-len(pos)+len(processed))
+	if len(processed) == len(pos) {
+		res = append(res, es...)
+		for i, j := range pos {
+			res[j] = processed[i]
+		}
+		return res
+	}
+	res = append(res, es[:pos[0]]...)
+	res = append(res, processed...)
+	next := 1
+	for i := pos[0] + 1; i < len(es); i++ {
+		if next < len(pos) && pos[next] == i {
+			next++
+			continue
+		}
+		res = append(res, es[i])
+	}
+	return res
+}
+
+// WithFlushHandler implements formatters.Flusher, the events flushed by a nested processor
+// go through the following nested processors before being passed to h.
+func (p *Conditional) WithFlushHandler(h formatters.FlushHandler) {
+	formatters.SetFlushHandlers(p.processors, h)
+}
+
+// Flush implements formatters.Flusher, it returns the events held by the nested processors.
+func (p *Conditional) Flush() []*formatters.EventMsg {
+	return formatters.FlushProcessors(p.processors)
+}
+
+func (p *Conditional) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// WithTargets is a no-op, the targets are passed to the nested processors on Init.
+func (p *Conditional) WithTargets(map[string]interface{}) {}
@@ -0,0 +1,357 @@
+package event_conditional
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+	_ "github.com/karimra/gnmic/formatters/event_add_tag"
+	_ "github.com/karimra/gnmic/formatters/event_convert"
+	_ "github.com/karimra/gnmic/formatters/event_merge"
+	_ "github.com/karimra/gnmic/formatters/event_write"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"tag_match": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"condition": `.tags.source == "router1"`,
+			"processors": []interface{}{
+				map[string]interface{}{
+					"event-convert": map[string]interface{}{
+						"value-names": []string{"octets$"},
+						"type":        "int",
+					},
+				},
+				map[string]interface{}{
+					"event-add-tag": map[string]interface{}{
+						"add": map[string]string{"vendor": "vendor1"},
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"in-octets": "42"},
+					},
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"in-octets": "42"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "vendor": "vendor1"},
+						Values: map[string]interface{}{"in-octets": 42},
+					},
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"in-octets": "42"},
+					},
+				},
+			},
+		},
+	},
+	"value_match": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"condition": `.values | has("cpu")`,
+			"processors": []interface{}{
+				map[string]interface{}{
+					"event-add-tag": map[string]interface{}{
+						"add": map[string]string{"kind": "cpu"},
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"memory": 34},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "kind": "cpu"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"memory": 34},
+					},
+				},
+			},
+		},
+	},
+	"nested_conditional": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"condition": `.tags.source == "router1"`,
+			"processors": []interface{}{
+				map[string]interface{}{
+					"event-conditional": map[string]interface{}{
+						"condition": `.values | has("cpu")`,
+						"processors": []interface{}{
+							map[string]interface{}{
+								"event-add-tag": map[string]interface{}{
+									"add": map[string]string{"kind": "cpu"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "kind": "cpu"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+				},
+			},
+		},
+	},
+	"nested_merge": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"condition": `.tags.source == "router1"`,
+			"processors": []interface{}{
+				map[string]interface{}{
+					"event-merge": map[string]interface{}{
+						"key-tags": []string{"source"},
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router3"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"memory": 34},
+					},
+				},
+				// the merged event takes the place of the first matching one
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router2"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{"cpu": 12, "memory": 34},
+					},
+					{
+						Tags:   map[string]string{"source": "router3"},
+						Values: map[string]interface{}{"cpu": 12},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventConditional(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventConditionalErrors(t *testing.T) {
+	var errs []error
+	p := &Conditional{logger: log.New(os.Stderr, loggingPrefix, 0)}
+	err := p.Init(map[string]interface{}{
+		"condition": `.values.cpu + 1 > 50`,
+		"processors": []interface{}{
+			map[string]interface{}{
+				"event-add-tag": map[string]interface{}{
+					"add": map[string]string{"high": "true"},
+				},
+			},
+		},
+	}, formatters.WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the failed condition leaves the event unchanged and is reported
+	e := &formatters.EventMsg{Values: map[string]interface{}{"cpu": "high"}}
+	outs := p.Apply(e)
+	if len(outs) != 1 || len(outs[0].Tags) != 0 {
+		t.Errorf("expected the event to be unchanged, got %+v", outs)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 reported error, got %v", errs)
+	}
+}
+
+func TestEventConditionalInit(t *testing.T) {
+	addTag := map[string]interface{}{"event-add-tag": map[string]interface{}{"add": map[string]string{"k": "v"}}}
+	tests := map[string]map[string]interface{}{
+		"missing_condition":  {"processors": []interface{}{addTag}},
+		"invalid_condition":  {"condition": `.tags.source ==`, "processors": []interface{}{addTag}},
+		"missing_processors": {"condition": `.tags.source == "router1"`},
+		"unknown_processor":  {"condition": `.tags.source == "router1"`, "processors": []interface{}{map[string]interface{}{"event-unknown": map[string]interface{}{}}}},
+		"multiple_types":     {"condition": `.tags.source == "router1"`, "processors": []interface{}{map[string]interface{}{"event-add-tag": map[string]interface{}{}, "event-convert": map[string]interface{}{}}}},
+		"invalid_nested":     {"condition": `.tags.source == "router1"`, "processors": []interface{}{map[string]interface{}{"event-convert": map[string]interface{}{"value-names": []string{"("}}}}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Conditional{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+			p = &Conditional{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.ValidateConfig(cfg); err == nil {
+				t.Errorf("expected a validation error")
+			}
+		})
+	}
+}
+
+func TestEventConditionalValidateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "event-conditional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "events.log")
+	cfg := map[string]interface{}{
+		"condition": `.tags.source == "router1"`,
+		"processors": []interface{}{
+			map[string]interface{}{"event-write": map[string]interface{}{"dst": dst}},
+		},
+	}
+	p := &Conditional{logger: log.New(os.Stderr, loggingPrefix, 0)}
+	if err := p.ValidateConfig(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected the nested event-write dst file not to be created, got: %v", err)
+	}
+}
+
+func TestEventConditionalFlush(t *testing.T) {
+	p := &Conditional{logger: log.New(os.Stderr, loggingPrefix, 0)}
+	err := p.Init(map[string]interface{}{
+		"condition": `.tags.source == "router1"`,
+		"processors": []interface{}{
+			map[string]interface{}{
+				"event-merge": map[string]interface{}{"window": "1h", "key-tags": []string{"source"}},
+			},
+			map[string]interface{}{
+				"event-add-tag": map[string]interface{}{"add": map[string]string{"merged": "true"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flushed []*formatters.EventMsg
+	p.WithFlushHandler(func(es []*formatters.EventMsg) { flushed = append(flushed, es...) })
+	outs := p.Apply(
+		&formatters.EventMsg{Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"cpu": 12}},
+		&formatters.EventMsg{Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"memory": 34}},
+	)
+	if len(outs) != 0 {
+		t.Fatalf("expected the events to be held by the nested merge, got %+v", outs)
+	}
+	// the held events go through the nested processors following the merge
+	outs = p.Flush()
+	want := []*formatters.EventMsg{
+		{
+			Tags:   map[string]string{"source": "router1", "merged": "true"},
+			Values: map[string]interface{}{"cpu": 12, "memory": 34},
+		},
+	}
+	if !reflect.DeepEqual(outs, want) {
+		t.Errorf("expected %+v, got %+v", want, outs)
+	}
+	if len(flushed) != 0 {
+		t.Errorf("unexpected events passed to the flush handler: %+v", flushed)
+	}
+}
@@ -20,6 +20,7 @@ const (
 // Convert converts the value with key matching one of regexes, to the specified Type
 type Convert struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 
 	Values []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
 	Type   string   `mapstructure:"type,omitempty" json:"type,omitempty"`
@@ -78,6 +79,7 @@ func (c *Convert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 						iv, err := convertToInt(v)
 						if err != nil {
 							c.logger.Printf("convert error: %v", err)
+							c.ReportError(err)
 							break
 						}
 						c.logger.Printf("key '%s', value %v converted to %s: %d", k, v, c.Type, iv)
@@ -86,6 +88,7 @@ func (c *Convert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 						iv, err := convertToUint(v)
 						if err != nil {
 							c.logger.Printf("convert error: %v", err)
+							c.ReportError(err)
 							break
 						}
 						c.logger.Printf("key '%s', value %v converted to %s: %d", k, v, c.Type, iv)
@@ -94,6 +97,7 @@ func (c *Convert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 						iv, err := convertToString(v)
 						if err != nil {
 							c.logger.Printf("convert error: %v", err)
+							c.ReportError(err)
 							break
 						}
 						c.logger.Printf("key '%s', value %v converted to %s: %s", k, v, c.Type, iv)
@@ -102,6 +106,7 @@ func (c *Convert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 						iv, err := convertToFloat(v)
 						if err != nil {
 							c.logger.Printf("convert error: %v", err)
+							c.ReportError(err)
 							break
 						}
 						c.logger.Printf("key '%s', value %v converted to %s: %f", k, v, c.Type, iv)
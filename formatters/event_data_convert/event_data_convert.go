@@ -0,0 +1,158 @@
+package event_data_convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-data-convert"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// bases maps the supported base names to their value and accepted prefixes,
+// base 0 detects the base from the value prefix.
+var bases = map[string]struct {
+	base     int
+	prefixes []string
+}{
+	"auto":    {base: 0},
+	"hex":     {base: 16, prefixes: []string{"0x", "0X"}},
+	"octal":   {base: 8, prefixes: []string{"0o", "0O", "0"}},
+	"binary":  {base: 2, prefixes: []string{"0b", "0B"}},
+	"decimal": {base: 10},
+}
+
+// DataConvert parses the string values with key matching one of the regexes as integers written in the configured base,
+// the values are replaced with the parsed int64, or uint64 if they overflow an int64.
+type DataConvert struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	Values []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Base   string   `mapstructure:"base,omitempty" json:"base,omitempty"`
+	Debug  bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values   []*regexp.Regexp
+	base     int
+	prefixes []string
+	logger   *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &DataConvert{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (c *DataConvert) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, c)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.Base == "" {
+		c.Base = "auto"
+	}
+	b, ok := bases[strings.ToLower(c.Base)]
+	if !ok {
+		return fmt.Errorf("%s: unknown base %q, must be one of auto, hex, octal, binary or decimal", processorType, c.Base)
+	}
+	c.base, c.prefixes = b.base, b.prefixes
+	c.values = make([]*regexp.Regexp, 0, len(c.Values))
+	for _, reg := range c.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		c.values = append(c.values, re)
+	}
+	if c.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(c)
+		if err != nil {
+			c.logger.Printf("initialized processor '%s': %+v", processorType, c)
+			return nil
+		}
+		c.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (c *DataConvert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			for _, re := range c.values {
+				if re.MatchString(k) {
+					c.logger.Printf("key '%s' matched regex '%s'", k, re.String())
+					s, ok := v.(string)
+					if !ok {
+						c.logger.Printf("key '%s', skipping non string value %v", k, v)
+						break
+					}
+					iv, err := c.parse(s)
+					if err != nil {
+						c.logger.Printf("convert error: %v", err)
+						c.ReportError(err)
+						break
+					}
+					c.logger.Printf("key '%s', value %q converted to %v", k, s, iv)
+					e.Values[k] = iv
+					break
+				}
+			}
+		}
+	}
+	return es
+}
+
+func (c *DataConvert) WithLogger(l *log.Logger) {
+	if c.Debug && l != nil {
+		c.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if c.Debug {
+		c.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// parse returns the integer written in s, as an int64 or as an uint64 if it overflows an int64.
+// With an explicit base, the base prefix is optional.
+func (c *DataConvert) parse(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	sign := ""
+	digits := s
+	if c.base != 0 && (strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+")) {
+		sign, digits = s[:1], s[1:]
+	}
+	for _, p := range c.prefixes {
+		if len(digits) > len(p) && strings.HasPrefix(digits, p) {
+			digits = strings.TrimPrefix(digits, p)
+			break
+		}
+	}
+	i, err := strconv.ParseInt(sign+digits, c.base, 64)
+	if err == nil {
+		return i, nil
+	}
+	if errors.Is(err, strconv.ErrRange) && sign != "-" && !strings.HasPrefix(s, "-") {
+		u, uerr := strconv.ParseUint(strings.TrimPrefix(sign+digits, "+"), c.base, 64)
+		if uerr == nil {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to parse %q: %v", s, err)
+}
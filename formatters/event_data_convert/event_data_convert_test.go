@@ -0,0 +1,238 @@
+package event_data_convert
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"auto": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^counter"},
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"counter_hex":    "0x1f",
+							"counter_oct":    "0o17",
+							"counter_bin":    "0b101",
+							"counter_dec":    "42",
+							"counter_neg":    "-0x10",
+							"counter_big":    "0xffffffffffffffff",
+							"counter_int":    12,
+							"counter_string": "value",
+							"other":          "0x1f",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"counter_hex":    int64(31),
+							"counter_oct":    int64(15),
+							"counter_bin":    int64(5),
+							"counter_dec":    int64(42),
+							"counter_neg":    int64(-16),
+							"counter_big":    uint64(18446744073709551615),
+							"counter_int":    12,
+							"counter_string": "value",
+							"other":          "0x1f",
+						},
+					},
+				},
+			},
+		},
+	},
+	"hex": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{".*"},
+			"base":        "hex",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    "0x1F",
+							"without_prefix": "ff",
+							"signed":         "-a",
+							"invalid":        "0xzz",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    int64(31),
+							"without_prefix": int64(255),
+							"signed":         int64(-10),
+							"invalid":        "0xzz",
+						},
+					},
+				},
+			},
+		},
+	},
+	"octal": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{".*"},
+			"base":        "octal",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    "0o17",
+							"leading_zero":   "017",
+							"without_prefix": "17",
+							"invalid":        "18",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    int64(15),
+							"leading_zero":   int64(15),
+							"without_prefix": int64(15),
+							"invalid":        "18",
+						},
+					},
+				},
+			},
+		},
+	},
+	"binary": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{".*"},
+			"base":        "binary",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    "0b1010",
+							"without_prefix": "1010",
+							"invalid":        "0b102",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"with_prefix":    int64(10),
+							"without_prefix": int64(10),
+							"invalid":        "0b102",
+						},
+					},
+				},
+			},
+		},
+	},
+	"decimal": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{".*"},
+			"base":        "decimal",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"leading_zero": "010",
+							"invalid":      "0x10",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"leading_zero": int64(10),
+							"invalid":      "0x10",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventDataConvert(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventDataConvertInit(t *testing.T) {
+	p := &DataConvert{logger: log.New(os.Stderr, loggingPrefix, 0)}
+	err := p.Init(map[string]interface{}{"base": "base64"})
+	if err == nil {
+		t.Errorf("expected an error for an unknown base")
+	}
+}
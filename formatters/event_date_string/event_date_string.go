@@ -23,6 +23,7 @@ const (
 // DateTimeFormat is the desired datetime format, it defaults to RFC3339
 type DateString struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 
 	Tags      []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
 	Values    []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
@@ -103,6 +104,7 @@ func (d *DateString) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 					iv, err := convertToInt(v)
 					if err != nil {
 						d.logger.Printf("failed to convert '%v' to date string: %v", v, err)
+						d.ReportError(err)
 						continue
 					}
 					var td time.Time
@@ -131,6 +133,7 @@ func (d *DateString) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 					iv, err := strconv.Atoi(v)
 					if err != nil {
 						log.Printf("failed to convert %s to int: %v", v, err)
+						d.ReportError(err)
 					}
 					var td time.Time
 					switch d.Precision {
@@ -23,6 +23,7 @@ type Delete struct {
 	Values     []string `mapstructure:"values,omitempty" json:"values,omitempty"`
 	TagNames   []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
 	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	DropEmpty  bool     `mapstructure:"drop-empty,omitempty" json:"drop-empty,omitempty"`
 	Debug      bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
 
 	tags   []*regexp.Regexp
@@ -98,8 +99,15 @@ func (d *Delete) Init(cfg interface{}, opts ...formatters.Option) error {
 }
 
 func (d *Delete) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	res := es
+	if d.DropEmpty {
+		res = make([]*formatters.EventMsg, 0, len(es))
+	}
 	for _, e := range es {
 		if e == nil {
+			if d.DropEmpty {
+				res = append(res, e)
+			}
 			continue
 		}
 		for k, v := range e.Values {
@@ -132,8 +140,16 @@ func (d *Delete) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 				}
 			}
 		}
+		if !d.DropEmpty {
+			continue
+		}
+		if len(e.Values) == 0 && len(e.Deletes) == 0 {
+			d.logger.Printf("dropping event without values or deletes: %+v", e)
+			continue
+		}
+		res = append(res, e)
 	}
-	return es
+	return res
 }
 
 func (d *Delete) WithLogger(l *log.Logger) {
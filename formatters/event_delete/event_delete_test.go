@@ -318,6 +318,61 @@ var testset = map[string]struct {
 			},
 		},
 	},
+	"value-names_delete_drop_empty": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^deleteme"},
+			"tag-names":   []string{"^deleteme"},
+			"drop-empty":  true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"deleteme": 1},
+						Tags:   map[string]string{"deleteme": "tag"},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"deleteme": 1},
+						Tags:   map[string]string{"name": "tag"},
+					},
+					{
+						Values: map[string]interface{}{"deleteme": 1, "dont-deleteme": 1},
+						Tags:   map[string]string{"deleteme": "tag"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"dont-deleteme": 1},
+						Tags:   map[string]string{},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:    map[string]string{"deleteme": "tag"},
+						Deletes: []string{"/interface/counter"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:    map[string]string{},
+						Deletes: []string{"/interface/counter"},
+					},
+				},
+			},
+		},
+	},
 	"tags_delete": {
 		processorType: processorType,
 		processor: map[string]interface{}{
@@ -642,6 +697,9 @@ func TestEventDelete(t *testing.T) {
 				t.Run(name, func(t *testing.T) {
 					t.Logf("running test item %d", i)
 					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Fatalf("failed at event delete, item %d, expected %d events, got %d", i, len(item.output), len(outs))
+					}
 					for j := range outs {
 						if !reflect.DeepEqual(outs[j], item.output[j]) {
 							t.Logf("failed at event delete, item %d, index %d", i, j)
@@ -0,0 +1,126 @@
+package event_duration_convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-duration-convert"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// units maps the supported unit names to their duration
+var units = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"cs": 10 * time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// DurationConvert converts the numeric values with key matching one of the regexes from a duration unit to another,
+// the converted values are float64.
+type DurationConvert struct {
+	formatters.EventProcessor
+
+	Values   []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	FromUnit string   `mapstructure:"from-unit,omitempty" json:"from-unit,omitempty"`
+	ToUnit   string   `mapstructure:"to-unit,omitempty" json:"to-unit,omitempty"`
+	Debug    bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values []*regexp.Regexp
+	from   float64
+	to     float64
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &DurationConvert{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (c *DurationConvert) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, c)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.FromUnit == "" || c.ToUnit == "" {
+		return errors.New(processorType + ": from-unit and to-unit are required")
+	}
+	from, ok := units[strings.ToLower(c.FromUnit)]
+	if !ok {
+		return fmt.Errorf("%s: unknown from-unit %q, must be one of ns, us, ms, cs, s, m or h", processorType, c.FromUnit)
+	}
+	to, ok := units[strings.ToLower(c.ToUnit)]
+	if !ok {
+		return fmt.Errorf("%s: unknown to-unit %q, must be one of ns, us, ms, cs, s, m or h", processorType, c.ToUnit)
+	}
+	c.from, c.to = float64(from), float64(to)
+	c.values = make([]*regexp.Regexp, 0, len(c.Values))
+	for _, reg := range c.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		c.values = append(c.values, re)
+	}
+	if c.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(c)
+		if err != nil {
+			c.logger.Printf("initialized processor '%s': %+v", processorType, c)
+			return nil
+		}
+		c.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (c *DurationConvert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			for _, re := range c.values {
+				if re.MatchString(k) {
+					c.logger.Printf("key '%s' matched regex '%s'", k, re.String())
+					f, err := formatters.ToFloat(v)
+					if err != nil {
+						c.logger.Printf("key '%s', skipping non numeric value %v: %v", k, v, err)
+						break
+					}
+					e.Values[k] = f * c.from / c.to
+					c.logger.Printf("key '%s', value %v %s converted to %v %s", k, v, c.FromUnit, e.Values[k], c.ToUnit)
+					break
+				}
+			}
+		}
+	}
+	return es
+}
+
+func (c *DurationConvert) WithLogger(l *log.Logger) {
+	if c.Debug && l != nil {
+		c.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if c.Debug {
+		c.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
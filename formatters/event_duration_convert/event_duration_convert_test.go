@@ -0,0 +1,181 @@
+package event_duration_convert
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"cs_to_s": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"uptime$"},
+			"from-unit":   "cs",
+			"to-unit":     "s",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": uint64(12345), "/system/cpu": 12},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": 123.45, "/system/cpu": 12},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": "500"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": float64(5)},
+					},
+				},
+			},
+		},
+	},
+	"ns_to_s": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"uptime$"},
+			"from-unit":   "ns",
+			"to-unit":     "s",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": int64(2500000000)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": 2.5},
+					},
+				},
+			},
+		},
+	},
+	"s_to_ms": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"uptime$"},
+			"from-unit":   "s",
+			"to-unit":     "ms",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": 1.5},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": float64(1500)},
+					},
+				},
+			},
+		},
+	},
+	"non_numeric": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"uptime$"},
+			"from-unit":   "cs",
+			"to-unit":     "s",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": "1 day", "/bgp/uptime": true},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/system/uptime": "1 day", "/bgp/uptime": true},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventDurationConvert(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventDurationConvertInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_units":     {"value-names": []string{".*"}},
+		"missing_to_unit":   {"value-names": []string{".*"}, "from-unit": "s"},
+		"unknown_from_unit": {"from-unit": "days", "to-unit": "s"},
+		"unknown_to_unit":   {"from-unit": "s", "to-unit": "ps"},
+		"invalid_regex":     {"value-names": []string{"("}, "from-unit": "s", "to-unit": "ms"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &DurationConvert{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package event_flatten
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType    = "event-flatten"
+	loggingPrefix    = "[" + processorType + "] "
+	defaultSeparator = "."
+)
+
+// Flatten replaces the event messages values holding nested maps or slices
+// with one value per scalar leaf, named after the leaf path joined with the configured separator.
+// Slice elements are named using their index.
+type Flatten struct {
+	formatters.EventProcessor
+
+	Separator string `mapstructure:"separator,omitempty" json:"separator,omitempty"`
+	Debug     bool   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Flatten{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *Flatten) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Separator == "" {
+		p.Separator = defaultSeparator
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *Flatten) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		// the added flattened values are scalars, they are skipped if visited by this loop
+		for k, v := range e.Values {
+			if !isNested(v) {
+				continue
+			}
+			delete(e.Values, k)
+			p.flatten(e.Values, k, v)
+		}
+	}
+	return es
+}
+
+func (p *Flatten) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// flatten adds the scalar leaves of v to values, prefixing their names with name
+func (p *Flatten) flatten(values map[string]interface{}, name string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			p.flatten(values, name+p.Separator+k, vv)
+		}
+	case map[interface{}]interface{}:
+		for k, vv := range v {
+			p.flatten(values, name+p.Separator+fmt.Sprint(k), vv)
+		}
+	case []interface{}:
+		for i, vv := range v {
+			p.flatten(values, name+p.Separator+strconv.Itoa(i), vv)
+		}
+	default:
+		p.logger.Printf("adding flattened value %q: %v", name, v)
+		values[name] = v
+	}
+}
+
+func isNested(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
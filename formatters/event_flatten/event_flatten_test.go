@@ -0,0 +1,164 @@
+package event_flatten
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"nested_map": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"debug": true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"counters": map[string]interface{}{
+								"in-octets": 100,
+								"errors": map[string]interface{}{
+									"in":  1,
+									"out": 2,
+								},
+							},
+							"oper-state": "up",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"counters.in-octets":  100,
+							"counters.errors.in":  1,
+							"counters.errors.out": 2,
+							"oper-state":          "up",
+						},
+					},
+				},
+			},
+		},
+	},
+	"slice": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"debug": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"queues": []interface{}{
+								map[string]interface{}{"id": 0, "drops": 10},
+								map[string]interface{}{"id": 1, "drops": 11},
+							},
+							"labels": []interface{}{"a", "b"},
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"queues.0.id":    0,
+							"queues.0.drops": 10,
+							"queues.1.id":    1,
+							"queues.1.drops": 11,
+							"labels.0":       "a",
+							"labels.1":       "b",
+						},
+					},
+				},
+			},
+		},
+	},
+	"custom_separator": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"separator": "_",
+			"debug":     true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu": map[interface{}]interface{}{
+								"load": []interface{}{1.5, 2.5},
+							},
+							"memory": 1024,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu_load_0": 1.5,
+							"cpu_load_1": 2.5,
+							"memory":     1024,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventFlatten(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
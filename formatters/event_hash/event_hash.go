@@ -0,0 +1,157 @@
+package event_hash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType    = "event-hash"
+	loggingPrefix    = "[" + processorType + "] "
+	defaultAlgorithm = "sha256"
+)
+
+// Hash replaces the tags and string values with name matching one of the regexes with their hex encoded hash,
+// the same input is always replaced with the same hash.
+type Hash struct {
+	formatters.EventProcessor
+
+	Tags      []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	Values    []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Algorithm string   `mapstructure:"algorithm,omitempty" json:"algorithm,omitempty"`
+	Salt      string   `mapstructure:"salt,omitempty" json:"-"`
+	Length    int      `mapstructure:"length,omitempty" json:"length,omitempty"`
+	Debug     bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	tags   []*regexp.Regexp
+	values []*regexp.Regexp
+	newFn  func() hash.Hash
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Hash{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *Hash) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	switch p.Algorithm {
+	case "":
+		p.Algorithm = defaultAlgorithm
+		p.newFn = sha256.New
+	case "sha256":
+		p.newFn = sha256.New
+	case "fnv":
+		p.newFn = func() hash.Hash { return fnv.New64a() }
+	default:
+		return fmt.Errorf("%s: unknown algorithm %q, must be one of 'sha256' or 'fnv'", processorType, p.Algorithm)
+	}
+	if p.Length < 0 {
+		return fmt.Errorf("%s: length must be a positive number", processorType)
+	}
+	p.tags, err = compileRegexes(p.Tags)
+	if err != nil {
+		return err
+	}
+	p.values, err = compileRegexes(p.Values)
+	if err != nil {
+		return err
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *Hash) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Tags {
+			if matchAny(p.tags, k) {
+				e.Tags[k] = p.hash(v)
+				p.logger.Printf("tag '%s' value hashed", k)
+			}
+		}
+		for k, v := range e.Values {
+			if !matchAny(p.values, k) {
+				continue
+			}
+			s, ok := v.(string)
+			if !ok {
+				p.logger.Printf("key '%s' value is not a string: %T", k, v)
+				continue
+			}
+			e.Values[k] = p.hash(s)
+			p.logger.Printf("key '%s' value hashed", k)
+		}
+	}
+	return es
+}
+
+func (p *Hash) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// hash returns the hex encoded hash of the salted input, truncated to the configured length
+func (p *Hash) hash(s string) string {
+	h := p.newFn()
+	h.Write([]byte(p.Salt))
+	h.Write([]byte(s))
+	r := hex.EncodeToString(h.Sum(nil))
+	if p.Length > 0 && p.Length < len(r) {
+		return r[:p.Length]
+	}
+	return r
+}
+
+func compileRegexes(regs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(regs))
+	for _, reg := range regs {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package event_hash
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"sha256": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names":   []string{"^source$"},
+			"value-names": []string{"serial-number$"},
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1", "subscription-name": "sub1"},
+						Values: map[string]interface{}{
+							"/platform/component/state/serial-number": "SN123",
+							"/platform/component/state/temperature":   42,
+						},
+					},
+					{
+						// same input, same output
+						Tags: map[string]string{"source": "router1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":            "afede289cb603d8344c943cc419938e1f4351ef593d99972cc74f49093772bde",
+							"subscription-name": "sub1",
+						},
+						Values: map[string]interface{}{
+							"/platform/component/state/serial-number": "eaa51384b8e9f0c15c46c3534ce364dc3bc617c198f09dced21342f175be5e33",
+							"/platform/component/state/temperature":   42,
+						},
+					},
+					{
+						Tags: map[string]string{"source": "afede289cb603d8344c943cc419938e1f4351ef593d99972cc74f49093772bde"},
+					},
+				},
+			},
+		},
+	},
+	"sha256_salt": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names": []string{"^source$"},
+			"salt":      "salt",
+			"debug":     true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "router1"}},
+				},
+				output: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "e8dc9847f7db360aef9c5d067da04aa344ea88c30c50acde4843a1217c43821d"}},
+				},
+			},
+		},
+	},
+	"sha256_length": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names": []string{"^source$"},
+			"length":    12,
+			"debug":     true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "router1"}},
+				},
+				output: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "afede289cb60"}},
+				},
+			},
+		},
+	},
+	"fnv": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names": []string{"^source$"},
+			"algorithm": "fnv",
+			"debug":     true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "router1"}},
+				},
+				output: []*formatters.EventMsg{
+					{Tags: map[string]string{"source": "60aed4974e7895cf"}},
+				},
+			},
+		},
+	},
+}
+
+func TestEventHash(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.Fail()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package event_index_tag
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-index-tag"
+	loggingPrefix = "[" + processorType + "] "
+
+	defaultTagName = "index"
+)
+
+// IndexTag numbers the events generated from the same gNMI message,
+// it adds a tag holding the position of each event in the batch, starting from 0.
+type IndexTag struct {
+	formatters.EventProcessor
+
+	TagName string `mapstructure:"tag-name,omitempty" json:"tag-name,omitempty"`
+	Debug   bool   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &IndexTag{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *IndexTag) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.TagName == "" {
+		p.TagName = defaultTagName
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *IndexTag) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	i := 0
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if e.Tags == nil {
+			e.Tags = make(map[string]string)
+		}
+		e.Tags[p.TagName] = strconv.Itoa(i)
+		i++
+	}
+	return es
+}
+
+func (p *IndexTag) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
@@ -0,0 +1,142 @@
+package event_index_tag
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"default_tag_name": {
+		processorType: processorType,
+		processor:     map[string]interface{}{},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"interface_name": "ethernet-1/1"},
+						Values: map[string]interface{}{"counter": 1},
+					},
+					{
+						Values: map[string]interface{}{"counter": 2},
+					},
+					{
+						Tags:   map[string]string{"interface_name": "ethernet-1/3", "index": "7"},
+						Values: map[string]interface{}{"counter": 3},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"interface_name": "ethernet-1/1", "index": "0"},
+						Values: map[string]interface{}{"counter": 1},
+					},
+					{
+						Tags:   map[string]string{"index": "1"},
+						Values: map[string]interface{}{"counter": 2},
+					},
+					{
+						Tags:   map[string]string{"interface_name": "ethernet-1/3", "index": "2"},
+						Values: map[string]interface{}{"counter": 3},
+					},
+				},
+			},
+		},
+	},
+	"custom_tag_name": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-name": "seq",
+		},
+		tests: []item{
+			{
+				// each batch is numbered from 0
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"counter": 1},
+					},
+					nil,
+					{
+						Values: map[string]interface{}{"counter": 2},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"seq": "0"},
+						Values: map[string]interface{}{"counter": 1},
+					},
+					nil,
+					{
+						Tags:   map[string]string{"seq": "1"},
+						Values: map[string]interface{}{"counter": 2},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"counter": 3},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"seq": "0"},
+						Values: map[string]interface{}{"counter": 3},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventIndexTag(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
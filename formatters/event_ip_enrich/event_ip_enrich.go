@@ -0,0 +1,270 @@
+package event_ip_enrich
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-ip-enrich"
+	loggingPrefix = "[" + processorType + "] "
+
+	defaultTTL        = 10 * time.Minute
+	defaultTimeout    = time.Second
+	defaultMaxEntries = 10000
+	hostnameSuffix    = "_hostname"
+)
+
+// resolver performs reverse DNS lookups, it is implemented by *net.Resolver
+type resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// IPEnrich adds a tag named <name>_hostname holding the reverse DNS name of the values and tags
+// with key matching one of the regexes and holding an IP address.
+// The lookups results, including the failures, are cached for the configured TTL.
+// At most MaxEntries results are cached, the least recently used one is evicted to make room for a new one.
+type IPEnrich struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	Values     []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Tags       []string      `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	TTL        time.Duration `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	MaxEntries int           `mapstructure:"max-entries,omitempty" json:"max-entries,omitempty"`
+	Timeout    time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Debug      bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values   []*regexp.Regexp
+	tags     []*regexp.Regexp
+	resolver resolver
+
+	m     *sync.Mutex
+	cache map[string]*list.Element
+	// cache entries ordered from the most to the least recently used
+	lru *list.List
+
+	logger *log.Logger
+}
+
+type cacheEntry struct {
+	addr string
+	// empty if the lookup failed
+	hostname string
+	expires  time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &IPEnrich{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *IPEnrich) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.Values) == 0 && len(p.Tags) == 0 {
+		return errors.New(processorType + ": at least one of value-names or tag-names is required")
+	}
+	if p.TTL <= 0 {
+		p.TTL = defaultTTL
+	}
+	if p.MaxEntries <= 0 {
+		p.MaxEntries = defaultMaxEntries
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultTimeout
+	}
+	p.values, err = compileRegexes(p.Values)
+	if err != nil {
+		return err
+	}
+	p.tags, err = compileRegexes(p.Tags)
+	if err != nil {
+		return err
+	}
+	if p.resolver == nil {
+		p.resolver = net.DefaultResolver
+	}
+	p.m = new(sync.Mutex)
+	p.cache = make(map[string]*list.Element)
+	p.lru = list.New()
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *IPEnrich) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		hostnames := make(map[string]string)
+		for k, v := range e.Values {
+			for _, re := range p.values {
+				if re.MatchString(k) {
+					s, ok := v.(string)
+					if !ok {
+						break
+					}
+					if h := p.lookup(s); h != "" {
+						hostnames[k+hostnameSuffix] = h
+					}
+					break
+				}
+			}
+		}
+		for k, v := range e.Tags {
+			for _, re := range p.tags {
+				if re.MatchString(k) {
+					if h := p.lookup(v); h != "" {
+						hostnames[k+hostnameSuffix] = h
+					}
+					break
+				}
+			}
+		}
+		if len(hostnames) == 0 {
+			continue
+		}
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(hostnames))
+		}
+		for k, v := range hostnames {
+			e.Tags[k] = v
+		}
+	}
+	return es
+}
+
+func (p *IPEnrich) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// lookup returns the hostname of the IP address s,
+// or an empty string if s is not an IP address or if the lookup fails.
+func (p *IPEnrich) lookup(s string) string {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		// values such as 10.1.1.1/24
+		var err error
+		ip, _, err = net.ParseCIDR(strings.TrimSpace(s))
+		if err != nil {
+			return ""
+		}
+	}
+	addr := ip.String()
+	now := time.Now()
+	if ce, ok := p.cached(addr, now); ok {
+		return ce.hostname
+	}
+	ce := &cacheEntry{addr: addr, expires: now.Add(p.TTL)}
+	names, err := p.lookupAddr(addr)
+	if err != nil {
+		p.logger.Printf("failed reverse lookup of %q: %v", addr, err)
+		p.ReportError(err)
+	} else if len(names) > 0 {
+		ce.hostname = strings.TrimSuffix(names[0], ".")
+		p.logger.Printf("address %q resolved to %q", addr, ce.hostname)
+	}
+	p.store(ce)
+	return ce.hostname
+}
+
+// cached returns the cache entry of addr, if it is not expired.
+func (p *IPEnrich) cached(addr string, now time.Time) (*cacheEntry, bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	el, ok := p.cache[addr]
+	if !ok {
+		return nil, false
+	}
+	ce := el.Value.(*cacheEntry)
+	if !now.Before(ce.expires) {
+		p.lru.Remove(el)
+		delete(p.cache, addr)
+		return nil, false
+	}
+	p.lru.MoveToFront(el)
+	return ce, true
+}
+
+// store adds ce to the cache, evicting the least recently used entry if the cache is full.
+func (p *IPEnrich) store(ce *cacheEntry) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if el, ok := p.cache[ce.addr]; ok {
+		el.Value = ce
+		p.lru.MoveToFront(el)
+		return
+	}
+	if len(p.cache) >= p.MaxEntries {
+		el := p.lru.Back()
+		p.lru.Remove(el)
+		delete(p.cache, el.Value.(*cacheEntry).addr)
+	}
+	p.cache[ce.addr] = p.lru.PushFront(ce)
+}
+
+// lookupAddr runs the reverse lookup of addr,
+// it returns after at most p.Timeout even if the resolver does not honor the context deadline.
+func (p *IPEnrich) lookupAddr(addr string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+	type result struct {
+		names []string
+		err   error
+	}
+	resCh := make(chan *result, 1)
+	go func() {
+		names, err := p.resolver.LookupAddr(ctx, addr)
+		resCh <- &result{names: names, err: err}
+	}()
+	select {
+	case r := <-resCh:
+		return r.names, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func compileRegexes(regs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(regs))
+	for _, reg := range regs {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
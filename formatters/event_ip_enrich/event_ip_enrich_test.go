@@ -0,0 +1,291 @@
+package event_ip_enrich
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+// fakeResolver resolves the addresses in names,
+// blocks for the addresses in slow and fails for the others.
+type fakeResolver struct {
+	m       sync.Mutex
+	names   map[string]string
+	slow    map[string]bool
+	lookups map[string]int
+}
+
+func (r *fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.m.Lock()
+	r.lookups[addr]++
+	r.m.Unlock()
+	if r.slow[addr] {
+		// ignores ctx, the processor must not wait for it
+		time.Sleep(time.Second)
+		return []string{"slow.example.com."}, nil
+	}
+	if n, ok := r.names[addr]; ok {
+		return []string{n}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) count(addr string) int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.lookups[addr]
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		names: map[string]string{
+			"192.0.2.1":   "router1.example.com.",
+			"2001:db8::1": "router2.example.com.",
+		},
+		slow: map[string]bool{
+			"192.0.2.3": true,
+		},
+		lookups: make(map[string]int),
+	}
+}
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"values": {
+		processor: map[string]interface{}{
+			"value-names": []string{"address$"},
+			"timeout":     "50ms",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				// hit
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "192.0.2.1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"peer-address_hostname": "router1.example.com"},
+						Values: map[string]interface{}{"peer-address": "192.0.2.1"},
+					},
+				},
+			},
+			{
+				// hit, IPv6 prefix
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "r1"},
+						Values: map[string]interface{}{"local-address": "2001:db8::1/64"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "r1", "local-address_hostname": "router2.example.com"},
+						Values: map[string]interface{}{"local-address": "2001:db8::1/64"},
+					},
+				},
+			},
+			{
+				// miss
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "192.0.2.2"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "192.0.2.2"},
+					},
+				},
+			},
+			{
+				// timeout
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "192.0.2.3"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "192.0.2.3"},
+					},
+				},
+			},
+			{
+				// not an IP address
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "router1", "mac-address": 42},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"peer-address": "router1", "mac-address": 42},
+					},
+				},
+			},
+		},
+	},
+	"tags": {
+		processor: map[string]interface{}{
+			"tag-names": []string{"^neighbor$"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"neighbor": "192.0.2.1", "peer": "192.0.2.1"},
+						Values: map[string]interface{}{"state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"neighbor":          "192.0.2.1",
+							"peer":              "192.0.2.1",
+							"neighbor_hostname": "router1.example.com",
+						},
+						Values: map[string]interface{}{"state": "up"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventIPEnrich(t *testing.T) {
+	for name, ts := range testset {
+		p := &IPEnrich{
+			resolver: newFakeResolver(),
+			logger:   log.New(os.Stderr, loggingPrefix, 0),
+		}
+		err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+		if err != nil {
+			t.Errorf("failed to initialize processors: %v", err)
+			return
+		}
+		t.Logf("processor: %+v", p)
+		for i, item := range ts.tests {
+			t.Run(name, func(t *testing.T) {
+				t.Logf("running test item %d", i)
+				outs := p.Apply(item.input...)
+				if len(outs) != len(item.output) {
+					t.Logf("expected and gotten outputs are not of the same length")
+					t.Logf("expected: %+v", item.output)
+					t.Logf("     got: %+v", outs)
+					t.FailNow()
+				}
+				for j := range outs {
+					if !reflect.DeepEqual(outs[j], item.output[j]) {
+						t.Logf("failed at %s item %d, index %d", name, i, j)
+						t.Logf("expected: %+v", item.output[j])
+						t.Logf("     got: %+v", outs[j])
+						t.Fail()
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEventIPEnrichCache(t *testing.T) {
+	r := newFakeResolver()
+	p := &IPEnrich{
+		resolver: r,
+		logger:   log.New(os.Stderr, loggingPrefix, 0),
+	}
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"address$"},
+		"ttl":         "100ms",
+		"timeout":     "50ms",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	newEvent := func(addr string) *formatters.EventMsg {
+		return &formatters.EventMsg{Values: map[string]interface{}{"address": addr}}
+	}
+	for _, addr := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			p.Apply(newEvent(addr))
+		}
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Errorf("address %s: lookups not bounded by the timeout, took %s", addr, d)
+		}
+		if c := r.count(addr); c != 1 {
+			t.Errorf("address %s: expected 1 lookup, got %d", addr, c)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+	p.Apply(newEvent("192.0.2.1"))
+	if c := r.count("192.0.2.1"); c != 2 {
+		t.Errorf("expected a new lookup after the ttl expired, got %d lookups", c)
+	}
+}
+
+func TestEventIPEnrichCacheMaxEntries(t *testing.T) {
+	r := newFakeResolver()
+	p := &IPEnrich{
+		resolver: r,
+		logger:   log.New(os.Stderr, loggingPrefix, 0),
+	}
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"address$"},
+		"max-entries": 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	for _, addr := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1", "192.0.2.3"} {
+		p.Apply(&formatters.EventMsg{Values: map[string]interface{}{"address": addr}})
+	}
+	if len(p.cache) != 2 || p.lru.Len() != 2 {
+		t.Fatalf("expected 2 cache entries, got %d", len(p.cache))
+	}
+	// 192.0.2.2 is the least recently used entry
+	for addr, want := range map[string]bool{"192.0.2.1": true, "192.0.2.2": false, "192.0.2.3": true} {
+		if _, ok := p.cache[addr]; ok != want {
+			t.Errorf("address %s cached=%t, expected %t", addr, ok, want)
+		}
+	}
+	p.Apply(&formatters.EventMsg{Values: map[string]interface{}{"address": "192.0.2.2"}})
+	if c := r.count("192.0.2.2"); c != 2 {
+		t.Errorf("expected a new lookup of the evicted address, got %d lookups", c)
+	}
+}
+
+func TestEventIPEnrichInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_names":   {"ttl": "1m"},
+		"bad_value_regex": {"value-names": []string{"("}},
+		"bad_tag_regex":   {"tag-names": []string{"["}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &IPEnrich{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ const (
 // jq runs a jq expression on the received event messages
 type jq struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 	Condition  string `mapstructure:"condition,omitempty"`
 	Expression string `mapstructure:"expression,omitempty"`
 	Debug      bool   `mapstructure:"debug,omitempty"`
@@ -88,6 +89,7 @@ func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 		ok, err := p.evaluateCondition(input)
 		if err != nil {
 			p.logger.Printf("failed to evaluate condition: %v", err)
+			p.ReportError(err)
 			continue
 		}
 		if ok {
@@ -97,6 +99,7 @@ func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 	evs, err := p.applyExpression(inputs)
 	if err != nil {
 		p.logger.Printf("failed to apply jq expression: %v", err)
+		p.ReportError(err)
 		return nil
 	}
 	return evs
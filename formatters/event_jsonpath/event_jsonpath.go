@@ -0,0 +1,224 @@
+package event_jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-jsonpath"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// JSONPath extracts fields from values holding a JSON document
+// and adds them to the event as new values
+type JSONPath struct {
+	formatters.EventProcessor
+
+	ValueName   string        `mapstructure:"value-name,omitempty" json:"value-name,omitempty"`
+	Extractions []*Extraction `mapstructure:"extractions,omitempty" json:"extractions,omitempty"`
+	Debug       bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueName *regexp.Regexp
+	logger    *log.Logger
+}
+
+// Extraction defines a JSON path to extract and the name of the value it's written to
+type Extraction struct {
+	JSONPath        string `mapstructure:"jsonpath,omitempty" json:"jsonpath,omitempty"`
+	TargetValueName string `mapstructure:"target-value-name,omitempty" json:"target-value-name,omitempty"`
+
+	steps []step
+}
+
+// step is a single JSON path element, either an object key or an array index
+type step struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &JSONPath{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *JSONPath) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.ValueName == "" {
+		return fmt.Errorf("%s: missing value-name", processorType)
+	}
+	p.valueName, err = regexp.Compile(p.ValueName)
+	if err != nil {
+		return err
+	}
+	for _, ex := range p.Extractions {
+		if ex.TargetValueName == "" {
+			return fmt.Errorf("%s: missing target-value-name for jsonpath %q", processorType, ex.JSONPath)
+		}
+		ex.steps, err = parseJSONPath(ex.JSONPath)
+		if err != nil {
+			return err
+		}
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *JSONPath) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		extracted := make(map[string]interface{})
+		for k, v := range e.Values {
+			if !p.valueName.MatchString(k) {
+				continue
+			}
+			var b []byte
+			switch v := v.(type) {
+			case string:
+				b = []byte(v)
+			case []byte:
+				b = v
+			default:
+				continue
+			}
+			var doc interface{}
+			err := json.Unmarshal(b, &doc)
+			if err != nil {
+				p.logger.Printf("value %q is not a valid JSON document, skipping: %v", k, err)
+				continue
+			}
+			for _, ex := range p.Extractions {
+				ev, ok := ex.extract(doc)
+				if !ok {
+					p.logger.Printf("value %q: jsonpath %q did not match a scalar", k, ex.JSONPath)
+					continue
+				}
+				extracted[ex.TargetValueName] = ev
+			}
+		}
+		for k, v := range extracted {
+			e.Values[k] = v
+		}
+	}
+	return es
+}
+
+func (p *JSONPath) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// extract walks doc following the extraction steps,
+// it returns the found value if it is a scalar.
+// strings holding a number are converted to float64.
+func (ex *Extraction) extract(doc interface{}) (interface{}, bool) {
+	v := doc
+	for _, s := range ex.steps {
+		switch d := v.(type) {
+		case map[string]interface{}:
+			if s.isIdx {
+				return nil, false
+			}
+			var ok bool
+			v, ok = d[s.key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if !s.isIdx || s.index < 0 || s.index >= len(d) {
+				return nil, false
+			}
+			v = d[s.index]
+		default:
+			return nil, false
+		}
+	}
+	switch v := v.(type) {
+	case float64, bool:
+		return v, true
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// parseJSONPath parses a JSON path such as `$.a.b[0]['c.d']` into a list of steps
+func parseJSONPath(path string) ([]step, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	steps := make([]step, 0)
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: empty key", path)
+			}
+			steps = append(steps, step{key: p[:end]})
+			p = p[end:]
+		case '[':
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: missing ']'", path)
+			}
+			elem := p[1:end]
+			p = p[end+1:]
+			if len(elem) >= 2 && (elem[0] == '\'' || elem[0] == '"') && elem[len(elem)-1] == elem[0] {
+				steps = append(steps, step{key: elem[1 : len(elem)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(elem)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jsonpath %q: invalid index %q", path, elem)
+			}
+			steps = append(steps, step{index: idx, isIdx: true})
+		default:
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q", path)
+			}
+			// path without the leading `$.`
+			p = "." + p
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid jsonpath %q: no elements", path)
+	}
+	return steps, nil
+}
@@ -0,0 +1,194 @@
+package event_jsonpath
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"extract_nested_fields": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-name": "^blob$",
+			"extractions": []map[string]interface{}{
+				{
+					"jsonpath":          "$.cpu.usage",
+					"target-value-name": "cpu_usage",
+				},
+				{
+					"jsonpath":          "$.memory.pools[1]['free']",
+					"target-value-name": "pool1_free",
+				},
+			},
+			"debug": true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob": `{"cpu":{"usage":42.5},"memory":{"pools":[{"free":"1"},{"free":"2048"}]}}`,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob":       `{"cpu":{"usage":42.5},"memory":{"pools":[{"free":"1"},{"free":"2048"}]}}`,
+							"cpu_usage":  42.5,
+							"pool1_free": float64(2048),
+						},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob": `{"cpu":{"usage":"high"}}`,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob":      `{"cpu":{"usage":"high"}}`,
+							"cpu_usage": "high",
+						},
+					},
+				},
+			},
+		},
+	},
+	"malformed_json": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-name": "^blob$",
+			"extractions": []map[string]interface{}{
+				{
+					"jsonpath":          "$.cpu.usage",
+					"target-value-name": "cpu_usage",
+				},
+			},
+			"debug": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob":  `{"cpu":{"usage":42`,
+							"other": 1,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Values: map[string]interface{}{
+							"blob":  `{"cpu":{"usage":42`,
+							"other": 1,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventJSONPath(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.Fail()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		want    []step
+		wantErr bool
+	}{
+		"dotted":          {path: "$.a.b", want: []step{{key: "a"}, {key: "b"}}},
+		"no_root":         {path: "a.b", want: []step{{key: "a"}, {key: "b"}}},
+		"index":           {path: "$.a[2]", want: []step{{key: "a"}, {index: 2, isIdx: true}}},
+		"bracket_key":     {path: `$['a.b']["c"]`, want: []step{{key: "a.b"}, {key: "c"}}},
+		"missing_bracket": {path: "$.a[2", wantErr: true},
+		"invalid_index":   {path: "$.a[x]", wantErr: true},
+		"empty":           {path: "$", wantErr: true},
+		"empty_key":       {path: "$..a", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseJSONPath(tc.path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
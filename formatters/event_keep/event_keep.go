@@ -0,0 +1,139 @@
+package event_keep
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-keep"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	emptyKeepAll  = "keep-all"
+	emptyKeepNone = "keep-none"
+)
+
+// Keep deletes ALL the tags or values NOT matching one of the regexes.
+// An unset list keeps all the tags (or values) if EmptyAllowlist is keep-all, and none if it is keep-none.
+type Keep struct {
+	formatters.EventProcessor
+
+	TagNames       []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	ValueNames     []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	EmptyAllowlist string   `mapstructure:"empty-allowlist,omitempty" json:"empty-allowlist,omitempty"`
+	Debug          bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	tagNames   []*regexp.Regexp
+	valueNames []*regexp.Regexp
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Keep{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (k *Keep) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, k)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if len(k.TagNames) == 0 && len(k.ValueNames) == 0 {
+		return errors.New(processorType + ": at least one of tag-names or value-names is required")
+	}
+	k.EmptyAllowlist = strings.ToLower(k.EmptyAllowlist)
+	switch k.EmptyAllowlist {
+	case "":
+		k.EmptyAllowlist = emptyKeepAll
+	case emptyKeepAll, emptyKeepNone:
+	default:
+		return fmt.Errorf("%s: unknown empty-allowlist %q, must be one of %s or %s", processorType, k.EmptyAllowlist, emptyKeepAll, emptyKeepNone)
+	}
+	// init tag names regex
+	k.tagNames = make([]*regexp.Regexp, 0, len(k.TagNames))
+	for _, reg := range k.TagNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		k.tagNames = append(k.tagNames, re)
+	}
+	// init values names regex
+	k.valueNames = make([]*regexp.Regexp, 0, len(k.ValueNames))
+	for _, reg := range k.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		k.valueNames = append(k.valueNames, re)
+	}
+	if k.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(k)
+		if err != nil {
+			k.logger.Printf("initialized processor '%s': %+v", processorType, k)
+			return nil
+		}
+		k.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (k *Keep) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for n := range e.Values {
+			if !k.keep(n, k.valueNames) {
+				k.logger.Printf("deleting value '%s'", n)
+				delete(e.Values, n)
+			}
+		}
+		for n := range e.Tags {
+			if !k.keep(n, k.tagNames) {
+				k.logger.Printf("deleting tag '%s'", n)
+				delete(e.Tags, n)
+			}
+		}
+	}
+	return es
+}
+
+func (k *Keep) WithLogger(l *log.Logger) {
+	if k.Debug && l != nil {
+		k.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if k.Debug {
+		k.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// keep returns true if name matches one of the regexes,
+// or if the list is empty and the empty allowlist keeps all.
+func (k *Keep) keep(name string, res []*regexp.Regexp) bool {
+	if len(res) == 0 {
+		return k.EmptyAllowlist == emptyKeepAll
+	}
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package event_keep
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"keep_values": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets$", "out-octets$"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1"},
+						Values: map[string]interface{}{
+							"/interface/statistics/in-octets":  1,
+							"/interface/statistics/out-octets": 2,
+							"/interface/statistics/in-errors":  3,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1"},
+						Values: map[string]interface{}{
+							"/interface/statistics/in-octets":  1,
+							"/interface/statistics/out-octets": 2,
+						},
+					},
+				},
+			},
+			// the delete events are kept with their deleted paths
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:    map[string]string{"source": "router1"},
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interface/statistics"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:    map[string]string{"source": "router1"},
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interface/statistics"},
+					},
+				},
+			},
+		},
+	},
+	"keep_tags": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names": []string{"^source$", "^interface_name$"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":            "router1",
+							"interface_name":    "ethernet-1/1",
+							"subscription-name": "sub1",
+						},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":         "router1",
+							"interface_name": "ethernet-1/1",
+						},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+			},
+		},
+	},
+	"keep_tags_empty_values_keep_none": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names":       []string{"^source$"},
+			"empty-allowlist": "keep-none",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "subscription-name": "sub1"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1"},
+						Values: map[string]interface{}{},
+					},
+				},
+			},
+		},
+	},
+	"keep_values_empty_tags_keep_all": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names":     []string{"in-octets"},
+			"empty-allowlist": "keep-all",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "subscription-name": "sub1"},
+						Values: map[string]interface{}{"in-octets": 1, "out-octets": 2},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "router1", "subscription-name": "sub1"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventKeep(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventKeepInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_allowlists":      {"empty-allowlist": "keep-none"},
+		"unknown_empty_allowlist": {"value-names": []string{"octets"}, "empty-allowlist": "drop"},
+		"bad_regex":               {"tag-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Keep{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package event_mac_normalize
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-mac-normalize"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	formatColon = "colon"
+	formatDash  = "dash"
+	formatDot   = "dot"
+)
+
+// MACNormalize rewrites the MAC addresses held by the values and tags with a name matching one of the regexes
+// in .ValueNames and .TagNames in a single format.
+// The colon (00:11:22:33:44:55), dash (00-11-22-33-44-55), dotted (0011.2233.4455) and bare (001122334455) formats are parsed,
+// the result is formatted using .Format, in lower case unless .UpperCase is true.
+// Strings that are not a MAC address are left unchanged.
+type MACNormalize struct {
+	formatters.EventProcessor
+
+	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	TagNames   []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	Format     string   `mapstructure:"format,omitempty" json:"format,omitempty"`
+	UpperCase  bool     `mapstructure:"upper-case,omitempty" json:"upper-case,omitempty"`
+	Debug      bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	tagNames   []*regexp.Regexp
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &MACNormalize{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (m *MACNormalize) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, m)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if len(m.ValueNames) == 0 && len(m.TagNames) == 0 {
+		return errors.New(processorType + ": missing value-names or tag-names")
+	}
+	m.Format = strings.ToLower(m.Format)
+	switch m.Format {
+	case "":
+		m.Format = formatColon
+	case formatColon, formatDash, formatDot:
+	default:
+		return fmt.Errorf("%s: unknown format %q, must be one of %s, %s or %s", processorType, m.Format, formatColon, formatDash, formatDot)
+	}
+	m.valueNames = make([]*regexp.Regexp, 0, len(m.ValueNames))
+	for _, reg := range m.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		m.valueNames = append(m.valueNames, re)
+	}
+	m.tagNames = make([]*regexp.Regexp, 0, len(m.TagNames))
+	for _, reg := range m.TagNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		m.tagNames = append(m.tagNames, re)
+	}
+	if m.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(m)
+		if err != nil {
+			m.logger.Printf("initialized processor '%s': %+v", processorType, m)
+			return nil
+		}
+		m.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (m *MACNormalize) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			if !match(m.valueNames, k) {
+				continue
+			}
+			vs, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if mac, ok := m.normalize(k, vs); ok {
+				e.Values[k] = mac
+			}
+		}
+		for k, v := range e.Tags {
+			if !match(m.tagNames, k) {
+				continue
+			}
+			if mac, ok := m.normalize(k, v); ok {
+				e.Tags[k] = mac
+			}
+		}
+	}
+	return es
+}
+
+func (m *MACNormalize) WithLogger(l *log.Logger) {
+	if m.Debug && l != nil {
+		m.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if m.Debug {
+		m.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// normalize returns the MAC address s in the configured format,
+// and false if s is not a MAC address.
+func (m *MACNormalize) normalize(name, s string) (string, bool) {
+	hw, err := parseMAC(s)
+	if err != nil {
+		m.logger.Printf("%q: %q is not a MAC address: %v", name, s, err)
+		return "", false
+	}
+	digits := hex.EncodeToString(hw)
+	if m.UpperCase {
+		digits = strings.ToUpper(digits)
+	}
+	sb := new(strings.Builder)
+	switch m.Format {
+	case formatColon, formatDash:
+		sep := ":"
+		if m.Format == formatDash {
+			sep = "-"
+		}
+		for i := 0; i < len(digits); i += 2 {
+			if i > 0 {
+				sb.WriteString(sep)
+			}
+			sb.WriteString(digits[i : i+2])
+		}
+	case formatDot:
+		for i := 0; i < len(digits); i += 4 {
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(digits[i : i+4])
+		}
+	}
+	return sb.String(), true
+}
+
+// parseMAC parses a 48 bits MAC address in the colon, dash, dotted or bare hexadecimal format.
+func parseMAC(s string) (net.HardwareAddr, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 12 {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return net.HardwareAddr(b), nil
+	}
+	hw, err := net.ParseMAC(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(hw) != 6 {
+		return nil, fmt.Errorf("unsupported address length %d", len(hw))
+	}
+	return hw, nil
+}
+
+func match(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
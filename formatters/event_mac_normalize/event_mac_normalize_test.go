@@ -0,0 +1,184 @@
+package event_mac_normalize
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"values_colon": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"mac-address$"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/colon/mac-address": "00:1A:2b:3C:4d:5E",
+							"/interface/dash/mac-address":  "00-1a-2b-3c-4d-5e",
+							"/interface/dot/mac-address":   "001a.2b3c.4d5e",
+							"/interface/bare/mac-address":  "001A2B3C4D5E",
+							"/interface/mtu":               1500,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/colon/mac-address": "00:1a:2b:3c:4d:5e",
+							"/interface/dash/mac-address":  "00:1a:2b:3c:4d:5e",
+							"/interface/dot/mac-address":   "00:1a:2b:3c:4d:5e",
+							"/interface/bare/mac-address":  "00:1a:2b:3c:4d:5e",
+							"/interface/mtu":               1500,
+						},
+					},
+				},
+			},
+			{
+				// values which are not MAC addresses are left unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/a/mac-address": "not-a-mac",
+							"/interface/b/mac-address": "00:1a:2b:3c:4d:5e:6f:70",
+							"/interface/c/mac-address": 42,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/a/mac-address": "not-a-mac",
+							"/interface/b/mac-address": "00:1a:2b:3c:4d:5e:6f:70",
+							"/interface/c/mac-address": 42,
+						},
+					},
+				},
+			},
+		},
+	},
+	"tags_dot_upper_case": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tag-names":  []string{"^neighbor_mac$"},
+			"format":     "dot",
+			"upper-case": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"neighbor_mac": "00-1a-2b-3c-4d-5e",
+							"source":       "router1",
+						},
+						Values: map[string]interface{}{"mac-address": "00-1a-2b-3c-4d-5e"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"neighbor_mac": "001A.2B3C.4D5E",
+							"source":       "router1",
+						},
+						Values: map[string]interface{}{"mac-address": "00-1a-2b-3c-4d-5e"},
+					},
+				},
+			},
+		},
+	},
+	"values_dash": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"mac-address$"},
+			"format":      "dash",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"mac-address": "001a.2b3c.4d5e"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"mac-address": "00-1a-2b-3c-4d-5e"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventMACNormalize(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventMACNormalizeInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_names":  {"format": "colon"},
+		"unknown_format": {"value-names": []string{"mac"}, "format": "cisco"},
+		"bad_value_name": {"value-names": []string{"("}},
+		"bad_tag_name":   {"tag-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &MACNormalize{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package event_merge
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/karimra/gnmic/formatters"
 )
@@ -12,22 +16,44 @@ import (
 const (
 	processorType = "event-merge"
 	loggingPrefix = "[" + processorType + "] "
+
+	conflictLast  = "last"
+	conflictFirst = "first"
 )
 
 // Merge merges a list of event messages into one or multiple messages based on some criteria
 type Merge struct {
 	formatters.EventProcessor
 
-	Always bool `mapstructure:"always,omitempty" json:"always,omitempty"`
-	Debug  bool `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	Always   bool          `mapstructure:"always,omitempty" json:"always,omitempty"`
+	KeyTags  []string      `mapstructure:"key-tags,omitempty" json:"key-tags,omitempty"`
+	Window   time.Duration `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Conflict string        `mapstructure:"conflict,omitempty" json:"conflict,omitempty"`
+	Debug    bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
 
+	m *sync.Mutex
+	// events waiting for their merge window to expire, by key
+	pending map[string]*pendingEvent
+	// pending events keys, in order of arrival
+	keys []string
+	// called with the merged events once their window expires, if set
+	flushHandler formatters.FlushHandler
+	// fires when the oldest pending window expires, if flushHandler is set
+	timer  *time.Timer
 	logger *log.Logger
 }
 
+type pendingEvent struct {
+	e     *formatters.EventMsg
+	start time.Time
+}
+
 func init() {
 	formatters.Register(processorType, func() formatters.EventProcessor {
 		return &Merge{
-			logger: log.New(ioutil.Discard, "", 0),
+			m:       new(sync.Mutex),
+			pending: make(map[string]*pendingEvent),
+			logger:  log.New(ioutil.Discard, "", 0),
 		}
 	})
 }
@@ -40,7 +66,16 @@ func (p *Merge) Init(cfg interface{}, opts ...formatters.Option) error {
 	for _, opt := range opts {
 		opt(p)
 	}
-
+	switch p.Conflict {
+	case "":
+		p.Conflict = conflictLast
+	case conflictLast, conflictFirst:
+	default:
+		return fmt.Errorf("%s: unknown conflict value %q, must be one of '%s' or '%s'", processorType, p.Conflict, conflictLast, conflictFirst)
+	}
+	if p.Window > 0 && len(p.KeyTags) == 0 {
+		return fmt.Errorf("%s: a window requires at least one key tag", processorType)
+	}
 	if p.logger.Writer() != ioutil.Discard {
 		b, err := json.Marshal(p)
 		if err != nil {
@@ -53,16 +88,23 @@ func (p *Merge) Init(cfg interface{}, opts ...formatters.Option) error {
 }
 
 func (p *Merge) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	if len(p.KeyTags) > 0 {
+		if p.Window > 0 {
+			return p.applyWindow(es)
+		}
+		return p.applyKeyTags(es)
+	}
 	if len(es) == 0 {
 		return nil
 	}
+	first := p.Conflict == conflictFirst
 	if p.Always {
 		for i, e := range es {
 			if e == nil {
 				continue
 			}
 			if i > 0 {
-				merge(es[0], e)
+				merge(es[0], e, first)
 			}
 		}
 		return []*formatters.EventMsg{es[0]}
@@ -74,7 +116,7 @@ func (p *Merge) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 			continue
 		}
 		if idx, ok := timestamps[e.Timestamp]; ok {
-			merge(result[idx], e)
+			merge(result[idx], e, first)
 			continue
 		}
 		result = append(result, e)
@@ -83,6 +125,148 @@ func (p *Merge) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 	return result
 }
 
+// applyKeyTags merges the events sharing the same key tags values,
+// events missing one of the key tags are not merged.
+func (p *Merge) applyKeyTags(es []*formatters.EventMsg) []*formatters.EventMsg {
+	if len(es) == 0 {
+		return nil
+	}
+	first := p.Conflict == conflictFirst
+	result := make([]*formatters.EventMsg, 0, len(es))
+	keys := make(map[string]int)
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		k, ok := p.key(e)
+		if !ok {
+			result = append(result, e)
+			continue
+		}
+		if idx, ok := keys[k]; ok {
+			merge(result[idx], e, first)
+			continue
+		}
+		result = append(result, e)
+		keys[k] = len(result) - 1
+	}
+	return result
+}
+
+// applyWindow holds the events sharing the same key tags values and merges them
+// until the window started by the first one expires.
+// The merged events are passed to the flush handler once their window expires,
+// without a flush handler they are returned by the first call to Apply after their window expired.
+func (p *Merge) applyWindow(es []*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := time.Now()
+	first := p.Conflict == conflictFirst
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		k, ok := p.key(e)
+		if !ok {
+			result = append(result, e)
+			continue
+		}
+		if pe, ok := p.pending[k]; ok {
+			merge(pe.e, e, first)
+			continue
+		}
+		p.pending[k] = &pendingEvent{e: e, start: now}
+		p.keys = append(p.keys, k)
+	}
+	result = append(result, p.expired(now)...)
+	p.scheduleFlush(now)
+	return result
+}
+
+// expired removes the pending events with a window expired at now and returns them.
+// It must be called with p.m locked.
+func (p *Merge) expired(now time.Time) []*formatters.EventMsg {
+	var result []*formatters.EventMsg
+	i := 0
+	for ; i < len(p.keys); i++ {
+		pe := p.pending[p.keys[i]]
+		if now.Sub(pe.start) < p.Window {
+			break
+		}
+		p.logger.Printf("merge window expired for key %q", p.keys[i])
+		result = append(result, pe.e)
+		delete(p.pending, p.keys[i])
+	}
+	p.keys = p.keys[i:]
+	return result
+}
+
+// scheduleFlush arms the timer for the oldest pending window, if there is a flush handler.
+// It must be called with p.m locked.
+func (p *Merge) scheduleFlush(now time.Time) {
+	if p.flushHandler == nil || p.timer != nil || len(p.keys) == 0 {
+		return
+	}
+	d := p.pending[p.keys[0]].start.Add(p.Window).Sub(now)
+	p.timer = time.AfterFunc(d, p.flushExpired)
+}
+
+// flushExpired passes the events with an expired window to the flush handler,
+// and re-arms the timer for the remaining ones.
+func (p *Merge) flushExpired() {
+	p.m.Lock()
+	now := time.Now()
+	p.timer = nil
+	es := p.expired(now)
+	p.scheduleFlush(now)
+	h := p.flushHandler
+	p.m.Unlock()
+	if len(es) > 0 {
+		h(es)
+	}
+}
+
+// WithFlushHandler implements formatters.Flusher, the merged events
+// are passed to h as soon as their window expires.
+func (p *Merge) WithFlushHandler(h formatters.FlushHandler) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.flushHandler = h
+}
+
+// Flush implements formatters.Flusher, it returns the pending merged events
+// without waiting for their window to expire.
+func (p *Merge) Flush() []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	result := make([]*formatters.EventMsg, 0, len(p.keys))
+	for _, k := range p.keys {
+		result = append(result, p.pending[k].e)
+		delete(p.pending, k)
+	}
+	p.keys = nil
+	return result
+}
+
+// key returns the event merge key built from the key tags values,
+// false is returned if the event is missing one of the key tags.
+func (p *Merge) key(e *formatters.EventMsg) (string, bool) {
+	vals := make([]string, 0, len(p.KeyTags))
+	for _, t := range p.KeyTags {
+		v, ok := e.Tags[t]
+		if !ok {
+			return "", false
+		}
+		vals = append(vals, v)
+	}
+	return strings.Join(vals, "\x00"), true
+}
+
 func (p *Merge) WithLogger(l *log.Logger) {
 	if p.Debug && l != nil {
 		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
@@ -91,7 +275,8 @@ func (p *Merge) WithLogger(l *log.Logger) {
 	}
 }
 
-func merge(e1, e2 *formatters.EventMsg) {
+// merge merges e2 into e1, if first is true the tags and values already present in e1 are kept
+func merge(e1, e2 *formatters.EventMsg, first bool) {
 	if e1.Tags == nil {
 		e1.Tags = make(map[string]string)
 	}
@@ -99,9 +284,15 @@ func merge(e1, e2 *formatters.EventMsg) {
 		e1.Values = make(map[string]interface{})
 	}
 	for n, t := range e2.Tags {
+		if _, ok := e1.Tags[n]; ok && first {
+			continue
+		}
 		e1.Tags[n] = t
 	}
 	for n, v := range e2.Values {
+		if _, ok := e1.Values[n]; ok && first {
+			continue
+		}
 		e1.Values[n] = v
 	}
 	e1.Deletes = append(e1.Deletes, e2.Deletes...)
@@ -3,6 +3,7 @@ package event_merge
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/karimra/gnmic/formatters"
 )
@@ -190,6 +191,85 @@ var testset = map[string]struct {
 			},
 		},
 	},
+	"merge_by_key_tags": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"key-tags": []string{"source", "interface_name"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"in-octets": 100, "description": "old"},
+						Tags:      map[string]string{"source": "r1", "interface_name": "eth0"},
+					},
+					{
+						Timestamp: 2,
+						Values:    map[string]interface{}{"oper-status": "UP", "description": "new"},
+						Tags:      map[string]string{"source": "r1", "interface_name": "eth0", "kind": "meta"},
+					},
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"in-octets": 200},
+						Tags:      map[string]string{"source": "r1", "interface_name": "eth1"},
+					},
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"cpu": 10},
+						Tags:      map[string]string{"source": "r1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 2,
+						Values:    map[string]interface{}{"in-octets": 100, "oper-status": "UP", "description": "new"},
+						Tags:      map[string]string{"source": "r1", "interface_name": "eth0", "kind": "meta"},
+					},
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"in-octets": 200},
+						Tags:      map[string]string{"source": "r1", "interface_name": "eth1"},
+					},
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"cpu": 10},
+						Tags:      map[string]string{"source": "r1"},
+					},
+				},
+			},
+		},
+	},
+	"merge_by_key_tags_conflict_first": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"key-tags": []string{"source"},
+			"conflict": "first",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1,
+						Values:    map[string]interface{}{"description": "old"},
+						Tags:      map[string]string{"source": "r1", "kind": "counter"},
+					},
+					{
+						Timestamp: 2,
+						Values:    map[string]interface{}{"oper-status": "UP", "description": "new"},
+						Tags:      map[string]string{"source": "r1", "kind": "meta"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 2,
+						Values:    map[string]interface{}{"oper-status": "UP", "description": "old"},
+						Tags:      map[string]string{"source": "r1", "kind": "counter"},
+					},
+				},
+			},
+		},
+	},
 }
 
 func TestEventMerge(t *testing.T) {
@@ -207,6 +287,9 @@ func TestEventMerge(t *testing.T) {
 				t.Run(name, func(t *testing.T) {
 					t.Logf("running test item %d", i)
 					outs := p.Apply(item.input...)
+					if len(item.output) > 0 && len(outs) != len(item.output) {
+						t.Fatalf("failed at %s item %d, expected %d events, got %d: %+v", name, i, len(item.output), len(outs), outs)
+					}
 					for j := range outs {
 						if !reflect.DeepEqual(outs[j], item.output[j]) {
 							t.Errorf("failed at %s item %d, index %d, expected %+v, got: %+v", name, i, j, item.output[j], outs[j])
@@ -219,3 +302,145 @@ func TestEventMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestEventMergeWindow(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"key-tags": []string{"source"},
+		"window":   "50ms",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	outs := p.Apply(&formatters.EventMsg{
+		Timestamp: 1,
+		Values:    map[string]interface{}{"counter": 1},
+		Tags:      map[string]string{"source": "r1"},
+	})
+	if len(outs) != 0 {
+		t.Fatalf("expected the event to be held until the window expires, got %+v", outs)
+	}
+	outs = p.Apply(
+		&formatters.EventMsg{
+			Timestamp: 2,
+			Values:    map[string]interface{}{"counter": 2, "description": "uplink"},
+			Tags:      map[string]string{"source": "r1"},
+		},
+		&formatters.EventMsg{
+			Timestamp: 2,
+			Values:    map[string]interface{}{"cpu": 10},
+		},
+	)
+	// the event without key tags is not held
+	if len(outs) != 1 || outs[0].Values["cpu"] != 10 {
+		t.Fatalf("expected the event without key tags only, got %+v", outs)
+	}
+	time.Sleep(100 * time.Millisecond)
+	outs = p.Apply()
+	want := []*formatters.EventMsg{
+		{
+			Timestamp: 2,
+			Values:    map[string]interface{}{"counter": 2, "description": "uplink"},
+			Tags:      map[string]string{"source": "r1"},
+		},
+	}
+	if !reflect.DeepEqual(outs, want) {
+		t.Errorf("expected %+v, got %+v", want, outs)
+	}
+	outs = p.Apply()
+	if len(outs) != 0 {
+		t.Errorf("expected no events after the window expired, got %+v", outs)
+	}
+}
+
+func TestEventMergeWindowFlushHandler(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"key-tags": []string{"source"},
+		"window":   "50ms",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	flushed := make(chan []*formatters.EventMsg, 1)
+	formatters.SetFlushHandlers([]formatters.EventProcessor{p}, func(es []*formatters.EventMsg) {
+		flushed <- es
+	})
+	outs := p.Apply(
+		&formatters.EventMsg{
+			Timestamp: 1,
+			Values:    map[string]interface{}{"counter": 1},
+			Tags:      map[string]string{"source": "r1"},
+		},
+		&formatters.EventMsg{
+			Timestamp: 2,
+			Values:    map[string]interface{}{"description": "uplink"},
+			Tags:      map[string]string{"source": "r1"},
+		},
+	)
+	if len(outs) != 0 {
+		t.Fatalf("expected the events to be held until the window expires, got %+v", outs)
+	}
+	// the window is flushed without any further event
+	select {
+	case es := <-flushed:
+		want := []*formatters.EventMsg{
+			{
+				Timestamp: 2,
+				Values:    map[string]interface{}{"counter": 1, "description": "uplink"},
+				Tags:      map[string]string{"source": "r1"},
+			},
+		}
+		if !reflect.DeepEqual(es, want) {
+			t.Errorf("expected %+v, got %+v", want, es)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the merge window to be flushed")
+	}
+	if outs := p.Apply(); len(outs) != 0 {
+		t.Errorf("expected no events after the window was flushed, got %+v", outs)
+	}
+}
+
+func TestEventMergeFlush(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"key-tags": []string{"source"},
+		"window":   "1h",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	p.Apply(
+		&formatters.EventMsg{
+			Timestamp: 1,
+			Values:    map[string]interface{}{"counter": 1},
+			Tags:      map[string]string{"source": "r1"},
+		},
+		&formatters.EventMsg{
+			Timestamp: 1,
+			Values:    map[string]interface{}{"counter": 2},
+			Tags:      map[string]string{"source": "r2"},
+		},
+	)
+	// the pending windows are flushed on close without waiting for them to expire
+	outs := formatters.FlushProcessors([]formatters.EventProcessor{p})
+	if len(outs) != 2 || outs[0].Tags["source"] != "r1" || outs[1].Tags["source"] != "r2" {
+		t.Fatalf("expected the 2 pending events in order, got %+v", outs)
+	}
+	if outs := formatters.FlushProcessors([]formatters.EventProcessor{p}); len(outs) != 0 {
+		t.Errorf("expected no events left, got %+v", outs)
+	}
+}
+
+func TestEventMergeInit(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"unknown_conflict":       {"conflict": "random"},
+		"window_without_keytags": {"window": "1s"},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
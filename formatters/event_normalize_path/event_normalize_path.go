@@ -0,0 +1,274 @@
+package event_normalize_path
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-normalize-path"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// NormalizePath rewrites the paths found in the event name, values names, tags names and deletes
+// in a canonical form, so that equivalent paths map to the same series:
+// the keys of each path element are sorted by name, the key values escaping is made consistent,
+// the empty elements are removed and, if .Lowercase is true, the elements and keys names are lowercased.
+// The values of the tags matching one of the regexes in .TagNames are normalized as paths as well.
+type NormalizePath struct {
+	formatters.EventProcessor
+
+	Lowercase bool     `mapstructure:"lowercase,omitempty" json:"lowercase,omitempty"`
+	TagNames  []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	Debug     bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	tagNames []*regexp.Regexp
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &NormalizePath{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (n *NormalizePath) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, n)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	n.tagNames = make([]*regexp.Regexp, 0, len(n.TagNames))
+	for _, reg := range n.TagNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		n.tagNames = append(n.tagNames, re)
+	}
+	if n.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(n)
+		if err != nil {
+			n.logger.Printf("initialized processor '%s': %+v", processorType, n)
+			return nil
+		}
+		n.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (n *NormalizePath) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		e.Name = n.normalize(e.Name)
+		if len(e.Values) > 0 {
+			values := make(map[string]interface{}, len(e.Values))
+			for k, v := range e.Values {
+				values[n.normalize(k)] = v
+			}
+			e.Values = values
+		}
+		if len(e.Tags) > 0 {
+			tags := make(map[string]string, len(e.Tags))
+			for k, v := range e.Tags {
+				if n.matchTag(k) {
+					v = n.normalize(v)
+				}
+				tags[n.normalize(k)] = v
+			}
+			e.Tags = tags
+		}
+		for i, d := range e.Deletes {
+			e.Deletes[i] = n.normalize(d)
+		}
+	}
+	return es
+}
+
+func (n *NormalizePath) WithLogger(l *log.Logger) {
+	if n.Debug && l != nil {
+		n.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if n.Debug {
+		n.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (n *NormalizePath) matchTag(name string) bool {
+	for _, re := range n.tagNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+type pathKey struct {
+	name  string
+	value string
+}
+
+type pathElem struct {
+	name string
+	keys []pathKey
+}
+
+// normalize returns the canonical form of the path p,
+// strings without any '/' or '[' are not considered as paths and are returned as is.
+// The path origin and leading '/', if any, are kept.
+func (n *NormalizePath) normalize(p string) string {
+	if !strings.ContainsAny(p, "/[") {
+		return p
+	}
+	origin := ""
+	path := p
+	if idx := strings.Index(path, ":/"); idx > 0 && !strings.ContainsAny(path[:idx], "/[") {
+		origin = path[:idx+1]
+		path = path[idx+1:]
+	}
+	elems, ok := splitPath(path)
+	if !ok {
+		n.logger.Printf("failed to parse path %q, leaving it unchanged", p)
+		return p
+	}
+	if len(elems) == 0 {
+		return p
+	}
+	sb := new(strings.Builder)
+	sb.WriteString(origin)
+	for i, el := range elems {
+		if i > 0 || strings.HasPrefix(path, "/") {
+			sb.WriteString("/")
+		}
+		if n.Lowercase {
+			el.name = strings.ToLower(el.name)
+		}
+		sb.WriteString(el.name)
+		for i := range el.keys {
+			if n.Lowercase {
+				el.keys[i].name = strings.ToLower(el.keys[i].name)
+			}
+		}
+		sort.SliceStable(el.keys, func(i, j int) bool {
+			return el.keys[i].name < el.keys[j].name
+		})
+		for _, k := range el.keys {
+			sb.WriteString("[")
+			sb.WriteString(k.name)
+			sb.WriteString("=")
+			sb.WriteString(escapeKeyValue(k.value))
+			sb.WriteString("]")
+		}
+	}
+	res := sb.String()
+	if res != p {
+		n.logger.Printf("normalized path %q to %q", p, res)
+	}
+	return res
+}
+
+// splitPath splits the path p into its elements and their keys,
+// the backslash escaped characters are unescaped.
+// It returns false if a key is not terminated, has no '=' or is followed by anything else than a key or a '/'.
+func splitPath(p string) ([]*pathElem, bool) {
+	elems := make([]*pathElem, 0)
+	var el *pathElem
+	name := new(strings.Builder)
+	flush := func() {
+		if el == nil && name.Len() == 0 {
+			return
+		}
+		if el == nil {
+			el = &pathElem{}
+		}
+		el.name = name.String()
+		elems = append(elems, el)
+		el = nil
+		name.Reset()
+	}
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if el != nil && len(el.keys) > 0 && c != '/' && c != '[' {
+			return nil, false
+		}
+		switch c {
+		case '/':
+			flush()
+		case '[':
+			end, key, ok := readKey(p, i+1)
+			if !ok {
+				return nil, false
+			}
+			if el == nil {
+				el = &pathElem{}
+			}
+			el.keys = append(el.keys, key)
+			i = end
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			name.WriteByte(p[i])
+		default:
+			name.WriteByte(c)
+		}
+	}
+	flush()
+	return elems, true
+}
+
+// readKey reads a key starting at index start of p, right after a '[',
+// it returns the index of the closing ']'.
+func readKey(p string, start int) (int, pathKey, bool) {
+	sb := new(strings.Builder)
+	key := pathKey{}
+	nameDone := false
+	for i := start; i < len(p); i++ {
+		switch c := p[i]; {
+		case c == '\\' && i+1 < len(p):
+			i++
+			sb.WriteByte(p[i])
+		case c == '=' && !nameDone:
+			key.name = sb.String()
+			nameDone = true
+			sb.Reset()
+		case c == ']':
+			if !nameDone {
+				return 0, key, false
+			}
+			key.value = sb.String()
+			return i, key, true
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return 0, key, false
+}
+
+// escapeKeyValue escapes the characters having a special meaning in a key value
+func escapeKeyValue(v string) string {
+	if !strings.ContainsAny(v, `]\`) {
+		return v
+	}
+	sb := new(strings.Builder)
+	for i := 0; i < len(v); i++ {
+		if v[i] == ']' || v[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(v[i])
+	}
+	return sb.String()
+}
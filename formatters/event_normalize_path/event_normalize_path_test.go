@@ -0,0 +1,170 @@
+package event_normalize_path
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"sort_keys": {
+		processorType: processorType,
+		processor:     map[string]interface{}{},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"/network-instance[name=default]/protocols/bgp/neighbor[peer-address=10.0.0.1][afi-safi=ipv4]/state/received": 10},
+					},
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"/network-instance[name=default]/protocols/bgp/neighbor[afi-safi=ipv4][peer-address=10.0.0.1]/state/received": 10},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"/network-instance[name=default]/protocols/bgp/neighbor[afi-safi=ipv4][peer-address=10.0.0.1]/state/received": 10},
+					},
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"/network-instance[name=default]/protocols/bgp/neighbor[afi-safi=ipv4][peer-address=10.0.0.1]/state/received": 10},
+					},
+				},
+			},
+			{
+				// escaping and empty elements
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"openconfig:/interfaces//interface[name=ethernet\\/1\\/1]/state/": 1},
+						Deletes: []string{
+							"/interfaces/interface[name=a\\]b]",
+							"/interfaces/interface[name=a]b]",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"openconfig:/interfaces/interface[name=ethernet/1/1]/state": 1},
+						Deletes: []string{
+							"/interfaces/interface[name=a\\]b]",
+							// not terminated key, left unchanged
+							"/interfaces/interface[name=a]b]",
+						},
+					},
+				},
+			},
+		},
+	},
+	"lowercase_and_tags": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"lowercase": true,
+			"tag-names": []string{"^path$"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source": "router1",
+							"path":   "/Interfaces/Interface[Name=Ethernet1][Unit=0]",
+						},
+						Values: map[string]interface{}{"/Interfaces/Interface/State/Counters/In-Octets": 10},
+					},
+					{
+						Tags: map[string]string{
+							"source": "router1",
+							"path":   "/interfaces/interface[unit=0][name=Ethernet1]",
+						},
+						Values: map[string]interface{}{"/interfaces/interface/state/counters/in-octets": 10},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source": "router1",
+							"path":   "/interfaces/interface[name=Ethernet1][unit=0]",
+						},
+						Values: map[string]interface{}{"/interfaces/interface/state/counters/in-octets": 10},
+					},
+					{
+						Tags: map[string]string{
+							"source": "router1",
+							"path":   "/interfaces/interface[name=Ethernet1][unit=0]",
+						},
+						Values: map[string]interface{}{"/interfaces/interface/state/counters/in-octets": 10},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventNormalizePath(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventNormalizePathInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"bad_regex": {"tag-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &NormalizePath{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
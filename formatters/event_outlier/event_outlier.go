@@ -0,0 +1,285 @@
+package event_outlier
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-outlier"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	defaultWindowSize = 60
+	defaultWarmup     = 10
+	defaultK          = 3
+	defaultMaxSeries  = 10000
+
+	actionDrop = "drop"
+	actionTag  = "tag"
+
+	outlierTagSuffix = "_outlier"
+)
+
+// Outlier drops (or tags) the values deviating from the moving mean of their series
+// by more than K standard deviations.
+// A series is identified by the event name, its tags and the value name.
+// At most MaxSeries series are kept, the least recently updated one is evicted to make room for a new one.
+type Outlier struct {
+	formatters.EventProcessor
+
+	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	WindowSize int      `mapstructure:"window-size,omitempty" json:"window-size,omitempty"`
+	K          float64  `mapstructure:"k,omitempty" json:"k,omitempty"`
+	Warmup     int      `mapstructure:"warmup,omitempty" json:"warmup,omitempty"`
+	Action     string   `mapstructure:"action,omitempty" json:"action,omitempty"`
+	MaxSeries  int      `mapstructure:"max-series,omitempty" json:"max-series,omitempty"`
+	Debug      bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+
+	m      *sync.Mutex
+	series map[string]*list.Element
+	// series ordered from the most to the least recently updated
+	lru *list.List
+
+	logger *log.Logger
+}
+
+// series holds the last WindowSize values of a series in a ring buffer,
+// with their running sum and sum of squares.
+type series struct {
+	key    string
+	values []float64
+	next   int
+	sum    float64
+	sumSq  float64
+	// number of consecutive outliers
+	outliers int
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Outlier{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (o *Outlier) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, o)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.WindowSize <= 0 {
+		o.WindowSize = defaultWindowSize
+	}
+	if o.Warmup <= 0 {
+		o.Warmup = defaultWarmup
+	}
+	if o.Warmup < 2 || o.Warmup > o.WindowSize {
+		return fmt.Errorf("%s: warmup must be between 2 and the window-size %d, got %d", processorType, o.WindowSize, o.Warmup)
+	}
+	if o.K < 0 {
+		return fmt.Errorf("%s: k must be a positive number, got %v", processorType, o.K)
+	}
+	if o.K == 0 {
+		o.K = defaultK
+	}
+	if o.MaxSeries <= 0 {
+		o.MaxSeries = defaultMaxSeries
+	}
+	o.Action = strings.ToLower(o.Action)
+	switch o.Action {
+	case "":
+		o.Action = actionDrop
+	case actionDrop, actionTag:
+	default:
+		return fmt.Errorf("%s: unknown action %q, must be one of %s or %s", processorType, o.Action, actionDrop, actionTag)
+	}
+	if len(o.ValueNames) == 0 {
+		o.ValueNames = []string{".*"}
+	}
+	o.valueNames = make([]*regexp.Regexp, 0, len(o.ValueNames))
+	for _, reg := range o.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		o.valueNames = append(o.valueNames, re)
+	}
+	o.m = new(sync.Mutex)
+	o.series = make(map[string]*list.Element)
+	o.lru = list.New()
+	if o.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(o)
+		if err != nil {
+			o.logger.Printf("initialized processor '%s': %+v", processorType, o)
+			return nil
+		}
+		o.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (o *Outlier) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	o.m.Lock()
+	defer o.m.Unlock()
+	res := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if len(e.Values) == 0 {
+			res = append(res, e)
+			continue
+		}
+		// the series key prefix is computed before tagging any outlier
+		prefix := seriesPrefix(e)
+		for vn, v := range e.Values {
+			if !o.match(vn) {
+				continue
+			}
+			f, err := formatters.ToFloat(v)
+			if err != nil {
+				continue
+			}
+			if !o.isOutlier(prefix+vn, f) {
+				continue
+			}
+			switch o.Action {
+			case actionDrop:
+				o.logger.Printf("dropping value %q=%v", vn, v)
+				delete(e.Values, vn)
+			case actionTag:
+				o.logger.Printf("tagging value %q=%v", vn, v)
+				if e.Tags == nil {
+					e.Tags = make(map[string]string)
+				}
+				e.Tags[vn+outlierTagSuffix] = "true"
+			}
+		}
+		// an event left without values is dropped
+		if len(e.Values) == 0 {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func (o *Outlier) WithLogger(l *log.Logger) {
+	if o.Debug && l != nil {
+		o.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if o.Debug {
+		o.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (o *Outlier) match(name string) bool {
+	for _, re := range o.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutlier reports whether v is more than K standard deviations away from the mean of the series window,
+// v is added to the window if it is not an outlier.
+// The values are not filtered until the series window holds Warmup values.
+// After Warmup consecutive outliers, the series is considered settled to a new level and its window restarts from v.
+func (o *Outlier) isOutlier(key string, v float64) bool {
+	s := o.getSeries(key)
+	if n := len(s.values); n >= o.Warmup {
+		mean := s.sum / float64(n)
+		variance := s.sumSq/float64(n) - mean*mean
+		if variance < 0 {
+			variance = 0
+		}
+		if math.Abs(v-mean) > o.K*math.Sqrt(variance) {
+			s.outliers++
+			if s.outliers < o.Warmup {
+				return true
+			}
+			o.logger.Printf("series %q settled to a new level, restarting its window", key)
+			s.reset()
+		}
+	}
+	s.outliers = 0
+	s.add(v)
+	return false
+}
+
+// getSeries returns the series named key, creating it if needed.
+func (o *Outlier) getSeries(key string) *series {
+	if el, ok := o.series[key]; ok {
+		o.lru.MoveToFront(el)
+		return el.Value.(*series)
+	}
+	if len(o.series) >= o.MaxSeries {
+		el := o.lru.Back()
+		o.lru.Remove(el)
+		delete(o.series, el.Value.(*series).key)
+	}
+	s := &series{key: key, values: make([]float64, 0, o.WindowSize)}
+	o.series[key] = o.lru.PushFront(s)
+	return s
+}
+
+func (s *series) reset() {
+	s.values = s.values[:0]
+	s.next = 0
+	s.sum = 0
+	s.sumSq = 0
+	s.outliers = 0
+}
+
+func (s *series) add(v float64) {
+	if len(s.values) < cap(s.values) {
+		s.values = append(s.values, v)
+	} else {
+		old := s.values[s.next]
+		s.sum -= old
+		s.sumSq -= old * old
+		s.values[s.next] = v
+		s.next = (s.next + 1) % len(s.values)
+	}
+	s.sum += v
+	s.sumSq += v * v
+}
+
+// seriesPrefix returns the event name and its sorted tags as a string,
+// the value name is appended to it to form a series key.
+func seriesPrefix(e *formatters.EventMsg) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	sb.WriteString(",")
+	return sb.String()
+}
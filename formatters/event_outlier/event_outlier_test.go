@@ -0,0 +1,255 @@
+package event_outlier
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func event(source string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:   "sub1",
+		Tags:   map[string]string{"source": source},
+		Values: values,
+	}
+}
+
+// steady returns n items with an in_octets value alternating between 10 and 11,
+// passing through the processor unchanged.
+func steady(source string, n int) []item {
+	items := make([]item, 0, n)
+	for i := 0; i < n; i++ {
+		v := 10 + i%2
+		items = append(items, item{
+			input:  []*formatters.EventMsg{event(source, map[string]interface{}{"in_octets": v})},
+			output: []*formatters.EventMsg{event(source, map[string]interface{}{"in_octets": v})},
+		})
+	}
+	return items
+}
+
+// spikes returns n items with an in_octets value v, dropped by the processor unless kept is true.
+func spikes(source string, v, n int, kept bool) []item {
+	items := make([]item, 0, n)
+	for i := 0; i < n; i++ {
+		it := item{input: []*formatters.EventMsg{event(source, map[string]interface{}{"in_octets": v})}}
+		if kept {
+			it.output = []*formatters.EventMsg{event(source, map[string]interface{}{"in_octets": v})}
+		} else {
+			it.output = []*formatters.EventMsg{}
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"drop_spike_after_warmup": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^in_octets$"},
+			"window-size": 10,
+			"warmup":      5,
+		},
+		tests: append(append(steady("router1", 8),
+			item{
+				input:  nil,
+				output: nil,
+			},
+			// the spike is dropped, the non matching value is kept
+			item{
+				input: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": 100, "mtu": 1500}),
+				},
+				output: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"mtu": 1500}),
+				},
+			},
+			// another series is still warming up
+			item{
+				input: []*formatters.EventMsg{
+					event("router2", map[string]interface{}{"in_octets": 100}),
+				},
+				output: []*formatters.EventMsg{
+					event("router2", map[string]interface{}{"in_octets": 100}),
+				},
+			},
+			// a delete event has no values, it is kept
+			item{
+				input: []*formatters.EventMsg{
+					{
+						Name:    "sub1",
+						Tags:    map[string]string{"source": "router1"},
+						Values:  map[string]interface{}{},
+						Deletes: []string{"in_octets"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:    "sub1",
+						Tags:    map[string]string{"source": "router1"},
+						Values:  map[string]interface{}{},
+						Deletes: []string{"in_octets"},
+					},
+				},
+			},
+			// an event left without values is dropped
+			item{
+				input: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": 10}),
+					event("router1", map[string]interface{}{"in_octets": -500}),
+				},
+				output: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": 10}),
+				},
+			},
+		), steady("router1", 2)...),
+	},
+	"repeated_spikes": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"window-size": 10,
+			"warmup":      5,
+		},
+		// the dropped spikes are not added to the window, they do not inflate its standard deviation
+		tests: append(append(steady("router1", 8), spikes("router1", 100, 3, false)...), steady("router1", 1)...),
+	},
+	"level_shift": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"window-size": 10,
+			"warmup":      5,
+		},
+		// after warmup consecutive outliers, the series window restarts from the new level
+		tests: append(append(steady("router1", 8), spikes("router1", 50, 4, false)...), spikes("router1", 50, 5, true)...),
+	},
+	"spike_during_warmup": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"window-size": 10,
+			"warmup":      5,
+		},
+		tests: append(steady("router1", 3),
+			item{
+				input: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": 100}),
+				},
+				output: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": 100}),
+				},
+			},
+		),
+	},
+	"tag_spike": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"window-size": 10,
+			"warmup":      5,
+			"k":           2,
+			"action":      "tag",
+		},
+		tests: append(steady("router1", 6),
+			item{
+				input: []*formatters.EventMsg{
+					event("router1", map[string]interface{}{"in_octets": "100"}),
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name: "sub1",
+						Tags: map[string]string{
+							"source":            "router1",
+							"in_octets_outlier": "true",
+						},
+						Values: map[string]interface{}{"in_octets": "100"},
+					},
+				},
+			},
+		),
+	},
+}
+
+func TestEventOutlier(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventOutlierMaxSeries(t *testing.T) {
+	p := &Outlier{logger: log.New(os.Stderr, loggingPrefix, 0)}
+	if err := p.Init(map[string]interface{}{"max-series": 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []string{"router1", "router2", "router1", "router3"} {
+		p.Apply(event(source, map[string]interface{}{"in_octets": 10}))
+	}
+	if len(p.series) != 2 || p.lru.Len() != 2 {
+		t.Fatalf("expected 2 series, got %d", len(p.series))
+	}
+	// router2 is the least recently updated series
+	for source, want := range map[string]bool{"router1": true, "router2": false, "router3": true} {
+		if _, ok := p.series[seriesPrefix(event(source, nil))+"in_octets"]; ok != want {
+			t.Errorf("series of %s kept=%t, expected %t", source, ok, want)
+		}
+	}
+}
+
+func TestEventOutlierInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"warmup_too_small":  {"warmup": 1},
+		"warmup_above_size": {"window-size": 5, "warmup": 6},
+		"negative_k":        {"k": -1},
+		"unknown_action":    {"action": "delete"},
+		"bad_regex":         {"value-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Outlier{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
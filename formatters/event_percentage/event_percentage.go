@@ -0,0 +1,142 @@
+package event_percentage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-percentage"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	zeroDenominatorSkip = "skip"
+	zeroDenominatorZero = "zero"
+)
+
+// Percentage computes the ratio of the values named Numerator and Denominator, multiplied by 100.
+// The result is stored as a new value named Target, rounded to Precision decimal places if set.
+// Events missing one of the operands, or with a non numeric one, are left unchanged.
+type Percentage struct {
+	formatters.EventProcessor
+
+	Numerator       string `mapstructure:"numerator,omitempty" json:"numerator,omitempty"`
+	Denominator     string `mapstructure:"denominator,omitempty" json:"denominator,omitempty"`
+	Target          string `mapstructure:"target,omitempty" json:"target,omitempty"`
+	ZeroDenominator string `mapstructure:"zero-denominator,omitempty" json:"zero-denominator,omitempty"`
+	Precision       *int   `mapstructure:"precision,omitempty" json:"precision,omitempty"`
+	Debug           bool   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Percentage{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *Percentage) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Numerator == "" {
+		return errors.New(processorType + ": missing numerator")
+	}
+	if p.Denominator == "" {
+		return errors.New(processorType + ": missing denominator")
+	}
+	if p.Target == "" {
+		return errors.New(processorType + ": missing target")
+	}
+	p.ZeroDenominator = strings.ToLower(p.ZeroDenominator)
+	switch p.ZeroDenominator {
+	case "":
+		p.ZeroDenominator = zeroDenominatorSkip
+	case zeroDenominatorSkip, zeroDenominatorZero:
+	default:
+		return fmt.Errorf("%s: unknown zero-denominator %q, must be one of %s or %s", processorType, p.ZeroDenominator, zeroDenominatorSkip, zeroDenominatorZero)
+	}
+	if p.Precision != nil && *p.Precision < 0 {
+		return fmt.Errorf("%s: precision must be a positive number, got %d", processorType, *p.Precision)
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *Percentage) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		num, ok := p.operand(e, p.Numerator)
+		if !ok {
+			continue
+		}
+		den, ok := p.operand(e, p.Denominator)
+		if !ok {
+			continue
+		}
+		var res float64
+		if den == 0 {
+			if p.ZeroDenominator == zeroDenominatorSkip {
+				p.logger.Printf("denominator '%s' is zero, skipping event", p.Denominator)
+				continue
+			}
+		} else {
+			res = num / den * 100
+		}
+		if p.Precision != nil {
+			scale := math.Pow10(*p.Precision)
+			res = math.Round(res*scale) / scale
+		}
+		e.Values[p.Target] = res
+		p.logger.Printf("set value '%s' to %v", p.Target, res)
+	}
+	return es
+}
+
+func (p *Percentage) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// operand returns the numeric value named name
+func (p *Percentage) operand(e *formatters.EventMsg, name string) (float64, bool) {
+	v, ok := e.Values[name]
+	if !ok {
+		p.logger.Printf("value '%s' not found, skipping event", name)
+		return 0, false
+	}
+	f, err := formatters.ToFloat(v)
+	if err != nil {
+		p.logger.Printf("value '%s' is not a number, skipping event: %v", name, err)
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,222 @@
+package event_percentage
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"ratio": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"numerator":   "memory/used",
+			"denominator": "memory/total",
+			"target":      "memory/used-percent",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": 1, "memory/total": uint64(3)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"memory/used":         1,
+							"memory/total":        uint64(3),
+							"memory/used-percent": float64(1) / 3 * 100,
+						},
+					},
+				},
+			},
+			{
+				// numeric strings are parsed
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": "512", "memory/total": "2048"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"memory/used":         "512",
+							"memory/total":        "2048",
+							"memory/used-percent": float64(25),
+						},
+					},
+				},
+			},
+			{
+				// zero denominator, the event is unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": 0, "memory/total": 0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": 0, "memory/total": 0},
+					},
+				},
+			},
+			{
+				// missing operand, the event is unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": 10},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": 10},
+					},
+				},
+			},
+			{
+				// non numeric operand, the event is unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": "high", "memory/total": 100},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"memory/used": "high", "memory/total": 100},
+					},
+				},
+			},
+		},
+	},
+	"zero_and_precision": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"numerator":        "used",
+			"denominator":      "total",
+			"target":           "percent",
+			"zero-denominator": "zero",
+			"precision":        2,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 1, "total": 3},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 1, "total": 3, "percent": 33.33},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 2.5, "total": 0.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 2.5, "total": 0.0, "percent": float64(0)},
+					},
+				},
+			},
+		},
+	},
+	"precision_zero": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"numerator":   "used",
+			"denominator": "total",
+			"target":      "percent",
+			"precision":   0,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 2, "total": 3},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"used": 2, "total": 3, "percent": float64(67)},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventPercentage(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventPercentageInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_numerator":        {"denominator": "total", "target": "percent"},
+		"missing_denominator":      {"numerator": "used", "target": "percent"},
+		"missing_target":           {"numerator": "used", "denominator": "total"},
+		"unknown_zero_denominator": {"numerator": "used", "denominator": "total", "target": "percent", "zero-denominator": "nan"},
+		"negative_precision":       {"numerator": "used", "denominator": "total", "target": "percent", "precision": -1},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Percentage{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
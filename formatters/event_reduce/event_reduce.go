@@ -0,0 +1,218 @@
+package event_reduce
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-reduce"
+	loggingPrefix = "[" + processorType + "] "
+
+	defaultTargetNameTemplate = "{{ .Group }}"
+)
+
+const (
+	aggregationSum   = "sum"
+	aggregationAvg   = "avg"
+	aggregationMin   = "min"
+	aggregationMax   = "max"
+	aggregationCount = "count"
+)
+
+// Reduce aggregates the values with a name matching .Pattern into a single value per group.
+// The group of a value is the name built by executing .TargetNameTemplate with the .Pattern capture groups,
+// all the values rendering the same name are aggregated together using .Aggregation.
+// The aggregated values are removed from the event unless .KeepValues is true.
+type Reduce struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	Pattern            string `mapstructure:"pattern,omitempty" json:"pattern,omitempty"`
+	Aggregation        string `mapstructure:"aggregation,omitempty" json:"aggregation,omitempty"`
+	TargetNameTemplate string `mapstructure:"target-name-template,omitempty" json:"target-name-template,omitempty"`
+	KeepValues         bool   `mapstructure:"keep-values,omitempty" json:"keep-values,omitempty"`
+	Debug              bool   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	pattern  *regexp.Regexp
+	template *template.Template
+
+	logger *log.Logger
+}
+
+// group holds the running aggregation of a group of values
+type group struct {
+	sum   float64
+	min   float64
+	max   float64
+	count int
+	// names of the aggregated values
+	names []string
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Reduce{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (r *Reduce) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.Pattern == "" {
+		return errors.New(processorType + ": missing pattern")
+	}
+	r.pattern, err = regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	if r.pattern.NumSubexp() == 0 {
+		return fmt.Errorf("%s: pattern %q does not have a capture group", processorType, r.Pattern)
+	}
+	r.Aggregation = strings.ToLower(r.Aggregation)
+	switch r.Aggregation {
+	case "":
+		r.Aggregation = aggregationSum
+	case aggregationSum, aggregationAvg, aggregationMin, aggregationMax, aggregationCount:
+	default:
+		return fmt.Errorf("%s: unknown aggregation %q, must be one of %s, %s, %s, %s or %s", processorType, r.Aggregation,
+			aggregationSum, aggregationAvg, aggregationMin, aggregationMax, aggregationCount)
+	}
+	if r.TargetNameTemplate == "" {
+		r.TargetNameTemplate = defaultTargetNameTemplate
+	}
+	r.template, err = template.New("target-name").Option("missingkey=zero").Parse(r.TargetNameTemplate)
+	if err != nil {
+		return err
+	}
+	if r.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *Reduce) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		r.reduce(e)
+	}
+	return es
+}
+
+func (r *Reduce) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (r *Reduce) reduce(e *formatters.EventMsg) {
+	names := make([]string, 0, len(e.Values))
+	for k := range e.Values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	groups := make(map[string]*group)
+	for _, k := range names {
+		matches := r.pattern.FindStringSubmatch(k)
+		if matches == nil {
+			continue
+		}
+		v, err := formatters.ToFloat(e.Values[k])
+		if err != nil {
+			r.logger.Printf("failed to convert value %q=%v to a number: %v", k, e.Values[k], err)
+			r.ReportError(err)
+			continue
+		}
+		target, err := r.targetName(matches)
+		if err != nil {
+			r.logger.Printf("failed to execute target-name-template for value %q: %v", k, err)
+			r.ReportError(err)
+			continue
+		}
+		g, ok := groups[target]
+		if !ok {
+			g = &group{min: v, max: v}
+			groups[target] = g
+		}
+		g.sum += v
+		g.count++
+		if v < g.min {
+			g.min = v
+		}
+		if v > g.max {
+			g.max = v
+		}
+		g.names = append(g.names, k)
+	}
+	for target, g := range groups {
+		if !r.KeepValues {
+			for _, k := range g.names {
+				delete(e.Values, k)
+			}
+		}
+		e.Values[target] = r.aggregate(g)
+	}
+}
+
+// targetName executes the target name template with the pattern matches:
+// .Group is the first capture group, the named capture groups are accessible by name.
+func (r *Reduce) targetName(matches []string) (string, error) {
+	data := map[string]string{
+		"Group":       matches[1],
+		"Aggregation": r.Aggregation,
+	}
+	for i, name := range r.pattern.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		data[name] = matches[i]
+	}
+	b := new(bytes.Buffer)
+	err := r.template.Execute(b, data)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (r *Reduce) aggregate(g *group) interface{} {
+	switch r.Aggregation {
+	case aggregationAvg:
+		return g.sum / float64(g.count)
+	case aggregationMin:
+		return g.min
+	case aggregationMax:
+		return g.max
+	case aggregationCount:
+		return g.count
+	default:
+		return g.sum
+	}
+}
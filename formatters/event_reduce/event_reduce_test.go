@@ -0,0 +1,215 @@
+package event_reduce
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"sum_per_interface": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"pattern":              `^interface/([^/]+)/queue/[^/]+/dropped$`,
+			"target-name-template": "interface/{{ .Group }}/dropped",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"interface/ethernet-1/queue/0/dropped": 1,
+							"interface/ethernet-1/queue/1/dropped": uint64(2),
+							"interface/ethernet-1/queue/2/dropped": "3",
+							"interface/ethernet-2/queue/0/dropped": int64(10),
+							"interface/ethernet-2/queue/1/dropped": 20.5,
+							"interface/ethernet-2/oper-state":      "up",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"interface/ethernet-1/dropped":    float64(6),
+							"interface/ethernet-2/dropped":    30.5,
+							"interface/ethernet-2/oper-state": "up",
+						},
+					},
+				},
+			},
+			{
+				// values that are not numbers are not aggregated
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"interface/ethernet-1/queue/0/dropped": 1,
+							"interface/ethernet-1/queue/1/dropped": "n/a",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"interface/ethernet-1/dropped":         float64(1),
+							"interface/ethernet-1/queue/1/dropped": "n/a",
+						},
+					},
+				},
+			},
+		},
+	},
+	"avg_named_groups_keep_values": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"pattern":              `^(?P<interface>[^/]+)/queue/[^/]+/(?P<counter>[^/]+)$`,
+			"aggregation":          "avg",
+			"target-name-template": "{{ .interface }}/{{ .counter }}_{{ .Aggregation }}",
+			"keep-values":          true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"ethernet-1/queue/0/dropped": 1,
+							"ethernet-1/queue/1/dropped": 3,
+							"ethernet-1/queue/0/sent":    10,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"ethernet-1/queue/0/dropped": 1,
+							"ethernet-1/queue/1/dropped": 3,
+							"ethernet-1/queue/0/sent":    10,
+							"ethernet-1/dropped_avg":     float64(2),
+							"ethernet-1/sent_avg":        float64(10),
+						},
+					},
+				},
+			},
+		},
+	},
+	"max_count": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"pattern":     `^queue/[^/]+/(dropped)$`,
+			"aggregation": "max",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"queue/0/dropped": 5,
+							"queue/1/dropped": 7,
+							"queue/2/dropped": 6,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"dropped": float64(7)},
+					},
+				},
+			},
+		},
+	},
+	"count": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"pattern":              `^queue/[^/]+/(dropped)$`,
+			"aggregation":          "count",
+			"target-name-template": "queues",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"queue/0/dropped": 5,
+							"queue/1/dropped": 7,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"queues": 2},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventReduce(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventReduceInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_pattern":     {"aggregation": "sum"},
+		"bad_pattern":         {"pattern": "("},
+		"no_capture_group":    {"pattern": "^queue/.*$"},
+		"unknown_aggregation": {"pattern": "^(.*)/queue", "aggregation": "median"},
+		"bad_template":        {"pattern": "^(.*)/queue", "target-name-template": "{{ .Group"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Reduce{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package event_regex_replace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-regex-replace"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// RegexReplace replaces the matches of a regex in the string values (and tag values) with key matching one of the regexes,
+// the replacement string supports captures, e.g ${1}. The result can then be converted to upper or lower case.
+type RegexReplace struct {
+	formatters.EventProcessor
+
+	Values      []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Tags        []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	Pattern     string   `mapstructure:"pattern,omitempty" json:"pattern,omitempty"`
+	Replacement string   `mapstructure:"replacement,omitempty" json:"replacement,omitempty"`
+	Case        string   `mapstructure:"case,omitempty" json:"case,omitempty"`
+	Debug       bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values  []*regexp.Regexp
+	tags    []*regexp.Regexp
+	pattern *regexp.Regexp
+	logger  *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &RegexReplace{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (r *RegexReplace) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.Pattern == "" {
+		return errors.New(processorType + ": missing pattern")
+	}
+	r.pattern, err = regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	r.Case = strings.ToLower(r.Case)
+	switch r.Case {
+	case "", "upper", "lower":
+	default:
+		return fmt.Errorf("%s: unknown case %q, must be one of upper or lower", processorType, r.Case)
+	}
+	r.values, err = compileRegexes(r.Values)
+	if err != nil {
+		return err
+	}
+	r.tags, err = compileRegexes(r.Tags)
+	if err != nil {
+		return err
+	}
+	if r.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *RegexReplace) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			for _, re := range r.values {
+				if re.MatchString(k) {
+					r.logger.Printf("value key '%s' matched regex '%s'", k, re.String())
+					s, ok := v.(string)
+					if !ok {
+						r.logger.Printf("value key '%s', skipping non string value %v", k, v)
+						break
+					}
+					e.Values[k] = r.replace(s)
+					r.logger.Printf("value key '%s', value %q replaced with %q", k, s, e.Values[k])
+					break
+				}
+			}
+		}
+		for k, v := range e.Tags {
+			for _, re := range r.tags {
+				if re.MatchString(k) {
+					r.logger.Printf("tag key '%s' matched regex '%s'", k, re.String())
+					e.Tags[k] = r.replace(v)
+					r.logger.Printf("tag key '%s', value %q replaced with %q", k, v, e.Tags[k])
+					break
+				}
+			}
+		}
+	}
+	return es
+}
+
+func (r *RegexReplace) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (r *RegexReplace) replace(s string) string {
+	s = r.pattern.ReplaceAllString(s, r.Replacement)
+	switch r.Case {
+	case "upper":
+		return strings.ToUpper(s)
+	case "lower":
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func compileRegexes(regs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(regs))
+	for _, reg := range regs {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
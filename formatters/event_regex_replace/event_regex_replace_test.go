@@ -0,0 +1,155 @@
+package event_regex_replace
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"capture_group": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"description$"},
+			"pattern":     `^port-(\d+)/(\d+)$`,
+			"replacement": "ethernet-${1}/${2}",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"interface_name": "port-1/1"},
+						Values: map[string]interface{}{
+							"/interface/description": "port-1/1",
+							"/interface/other":       "port-1/2",
+							"/sub/description":       "uplink",
+							"/int/description":       10,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"interface_name": "port-1/1"},
+						Values: map[string]interface{}{
+							"/interface/description": "ethernet-1/1",
+							"/interface/other":       "port-1/2",
+							"/sub/description":       "uplink",
+							"/int/description":       10,
+						},
+					},
+				},
+			},
+		},
+	},
+	"tags_case": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"oper-status$"},
+			"tag-names":   []string{"^interface_name$"},
+			"pattern":     `^if-`,
+			"replacement": "",
+			"case":        "lower",
+			"debug":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name": "if-Ethernet1",
+							"source":         "Router1",
+						},
+						Values: map[string]interface{}{
+							"/interface/oper-status": "UP",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name": "ethernet1",
+							"source":         "Router1",
+						},
+						Values: map[string]interface{}{
+							"/interface/oper-status": "up",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRegexReplace(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventRegexReplaceInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_pattern": {"value-names": []string{".*"}},
+		"invalid_pattern": {"pattern": "(", "value-names": []string{".*"}},
+		"unknown_case":    {"pattern": "a", "case": "title"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &RegexReplace{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
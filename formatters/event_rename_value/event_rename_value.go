@@ -0,0 +1,150 @@
+package event_rename_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-rename-value"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const (
+	collisionOverwrite = "overwrite"
+	collisionSkip      = "skip"
+)
+
+// RenameValue renames the values with a name matching one of the .Renames regexes,
+// the new name supports the regex captures, e.g ${1}.
+// The first matching rename applies. If the new name is already used by another value,
+// it is overwritten or the rename is skipped depending on .OnCollision.
+type RenameValue struct {
+	formatters.EventProcessor
+
+	Renames     []*Rename `mapstructure:"renames,omitempty" json:"renames,omitempty"`
+	OnCollision string    `mapstructure:"on-collision,omitempty" json:"on-collision,omitempty"`
+	Debug       bool      `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+// Rename defines a value name regex and its replacement
+type Rename struct {
+	Old string `mapstructure:"old,omitempty" json:"old,omitempty"`
+	New string `mapstructure:"new,omitempty" json:"new,omitempty"`
+
+	re *regexp.Regexp
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &RenameValue{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (r *RenameValue) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(r.Renames) == 0 {
+		return errors.New(processorType + ": missing renames")
+	}
+	for _, rn := range r.Renames {
+		if rn.Old == "" {
+			return errors.New(processorType + ": missing old value name")
+		}
+		if rn.New == "" {
+			return fmt.Errorf("%s: missing new value name for %q", processorType, rn.Old)
+		}
+		rn.re, err = regexp.Compile(rn.Old)
+		if err != nil {
+			return err
+		}
+	}
+	r.OnCollision = strings.ToLower(r.OnCollision)
+	switch r.OnCollision {
+	case "":
+		r.OnCollision = collisionOverwrite
+	case collisionOverwrite, collisionSkip:
+	default:
+		return fmt.Errorf("%s: unknown on-collision %q, must be one of %s or %s", processorType, r.OnCollision, collisionOverwrite, collisionSkip)
+	}
+	if r.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *RenameValue) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		// the value names are sorted so that the collisions are handled in a stable order,
+		// the renamed values are not renamed again
+		names := make([]string, 0, len(e.Values))
+		for k := range e.Values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			newName, ok := r.newName(k)
+			if !ok || newName == k {
+				continue
+			}
+			if _, exists := e.Values[newName]; exists && r.OnCollision == collisionSkip {
+				r.logger.Printf("skipping rename of value %q: %q already exists", k, newName)
+				continue
+			}
+			r.logger.Printf("renaming value %q to %q", k, newName)
+			e.Values[newName] = e.Values[k]
+			delete(e.Values, k)
+		}
+		// the deleted paths are renamed like the values they delete
+		for i, d := range e.Deletes {
+			if newName, ok := r.newName(d); ok {
+				e.Deletes[i] = newName
+			}
+		}
+	}
+	return es
+}
+
+func (r *RenameValue) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// newName returns the name resulting from the first rename matching name
+func (r *RenameValue) newName(name string) (string, bool) {
+	for _, rn := range r.Renames {
+		if rn.re.MatchString(name) {
+			return rn.re.ReplaceAllString(name, rn.New), true
+		}
+	}
+	return "", false
+}
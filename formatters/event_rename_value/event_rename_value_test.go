@@ -0,0 +1,225 @@
+package event_rename_value
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"direct_rename": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"renames": []map[string]interface{}{
+				{"old": "^octetsIn$", "new": "in_octets"},
+			},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"octetsIn":  100,
+							"octetsOut": 200,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"in_octets": 100,
+							"octetsOut": 200,
+						},
+					},
+				},
+			},
+		},
+	},
+	"rename_deletes": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"renames": []map[string]interface{}{
+				{"old": "^/interfaces/interface", "new": "/if"},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interfaces/interface/state", "/system"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/if/state", "/system"},
+					},
+				},
+			},
+		},
+	},
+	"regex_capture_rename": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"renames": []map[string]interface{}{
+				{"old": `^(.*)/octets(In|Out)$`, "new": "${1}/${2}_octets"},
+				{"old": `/counters/(.*)$`, "new": "/stats/${1}"},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/counters/octetsIn":  100,
+							"/interface/counters/octetsOut": 200,
+							"/interface/counters/errors":    1,
+							"/interface/mtu":                1500,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/counters/In_octets":  100,
+							"/interface/counters/Out_octets": 200,
+							"/interface/stats/errors":        1,
+							"/interface/mtu":                 1500,
+						},
+					},
+				},
+			},
+		},
+	},
+	"collision_overwrite": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"renames": []map[string]interface{}{
+				{"old": "^octetsIn$", "new": "in_octets"},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"octetsIn":  100,
+							"in_octets": 1,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"in_octets": 100,
+						},
+					},
+				},
+			},
+		},
+	},
+	"collision_skip": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"renames": []map[string]interface{}{
+				{"old": "^octetsIn$", "new": "in_octets"},
+			},
+			"on-collision": "skip",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"octetsIn":  100,
+							"in_octets": 1,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"octetsIn":  100,
+							"in_octets": 1,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRenameValue(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventRenameValueInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_renames": {"on-collision": "skip"},
+		"missing_old":     {"renames": []map[string]interface{}{{"new": "in_octets"}}},
+		"missing_new":     {"renames": []map[string]interface{}{{"old": "octetsIn"}}},
+		"bad_regex":       {"renames": []map[string]interface{}{{"old": "(", "new": "in_octets"}}},
+		"unknown_collision": {
+			"renames":      []map[string]interface{}{{"old": "octetsIn", "new": "in_octets"}},
+			"on-collision": "merge",
+		},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &RenameValue{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package event_round
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-round"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// Round rounds the numeric values with a name matching one of the Values regexes,
+// either to Precision decimal places or to the nearest Multiple.
+// Non numeric values are left unchanged.
+type Round struct {
+	formatters.EventProcessor
+
+	Values    []string `mapstructure:"values,omitempty" json:"values,omitempty"`
+	Precision *int     `mapstructure:"precision,omitempty" json:"precision,omitempty"`
+	Multiple  float64  `mapstructure:"multiple,omitempty" json:"multiple,omitempty"`
+	Debug     bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values []*regexp.Regexp
+	// number of decimal places of Multiple,
+	// used to clear the floating point error of the multiplication
+	multipleDecimals int
+	logger           *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Round{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (r *Round) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(r.Values) == 0 {
+		return errors.New(processorType + ": missing values")
+	}
+	switch {
+	case r.Precision == nil && r.Multiple == 0:
+		return errors.New(processorType + ": one of precision or multiple must be set")
+	case r.Precision != nil && r.Multiple != 0:
+		return errors.New(processorType + ": precision and multiple are mutually exclusive")
+	case r.Precision != nil && *r.Precision < 0:
+		return fmt.Errorf("%s: precision must be a positive number, got %d", processorType, *r.Precision)
+	case r.Multiple < 0:
+		return fmt.Errorf("%s: multiple must be a positive number, got %v", processorType, r.Multiple)
+	}
+	if r.Multiple != 0 {
+		s := strconv.FormatFloat(r.Multiple, 'f', -1, 64)
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			r.multipleDecimals = len(s) - i - 1
+		}
+	}
+	r.values = make([]*regexp.Regexp, 0, len(r.Values))
+	for _, reg := range r.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		r.values = append(r.values, re)
+	}
+	if r.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *Round) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for k, v := range e.Values {
+			for _, re := range r.values {
+				if !re.MatchString(k) {
+					continue
+				}
+				nv, ok := r.round(v)
+				if !ok {
+					r.logger.Printf("value '%s' is not a number, skipping: %v", k, v)
+					break
+				}
+				r.logger.Printf("value '%s' rounded from %v to %v", k, v, nv)
+				e.Values[k] = nv
+				break
+			}
+		}
+	}
+	return es
+}
+
+func (r *Round) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// round returns v rounded as per the processor config.
+// Integer values are already rounded to any number of decimal places,
+// they are returned as is unless a multiple is set.
+func (r *Round) round(v interface{}) (interface{}, bool) {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if r.Multiple == 0 {
+			return v, true
+		}
+		f, _ = strconv.ParseFloat(fmt.Sprint(v), 64)
+	default:
+		return nil, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return v, true
+	}
+	if r.Multiple != 0 {
+		return roundDecimals(math.Round(f/r.Multiple)*r.Multiple, r.multipleDecimals), true
+	}
+	return roundDecimals(f, *r.Precision), true
+}
+
+// roundDecimals rounds f to n decimal places, halfway values are rounded away from zero.
+func roundDecimals(f float64, n int) float64 {
+	scale := math.Pow10(n)
+	return math.Round(f*scale) / scale
+}
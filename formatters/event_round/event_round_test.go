@@ -0,0 +1,213 @@
+package event_round
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"precision": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"values":    []string{"^cpu/"},
+			"precision": 2,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu/usage": 99.99999999,
+							"cpu/load":  float32(1.23456),
+							"cpu/idle":  -12.3456,
+							"cpu/cores": 8,
+							"cpu/name":  "x86",
+							"mem/usage": 42.12345,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu/usage": float64(100),
+							"cpu/load":  1.23,
+							"cpu/idle":  -12.35,
+							"cpu/cores": 8,
+							"cpu/name":  "x86",
+							"mem/usage": 42.12345,
+						},
+					},
+				},
+			},
+		},
+	},
+	"precision_zero": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"values":    []string{".*"},
+			"precision": 0,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"a": 2.5, "b": -2.5, "c": -0.4},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"a": float64(3), "b": float64(-3), "c": float64(0)},
+					},
+				},
+			},
+		},
+	},
+	"multiple": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"values":   []string{"temperature$"},
+			"multiple": 0.5,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu/temperature":   41.3,
+							"board/temperature": 41.8,
+							"fan/temperature":   -3.74,
+							"psu/temperature":   uint32(40),
+							"name":              41.3,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"cpu/temperature":   41.5,
+							"board/temperature": float64(42),
+							"fan/temperature":   -3.5,
+							"psu/temperature":   float64(40),
+							"name":              41.3,
+						},
+					},
+				},
+			},
+		},
+	},
+	"multiple_integers": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"values":   []string{"octets"},
+			"multiple": 1000,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"in-octets": int64(123456), "out-octets": int64(-2500), "delta-octets": "1234"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"in-octets": float64(123000), "out-octets": float64(-3000), "delta-octets": "1234"},
+					},
+				},
+			},
+		},
+	},
+	"multiple_decimal": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"values":   []string{".*"},
+			"multiple": 0.1,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"a": 2.34, "b": -0.76},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"a": 2.3, "b": -0.8},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRound(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventRoundInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_values":         {"precision": 2},
+		"missing_rounding":       {"values": []string{".*"}},
+		"precision_and_multiple": {"values": []string{".*"}, "precision": 2, "multiple": 5},
+		"negative_precision":     {"values": []string{".*"}, "precision": -1},
+		"negative_multiple":      {"values": []string{".*"}, "multiple": -5},
+		"invalid_regex":          {"values": []string{"("}, "precision": 2},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &Round{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := r.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package event_static
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-static"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// matches ${hostname} and ${env:VAR}
+var tokenRegex = regexp.MustCompile(`\$\{(hostname|env:([^}]*))\}`)
+
+// Static adds a set of constant tags to all the event messages.
+// The tags values can reference the collector hostname with ${hostname},
+// and environment variables with ${env:VAR}, both are resolved once, at initialization.
+type Static struct {
+	formatters.EventProcessor
+
+	Tags      map[string]string `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	Overwrite bool              `mapstructure:"overwrite,omitempty" json:"overwrite,omitempty"`
+	Debug     bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	tags   map[string]string
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &Static{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (s *Static) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, s)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.Tags) == 0 {
+		return fmt.Errorf("%s: missing tags", processorType)
+	}
+	s.tags = make(map[string]string, len(s.Tags))
+	for k, v := range s.Tags {
+		s.tags[k], err = resolveTokens(v)
+		if err != nil {
+			return fmt.Errorf("%s: tag %q: %v", processorType, k, err)
+		}
+	}
+	if s.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(s)
+		if err != nil {
+			s.logger.Printf("initialized processor '%s': %+v", processorType, s)
+			return nil
+		}
+		s.logger.Printf("initialized processor '%s': %s, resolved tags: %v", processorType, string(b), s.tags)
+	}
+	return nil
+}
+
+func (s *Static) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(s.tags))
+		}
+		for k, v := range s.tags {
+			if _, ok := e.Tags[k]; ok && !s.Overwrite {
+				continue
+			}
+			e.Tags[k] = v
+		}
+	}
+	return es
+}
+
+func (s *Static) WithLogger(l *log.Logger) {
+	if s.Debug && l != nil {
+		s.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if s.Debug {
+		s.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// resolveTokens replaces the ${hostname} and ${env:VAR} tokens in v.
+// It fails if the hostname cannot be determined or if an environment variable is not set.
+func resolveTokens(v string) (string, error) {
+	var err error
+	res := tokenRegex.ReplaceAllStringFunc(v, func(token string) string {
+		if err != nil {
+			return ""
+		}
+		m := tokenRegex.FindStringSubmatch(token)
+		if m[1] == "hostname" {
+			var h string
+			h, err = os.Hostname()
+			return h
+		}
+		if m[2] == "" {
+			err = fmt.Errorf("missing environment variable name in %q", token)
+			return ""
+		}
+		ev, ok := os.LookupEnv(m[2])
+		if !ok {
+			err = fmt.Errorf("environment variable %q is not set", m[2])
+		}
+		return ev
+	})
+	if err != nil {
+		return "", err
+	}
+	return res, nil
+}
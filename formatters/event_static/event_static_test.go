@@ -0,0 +1,190 @@
+package event_static
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const testEnvVar = "GNMIC_TEST_EVENT_STATIC_SITE"
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testHostname, _ = os.Hostname()
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"constant_tags": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tags": map[string]string{"region": "eu", "dc": "dc1"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"region": "eu", "dc": "dc1"},
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"region": "us", "source": "router1"},
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"region": "us", "dc": "dc1", "source": "router1"},
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+			},
+		},
+	},
+	"overwrite": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tags":      map[string]string{"region": "eu"},
+			"overwrite": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"region": "us", "source": "router1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"region": "eu", "source": "router1"},
+					},
+				},
+			},
+		},
+	},
+	"hostname": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tags": map[string]string{"collector": "${hostname}", "collector_fqdn": "${hostname}.example.com"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":         "router1",
+							"collector":      testHostname,
+							"collector_fqdn": testHostname + ".example.com",
+						},
+					},
+				},
+			},
+		},
+	},
+	"env": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"tags": map[string]string{"site": "${env:" + testEnvVar + "}", "location": "${env:" + testEnvVar + "}/${hostname}"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{"source": "router1"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"source":   "router1",
+							"site":     "paris",
+							"location": "paris/" + testHostname,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventStatic(t *testing.T) {
+	os.Setenv(testEnvVar, "paris")
+	defer os.Unsetenv(testEnvVar)
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventStaticInit(t *testing.T) {
+	os.Unsetenv(testEnvVar)
+	tests := map[string]map[string]interface{}{
+		"missing_tags":    {},
+		"unset_env":       {"tags": map[string]string{"site": "${env:" + testEnvVar + "}"}},
+		"missing_env_var": {"tags": map[string]string{"site": "${env:}"}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Static{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/karimra/gnmic/formatters"
@@ -32,6 +34,8 @@ type Strings struct {
 	values    []*regexp.Regexp
 	tagKeys   []*regexp.Regexp
 	valueKeys []*regexp.Regexp
+	// transforms in the order they are applied
+	ops []*transform
 
 	logger *log.Logger
 }
@@ -58,6 +62,8 @@ type transform struct {
 	IgnoreFirst int `mapstructure:"ignore-first,omitempty" json:"ignore-first,omitempty"`
 	// number of last items to ignore when joining
 	IgnoreLast int `mapstructure:"ignore-last,omitempty" json:"ignore-last,omitempty"`
+	// split the value into indexed values instead of joining the items
+	Indexed bool `mapstructure:"indexed,omitempty" json:"indexed,omitempty"`
 }
 
 func init() {
@@ -76,9 +82,18 @@ func (s *Strings) Init(cfg interface{}, opts ...formatters.Option) error {
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.ops = make([]*transform, 0, len(s.Transforms))
 	for i := range s.Transforms {
+		// the transforms are applied in the list order,
+		// multiple transforms in the same list item are applied in alphabetical order
+		names := make([]string, 0, len(s.Transforms[i]))
 		for k := range s.Transforms[i] {
 			s.Transforms[i][k].op = k
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			s.ops = append(s.ops, s.Transforms[i][k])
 		}
 	}
 	// init tags regex
@@ -149,6 +164,24 @@ func (s *Strings) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 				}
 			}
 		}
+		// the deleted paths are value names, they get the same name transforms
+		numDeletes := len(e.Deletes)
+		for i := 0; i < numDeletes; i++ {
+			d := e.Deletes[i]
+			for _, re := range s.valueKeys {
+				if re.MatchString(d) {
+					s.logger.Printf("deleted path '%s' matched regex '%s'", d, re.String())
+					nd, keep := s.applyNameOps(d)
+					if keep {
+						// the values keep their old name as well
+						e.Deletes = append(e.Deletes, nd)
+					} else {
+						e.Deletes[i] = nd
+					}
+					break
+				}
+			}
+		}
 		for k, v := range e.Tags {
 			for _, re := range s.tagKeys {
 				if re.MatchString(k) {
@@ -176,34 +209,90 @@ func (s *Strings) WithLogger(l *log.Logger) {
 }
 
 func (s *Strings) applyValueTransformations(e *formatters.EventMsg, k string, v interface{}) {
-	for _, trans := range s.Transforms {
-		for _, t := range trans {
+	s.applyValueOps(e, k, v, s.ops)
+}
+
+// applyValueOps applies the transforms ops to the value k in order.
+// An indexed split replaces the value with one value per item,
+// the remaining transforms are then applied to each of them.
+func (s *Strings) applyValueOps(e *formatters.EventMsg, k string, v interface{}, ops []*transform) {
+	for i, t := range ops {
+		if t.indexedSplit() {
+			vs, ok := v.(string)
+			if !ok {
+				continue
+			}
 			if !t.Keep {
 				delete(e.Values, k)
 			}
-			k, v = t.apply(k, v)
-			e.Values[k] = v
+			for j, item := range t.splitItems(vs) {
+				ik := indexedName(k, j)
+				e.Values[ik] = item
+				s.applyValueOps(e, ik, item, ops[i+1:])
+			}
+			return
 		}
+		if !t.Keep {
+			delete(e.Values, k)
+		}
+		k, v = t.apply(k, v)
+		e.Values[k] = v
 	}
 }
 
+// applyNameOps returns name transformed by the transforms applying on names,
+// and true if one of them keeps the old name.
+func (s *Strings) applyNameOps(name string) (string, bool) {
+	keep := false
+	for _, t := range s.ops {
+		if t.ApplyOn != "name" {
+			continue
+		}
+		keep = keep || t.Keep
+		name, _ = t.apply(name, nil)
+	}
+	return name, keep
+}
+
 func (s *Strings) applyTagTransformations(e *formatters.EventMsg, k, v string) {
-	for _, trans := range s.Transforms {
-		for _, t := range trans {
+	s.applyTagOps(e, k, v, s.ops)
+}
+
+// applyTagOps applies the transforms ops to the tag k in order.
+// An indexed split replaces the tag with one tag per item,
+// the remaining transforms are then applied to each of them.
+func (s *Strings) applyTagOps(e *formatters.EventMsg, k, v string, ops []*transform) {
+	for i, t := range ops {
+		if t.indexedSplit() {
 			if !t.Keep {
 				delete(e.Tags, k)
 			}
-			var vi interface{}
-			k, vi = t.apply(k, v)
-			if vs, ok := vi.(string); ok {
-				e.Tags[k] = vs
-			} else {
-				s.logger.Printf("failed to assert %v type as string", vi)
+			for j, item := range t.splitItems(v) {
+				ik := indexedName(k, j)
+				e.Tags[ik] = item
+				s.applyTagOps(e, ik, item, ops[i+1:])
 			}
+			return
+		}
+		if !t.Keep {
+			delete(e.Tags, k)
+		}
+		var vi interface{}
+		k, vi = t.apply(k, v)
+		if vs, ok := vi.(string); ok {
+			v = vs
+			e.Tags[k] = vs
+		} else {
+			s.logger.Printf("failed to assert %v type as string", vi)
 		}
 	}
 }
 
+// indexedName returns the name of the item at index i of the indexed split of k.
+func indexedName(k string, i int) string {
+	return k + "_" + strconv.Itoa(i)
+}
+
 func (t *transform) apply(k string, v interface{}) (string, interface{}) {
 	switch t.op {
 	case "replace":
@@ -301,25 +390,30 @@ func (t *transform) toUpper(k string, v interface{}) (string, interface{}) {
 func (t *transform) split(k string, v interface{}) (string, interface{}) {
 	switch t.ApplyOn {
 	case "name":
-		items := strings.Split(k, t.SplitOn)
-		numItems := len(items)
-		if numItems <= t.IgnoreFirst || numItems <= t.IgnoreLast || t.IgnoreFirst >= numItems-t.IgnoreLast {
-			return "", v
-		}
-		k = strings.Join(items[t.IgnoreFirst:numItems-t.IgnoreLast], t.JoinWith)
+		k = strings.Join(t.splitItems(k), t.JoinWith)
 	case "value":
 		if vs, ok := v.(string); ok {
-			items := strings.Split(vs, t.SplitOn)
-			numItems := len(items)
-			if numItems <= t.IgnoreFirst || numItems <= t.IgnoreLast || t.IgnoreFirst >= numItems-t.IgnoreLast {
-				return k, ""
-			}
-			v = strings.Join(items[t.IgnoreFirst:numItems-t.IgnoreLast], t.JoinWith)
+			v = strings.Join(t.splitItems(vs), t.JoinWith)
 		}
 	}
 	return k, v
 }
 
+// splitItems splits s and returns the items left after ignoring the first and last ones.
+func (t *transform) splitItems(s string) []string {
+	items := strings.Split(s, t.SplitOn)
+	numItems := len(items)
+	if numItems <= t.IgnoreFirst || numItems <= t.IgnoreLast || t.IgnoreFirst >= numItems-t.IgnoreLast {
+		return nil
+	}
+	return items[t.IgnoreFirst : numItems-t.IgnoreLast]
+}
+
+// indexedSplit returns true if the transform splits a value into indexed values.
+func (t *transform) indexedSplit() bool {
+	return t.op == "split" && t.Indexed && t.ApplyOn == "value"
+}
+
 func (t *transform) pathBase(k string, v interface{}) (string, interface{}) {
 	switch t.ApplyOn {
 	case "name":
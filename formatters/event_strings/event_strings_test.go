@@ -17,6 +17,67 @@ var testset = map[string]struct {
 	processor     map[string]interface{}
 	tests         []item
 }{
+	"trim_prefix_deletes": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^/interfaces/"},
+			"transforms": []map[string]*transform{
+				{
+					"trim-prefix": &transform{
+						ApplyOn: "name",
+						Prefix:  "/interfaces/",
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interfaces/interface/state", "/system"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"interface/state", "/system"},
+					},
+				},
+			},
+		},
+	},
+	"trim_prefix_deletes_keep": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^/interfaces/"},
+			"transforms": []map[string]*transform{
+				{
+					"trim-prefix": &transform{
+						ApplyOn: "name",
+						Prefix:  "/interfaces/",
+						Keep:    true,
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interfaces/interface"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values:  map[string]interface{}{},
+						Deletes: []string{"/interfaces/interface", "interface"},
+					},
+				},
+			},
+		},
+	},
 	"replace": {
 		processorType: processorType,
 		processor: map[string]interface{}{
@@ -453,6 +514,126 @@ var testset = map[string]struct {
 			},
 		},
 	},
+	"split_indexed": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^/interface/name$"},
+			"tag-names":   []string{"^interface_name$"},
+			"transforms": []map[string]*transform{
+				{
+					"split": &transform{
+						ApplyOn: "value",
+						SplitOn: "/",
+						Indexed: true,
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name": "ethernet-1/2",
+						},
+						Values: map[string]interface{}{
+							"/interface/name": "ethernet-1/1",
+							"/interface/mtu":  "1500",
+						}},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name_0": "ethernet-1",
+							"interface_name_1": "2",
+						},
+						Values: map[string]interface{}{
+							"/interface/name_0": "ethernet-1",
+							"/interface/name_1": "1",
+							"/interface/mtu":    "1500",
+						}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/name": 1,
+						}},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/name": 1,
+						}},
+				},
+			},
+		},
+	},
+	"chained": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"^/interface/description$"},
+			"tag-names":   []string{"^interface_name$"},
+			"transforms": []map[string]*transform{
+				{
+					"trim-prefix": &transform{
+						ApplyOn: "value",
+						Prefix:  "if:",
+					},
+				},
+				{
+					"split": &transform{
+						ApplyOn: "value",
+						SplitOn: "/",
+						Indexed: true,
+					},
+				},
+				{
+					"replace": &transform{
+						ApplyOn: "value",
+						Old:     "Ethernet",
+						New:     "Eth",
+					},
+				},
+				{
+					"to-lower": &transform{
+						ApplyOn: "value",
+					},
+				},
+				{
+					"trim-suffix": &transform{
+						ApplyOn: "name",
+						Suffix:  "_0",
+					},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name": "if:Ethernet1/1",
+						},
+						Values: map[string]interface{}{
+							"/interface/description": "if:Ethernet2/1",
+						}},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"interface_name":   "eth1",
+							"interface_name_1": "1",
+						},
+						Values: map[string]interface{}{
+							"/interface/description":   "eth2",
+							"/interface/description_1": "1",
+						}},
+				},
+			},
+		},
+	},
 }
 
 func TestEventStrings(t *testing.T) {
@@ -195,3 +195,74 @@ func TestResponseToEventMsgs(t *testing.T) {
 	//b, _ := json.MarshalIndent(evs, "", "  ")
 	//fmt.Println(string(b))
 }
+
+func TestResponseToEventMsgsSplitDeletes(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Prefix: &gnmi.Path{
+					Elem: []*gnmi.PathElem{
+						{Name: "interfaces"},
+					},
+				},
+				Delete: []*gnmi.Path{
+					{
+						Elem: []*gnmi.PathElem{
+							{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+							{Name: "state"},
+						},
+					},
+					{
+						Elem: []*gnmi.PathElem{
+							{Name: "interface", Key: map[string]string{"name": "ethernet-1/2"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	evs, err := ResponseToEventMsgsSplitDeletes("sub1", rsp, map[string]string{"source": "router1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: 42,
+			Tags:      map[string]string{"interface_name": "ethernet-1/1", "source": "router1"},
+			Values:    map[string]interface{}{},
+			Deletes:   []string{"/interfaces/interface/state"},
+		},
+		{
+			Name:      "sub1",
+			Timestamp: 42,
+			Tags:      map[string]string{"interface_name": "ethernet-1/2", "source": "router1"},
+			Values:    map[string]interface{}{},
+			Deletes:   []string{"/interfaces/interface"},
+		},
+	}
+	if !reflect.DeepEqual(evs, want) {
+		t.Errorf("unexpected delete events:")
+		t.Logf("expected: %+v", want)
+		t.Logf("     got: %+v", evs)
+	}
+	// without splitting, a single event holds all the deleted paths
+	evs, err = ResponseToEventMsgs("sub1", rsp, map[string]string{"source": "router1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []*EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: 42,
+			Tags:      map[string]string{"source": "router1"},
+			Deletes:   []string{"interface[name=ethernet-1/1]/state", "interface[name=ethernet-1/2]"},
+		},
+	}
+	if !reflect.DeepEqual(evs, want) {
+		t.Errorf("unexpected delete event:")
+		t.Logf("expected: %+v", want)
+		t.Logf("     got: %+v", evs)
+	}
+}
@@ -0,0 +1,94 @@
+package event_time_bucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-time-bucket"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// TimeBucket aligns the event messages timestamp down to the nearest multiple of the configured interval,
+// the bucketed timestamp can optionally be added as a tag
+type TimeBucket struct {
+	formatters.EventProcessor
+
+	Interval time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	TagName  string        `mapstructure:"tag-name,omitempty" json:"tag-name,omitempty"`
+	Debug    bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &TimeBucket{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (p *TimeBucket) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Interval <= 0 {
+		return fmt.Errorf("%s: interval must be a positive duration", processorType)
+	}
+	if p.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *TimeBucket) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		ts := bucket(e.Timestamp, int64(p.Interval))
+		p.logger.Printf("timestamp %d aligned to %d", e.Timestamp, ts)
+		e.Timestamp = ts
+		if p.TagName != "" {
+			if e.Tags == nil {
+				e.Tags = make(map[string]string)
+			}
+			e.Tags[p.TagName] = time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return es
+}
+
+func (p *TimeBucket) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+// bucket returns the largest multiple of interval lower than or equal to ts
+func bucket(ts, interval int64) int64 {
+	r := ts % interval
+	if r < 0 {
+		r += interval
+	}
+	return ts - r
+}
@@ -0,0 +1,177 @@
+package event_time_bucket
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"10s_interval": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "10s",
+			"debug":    true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input:  make([]*formatters.EventMsg, 0),
+				output: make([]*formatters.EventMsg, 0),
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1615284691523204299},
+					{Timestamp: 1615284690000000000},
+					{Timestamp: 1615284699999999999},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1615284690000000000},
+					{Timestamp: 1615284690000000000},
+					{Timestamp: 1615284690000000000},
+				},
+			},
+		},
+	},
+	"1m_interval": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "1m",
+			"debug":    true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1615284691523204299},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1615284660000000000},
+				},
+			},
+		},
+	},
+	"250ms_interval": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "250ms",
+			"debug":    true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1615284691523204299},
+					{Timestamp: 1615284691249999999},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1615284691500000000},
+					{Timestamp: 1615284691000000000},
+				},
+			},
+		},
+	},
+	"with_tag": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "15s",
+			"tag-name": "bucket",
+			"debug":    true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1615284691523204299,
+						Tags:      map[string]string{"source": "router1"},
+					},
+					{
+						Timestamp: 1615284707000000000,
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 1615284690000000000,
+						Tags: map[string]string{
+							"source": "router1",
+							"bucket": "2021-03-09T10:11:30Z",
+						},
+					},
+					{
+						Timestamp: 1615284705000000000,
+						Tags: map[string]string{
+							"bucket": "2021-03-09T10:11:45Z",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventTimeBucket(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.Fail()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	for _, tc := range []struct {
+		ts, interval, want int64
+	}{
+		{ts: 0, interval: 10, want: 0},
+		{ts: 9, interval: 10, want: 0},
+		{ts: 10, interval: 10, want: 10},
+		{ts: 25, interval: 10, want: 20},
+		{ts: -1, interval: 10, want: -10},
+		{ts: -10, interval: 10, want: -10},
+	} {
+		if got := bucket(tc.ts, tc.interval); got != tc.want {
+			t.Errorf("bucket(%d, %d): expected %d, got %d", tc.ts, tc.interval, tc.want, got)
+		}
+	}
+}
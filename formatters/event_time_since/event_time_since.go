@@ -0,0 +1,193 @@
+package event_time_since
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const (
+	processorType = "event-time-since"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+const ageSuffix = "_age_seconds"
+
+// TimeSince adds a value named <value-name>_age_seconds for each value matching one of the regexes in .ValueNames,
+// holding the number of seconds elapsed since the epoch timestamp it contains.
+// .Precision is the unit of the timestamps, s, ms, us or ns.
+// The age of a timestamp in the future is 0, unless .AllowNegative is true.
+type TimeSince struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+
+	ValueNames    []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Precision     string   `mapstructure:"precision,omitempty" json:"precision,omitempty"`
+	AllowNegative bool     `mapstructure:"allow-negative,omitempty" json:"allow-negative,omitempty"`
+	Debug         bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	// duration of one timestamp unit
+	unit time.Duration
+	now  func() time.Time
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &TimeSince{
+			logger: log.New(ioutil.Discard, "", 0),
+		}
+	})
+}
+
+func (t *TimeSince) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, t)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if len(t.ValueNames) == 0 {
+		return errors.New(processorType + ": missing value-names")
+	}
+	switch t.Precision {
+	case "", "s", "sec", "second":
+		t.unit = time.Second
+	case "ms", "millisecond":
+		t.unit = time.Millisecond
+	case "us", "microsecond":
+		t.unit = time.Microsecond
+	case "ns", "nanosecond":
+		t.unit = time.Nanosecond
+	default:
+		return fmt.Errorf("%s: unknown precision %q, must be one of s, ms, us or ns", processorType, t.Precision)
+	}
+	t.valueNames = make([]*regexp.Regexp, 0, len(t.ValueNames))
+	for _, reg := range t.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		t.valueNames = append(t.valueNames, re)
+	}
+	t.now = time.Now
+	if t.logger.Writer() != ioutil.Discard {
+		b, err := json.Marshal(t)
+		if err != nil {
+			t.logger.Printf("initialized processor '%s': %+v", processorType, t)
+			return nil
+		}
+		t.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (t *TimeSince) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	now := t.now()
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		// the ages are added once all the values are checked
+		ages := make(map[string]interface{})
+		for k, v := range e.Values {
+			if !t.match(k) {
+				continue
+			}
+			ts, err := toInt64(v)
+			if err != nil {
+				t.logger.Printf("failed to convert value %q=%v to a timestamp: %v", k, v, err)
+				t.ReportError(err)
+				continue
+			}
+			age := now.Sub(time.Unix(0, 0).Add(time.Duration(ts) * t.unit)).Seconds()
+			if age < 0 && !t.AllowNegative {
+				age = 0
+			}
+			ages[k+ageSuffix] = age
+		}
+		for k, v := range ages {
+			e.Values[k] = v
+		}
+	}
+	return es
+}
+
+func (t *TimeSince) WithLogger(l *log.Logger) {
+	if t.Debug && l != nil {
+		t.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if t.Debug {
+		t.logger = log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	}
+}
+
+func (t *TimeSince) match(name string) bool {
+	for _, re := range t.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toInt64 returns the integer value of v, numeric strings are parsed.
+func toInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return uintToInt64(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintToInt64(v)
+	case float32:
+		return floatToInt64(float64(v))
+	case float64:
+		return floatToInt64(v)
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func uintToInt64(u uint64) (int64, error) {
+	if u > math.MaxInt64 {
+		return 0, fmt.Errorf("value %d out of range", u)
+	}
+	return int64(u), nil
+}
+
+// floatToInt64 converts the numbers decoded from JSON
+func floatToInt64(f float64) (int64, error) {
+	if math.IsNaN(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("value %v out of range", f)
+	}
+	return int64(f), nil
+}
@@ -0,0 +1,219 @@
+package event_time_since
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+// testNow is the time the ages are calculated from
+var testNow = time.Unix(1600000000, 0)
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"past_timestamp": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"last-change$"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/last-change": 1599999940,
+							"/interface/mtu":         1500,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/last-change":             1599999940,
+							"/interface/last-change_age_seconds": float64(60),
+							"/interface/mtu":                     1500,
+						},
+					},
+				},
+			},
+			{
+				// values that are not timestamps are left unchanged
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/last-change": "never"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/interface/last-change": "never"},
+					},
+				},
+			},
+		},
+	},
+	"future_timestamp_clamped": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"last-change$"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"last-change": "1600000030"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"last-change":             "1600000030",
+							"last-change_age_seconds": float64(0),
+						},
+					},
+				},
+			},
+		},
+	},
+	"future_timestamp_negative": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names":    []string{"last-change$"},
+			"allow-negative": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"last-change": uint64(1600000030)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"last-change":             uint64(1600000030),
+							"last-change_age_seconds": float64(-30),
+						},
+					},
+				},
+			},
+		},
+	},
+	"precision_ms": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"last-change$"},
+			"precision":   "ms",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"last-change": float64(1599999998500)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"last-change":             float64(1599999998500),
+							"last-change_age_seconds": 1.5,
+						},
+					},
+				},
+			},
+		},
+	},
+	"precision_ns": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"value-names": []string{"last-change$"},
+			"precision":   "ns",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"last-change": int64(1599999990000000000)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"last-change":             int64(1599999990000000000),
+							"last-change_age_seconds": float64(10),
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventTimeSince(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor, formatters.WithLogger(log.New(os.Stderr, loggingPrefix, log.LstdFlags|log.Lmicroseconds)))
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			p.(*TimeSince).now = func() time.Time { return testNow }
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Logf("expected and gotten outputs are not of the same length")
+						t.Logf("expected: %+v", item.output)
+						t.Logf("     got: %+v", outs)
+						t.FailNow()
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Logf("failed at %s item %d, index %d", name, i, j)
+							t.Logf("expected: %+v", item.output[j])
+							t.Logf("     got: %+v", outs[j])
+							t.Fail()
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventTimeSinceInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_value_names": {"precision": "s"},
+		"unknown_precision":   {"value-names": []string{"last-change"}, "precision": "minute"},
+		"bad_regex":           {"value-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &TimeSince{logger: log.New(os.Stderr, loggingPrefix, 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
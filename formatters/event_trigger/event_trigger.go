@@ -25,6 +25,7 @@ const (
 // Trigger triggers an action when certain conditions are met
 type Trigger struct {
 	formatters.EventProcessor `mapstructure:"-"`
+	formatters.ErrorReporter  `mapstructure:"-"`
 
 	Condition      string                 `mapstructure:"condition,omitempty"`
 	MinOccurrences int                    `mapstructure:"min-occurrences,omitempty"`
@@ -93,6 +94,7 @@ func (p *Trigger) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 		res, err := formatters.CheckCondition(p.code, e)
 		if err != nil {
 			p.logger.Printf("failed evaluating condition %q: %v", p.Condition, err)
+			p.ReportError(err)
 			continue
 		}
 		if p.Debug {
@@ -178,6 +180,7 @@ func (p *Trigger) triggerAction(e *formatters.EventMsg) {
 		res, err := p.action.Run(e)
 		if err != nil {
 			p.logger.Printf("trigger action %+v failed: %+v", p.action, err)
+			p.ReportError(err)
 			return
 		}
 		p.logger.Printf("action result: %+v", res)
@@ -20,6 +20,7 @@ const (
 
 type Write struct {
 	formatters.EventProcessor
+	formatters.ErrorReporter
 	Condition  string   `mapstructure:"condition,omitempty"`
 	Tags       []string `mapstructure:"tags,omitempty" json:"tags,omitempty"`
 	Values     []string `mapstructure:"values,omitempty" json:"values,omitempty"`
@@ -56,6 +57,42 @@ func (p *Write) Init(cfg interface{}, opts ...formatters.Option) error {
 	for _, opt := range opts {
 		opt(p)
 	}
+	err = p.compile()
+	if err != nil {
+		return err
+	}
+	switch p.Dst {
+	case "", "stdout":
+		p.dst = os.Stdout
+	case "stderr":
+		p.dst = os.Stderr
+	default:
+		p.dst, err = os.OpenFile(p.Dst, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+		return nil
+	}
+	p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	return nil
+}
+
+// ValidateConfig implements formatters.ConfigValidator, it does not open the dst file.
+func (p *Write) ValidateConfig(cfg interface{}) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	return p.compile()
+}
+
+// compile compiles the condition and the regular expressions.
+func (p *Write) compile() error {
 	p.Condition = strings.TrimSpace(p.Condition)
 	q, err := gojq.Parse(p.Condition)
 	if err != nil {
@@ -105,24 +142,6 @@ func (p *Write) Init(cfg interface{}, opts ...formatters.Option) error {
 		}
 		p.valueNames = append(p.valueNames, re)
 	}
-	switch p.Dst {
-	case "", "stdout":
-		p.dst = os.Stdout
-	case "stderr":
-		p.dst = os.Stderr
-	default:
-		p.dst, err = os.OpenFile(p.Dst, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-		if err != nil {
-			return err
-		}
-	}
-
-	b, err := json.Marshal(p)
-	if err != nil {
-		p.logger.Printf("initialized processor '%s': %+v", processorType, p)
-		return nil
-	}
-	p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
 	return nil
 }
 
@@ -137,11 +156,13 @@ OUTER:
 			ok, err := formatters.CheckCondition(p.code, e)
 			if err != nil {
 				p.logger.Printf("condition check failed: %v", err)
+				p.ReportError(err)
 			}
 			if ok {
 				err := p.write(e)
 				if err != nil {
 					p.logger.Printf("failed to write to destination: %v", err)
+					p.ReportError(err)
 					continue OUTER
 				}
 			}
@@ -153,6 +174,7 @@ OUTER:
 						err := p.write(e)
 						if err != nil {
 							p.logger.Printf("failed to write to destination: %v", err)
+							p.ReportError(err)
 							continue OUTER
 						}
 						continue OUTER
@@ -164,6 +186,7 @@ OUTER:
 					err := p.write(e)
 					if err != nil {
 						p.logger.Printf("failed to write to destination: %v", err)
+						p.ReportError(err)
 						continue OUTER
 					}
 					continue OUTER
@@ -176,6 +199,7 @@ OUTER:
 					err := p.write(e)
 					if err != nil {
 						p.logger.Printf("failed to write to destination: %v", err)
+						p.ReportError(err)
 						continue OUTER
 					}
 					continue OUTER
@@ -186,6 +210,7 @@ OUTER:
 					err := p.write(e)
 					if err != nil {
 						p.logger.Printf("failed to write to destination: %v", err)
+						p.ReportError(err)
 						continue OUTER
 					}
 					continue OUTER
@@ -44,14 +44,7 @@ func (o *MarshalOptions) Marshal(msg proto.Message, meta map[string]string, eps
 				if err != nil {
 					return nil, fmt.Errorf("failed converting response to events: %v", err)
 				}
-				if o.Multiline {
-					b, err = json.MarshalIndent(events, "", o.Indent)
-				} else {
-					b, err = json.Marshal(events)
-				}
-				if err != nil {
-					return nil, fmt.Errorf("failed marshaling format 'event': %v", err)
-				}
+				return o.MarshalEvents(events)
 			}
 			return b, nil
 		default:
@@ -60,6 +53,21 @@ func (o *MarshalOptions) Marshal(msg proto.Message, meta map[string]string, eps
 	}
 }
 
+// MarshalEvents marshals events in format 'event', whatever the configured format.
+func (o *MarshalOptions) MarshalEvents(events []*EventMsg) ([]byte, error) {
+	var b []byte
+	var err error
+	if o.Multiline {
+		b, err = json.MarshalIndent(events, "", o.Indent)
+	} else {
+		b, err = json.Marshal(events)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed marshaling format 'event': %v", err)
+	}
+	return b, nil
+}
+
 // FormatJSON formats a proto.Message and returns a []byte and an error
 func (o *MarshalOptions) FormatJSON(m proto.Message, meta map[string]string) ([]byte, error) {
 	if m == nil {
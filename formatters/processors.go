@@ -3,6 +3,8 @@ package formatters
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 
 	"github.com/itchyny/gojq"
@@ -13,12 +15,36 @@ var EventProcessors = map[string]Initializer{}
 
 var EventProcessorTypes = []string{
 	"event-add-tag",
+	"event-base64-decode",
+	"event-bit-decode",
+	"event-coalesce-tags",
+	"event-combine",
+	"event-conditional",
 	"event-convert",
+	"event-data-convert",
 	"event-date-string",
 	"event-delete",
 	"event-drop",
+	"event-duration-convert",
+	"event-flatten",
+	"event-hash",
+	"event-index-tag",
+	"event-ip-enrich",
+	"event-jsonpath",
+	"event-keep",
+	"event-mac-normalize",
+	"event-normalize-path",
+	"event-outlier",
 	"event-override-ts",
+	"event-percentage",
+	"event-reduce",
+	"event-regex-replace",
+	"event-rename-value",
+	"event-round",
+	"event-static",
 	"event-strings",
+	"event-time-bucket",
+	"event-time-since",
 	"event-to-tag",
 	"event-write",
 	"event-merge",
@@ -40,6 +66,49 @@ type EventProcessor interface {
 	WithLogger(l *log.Logger)
 }
 
+// NewEventProcessor creates an event processor from cfg, a map with a single key,
+// the processor type, set to the processor config. The processor is initialized with opts.
+// It is used by the processors running nested processors.
+func NewEventProcessor(cfg map[string]interface{}, opts ...Option) (EventProcessor, error) {
+	if len(cfg) != 1 {
+		return nil, fmt.Errorf("a processor config must have a single type, got %d", len(cfg))
+	}
+	for epType, epCfg := range cfg {
+		in, ok := EventProcessors[epType]
+		if !ok {
+			return nil, fmt.Errorf("unknown processor type %q", epType)
+		}
+		ep := in()
+		err := ep.Init(epCfg, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed initializing processor of type %q: %v", epType, err)
+		}
+		return ep, nil
+	}
+	return nil, nil
+}
+
+// ConfigValidator is optionally implemented by the event processors whose Init has side effects,
+// e.g opening a file, it decodes and validates the processor config without them.
+type ConfigValidator interface {
+	ValidateConfig(interface{}) error
+}
+
+// ValidateEventProcessor validates cfg, the config of a processor of type epType,
+// without running the processor: it calls ValidateConfig if the processor implements ConfigValidator,
+// Init otherwise.
+func ValidateEventProcessor(epType string, cfg interface{}) error {
+	in, ok := EventProcessors[epType]
+	if !ok {
+		return fmt.Errorf("unknown processor type %q", epType)
+	}
+	ep := in()
+	if v, ok := ep.(ConfigValidator); ok {
+		return v.ValidateConfig(cfg)
+	}
+	return ep.Init(cfg, WithLogger(log.New(ioutil.Discard, "", 0)))
+}
+
 func DecodeConfig(src, dst interface{}) error {
 	decoder, err := mapstructure.NewDecoder(
 		&mapstructure.DecoderConfig{
@@ -63,6 +132,83 @@ func WithTargets(tcs map[string]interface{}) Option {
 	}
 }
 
+// WithErrorHandler sets the function called with the errors the processor
+// encounters while processing events, it is a no-op for processors not reporting their errors.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(p EventProcessor) {
+		if r, ok := p.(interface{ WithErrorHandler(ErrorHandler) }); ok {
+			r.WithErrorHandler(h)
+		}
+	}
+}
+
+// ErrorHandler is called with an error encountered while processing an event.
+type ErrorHandler func(error)
+
+// ErrorReporter is embedded by the event processors reporting
+// their runtime errors to the handler set with WithErrorHandler.
+type ErrorReporter struct {
+	errorHandler ErrorHandler
+}
+
+func (r *ErrorReporter) WithErrorHandler(h ErrorHandler) {
+	r.errorHandler = h
+}
+
+// ReportError calls the error handler, if any, with err.
+func (r *ErrorReporter) ReportError(err error) {
+	if r.errorHandler != nil && err != nil {
+		r.errorHandler(err)
+	}
+}
+
+// FlushHandler is called with the events a processor emits on its own, outside of a call to Apply.
+type FlushHandler func([]*EventMsg)
+
+// Flusher is implemented by the event processors holding events across calls to Apply,
+// e.g to merge them over a time window.
+type Flusher interface {
+	// WithFlushHandler sets the handler called with the held events once they are due.
+	WithFlushHandler(FlushHandler)
+	// Flush returns the held events without waiting for them to be due, e.g when the output closes.
+	Flush() []*EventMsg
+}
+
+// SetFlushHandlers sets the flush handler of the processors in eps implementing Flusher,
+// the flushed events go through the processors following the flusher before being passed to write.
+func SetFlushHandlers(eps []EventProcessor, write func([]*EventMsg)) {
+	for i, ep := range eps {
+		f, ok := ep.(Flusher)
+		if !ok {
+			continue
+		}
+		next := eps[i+1:]
+		f.WithFlushHandler(func(es []*EventMsg) {
+			for _, nep := range next {
+				es = nep.Apply(es...)
+			}
+			if len(es) > 0 {
+				write(es)
+			}
+		})
+	}
+}
+
+// FlushProcessors flushes the events held by the processors in eps implementing Flusher,
+// the flushed events go through the processors following the flusher.
+func FlushProcessors(eps []EventProcessor) []*EventMsg {
+	var es []*EventMsg
+	for _, ep := range eps {
+		if len(es) > 0 {
+			es = ep.Apply(es...)
+		}
+		if f, ok := ep.(Flusher); ok {
+			es = append(es, f.Flush()...)
+		}
+	}
+	return es
+}
+
 func CheckCondition(code *gojq.Code, e *EventMsg) (bool, error) {
 	var res interface{}
 	if code != nil {
@@ -0,0 +1,137 @@
+package file_loader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+)
+
+// csvListSeparator separates the values of list columns (subscriptions, outputs, tags)
+const csvListSeparator = ";"
+
+// parseCSV parses a CSV targets file, the first row is a header row
+// listing the column names, the 'address' column is mandatory.
+// empty cells are ignored.
+func parseCSV(b []byte) (map[string]*collector.TargetConfig, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	hasAddress := false
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+		switch header[i] {
+		case "name", "username", "password", "insecure", "skip-verify",
+			"tls-ca", "tls-cert", "tls-key", "timeout", "subscriptions", "outputs", "tags":
+		case "address":
+			hasAddress = true
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", col)
+		}
+	}
+	if !hasAddress {
+		return nil, errors.New("missing 'address' column in CSV header row")
+	}
+	targets := make(map[string]*collector.TargetConfig)
+	row := 1
+	for {
+		row++
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tc := new(collector.TargetConfig)
+		for i, v := range record {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			err = setCSVField(tc, header[i], v)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+		}
+		if tc.Address == "" {
+			return nil, fmt.Errorf("row %d: missing target address", row)
+		}
+		name := tc.Name
+		if name == "" {
+			name = tc.Address
+		}
+		if _, ok := targets[name]; ok {
+			return nil, fmt.Errorf("row %d: duplicate target name %q", row, name)
+		}
+		targets[name] = tc
+	}
+	return targets, nil
+}
+
+func setCSVField(tc *collector.TargetConfig, col, v string) error {
+	switch col {
+	case "name":
+		tc.Name = v
+	case "address":
+		tc.Address = v
+	case "username":
+		tc.Username = &v
+	case "password":
+		tc.Password = &v
+	case "insecure", "skip-verify":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: %v", col, v, err)
+		}
+		if col == "insecure" {
+			tc.Insecure = &b
+		} else {
+			tc.SkipVerify = &b
+		}
+	case "tls-ca":
+		tc.TLSCA = &v
+	case "tls-cert":
+		tc.TLSCert = &v
+	case "tls-key":
+		tc.TLSKey = &v
+	case "timeout":
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid timeout value %q: %v", v, err)
+		}
+		tc.Timeout = d
+	case "subscriptions":
+		tc.Subscriptions = splitCSVList(v)
+	case "outputs":
+		tc.Outputs = splitCSVList(v)
+	case "tags":
+		tc.Tags = splitCSVList(v)
+	}
+	return nil
+}
+
+func splitCSVList(v string) []string {
+	items := strings.Split(v, csvListSeparator)
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/karimra/gnmic/collector"
@@ -99,8 +100,23 @@ func (f *FileLoader) readFile() (map[string]*collector.TargetConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	readTargets, err := parseTargets(filepath.Ext(f.cfg.File), b)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range dedupeTargets(readTargets) {
+		f.logger.Printf("target %q ignored: duplicate address %q", n, readTargets[n].Address)
+		delete(readTargets, n)
+	}
+	return readTargets, nil
+}
+
+// parseTargets parses the targets file content based on the file extension,
+// missing target names and addresses are set to the target key
+func parseTargets(ext string, b []byte) (map[string]*collector.TargetConfig, error) {
+	var err error
 	readTargets := make(map[string]*collector.TargetConfig)
-	switch filepath.Ext(f.cfg.File) {
+	switch ext {
 	case ".json":
 		err = json.Unmarshal(b, &readTargets)
 		if err != nil {
@@ -111,6 +127,11 @@ func (f *FileLoader) readFile() (map[string]*collector.TargetConfig, error) {
 		if err != nil {
 			return nil, err
 		}
+	case ".csv":
+		readTargets, err = parseCSV(b)
+		if err != nil {
+			return nil, err
+		}
 	}
 	for n, t := range readTargets {
 		if t == nil {
@@ -130,6 +151,26 @@ func (f *FileLoader) readFile() (map[string]*collector.TargetConfig, error) {
 	return readTargets, nil
 }
 
+// dedupeTargets returns the names of the targets sharing an address with another target,
+// the first target in name order is kept.
+func dedupeTargets(m map[string]*collector.TargetConfig) []string {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	addrs := make(map[string]struct{})
+	dups := make([]string, 0)
+	for _, n := range names {
+		if _, ok := addrs[m[n].Address]; ok {
+			dups = append(dups, n)
+			continue
+		}
+		addrs[m[n].Address] = struct{}{}
+	}
+	return dups
+}
+
 func (f *FileLoader) diff(m map[string]*collector.TargetConfig) *loaders.TargetOperation {
 	result := loaders.Diff(f.lastTargets, m)
 	for _, t := range result.Add {
@@ -0,0 +1,137 @@
+package file_loader
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/collector"
+)
+
+func strPtr(s string) *string { return &s }
+func boolPtr(b bool) *bool    { return &b }
+
+var testFiles = map[string]struct {
+	file    string
+	content string
+	want    map[string]*collector.TargetConfig
+	err     bool
+}{
+	"csv": {
+		file: "targets.csv",
+		content: `name,address,username,password,insecure,timeout,subscriptions,tags
+router1,10.0.0.1:57400,admin,secret,true,10s,sub1;sub2,dc1;core
+,10.0.0.2:57400,admin,,,,,
+# router3 has the same address as router1
+router3,10.0.0.1:57400,,,,,,
+`,
+		want: map[string]*collector.TargetConfig{
+			"router1": {
+				Name:          "router1",
+				Address:       "10.0.0.1:57400",
+				Username:      strPtr("admin"),
+				Password:      strPtr("secret"),
+				Insecure:      boolPtr(true),
+				Timeout:       10 * time.Second,
+				Subscriptions: []string{"sub1", "sub2"},
+				Tags:          []string{"dc1", "core"},
+			},
+			"10.0.0.2:57400": {
+				Name:     "10.0.0.2:57400",
+				Address:  "10.0.0.2:57400",
+				Username: strPtr("admin"),
+			},
+		},
+	},
+	"yaml": {
+		file: "targets.yaml",
+		content: `
+router1:
+  address: 10.0.0.1:57400
+  username: admin
+  password: secret
+  tags:
+    - dc1
+10.0.0.2:57400:
+router3:
+  address: 10.0.0.1:57400
+`,
+		want: map[string]*collector.TargetConfig{
+			"router1": {
+				Name:     "router1",
+				Address:  "10.0.0.1:57400",
+				Username: strPtr("admin"),
+				Password: strPtr("secret"),
+				Tags:     []string{"dc1"},
+			},
+			"10.0.0.2:57400": {
+				Name:    "10.0.0.2:57400",
+				Address: "10.0.0.2:57400",
+			},
+		},
+	},
+	"csv_missing_address_column": {
+		file:    "targets.csv",
+		content: "name,username\nrouter1,admin\n",
+		err:     true,
+	},
+	"csv_missing_address": {
+		file:    "targets.csv",
+		content: "name,address\nrouter1,\n",
+		err:     true,
+	},
+	"csv_unknown_column": {
+		file:    "targets.csv",
+		content: "address,color\n10.0.0.1,blue\n",
+		err:     true,
+	},
+	"csv_invalid_bool": {
+		file:    "targets.csv",
+		content: "address,insecure\n10.0.0.1,maybe\n",
+		err:     true,
+	},
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, tc := range testFiles {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+"_"+tc.file)
+			err := ioutil.WriteFile(path, []byte(tc.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := &FileLoader{
+				cfg:    &cfg{File: path},
+				logger: log.New(ioutil.Discard, "", 0),
+			}
+			got, err := f.readFile()
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got targets: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read targets file: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("unexpected targets")
+				for n, tg := range got {
+					t.Logf("got %s: %s", n, tg)
+				}
+				for n, tg := range tc.want {
+					t.Logf("want %s: %s", n, tg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelError = "error"
+)
+
+// Formats is the list of supported log formats
+var Formats = []string{FormatText, FormatJSON}
+
+// matches the file:line header added by log.Llongfile and log.Lshortfile
+var callerRegex = regexp.MustCompile(`^(\S+\.go:\d+): `)
+
+// matches the level written by a Logger
+var levelRegex = regexp.MustCompile(`^level=(debug|info|error) `)
+
+type entry struct {
+	Level     string            `json:"level"`
+	Timestamp string            `json:"ts"`
+	Component string            `json:"component"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields"`
+}
+
+// JSONWriter is an io.Writer that converts the lines written by a *log.Logger
+// into JSON lines with keys level, ts, component, message and fields.
+// The component is the logger "[name] " prefix, the caller file:line (if any) is added to the fields.
+// The level is the one written by a Logger, the lines written by a *log.Logger directly are at level info.
+type JSONWriter struct {
+	m   sync.Mutex
+	out io.Writer
+	now func() time.Time
+}
+
+func NewJSONWriter(out io.Writer) *JSONWriter {
+	return &JSONWriter{out: out, now: time.Now}
+}
+
+func (w *JSONWriter) Write(p []byte) (int, error) {
+	e := &entry{
+		Level:     LevelInfo,
+		Timestamp: w.now().UTC().Format(time.RFC3339Nano),
+		Fields:    make(map[string]string),
+	}
+	msg := strings.TrimSuffix(string(p), "\n")
+	if strings.HasPrefix(msg, "[") {
+		if i := strings.Index(msg, "] "); i > 0 {
+			e.Component = msg[1:i]
+			msg = msg[i+2:]
+		}
+	}
+	if m := callerRegex.FindStringSubmatch(msg); m != nil {
+		e.Fields["caller"] = m[1]
+		msg = msg[len(m[0]):]
+	}
+	if m := levelRegex.FindStringSubmatch(msg); m != nil {
+		e.Level = m[1]
+		msg = msg[len(m[0]):]
+	}
+	e.Message = msg
+	b, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	b = append(b, '\n')
+	w.m.Lock()
+	defer w.m.Unlock()
+	_, err = io.Copy(w.out, bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SetFormat switches logger to the given format.
+// In json format, the logger output is wrapped in a JSONWriter
+// and its date and time flags are removed since the JSONWriter adds its own timestamp.
+// A logger discarding its output is left untouched.
+func SetFormat(logger *log.Logger, format string) {
+	if format != FormatJSON || logger.Writer() == ioutil.Discard {
+		return
+	}
+	if _, ok := logger.Writer().(*JSONWriter); ok {
+		return
+	}
+	logger.SetOutput(NewJSONWriter(logger.Writer()))
+	logger.SetFlags(logger.Flags() & (log.Llongfile | log.Lshortfile))
+}
+
+// Logger wraps a *log.Logger to log leveled messages.
+// The level is written as a "level=<level> " message prefix,
+// a JSONWriter moves it to the JSON line level key.
+type Logger struct {
+	*log.Logger
+}
+
+func NewLogger(l *log.Logger) *Logger {
+	return &Logger{Logger: l}
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.output(LevelDebug, format, v...)
+}
+
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.output(LevelInfo, format, v...)
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.output(LevelError, format, v...)
+}
+
+func (l *Logger) output(level, format string, v ...interface{}) {
+	// the caller is the one of Debugf, Infof or Errorf
+	l.Output(3, "level="+level+" "+fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONWriter(t *testing.T) {
+	tests := map[string]struct {
+		prefix string
+		flags  int
+		level  string
+		msg    string
+		want   map[string]interface{}
+	}{
+		"with_component": {
+			prefix: "[prometheus_output] ",
+			flags:  log.LstdFlags,
+			msg:    "starting output",
+			want: map[string]interface{}{
+				"level":     "info",
+				"ts":        "2021-03-01T10:00:00Z",
+				"component": "prometheus_output",
+				"message":   "starting output",
+				"fields":    map[string]interface{}{},
+			},
+		},
+		"without_component": {
+			flags: log.LstdFlags | log.Lmicroseconds,
+			level: LevelDebug,
+			msg:   "using config file gnmic.yaml",
+			want: map[string]interface{}{
+				"level":     "debug",
+				"ts":        "2021-03-01T10:00:00Z",
+				"component": "",
+				"message":   "using config file gnmic.yaml",
+				"fields":    map[string]interface{}{},
+			},
+		},
+		"with_caller": {
+			prefix: "[gnmic] ",
+			flags:  log.LstdFlags | log.Lshortfile,
+			level:  LevelError,
+			msg:    "failed to create subscribe request: missing path",
+			want: map[string]interface{}{
+				"level":     "error",
+				"ts":        "2021-03-01T10:00:00Z",
+				"component": "gnmic",
+				"message":   "failed to create subscribe request: missing path",
+				"fields":    map[string]interface{}{"caller": "json_test.go"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, tc.prefix, tc.flags)
+			SetFormat(logger, FormatJSON)
+			logger.Writer().(*JSONWriter).now = func() time.Time {
+				return time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+			}
+			switch tc.level {
+			case LevelDebug:
+				NewLogger(logger).Debugf("%s", tc.msg)
+			case LevelError:
+				NewLogger(logger).Errorf("%s", tc.msg)
+			default:
+				logger.Print(tc.msg)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("expected 1 line, got %d: %q", len(lines), lines)
+			}
+			got := make(map[string]interface{})
+			err := json.Unmarshal([]byte(lines[0]), &got)
+			if err != nil {
+				t.Fatalf("failed to unmarshal log line %q: %v", lines[0], err)
+			}
+			// the caller line number is not compared
+			if fields, ok := got["fields"].(map[string]interface{}); ok {
+				if caller, ok := fields["caller"].(string); ok {
+					fields["caller"] = strings.SplitN(caller, ":", 2)[0]
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := NewLogger(log.New(buf, "[gnmic] ", log.Lshortfile))
+	logger.Infof("starting %d targets", 2)
+	if got := buf.String(); !strings.HasPrefix(got, "[gnmic] json_test.go:") || !strings.HasSuffix(got, ": level=info starting 2 targets\n") {
+		t.Errorf("unexpected log line %q", got)
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", log.LstdFlags)
+	SetFormat(logger, FormatText)
+	if logger.Writer() != buf {
+		t.Errorf("text format should not change the logger output")
+	}
+	SetFormat(logger, FormatJSON)
+	SetFormat(logger, FormatJSON)
+	w, ok := logger.Writer().(*JSONWriter)
+	if !ok || w.out != buf {
+		t.Errorf("expected a single JSONWriter wrapping the logger output, got %T", logger.Writer())
+	}
+	if logger.Flags() != 0 {
+		t.Errorf("expected date and time flags to be removed, got %d", logger.Flags())
+	}
+	discard := log.New(ioutil.Discard, "", log.LstdFlags)
+	SetFormat(discard, FormatJSON)
+	if discard.Writer() != ioutil.Discard {
+		t.Errorf("a discard logger should be left untouched")
+	}
+}
@@ -2,9 +2,11 @@ package all
 
 import (
 	_ "github.com/karimra/gnmic/outputs/file"
+	_ "github.com/karimra/gnmic/outputs/gnmi_server_output"
 	_ "github.com/karimra/gnmic/outputs/influxdb_output"
 	_ "github.com/karimra/gnmic/outputs/kafka_output"
 	_ "github.com/karimra/gnmic/outputs/nats_output"
+	_ "github.com/karimra/gnmic/outputs/otlp_output"
 	_ "github.com/karimra/gnmic/outputs/prometheus_output"
 	_ "github.com/karimra/gnmic/outputs/stan_output"
 	_ "github.com/karimra/gnmic/outputs/tcp_output"
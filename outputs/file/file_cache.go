@@ -0,0 +1,77 @@
+package file
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileCache keeps a limited number of open files,
+// the least recently used file is closed when the limit is reached.
+type fileCache struct {
+	m     *sync.Mutex
+	max   int
+	files map[string]*list.Element
+	lru   *list.List
+}
+
+func newFileCache(max int) *fileCache {
+	return &fileCache{
+		m:     new(sync.Mutex),
+		max:   max,
+		files: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// write writes b to the file named name,
+// the file and its parent directories are created if they don't exist.
+func (c *fileCache) write(name string, b []byte) (int, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	file, err := c.get(name)
+	if err != nil {
+		return 0, err
+	}
+	return file.Write(b)
+}
+
+func (c *fileCache) get(name string) (*os.File, error) {
+	if e, ok := c.files[name]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*os.File), nil
+	}
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	for c.lru.Len() >= c.max {
+		c.remove(c.lru.Back())
+	}
+	c.files[name] = c.lru.PushFront(file)
+	return file, nil
+}
+
+func (c *fileCache) remove(e *list.Element) error {
+	file := c.lru.Remove(e).(*os.File)
+	delete(c.files, file.Name())
+	return file.Close()
+}
+
+// close closes all the open files
+func (c *fileCache) close() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	var err error
+	for c.lru.Len() > 0 {
+		if cerr := c.remove(c.lru.Back()); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
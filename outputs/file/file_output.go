@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/karimra/gnmic/formatters"
@@ -21,6 +23,7 @@ const (
 	defaultFormat           = "json"
 	defaultWriteConcurrency = 1000
 	defaultSeparator        = "\n"
+	defaultMaxOpenFiles     = 100
 	loggingPrefix           = "[file_output] "
 )
 
@@ -41,11 +44,18 @@ type File struct {
 	mo     *formatters.MarshalOptions
 	sem    *semaphore.Weighted
 	evps   []formatters.EventProcessor
+
+	// filename template and open files cache,
+	// used when filename-template is set
+	fileNameTpl *template.Template
+	files       *fileCache
 }
 
 // Config //
 type Config struct {
 	FileName         string   `mapstructure:"filename,omitempty"`
+	FileNameTemplate string   `mapstructure:"filename-template,omitempty"`
+	MaxOpenFiles     int      `mapstructure:"max-open-files,omitempty"`
 	FileType         string   `mapstructure:"file-type,omitempty"`
 	Format           string   `mapstructure:"format,omitempty"`
 	Multiline        bool     `mapstructure:"multiline,omitempty"`
@@ -109,14 +119,23 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	if f.Cfg.Separator == "" {
 		f.Cfg.Separator = defaultSeparator
 	}
-	if f.Cfg.FileName == "" && f.Cfg.FileType == "" {
+	if f.Cfg.FileName == "" && f.Cfg.FileNameTemplate == "" && f.Cfg.FileType == "" {
 		f.Cfg.FileType = "stdout"
 	}
-	switch f.Cfg.FileType {
-	case "stdout":
+	switch {
+	case f.Cfg.FileType == "stdout":
 		f.file = os.Stdout
-	case "stderr":
+	case f.Cfg.FileType == "stderr":
 		f.file = os.Stderr
+	case f.Cfg.FileNameTemplate != "":
+		f.fileNameTpl, err = template.New("filename").Parse(f.Cfg.FileNameTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse filename-template: %v", err)
+		}
+		if f.Cfg.MaxOpenFiles < 1 {
+			f.Cfg.MaxOpenFiles = defaultMaxOpenFiles
+		}
+		f.files = newFileCache(f.Cfg.MaxOpenFiles)
 	default:
 	CRFILE:
 		f.file, err = os.OpenFile(f.Cfg.FileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -144,6 +163,7 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	f.sem = semaphore.NewWeighted(int64(f.Cfg.ConcurrencyLimit))
 
 	f.mo = &formatters.MarshalOptions{Multiline: f.Cfg.Multiline, Indent: f.Cfg.Indent, Format: f.Cfg.Format}
+	outputs.SetFlushHandlers(ctx, f, f.evps)
 
 	f.logger.Printf("initialized file output: %s", f.String())
 	go func() {
@@ -153,11 +173,50 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (f *File) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, f.Cfg)
+	if err != nil {
+		return err
+	}
+	if f.Cfg.Format == "proto" {
+		return fmt.Errorf("proto format not supported in output type 'file'")
+	}
+	if f.Cfg.FileNameTemplate != "" {
+		_, err = template.New("filename").Parse(f.Cfg.FileNameTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse filename-template: %v", err)
+		}
+	}
+	return nil
+}
+
 // Write //
 func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
 	if rsp == nil {
 		return
 	}
+	ev := &formatters.EventMsg{
+		Name: meta["subscription-name"],
+		Tags: meta,
+	}
+	f.writeMsg(ctx, ev, func() ([]byte, error) {
+		return f.mo.Marshal(rsp, meta, f.evps...)
+	})
+}
+
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	f.writeMsg(ctx, ev, func() ([]byte, error) {
+		return f.mo.MarshalEvents([]*formatters.EventMsg{ev})
+	})
+}
+
+// writeMsg writes the message returned by marshal to the file named after ev.
+func (f *File) writeMsg(ctx context.Context, ev *formatters.EventMsg, marshal func() ([]byte, error)) {
 	err := f.sem.Acquire(ctx, 1)
 	if errors.Is(err, context.Canceled) {
 		return
@@ -168,31 +227,67 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 	}
 	defer f.sem.Release(1)
 
-	NumberOfReceivedMsgs.WithLabelValues(f.file.Name()).Inc()
-	b, err := f.mo.Marshal(rsp, meta, f.evps...)
+	fileName, err := f.fileName(ev)
+	if err != nil {
+		if f.Cfg.Debug {
+			f.logger.Printf("failed to render filename: %v", err)
+		}
+		NumberOfFailWriteMsgs.WithLabelValues(f.Cfg.FileNameTemplate, "template_error").Inc()
+		return
+	}
+	NumberOfReceivedMsgs.WithLabelValues(fileName).Inc()
+	b, err := marshal()
 	if err != nil {
 		if f.Cfg.Debug {
 			f.logger.Printf("failed marshaling proto msg: %v", err)
 		}
-		NumberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "marshal_error").Inc()
+		NumberOfFailWriteMsgs.WithLabelValues(fileName, "marshal_error").Inc()
 		return
 	}
-	n, err := f.file.Write(append(b, []byte(f.Cfg.Separator)...))
+	n, err := f.write(fileName, append(b, []byte(f.Cfg.Separator)...))
 	if err != nil {
 		if f.Cfg.Debug {
-			f.logger.Printf("failed to write to file '%s': %v", f.file.Name(), err)
+			f.logger.Printf("failed to write to file '%s': %v", fileName, err)
 		}
-		NumberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "write_error").Inc()
+		NumberOfFailWriteMsgs.WithLabelValues(fileName, "write_error").Inc()
 		return
 	}
-	NumberOfWrittenBytes.WithLabelValues(f.file.Name()).Add(float64(n))
-	NumberOfWrittenMsgs.WithLabelValues(f.file.Name()).Inc()
+	NumberOfWrittenBytes.WithLabelValues(fileName).Add(float64(n))
+	NumberOfWrittenMsgs.WithLabelValues(fileName).Inc()
 }
 
-func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
+// fileName returns the name of the file a message is written to.
+// The filename template is executed against ev, built from the message metadata,
+// i.e: {{ .Name }} is the subscription name and {{ index .Tags "source" }} is the target name.
+func (f *File) fileName(ev *formatters.EventMsg) (string, error) {
+	if f.fileNameTpl == nil {
+		return f.file.Name(), nil
+	}
+	sb := new(strings.Builder)
+	err := f.fileNameTpl.Execute(sb, ev)
+	if err != nil {
+		return "", err
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("filename template rendered an empty filename")
+	}
+	return sb.String(), nil
+}
+
+func (f *File) write(fileName string, b []byte) (int, error) {
+	if f.files == nil {
+		return f.file.Write(b)
+	}
+	return f.files.write(fileName, b)
+}
 
 // Close //
 func (f *File) Close() error {
+	outputs.FlushEventProcessors(context.Background(), f, f.evps)
+	if f.files != nil {
+		f.logger.Printf("closing files '%s' output", f.Cfg.FileNameTemplate)
+		return f.files.close()
+	}
 	f.logger.Printf("closing file '%s' output", f.file.Name())
 	return f.file.Close()
 }
@@ -209,3 +304,13 @@ func (f *File) RegisterMetrics(reg *prometheus.Registry) {
 
 func (f *File) SetName(name string)        {}
 func (f *File) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (f *File) Depths() map[string]int {
+	if f.files == nil {
+		return map[string]int{}
+	}
+	f.files.m.Lock()
+	defer f.files.m.Unlock()
+	return map[string]int{"open-files": f.files.lru.Len()}
+}
@@ -0,0 +1,194 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func testResponse(value string) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "name"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: value}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func readLines(t *testing.T, name string) []string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read file %q: %v", name, err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestFileNameTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnmic-file-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, maxOpenFiles := range map[string]int{"default": 0, "max_open_files_1": 1} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			dir := filepath.Join(dir, name)
+			f := &File{Cfg: &Config{}, logger: log.New(ioutil.Discard, "", 0)}
+			err := f.Init(ctx, "file", map[string]interface{}{
+				"filename-template": filepath.Join(dir, `{{ index .Tags "source" }}`, "{{ .Name }}.json"),
+				"format":            "event",
+				"max-open-files":    maxOpenFiles,
+			})
+			if err != nil {
+				t.Fatalf("failed to init file output: %v", err)
+			}
+			for _, target := range []string{"router1", "router2", "router1"} {
+				f.Write(ctx, testResponse(target), outputs.Meta{"source": target, "subscription-name": "sub1"})
+			}
+			f.Close()
+
+			for target, count := range map[string]int{"router1": 2, "router2": 1} {
+				lines := readLines(t, filepath.Join(dir, target, "sub1.json"))
+				if len(lines) != count {
+					t.Fatalf("expected %d lines for target %q, got %d: %v", count, target, len(lines), lines)
+				}
+				for _, l := range lines {
+					evs := make([]map[string]interface{}, 0)
+					err = json.Unmarshal([]byte(l), &evs)
+					if err != nil {
+						t.Fatalf("failed to unmarshal line %q: %v", l, err)
+					}
+					tags, _ := evs[0]["tags"].(map[string]interface{})
+					if tags["source"] != target {
+						t.Errorf("target %q file contains an event from %v", target, tags["source"])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFilePerOutputFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnmic-file-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	formats := []string{"event", "prototext"}
+	outs := make([]*File, 0, len(formats))
+	for _, format := range formats {
+		f := &File{Cfg: &Config{}, logger: log.New(ioutil.Discard, "", 0)}
+		err := f.Init(ctx, format, map[string]interface{}{
+			"filename": filepath.Join(dir, format),
+			"format":   format,
+		})
+		if err != nil {
+			t.Fatalf("failed to init file output with format %q: %v", format, err)
+		}
+		outs = append(outs, f)
+	}
+	rsp := testResponse("router1")
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+	for _, f := range outs {
+		f.Write(ctx, rsp, meta)
+		f.Close()
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evs := make([]map[string]interface{}, 0)
+	err = json.Unmarshal(b, &evs)
+	if err != nil {
+		t.Fatalf("event output is not a list of events: %v: %s", err, b)
+	}
+	if len(evs) != 1 || evs[0]["name"] != "sub1" {
+		t.Errorf("unexpected events: %v", evs)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "prototext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `string_val:`) || json.Valid(b) {
+		t.Errorf("unexpected prototext output: %s", b)
+	}
+}
+
+func TestFileFlushedEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnmic-file-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the merged events are written once the window expires, or when the output closes
+	for name, window := range map[string]string{"window_expired": "50ms", "closed": "1h"} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fileName := filepath.Join(dir, name)
+			ep, err := formatters.NewEventProcessor(map[string]interface{}{
+				"event-merge": map[string]interface{}{"window": window, "key-tags": []string{"source"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := &File{Cfg: &Config{}, logger: log.New(ioutil.Discard, "", 0), evps: []formatters.EventProcessor{ep}}
+			err = f.Init(ctx, "file", map[string]interface{}{
+				"filename": fileName,
+				"format":   "event",
+			})
+			if err != nil {
+				t.Fatalf("failed to init file output: %v", err)
+			}
+			meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+			f.Write(ctx, testResponse("router1"), meta)
+			f.Write(ctx, testResponse("router2"), meta)
+			if name == "window_expired" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			f.Close()
+
+			var merged []map[string]interface{}
+			for _, l := range readLines(t, fileName) {
+				evs := make([]map[string]interface{}, 0)
+				err = json.Unmarshal([]byte(l), &evs)
+				if err != nil {
+					t.Fatalf("failed to unmarshal line %q: %v", l, err)
+				}
+				merged = append(merged, evs...)
+			}
+			if len(merged) != 1 {
+				t.Fatalf("expected a single merged event, got %v", merged)
+			}
+			values, _ := merged[0]["values"].(map[string]interface{})
+			if values["/name"] != "router2" {
+				t.Errorf("unexpected merged event: %v", merged[0])
+			}
+		})
+	}
+}
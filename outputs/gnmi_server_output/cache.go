@@ -0,0 +1,275 @@
+package gnmi_server_output
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// cache stores the latest update of each leaf per target,
+// and forwards the received notifications to the streaming subscribers.
+type cache struct {
+	m sync.Mutex
+	// target name to path key to the leaf latest update
+	targets     map[string]map[string]*leaf
+	subscribers map[*subscriber]struct{}
+}
+
+// leaf is the latest update received for a path,
+// its path is the notification prefix joined with the update path.
+type leaf struct {
+	timestamp int64
+	update    *gnmi.Update
+}
+
+func newCache() *cache {
+	return &cache{
+		targets:     make(map[string]map[string]*leaf),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// update applies the notification deletes then updates to the target leaves,
+// and sends the matching ones to the subscribers.
+func (c *cache) update(target string, n *gnmi.Notification) {
+	deletes := make([]*gnmi.Path, 0, len(n.GetDelete()))
+	for _, d := range n.GetDelete() {
+		deletes = append(deletes, joinPaths(n.GetPrefix(), d))
+	}
+	updates := make([]*gnmi.Update, 0, len(n.GetUpdate()))
+	for _, u := range n.GetUpdate() {
+		updates = append(updates, &gnmi.Update{
+			Path: joinPaths(n.GetPrefix(), u.GetPath()),
+			Val:  u.GetVal(),
+		})
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	leaves, ok := c.targets[target]
+	if !ok {
+		leaves = make(map[string]*leaf)
+		c.targets[target] = leaves
+	}
+	for _, d := range deletes {
+		for k, l := range leaves {
+			if matchPath(d, l.update.GetPath()) {
+				delete(leaves, k)
+			}
+		}
+	}
+	for _, u := range updates {
+		leaves[pathKey(u.GetPath())] = &leaf{timestamp: n.GetTimestamp(), update: u}
+	}
+	for s := range c.subscribers {
+		if !s.matchTarget(target) {
+			continue
+		}
+		rn := &gnmi.Notification{
+			Timestamp: n.GetTimestamp(),
+			Prefix:    &gnmi.Path{Target: target},
+		}
+		for _, d := range deletes {
+			if s.matchDelete(d) {
+				rn.Delete = append(rn.Delete, d)
+			}
+		}
+		for _, u := range updates {
+			if s.match(u.GetPath()) {
+				rn.Update = append(rn.Update, u)
+			}
+		}
+		if len(rn.Delete) == 0 && len(rn.Update) == 0 {
+			continue
+		}
+		s.send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: rn}})
+	}
+}
+
+// query returns the stored leaves matching the subscriber paths, sorted by target and path.
+// If register is true, the subscriber is added to the streaming subscribers
+// so that it does not miss any update received after the query.
+func (c *cache) query(s *subscriber, register bool) []*gnmi.Notification {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if register {
+		c.subscribers[s] = struct{}{}
+	}
+	targets := make([]string, 0, len(c.targets))
+	for t := range c.targets {
+		if s.matchTarget(t) {
+			targets = append(targets, t)
+		}
+	}
+	sort.Strings(targets)
+	ns := make([]*gnmi.Notification, 0)
+	for _, t := range targets {
+		keys := make([]string, 0, len(c.targets[t]))
+		for k, l := range c.targets[t] {
+			if s.match(l.update.GetPath()) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			l := c.targets[t][k]
+			ns = append(ns, &gnmi.Notification{
+				Timestamp: l.timestamp,
+				Prefix:    &gnmi.Path{Target: t},
+				Update:    []*gnmi.Update{l.update},
+			})
+		}
+	}
+	return ns
+}
+
+func (c *cache) unsubscribe(s *subscriber) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.subscribers, s)
+}
+
+// subscribersDepth returns the number of responses buffered for the streaming subscribers
+func (c *cache) subscribersDepth() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	depth := 0
+	for s := range c.subscribers {
+		depth += len(s.ch)
+	}
+	return depth
+}
+
+// subscriber is a Subscribe RPC client
+type subscriber struct {
+	// empty or "*" to match all targets
+	target string
+	paths  []*gnmi.Path
+	// streamed notifications
+	ch chan *gnmi.SubscribeResponse
+	// closed if ch is full, the subscriber is too slow to keep up with the updates
+	slow     chan struct{}
+	slowOnce sync.Once
+}
+
+func newSubscriber(sl *gnmi.SubscriptionList, bufferSize int) *subscriber {
+	s := &subscriber{
+		target: sl.GetPrefix().GetTarget(),
+		paths:  make([]*gnmi.Path, 0, len(sl.GetSubscription())),
+		ch:     make(chan *gnmi.SubscribeResponse, bufferSize),
+		slow:   make(chan struct{}),
+	}
+	for _, sub := range sl.GetSubscription() {
+		s.paths = append(s.paths, joinPaths(sl.GetPrefix(), sub.GetPath()))
+	}
+	return s
+}
+
+func (s *subscriber) send(rsp *gnmi.SubscribeResponse) {
+	select {
+	case s.ch <- rsp:
+	default:
+		s.slowOnce.Do(func() { close(s.slow) })
+	}
+}
+
+func (s *subscriber) matchTarget(target string) bool {
+	return s.target == "" || s.target == "*" || s.target == target
+}
+
+// match returns true if p is one of the subscribed paths or a descendant of one of them
+func (s *subscriber) match(p *gnmi.Path) bool {
+	for _, sp := range s.paths {
+		if matchPath(sp, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDelete returns true if the deleted path p matches one of the subscribed paths,
+// or is the ancestor of one of them.
+func (s *subscriber) matchDelete(p *gnmi.Path) bool {
+	for _, sp := range s.paths {
+		if matchPath(sp, p) || matchPath(p, sp) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPaths returns the path p prefixed with the elements of prefix,
+// the origin is taken from p, or from prefix if p does not set it.
+func joinPaths(prefix, p *gnmi.Path) *gnmi.Path {
+	jp := &gnmi.Path{
+		Origin: p.GetOrigin(),
+		Elem:   make([]*gnmi.PathElem, 0, len(prefix.GetElem())+len(p.GetElem())),
+	}
+	if jp.Origin == "" {
+		jp.Origin = prefix.GetOrigin()
+	}
+	jp.Elem = append(jp.Elem, prefix.GetElem()...)
+	jp.Elem = append(jp.Elem, p.GetElem()...)
+	return jp
+}
+
+// matchPath returns true if p is equal to the pattern path or is a descendant of it.
+// The pattern elements names can be the wildcards * and ..., and its keys values the wildcard *.
+// The origins are compared only if both paths set it.
+func matchPath(pattern, p *gnmi.Path) bool {
+	if pattern.GetOrigin() != "" && p.GetOrigin() != "" && pattern.GetOrigin() != p.GetOrigin() {
+		return false
+	}
+	return matchElems(pattern.GetElem(), p.GetElem())
+}
+
+func matchElems(pattern, elems []*gnmi.PathElem) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0].GetName() == "..." {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if pattern[0].GetName() != "*" && pattern[0].GetName() != elems[0].GetName() {
+		return false
+	}
+	for k, v := range pattern[0].GetKey() {
+		if v != "*" && elems[0].GetKey()[k] != v {
+			return false
+		}
+	}
+	return matchElems(pattern[1:], elems[1:])
+}
+
+// pathKey returns a string uniquely identifying the path p
+func pathKey(p *gnmi.Path) string {
+	sb := strings.Builder{}
+	sb.WriteString(p.GetOrigin())
+	sb.WriteString(":")
+	for _, e := range p.GetElem() {
+		sb.WriteString("/")
+		sb.WriteString(e.GetName())
+		keys := make([]string, 0, len(e.GetKey()))
+		for k := range e.GetKey() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sb.WriteString("[")
+			sb.WriteString(k)
+			sb.WriteString("=")
+			sb.WriteString(e.GetKey()[k])
+			sb.WriteString("]")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,186 @@
+package gnmi_server_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultAddress    = ":57400"
+	defaultBufferSize = 1000
+	loggingPrefix     = "[gnmi_server_output] "
+)
+
+func init() {
+	outputs.Register("gnmi_server", func() outputs.Output {
+		return &GNMIServerOutput{
+			Cfg:    &Config{},
+			cache:  newCache(),
+			logger: log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
+		}
+	})
+}
+
+// GNMIServerOutput keeps the latest state received from the targets
+// and serves it to gNMI clients over the Subscribe RPC.
+type GNMIServerOutput struct {
+	Cfg *Config
+
+	logger   *log.Logger
+	cancelFn context.CancelFunc
+	listener net.Listener
+	srv      *grpc.Server
+	cache    *cache
+	wg       *sync.WaitGroup
+}
+
+type Config struct {
+	Address    string `mapstructure:"address,omitempty"`
+	TLSCert    string `mapstructure:"tls-cert,omitempty"`
+	TLSKey     string `mapstructure:"tls-key,omitempty"`
+	BufferSize int    `mapstructure:"buffer-size,omitempty"`
+	Debug      bool   `mapstructure:"debug,omitempty"`
+}
+
+func (g *GNMIServerOutput) String() string {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (g *GNMIServerOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && g.logger != nil {
+		g.logger.SetOutput(logger.Writer())
+		g.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetEventProcessors is a no-op, the output serves the received gNMI notifications unchanged.
+func (g *GNMIServerOutput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]interface{}) {
+}
+
+func (g *GNMIServerOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, g.Cfg)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	err = g.setDefaults()
+	if err != nil {
+		return err
+	}
+	var srvOpts []grpc.ServerOption
+	if g.Cfg.TLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(g.Cfg.TLSCert, g.Cfg.TLSKey)
+		if err != nil {
+			return err
+		}
+		srvOpts = append(srvOpts, grpc.Creds(creds))
+	}
+	g.listener, err = net.Listen("tcp", g.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	g.srv = grpc.NewServer(srvOpts...)
+	gnmi.RegisterGNMIServer(g.srv, &server{
+		cache:      g.cache,
+		bufferSize: g.Cfg.BufferSize,
+		logger:     g.logger,
+		debug:      g.Cfg.Debug,
+	})
+	ctx, g.cancelFn = context.WithCancel(ctx)
+	g.wg = new(sync.WaitGroup)
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		err := g.srv.Serve(g.listener)
+		if err != nil {
+			g.logger.Printf("gnmi server stopped: %v", err)
+		}
+	}()
+	g.logger.Printf("initialized gnmi_server output, listening on %s: %s", g.listener.Addr(), g.String())
+	go func() {
+		<-ctx.Done()
+		g.Close()
+	}()
+	return nil
+}
+
+// ValidateConfig implements outputs.ConfigValidator
+func (g *GNMIServerOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, g.Cfg)
+	if err != nil {
+		return err
+	}
+	return g.setDefaults()
+}
+
+func (g *GNMIServerOutput) setDefaults() error {
+	if g.Cfg.Address == "" {
+		g.Cfg.Address = defaultAddress
+	}
+	if (g.Cfg.TLSCert == "") != (g.Cfg.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	if g.Cfg.BufferSize <= 0 {
+		g.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
+
+func (g *GNMIServerOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+	switch rsp := rsp.(type) {
+	case *gnmi.SubscribeResponse:
+		n := rsp.GetUpdate()
+		if n == nil {
+			return
+		}
+		target := meta["source"]
+		if target == "" {
+			target = n.GetPrefix().GetTarget()
+		}
+		g.cache.update(target, n)
+	}
+}
+
+func (g *GNMIServerOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
+
+func (g *GNMIServerOutput) Close() error {
+	if g.cancelFn == nil {
+		return nil
+	}
+	g.cancelFn()
+	g.srv.Stop()
+	g.wg.Wait()
+	return nil
+}
+
+func (g *GNMIServerOutput) RegisterMetrics(reg *prometheus.Registry) {}
+
+func (g *GNMIServerOutput) SetName(name string)        {}
+func (g *GNMIServerOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (g *GNMIServerOutput) Depths() map[string]int {
+	return map[string]int{"subscribers": g.cache.subscribersDepth()}
+}
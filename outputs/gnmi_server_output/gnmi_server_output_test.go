@@ -0,0 +1,331 @@
+package gnmi_server_output
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func startTestOutput(t *testing.T) (*GNMIServerOutput, gnmi.GNMIClient) {
+	g := outputs.Outputs["gnmi_server"]().(*GNMIServerOutput)
+	err := g.Init(context.Background(), "gnmi_server", map[string]interface{}{"address": "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("failed to initialize output: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, g.listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("failed to dial output: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return g, gnmi.NewGNMIClient(conn)
+}
+
+// interfaceUpdate returns a response with the interface counter in-octets
+func interfaceUpdate(ts int64, name string, v uint64) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: ts,
+				Prefix: &gnmi.Path{Elem: []*gnmi.PathElem{
+					{Name: "interfaces"},
+					{Name: "interface", Key: map[string]string{"name": name}},
+				}},
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "state"}, {Name: "counters"}, {Name: "in-octets"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func interfacePath(name string) *gnmi.Path {
+	return &gnmi.Path{Elem: []*gnmi.PathElem{
+		{Name: "interfaces"},
+		{Name: "interface", Key: map[string]string{"name": name}},
+	}}
+}
+
+func counterPath(name string) *gnmi.Path {
+	p := interfacePath(name)
+	p.Elem = append(p.Elem, &gnmi.PathElem{Name: "state"}, &gnmi.PathElem{Name: "counters"}, &gnmi.PathElem{Name: "in-octets"})
+	return p
+}
+
+func counterNotification(target string, ts int64, name string, v uint64) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: ts,
+				Prefix:    &gnmi.Path{Target: target},
+				Update: []*gnmi.Update{
+					{
+						Path: counterPath(name),
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}},
+					},
+				},
+			},
+		},
+	}
+}
+
+var syncResponse = &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}
+
+func recvResponses(t *testing.T, stream gnmi.GNMI_SubscribeClient, n int) []*gnmi.SubscribeResponse {
+	rsps := make([]*gnmi.SubscribeResponse, 0, n)
+	for i := 0; i < n; i++ {
+		rsp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive response %d: %v", i, err)
+		}
+		rsps = append(rsps, rsp)
+	}
+	return rsps
+}
+
+func checkResponses(t *testing.T, got, want []*gnmi.SubscribeResponse) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d responses, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("response %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	g, client := startTestOutput(t)
+	ctx := context.Background()
+	g.Write(ctx, interfaceUpdate(1, "ethernet-1/2", 20), outputs.Meta{"source": "router1"})
+	g.Write(ctx, interfaceUpdate(2, "ethernet-1/1", 10), outputs.Meta{"source": "router1"})
+	g.Write(ctx, interfaceUpdate(3, "ethernet-1/1", 11), outputs.Meta{"source": "router1"})
+	g.Write(ctx, interfaceUpdate(4, "ethernet-1/1", 30), outputs.Meta{"source": "router2"})
+
+	stream, err := client.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&gnmi.SubscribeRequest{
+		Request: &gnmi.SubscribeRequest_Subscribe{
+			Subscribe: &gnmi.SubscriptionList{
+				Prefix: &gnmi.Path{Target: "*"},
+				Mode:   gnmi.SubscriptionList_ONCE,
+				Subscription: []*gnmi.Subscription{
+					{Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}, {Name: "interface", Key: map[string]string{"name": "*"}}}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponses(t, recvResponses(t, stream, 4), []*gnmi.SubscribeResponse{
+		counterNotification("router1", 3, "ethernet-1/1", 11),
+		counterNotification("router1", 1, "ethernet-1/2", 20),
+		counterNotification("router2", 4, "ethernet-1/1", 30),
+		syncResponse,
+	})
+	if _, err = stream.Recv(); err != io.EOF {
+		t.Errorf("expected the stream to end after the sync response, got %v", err)
+	}
+}
+
+func TestSubscribeStream(t *testing.T) {
+	g, client := startTestOutput(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.Write(ctx, interfaceUpdate(1, "ethernet-1/1", 10), outputs.Meta{"source": "router1"})
+	g.Write(ctx, interfaceUpdate(1, "ethernet-1/2", 20), outputs.Meta{"source": "router1"})
+
+	stream, err := client.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&gnmi.SubscribeRequest{
+		Request: &gnmi.SubscribeRequest_Subscribe{
+			Subscribe: &gnmi.SubscriptionList{
+				Prefix: &gnmi.Path{Target: "router1"},
+				Mode:   gnmi.SubscriptionList_STREAM,
+				Subscription: []*gnmi.Subscription{
+					{Path: interfacePath("ethernet-1/1"), Mode: gnmi.SubscriptionMode_ON_CHANGE},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResponses(t, recvResponses(t, stream, 2), []*gnmi.SubscribeResponse{
+		counterNotification("router1", 1, "ethernet-1/1", 10),
+		syncResponse,
+	})
+	// not matching the subscription: other target and other interface
+	g.Write(ctx, interfaceUpdate(2, "ethernet-1/1", 100), outputs.Meta{"source": "router2"})
+	g.Write(ctx, interfaceUpdate(2, "ethernet-1/2", 21), outputs.Meta{"source": "router1"})
+	// matching the subscription
+	g.Write(ctx, interfaceUpdate(3, "ethernet-1/1", 11), outputs.Meta{"source": "router1"})
+	g.Write(ctx, &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 4,
+				Delete:    []*gnmi.Path{interfacePath("ethernet-1/1")},
+			},
+		},
+	}, outputs.Meta{"source": "router1"})
+	checkResponses(t, recvResponses(t, stream, 2), []*gnmi.SubscribeResponse{
+		counterNotification("router1", 3, "ethernet-1/1", 11),
+		{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: 4,
+					Prefix:    &gnmi.Path{Target: "router1"},
+					Delete:    []*gnmi.Path{interfacePath("ethernet-1/1")},
+				},
+			},
+		},
+	})
+	// the deleted leaf is removed from the cache
+	ns := g.cache.query(&subscriber{target: "router1", paths: []*gnmi.Path{{}}}, false)
+	if len(ns) != 1 || !proto.Equal(ns[0].GetUpdate()[0].GetPath(), counterPath("ethernet-1/2")) {
+		t.Errorf("expected only ethernet-1/2 in the cache, got %v", ns)
+	}
+}
+
+func TestSubscribeUnsupportedModes(t *testing.T) {
+	_, client := startTestOutput(t)
+	tests := map[string]*gnmi.SubscriptionList{
+		"poll": {
+			Mode:         gnmi.SubscriptionList_POLL,
+			Subscription: []*gnmi.Subscription{{Path: interfacePath("ethernet-1/1")}},
+		},
+		"sample": {
+			Mode:         gnmi.SubscriptionList_STREAM,
+			Subscription: []*gnmi.Subscription{{Path: interfacePath("ethernet-1/1"), Mode: gnmi.SubscriptionMode_SAMPLE}},
+		},
+	}
+	for name, sl := range tests {
+		t.Run(name, func(t *testing.T) {
+			stream, err := client.Subscribe(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = stream.Send(&gnmi.SubscribeRequest{Request: &gnmi.SubscribeRequest_Subscribe{Subscribe: sl}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = stream.Recv()
+			if status.Code(err) != codes.Unimplemented {
+				t.Errorf("expected an unimplemented error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	c := newCache()
+	s := newSubscriber(&gnmi.SubscriptionList{Subscription: []*gnmi.Subscription{{Path: &gnmi.Path{}}}}, 1)
+	c.query(s, true)
+	c.update("router1", interfaceUpdate(1, "ethernet-1/1", 10).GetUpdate())
+	select {
+	case <-s.slow:
+		t.Fatal("subscriber marked as slow before its buffer is full")
+	default:
+	}
+	c.update("router1", interfaceUpdate(2, "ethernet-1/1", 11).GetUpdate())
+	select {
+	case <-s.slow:
+	default:
+		t.Fatal("expected the subscriber to be marked as slow")
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := map[string]struct {
+		pattern *gnmi.Path
+		path    *gnmi.Path
+		want    bool
+	}{
+		"root": {
+			pattern: &gnmi.Path{},
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"exact": {
+			pattern: counterPath("ethernet-1/1"),
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"descendant": {
+			pattern: interfacePath("ethernet-1/1"),
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"ancestor": {
+			pattern: counterPath("ethernet-1/1"),
+			path:    interfacePath("ethernet-1/1"),
+			want:    false,
+		},
+		"other_key": {
+			pattern: interfacePath("ethernet-1/2"),
+			path:    counterPath("ethernet-1/1"),
+			want:    false,
+		},
+		"wildcard_key": {
+			pattern: interfacePath("*"),
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"unkeyed": {
+			pattern: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}, {Name: "interface"}, {Name: "state"}}},
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"wildcard_name": {
+			pattern: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "*"}, {Name: "interface"}, {Name: "*"}, {Name: "counters"}}},
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"multi_level_wildcard": {
+			pattern: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}, {Name: "..."}, {Name: "in-octets"}}},
+			path:    counterPath("ethernet-1/1"),
+			want:    true,
+		},
+		"multi_level_wildcard_no_match": {
+			pattern: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "..."}, {Name: "out-octets"}}},
+			path:    counterPath("ethernet-1/1"),
+			want:    false,
+		},
+		"other_origin": {
+			pattern: &gnmi.Path{Origin: "openconfig", Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+			path:    &gnmi.Path{Origin: "native", Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+			want:    false,
+		},
+		"unset_origin": {
+			pattern: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+			path:    &gnmi.Path{Origin: "native", Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+			want:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := matchPath(tc.pattern, tc.path); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package gnmi_server_output
+
+import (
+	"context"
+	"log"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const gnmiVersion = "0.7.0"
+
+// server implements the gNMI Capabilities and Subscribe RPCs,
+// the subscriptions are served from the cache.
+type server struct {
+	gnmi.UnimplementedGNMIServer
+
+	cache      *cache
+	bufferSize int
+	logger     *log.Logger
+	debug      bool
+}
+
+func (s *server) Capabilities(ctx context.Context, req *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	return &gnmi.CapabilityResponse{GNMIVersion: gnmiVersion}, nil
+}
+
+// Subscribe supports the ONCE subscriptions, and the STREAM subscriptions in ON_CHANGE or TARGET_DEFINED mode.
+// Both send the cached state matching the subscription followed by a sync response,
+// the STREAM subscriptions then receive the updates as they are written to the output.
+func (s *server) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	sl := req.GetSubscribe()
+	if sl == nil {
+		return status.Error(codes.InvalidArgument, "the first subscribe request must contain a subscription list")
+	}
+	if len(sl.GetSubscription()) == 0 {
+		return status.Error(codes.InvalidArgument, "missing subscriptions")
+	}
+	if s.debug {
+		if p, ok := peer.FromContext(stream.Context()); ok {
+			s.logger.Printf("received subscribe request from %s: %v", p.Addr, sl)
+		}
+	}
+	switch sl.GetMode() {
+	case gnmi.SubscriptionList_ONCE:
+		return s.sendInitialState(stream, newSubscriber(sl, 0), sl.GetUpdatesOnly(), false)
+	case gnmi.SubscriptionList_STREAM:
+		for _, subscription := range sl.GetSubscription() {
+			if subscription.GetMode() == gnmi.SubscriptionMode_SAMPLE {
+				return status.Error(codes.Unimplemented, "sample subscriptions are not supported, use on_change")
+			}
+		}
+	default:
+		return status.Errorf(codes.Unimplemented, "subscription list mode %s is not supported", sl.GetMode())
+	}
+	sub := newSubscriber(sl, s.bufferSize)
+	defer s.cache.unsubscribe(sub)
+	err = s.sendInitialState(stream, sub, sl.GetUpdatesOnly(), true)
+	if err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sub.slow:
+			s.logger.Printf("closing a subscription, the subscriber is not keeping up with the updates")
+			return status.Error(codes.ResourceExhausted, "subscriber too slow, updates were dropped")
+		case rsp := <-sub.ch:
+			err = stream.Send(rsp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendInitialState sends the cached leaves matching the subscription, unless updatesOnly is set,
+// followed by a sync response.
+func (s *server) sendInitialState(stream gnmi.GNMI_SubscribeServer, sub *subscriber, updatesOnly, register bool) error {
+	ns := s.cache.query(sub, register)
+	if !updatesOnly {
+		for _, n := range ns {
+			err := stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}})
+}
@@ -42,6 +42,7 @@ type InfluxDBOutput struct {
 	Cfg       *Config
 	client    influxdb2.Client
 	logger    *log.Logger
+	ctx       context.Context
 	cancelFn  context.CancelFunc
 	eventChan chan *formatters.EventMsg
 	reset     chan struct{}
@@ -135,6 +136,7 @@ func (i *InfluxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 		iopts.SetLogLevel(3)
 	}
 	ctx, i.cancelFn = context.WithCancel(ctx)
+	i.ctx = ctx
 CRCLIENT:
 	i.client = influxdb2.NewClientWithOptions(i.Cfg.URL, i.Cfg.Token, iopts)
 	// start influx health check
@@ -148,6 +150,7 @@ CRCLIENT:
 	go i.healthCheck(ctx)
 	i.logger.Printf("initialized influxdb client: %s", i.String())
 
+	outputs.SetFlushHandlers(ctx, i, i.evps)
 	for k := 0; k < numWorkers; k++ {
 		go i.worker(ctx, k)
 	}
@@ -158,6 +161,11 @@ CRCLIENT:
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (i *InfluxDBOutput) ValidateConfig(cfg map[string]interface{}) error {
+	return outputs.DecodeConfig(cfg, i.Cfg)
+}
+
 func (i *InfluxDBOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
 	if rsp == nil {
 		return
@@ -185,10 +193,21 @@ func (i *InfluxDBOutput) Write(ctx context.Context, rsp proto.Message, meta outp
 	}
 }
 
-func (i *InfluxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
+// WriteEvent writes ev as a point, e.g the events held and flushed by the event processors.
+func (i *InfluxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-i.reset:
+	case i.eventChan <- ev:
+	}
+}
 
 func (i *InfluxDBOutput) Close() error {
 	i.logger.Printf("closing client...")
+	outputs.FlushEventProcessors(i.ctx, i, i.evps)
 	i.cancelFn()
 	i.logger.Printf("closed.")
 	return nil
@@ -273,3 +292,8 @@ START:
 
 func (i *InfluxDBOutput) SetName(name string)        {}
 func (i *InfluxDBOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (i *InfluxDBOutput) Depths() map[string]int {
+	return map[string]int{"event-chan": len(i.eventChan)}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -34,14 +35,17 @@ const (
 type protoMsg struct {
 	m    proto.Message
 	meta outputs.Meta
+	// ev is set instead of m by WriteEvent
+	ev *formatters.EventMsg
 }
 
 func init() {
 	outputs.Register("kafka", func() outputs.Output {
 		return &KafkaOutput{
-			Cfg:    &Config{},
-			wg:     new(sync.WaitGroup),
-			logger: log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
+			Cfg:         &Config{},
+			wg:          new(sync.WaitGroup),
+			logger:      log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
+			newProducer: sarama.NewSyncProducer,
 		}
 	})
 }
@@ -51,10 +55,15 @@ type KafkaOutput struct {
 	Cfg      *Config
 	logger   sarama.StdLogger
 	mo       *formatters.MarshalOptions
+	ctx      context.Context
 	cancelFn context.CancelFunc
 	msgChan  chan *protoMsg
 	wg       *sync.WaitGroup
 	evps     []formatters.EventProcessor
+	// partition key template, set if the partition-key is a template
+	partitionKeyTpl *template.Template
+	// creates the workers producers
+	newProducer func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error)
 }
 
 // Config //
@@ -72,6 +81,7 @@ type Config struct {
 	BufferSize       int           `mapstructure:"buffer-size,omitempty"`
 	EnableMetrics    bool          `mapstructure:"enable-metrics,omitempty"`
 	EventProcessors  []string      `mapstructure:"event-processors,omitempty"`
+	PartitionKey     string        `mapstructure:"partition-key,omitempty"`
 }
 type sasl struct {
 	User      string `mapstructure:"user,omitempty"`
@@ -138,6 +148,8 @@ func (k *KafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 
 	config := k.createConfig()
 	ctx, k.cancelFn = context.WithCancel(ctx)
+	k.ctx = ctx
+	outputs.SetFlushHandlers(ctx, k, k.evps)
 	k.wg.Add(k.Cfg.NumWorkers)
 	for i := 0; i < k.Cfg.NumWorkers; i++ {
 		cfg := *config
@@ -151,6 +163,15 @@ func (k *KafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (k *KafkaOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, k.Cfg)
+	if err != nil {
+		return err
+	}
+	return k.setDefaults()
+}
+
 func (k *KafkaOutput) setDefaults() error {
 	if k.Cfg.Format == "" {
 		k.Cfg.Format = defaultFormat
@@ -179,6 +200,13 @@ func (k *KafkaOutput) setDefaults() error {
 	if k.Cfg.Name == "" {
 		k.Cfg.Name = "gnmic-" + uuid.New().String()
 	}
+	if strings.Contains(k.Cfg.PartitionKey, "{{") {
+		var err error
+		k.partitionKeyTpl, err = template.New("partition-key").Parse(k.Cfg.PartitionKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse partition-key template: %v", err)
+		}
+	}
 	if k.Cfg.SASL == nil {
 		return nil
 	}
@@ -199,14 +227,25 @@ func (k *KafkaOutput) Write(ctx context.Context, rsp proto.Message, meta outputs
 	if rsp == nil {
 		return
 	}
+	k.send(ctx, &protoMsg{m: rsp, meta: meta})
+}
 
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (k *KafkaOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	k.send(ctx, &protoMsg{meta: ev.Tags, ev: ev})
+}
+
+func (k *KafkaOutput) send(ctx context.Context, m *protoMsg) {
 	wctx, cancel := context.WithTimeout(ctx, k.Cfg.Timeout)
 	defer cancel()
 
 	select {
 	case <-ctx.Done():
 		return
-	case k.msgChan <- &protoMsg{m: rsp, meta: meta}:
+	case k.msgChan <- m:
 	case <-wctx.Done():
 		if k.Cfg.Debug {
 			k.logger.Printf("writing expired after %s, Kafka output might not be initialized", k.Cfg.Timeout)
@@ -218,10 +257,9 @@ func (k *KafkaOutput) Write(ctx context.Context, rsp proto.Message, meta outputs
 	}
 }
 
-func (k *KafkaOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
-
 // Close //
 func (k *KafkaOutput) Close() error {
+	outputs.FlushEventProcessors(k.ctx, k, k.evps)
 	k.cancelFn()
 	k.wg.Wait()
 	return nil
@@ -244,7 +282,7 @@ func (k *KafkaOutput) worker(ctx context.Context, idx int, config *sarama.Config
 	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
 	k.logger.Printf("%s starting", workerLogPrefix)
 CRPROD:
-	producer, err = sarama.NewSyncProducer(strings.Split(k.Cfg.Address, ","), config)
+	producer, err = k.newProducer(strings.Split(k.Cfg.Address, ","), config)
 	if err != nil {
 		k.logger.Printf("%s failed to create kafka producer: %v", workerLogPrefix, err)
 		time.Sleep(k.Cfg.RecoveryWaitTime)
@@ -258,7 +296,7 @@ CRPROD:
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			b, err := k.mo.Marshal(m.m, m.meta, k.evps...)
+			b, err := k.marshal(m)
 			if err != nil {
 				if k.Cfg.Debug {
 					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -272,6 +310,17 @@ CRPROD:
 				Topic: k.Cfg.Topic,
 				Value: sarama.ByteEncoder(b),
 			}
+			if k.Cfg.PartitionKey != "" {
+				key, err := k.partitionKey(m.meta)
+				if err != nil {
+					// the message is still sent, without a key
+					if k.Cfg.Debug {
+						k.logger.Printf("%s failed to render the partition key: %v", workerLogPrefix, err)
+					}
+				} else if key != "" {
+					msg.Key = sarama.StringEncoder(key)
+				}
+			}
 
 			var start time.Time
 			if k.Cfg.EnableMetrics {
@@ -298,6 +347,35 @@ CRPROD:
 	}
 }
 
+// partitionKey returns the key of the message with metadata meta,
+// messages with the same key are sent to the same partition, keeping their order.
+// The partition-key is either the name of a metadata field, e.g source,
+// or a template executed against an event built from the message metadata,
+// i.e: {{ .Name }} is the subscription name and {{ index .Tags "source" }} is the target name.
+// marshal marshals the message m, or its event in format 'event'.
+func (k *KafkaOutput) marshal(m *protoMsg) ([]byte, error) {
+	if m.ev != nil {
+		return k.mo.MarshalEvents([]*formatters.EventMsg{m.ev})
+	}
+	return k.mo.Marshal(m.m, m.meta, k.evps...)
+}
+
+func (k *KafkaOutput) partitionKey(meta outputs.Meta) (string, error) {
+	if k.partitionKeyTpl == nil {
+		return meta[k.Cfg.PartitionKey], nil
+	}
+	ev := &formatters.EventMsg{
+		Name: meta["subscription-name"],
+		Tags: meta,
+	}
+	sb := new(strings.Builder)
+	err := k.partitionKeyTpl.Execute(sb, ev)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
 func (k *KafkaOutput) SetName(name string) {
 	sb := strings.Builder{}
 	if name != "" {
@@ -311,6 +389,11 @@ func (k *KafkaOutput) SetName(name string) {
 
 func (k *KafkaOutput) SetClusterName(name string) {}
 
+// Depths implements outputs.DepthReporter
+func (k *KafkaOutput) Depths() map[string]int {
+	return map[string]int{"msg-chan": len(k.msgChan)}
+}
+
 func (k *KafkaOutput) createConfig() *sarama.Config {
 	cfg := sarama.NewConfig()
 	cfg.ClientID = k.Cfg.Name
@@ -0,0 +1,145 @@
+package kafka_output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// fakeProducer implements sarama.SyncProducer, it records the sent messages
+type fakeProducer struct {
+	m    *sync.Mutex
+	msgs []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(len(p.msgs)), nil
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	for _, msg := range msgs {
+		p.SendMessage(msg)
+	}
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+func (p *fakeProducer) sent() []*sarama.ProducerMessage {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return append([]*sarama.ProducerMessage(nil), p.msgs...)
+}
+
+func testResponse(value int64) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "counter"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: value}},
+				}},
+			},
+		},
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	metas := []outputs.Meta{
+		{"source": "router1", "subscription-name": "sub1"},
+		{"source": "router2", "subscription-name": "sub2"},
+		{"subscription-name": "sub1"},
+	}
+	tests := map[string]struct {
+		partitionKey string
+		// expected key per meta, nil if no key is set
+		want []sarama.Encoder
+	}{
+		"no_key": {
+			want: []sarama.Encoder{nil, nil, nil},
+		},
+		"meta_field": {
+			partitionKey: "source",
+			want:         []sarama.Encoder{sarama.StringEncoder("router1"), sarama.StringEncoder("router2"), nil},
+		},
+		"template": {
+			partitionKey: `{{ index .Tags "source" }}/{{ .Name }}`,
+			want: []sarama.Encoder{
+				sarama.StringEncoder("router1/sub1"),
+				sarama.StringEncoder("router2/sub2"),
+				sarama.StringEncoder("/sub1"),
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fp := &fakeProducer{m: new(sync.Mutex)}
+			k := &KafkaOutput{
+				Cfg:    &Config{},
+				wg:     new(sync.WaitGroup),
+				logger: log.New(ioutil.Discard, "", 0),
+				newProducer: func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+					return fp, nil
+				},
+			}
+			err := k.Init(context.Background(), "kafka1", map[string]interface{}{
+				"format":        "event",
+				"partition-key": tc.partitionKey,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer k.Close()
+			// a single worker keeps the messages order
+			for i, meta := range metas {
+				k.Write(context.Background(), testResponse(int64(i)), meta)
+			}
+			deadline := time.Now().Add(time.Second)
+			for len(fp.sent()) < len(metas) && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			msgs := fp.sent()
+			if len(msgs) != len(metas) {
+				t.Fatalf("expected %d messages, got %d", len(metas), len(msgs))
+			}
+			for i, msg := range msgs {
+				if msg.Key != tc.want[i] {
+					t.Errorf("message %d: unexpected key: got %v, want %v", i, msg.Key, tc.want[i])
+				}
+				// the value is formatted using the configured format
+				b, err := msg.Value.Encode()
+				if err != nil {
+					t.Fatal(err)
+				}
+				evs := make([]*formatters.EventMsg, 0)
+				if err := json.Unmarshal(b, &evs); err != nil {
+					t.Fatalf("message %d: failed to decode events: %v", i, err)
+				}
+				if len(evs) != 1 || evs[0].Tags["subscription-name"] != metas[i]["subscription-name"] {
+					t.Errorf("message %d: unexpected events: %s", i, string(b))
+				}
+			}
+		})
+	}
+}
+
+func TestPartitionKeyInvalidTemplate(t *testing.T) {
+	k := &KafkaOutput{Cfg: &Config{}}
+	err := k.ValidateConfig(map[string]interface{}{"partition-key": "{{ .Name"})
+	if err == nil {
+		t.Fatalf("expected an invalid template error")
+	}
+}
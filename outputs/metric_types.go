@@ -0,0 +1,144 @@
+package outputs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// MetricType is the type of a metric inferred from the YANG schema
+type MetricType int
+
+const (
+	GaugeMetric MetricType = iota
+	CounterMetric
+)
+
+// LoadMetricTypes reads the YANG files, or the YANG files found in the directories, listed in files.
+// the directories listed in dirs are searched for the imported and included modules.
+// It returns a map of the schema leaves paths (without prefixes) to the metric type of their values,
+// the event value names are converted to those paths using SchemaPath.
+func LoadMetricTypes(files, dirs []string) (map[string]MetricType, error) {
+	for _, dir := range dirs {
+		expanded, err := yang.PathsWithModules(dir)
+		if err != nil {
+			return nil, err
+		}
+		yang.AddPath(expanded...)
+	}
+	yfiles, err := findYangFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(yfiles) == 0 {
+		return nil, fmt.Errorf("no YANG files found in %v", files)
+	}
+	ms := yang.NewModules()
+	for _, f := range yfiles {
+		if err := ms.Read(f); err != nil {
+			return nil, err
+		}
+	}
+	if errs := ms.Process(); len(errs) > 0 {
+		return nil, fmt.Errorf("yang processing failed with %d errors: %v", len(errs), errs[0])
+	}
+	types := make(map[string]MetricType)
+	seen := make(map[string]struct{})
+	for _, m := range ms.Modules {
+		if _, ok := seen[m.Name]; ok {
+			continue
+		}
+		seen[m.Name] = struct{}{}
+		for _, e := range yang.ToEntry(m).Dir {
+			collectMetricTypes(e, "", types)
+		}
+	}
+	return types, nil
+}
+
+func collectMetricTypes(e *yang.Entry, parent string, types map[string]MetricType) {
+	if e == nil {
+		return
+	}
+	path := parent + "/" + e.Name
+	if e.IsLeaf() || e.IsLeafList() {
+		types[path] = leafMetricType(e)
+		return
+	}
+	for _, c := range e.Dir {
+		collectMetricTypes(c, path, types)
+	}
+}
+
+// leafMetricType returns CounterMetric if the leaf type is,
+// or is derived from, a counter32 or counter64 typedef; GaugeMetric otherwise.
+func leafMetricType(e *yang.Entry) MetricType {
+	for t := e.Type; t != nil; {
+		if isCounterType(t.Name) {
+			return CounterMetric
+		}
+		if t.Base == nil {
+			break
+		}
+		if isCounterType(t.Base.Name) {
+			return CounterMetric
+		}
+		t = t.Base.YangType
+	}
+	return GaugeMetric
+}
+
+func isCounterType(name string) bool {
+	if idx := strings.Index(name, ":"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name == "counter32" || name == "counter64"
+}
+
+// SchemaPath removes the module prefixes and the list keys from an event value name
+func SchemaPath(valueName string) string {
+	elems := strings.Split(strings.TrimPrefix(valueName, "/"), "/")
+	for i, elem := range elems {
+		if idx := strings.Index(elem, "["); idx >= 0 {
+			elem = elem[:idx]
+		}
+		if idx := strings.Index(elem, ":"); idx >= 0 {
+			elem = elem[idx+1:]
+		}
+		elems[i] = elem
+	}
+	return "/" + strings.Join(elems, "/")
+}
+
+func findYangFiles(files []string) ([]string, error) {
+	yfiles := make([]string, 0, len(files))
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			err = filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.Mode().IsRegular() && filepath.Ext(path) == ".yang" {
+					yfiles = append(yfiles, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		case mode.IsRegular():
+			if filepath.Ext(file) == ".yang" {
+				yfiles = append(yfiles, file)
+			}
+		}
+	}
+	return yfiles, nil
+}
@@ -0,0 +1,77 @@
+package outputs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTypesModule = `module test-types {
+  namespace "urn:test-types";
+  prefix tt;
+  typedef counter64 {
+    type uint64;
+  }
+  typedef octets-counter {
+    type counter64;
+  }
+}
+`
+
+const testModule = `module test-interfaces {
+  namespace "urn:test-interfaces";
+  prefix ti;
+  import test-types { prefix tt; }
+  container interfaces {
+    list interface {
+      key name;
+      leaf name { type string; }
+      container state {
+        leaf mtu { type uint16; }
+        container counters {
+          leaf in-octets { type tt:counter64; }
+          leaf out-octets { type tt:octets-counter; }
+        }
+      }
+    }
+  }
+}
+`
+
+func writeTestSchema(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "metric_types")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range map[string]string{
+		"test-types.yang":      testTypesModule,
+		"test-interfaces.yang": testModule,
+	} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMetricTypes(t *testing.T) {
+	dir := writeTestSchema(t)
+	types, err := LoadMetricTypes([]string{dir}, []string{dir})
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	for valueName, want := range map[string]MetricType{
+		"/interfaces/interface/state/counters/in-octets":                            CounterMetric,
+		"/test-interfaces:interfaces/interface[name=eth0]/state/counters/in-octets": CounterMetric,
+		"/interfaces/interface/state/counters/out-octets":                           CounterMetric,
+		"/interfaces/interface/state/mtu":                                           GaugeMetric,
+		"/interfaces/interface/state/unknown":                                       GaugeMetric,
+	} {
+		if got := types[SchemaPath(valueName)]; got != want {
+			t.Errorf("%s: expected type %v, got %v", valueName, want, got)
+		}
+	}
+}
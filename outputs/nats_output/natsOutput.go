@@ -43,6 +43,8 @@ func init() {
 type protoMsg struct {
 	m    proto.Message
 	meta outputs.Meta
+	// ev is set instead of m by WriteEvent
+	ev *formatters.EventMsg
 }
 
 // NatsOutput //
@@ -131,6 +133,7 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	initMetrics()
 	n.mo = &formatters.MarshalOptions{Format: n.Cfg.Format}
 	n.ctx, n.cancelFn = context.WithCancel(ctx)
+	outputs.SetFlushHandlers(n.ctx, n, n.evps)
 	n.wg.Add(n.Cfg.NumWorkers)
 	for i := 0; i < n.Cfg.NumWorkers; i++ {
 		cfg := *n.Cfg
@@ -145,6 +148,15 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (n *NatsOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, n.Cfg)
+	if err != nil {
+		return err
+	}
+	return n.setDefaults()
+}
+
 func (n *NatsOutput) setDefaults() error {
 	if n.Cfg.Format == "" {
 		n.Cfg.Format = defaultFormat
@@ -178,14 +190,25 @@ func (n *NatsOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 	if rsp == nil || n.mo == nil {
 		return
 	}
+	n.send(ctx, &protoMsg{m: rsp, meta: meta})
+}
 
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (n *NatsOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil || n.mo == nil {
+		return
+	}
+	n.send(ctx, &protoMsg{meta: ev.Tags, ev: ev})
+}
+
+func (n *NatsOutput) send(ctx context.Context, m *protoMsg) {
 	wctx, cancel := context.WithTimeout(ctx, n.Cfg.WriteTimeout)
 	defer cancel()
 
 	select {
 	case <-ctx.Done():
 		return
-	case n.msgChan <- &protoMsg{m: rsp, meta: meta}:
+	case n.msgChan <- m:
 	case <-wctx.Done():
 		if n.Cfg.Debug {
 			n.logger.Printf("writing expired after %s, NATS output might not be initialized", n.Cfg.WriteTimeout)
@@ -197,10 +220,9 @@ func (n *NatsOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 	}
 }
 
-func (n *NatsOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
-
 // Close //
 func (n *NatsOutput) Close() error {
+	outputs.FlushEventProcessors(n.ctx, n, n.evps)
 	//	n.conn.Close()
 	n.cancelFn()
 	n.wg.Wait()
@@ -291,7 +313,7 @@ CRCONN:
 			n.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-n.msgChan:
-			b, err := n.mo.Marshal(m.m, m.meta, n.evps...)
+			b, err := n.marshal(m)
 			if err != nil {
 				if n.Cfg.Debug {
 					n.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -327,6 +349,14 @@ CRCONN:
 	}
 }
 
+// marshal marshals the message m, or its event in format 'event'.
+func (n *NatsOutput) marshal(m *protoMsg) ([]byte, error) {
+	if m.ev != nil {
+		return n.mo.MarshalEvents([]*formatters.EventMsg{m.ev})
+	}
+	return n.mo.Marshal(m.m, m.meta, n.evps...)
+}
+
 func (n *NatsOutput) subjectName(c *Config, meta outputs.Meta) string {
 	if c.SubjectPrefix != "" {
 		ssb := strings.Builder{}
@@ -358,3 +388,8 @@ func (n *NatsOutput) SetName(name string) {
 }
 
 func (n *NatsOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (n *NatsOutput) Depths() map[string]int {
+	return map[string]int{"msg-chan": len(n.msgChan)}
+}
@@ -0,0 +1,125 @@
+package otlp_output
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const httpMetricsPath = "/v1/metrics"
+
+// exporter sends an export request to an OTLP receiver
+type exporter interface {
+	export(context.Context, *colmetricspb.ExportMetricsServiceRequest) error
+	close() error
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	headers metadata.MD
+}
+
+func newGRPCExporter(ctx context.Context, cfg *Config) (*grpcExporter, error) {
+	opts := []grpc.DialOption{}
+	if cfg.Insecure {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+	}
+	conn, err := grpc.DialContext(ctx, cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %q: %v", cfg.Endpoint, err)
+	}
+	return &grpcExporter{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	_, err := e.client.Export(ctx, req)
+	return err
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// newHTTPExporter creates an OTLP/HTTP exporter sending protobuf encoded requests.
+// If the endpoint does not include a scheme, https is used unless insecure is set.
+// If it does not include a path, the default metrics path "/v1/metrics" is used.
+func newHTTPExporter(cfg *Config) (*httpExporter, error) {
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		if cfg.Insecure {
+			endpoint = "http://" + endpoint
+		} else {
+			endpoint = "https://" + endpoint
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %v", cfg.Endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = httpMetricsPath
+	}
+	return &httpExporter{
+		client:  &http.Client{},
+		url:     u.String(),
+		headers: cfg.Headers,
+	}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.headers {
+		hreq.Header.Set(k, v)
+	}
+	rsp, err := e.client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, rsp.Body)
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status from %s: %s", e.url, rsp.Status)
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
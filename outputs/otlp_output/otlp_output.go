@@ -0,0 +1,462 @@
+package otlp_output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultProtocol     = "grpc"
+	defaultGRPCEndpoint = "localhost:4317"
+	defaultHTTPEndpoint = "localhost:4318"
+	defaultTimeout      = 10 * time.Second
+	defaultBatchSize    = 1000
+	defaultFlushTimer   = 10 * time.Second
+	defaultServiceName  = "gnmic"
+	scopeName           = "gnmic"
+	loggingPrefix       = "[otlp_output] "
+)
+
+func init() {
+	outputs.Register("otlp", func() outputs.Output {
+		return &OTLPOutput{
+			Cfg:       &Config{},
+			eventChan: make(chan *formatters.EventMsg),
+			wg:        new(sync.WaitGroup),
+			logger:    log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
+		}
+	})
+}
+
+// OTLPOutput converts the events numeric values into OpenTelemetry metrics data points
+// and exports them in batches to an OTLP receiver.
+type OTLPOutput struct {
+	Cfg       *Config
+	logger    *log.Logger
+	eventChan chan *formatters.EventMsg
+	cancelFn  context.CancelFunc
+	wg        *sync.WaitGroup
+	// Close is called explicitly and once the output context is done
+	closeOnce sync.Once
+	closeErr  error
+	evps      []formatters.EventProcessor
+	exporter  exporter
+	resource  *resourcepb.Resource
+	// start time of the cumulative sums
+	startTime time.Time
+	// schema leaves paths to metric types, used if InferMetricTypes is true
+	metricTypes map[string]outputs.MetricType
+}
+
+type Config struct {
+	Endpoint           string            `mapstructure:"endpoint,omitempty"`
+	Protocol           string            `mapstructure:"protocol,omitempty"`
+	Headers            map[string]string `mapstructure:"headers,omitempty"`
+	Insecure           bool              `mapstructure:"insecure,omitempty"`
+	Timeout            time.Duration     `mapstructure:"timeout,omitempty"`
+	BatchSize          int               `mapstructure:"batch-size,omitempty"`
+	FlushTimer         time.Duration     `mapstructure:"flush-timer,omitempty"`
+	MetricPrefix       string            `mapstructure:"metric-prefix,omitempty"`
+	ResourceAttributes map[string]string `mapstructure:"resource-attributes,omitempty"`
+	InferMetricTypes   bool              `mapstructure:"infer-metric-types,omitempty"`
+	YangFiles          []string          `mapstructure:"yang-files,omitempty"`
+	YangDirs           []string          `mapstructure:"yang-dirs,omitempty"`
+	Debug              bool              `mapstructure:"debug,omitempty"`
+	EventProcessors    []string          `mapstructure:"event-processors,omitempty"`
+}
+
+func (o *OTLPOutput) String() string {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (o *OTLPOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && o.logger != nil {
+		o.logger.SetOutput(logger.Writer())
+		o.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (o *OTLPOutput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]interface{}) {
+	for _, epName := range o.Cfg.EventProcessors {
+		if epCfg, ok := ps[epName]; ok {
+			epType := ""
+			for k := range epCfg {
+				epType = k
+				break
+			}
+			if in, ok := formatters.EventProcessors[epType]; ok {
+				ep := in()
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				if err != nil {
+					o.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
+					continue
+				}
+				o.evps = append(o.evps, ep)
+				o.logger.Printf("added event processor '%s' of type=%s to otlp output", epName, epType)
+			}
+		}
+	}
+}
+
+func (o *OTLPOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, o.Cfg)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	err = o.setDefaults()
+	if err != nil {
+		return err
+	}
+	if o.Cfg.InferMetricTypes {
+		o.metricTypes, err = outputs.LoadMetricTypes(o.Cfg.YangFiles, o.Cfg.YangDirs)
+		if err != nil {
+			o.logger.Printf("failed to load YANG schema, metrics will be exported as gauges: %v", err)
+		}
+	}
+	o.resource = newResource(o.Cfg.ResourceAttributes)
+	o.startTime = time.Now()
+	ctx, o.cancelFn = context.WithCancel(ctx)
+	switch o.Cfg.Protocol {
+	case "grpc":
+		o.exporter, err = newGRPCExporter(ctx, o.Cfg)
+	case "http":
+		o.exporter, err = newHTTPExporter(o.Cfg)
+	}
+	if err != nil {
+		o.cancelFn()
+		return err
+	}
+	// the events held by the processors, e.g over a merge window, are exported once due
+	outputs.SetFlushHandlers(ctx, o, o.evps)
+	o.wg.Add(1)
+	go o.worker(ctx)
+	o.logger.Printf("initialized otlp output: %s", o.String())
+	go func() {
+		<-ctx.Done()
+		o.Close()
+	}()
+	return nil
+}
+
+// ValidateConfig implements outputs.ConfigValidator
+func (o *OTLPOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, o.Cfg)
+	if err != nil {
+		return err
+	}
+	return o.setDefaults()
+}
+
+func (o *OTLPOutput) setDefaults() error {
+	if o.Cfg.Protocol == "" {
+		o.Cfg.Protocol = defaultProtocol
+	}
+	o.Cfg.Protocol = strings.ToLower(o.Cfg.Protocol)
+	switch o.Cfg.Protocol {
+	case "grpc":
+		if o.Cfg.Endpoint == "" {
+			o.Cfg.Endpoint = defaultGRPCEndpoint
+		}
+	case "http":
+		if o.Cfg.Endpoint == "" {
+			o.Cfg.Endpoint = defaultHTTPEndpoint
+		}
+	default:
+		return fmt.Errorf("unknown protocol %q, must be one of 'grpc' or 'http'", o.Cfg.Protocol)
+	}
+	if o.Cfg.Timeout <= 0 {
+		o.Cfg.Timeout = defaultTimeout
+	}
+	if o.Cfg.BatchSize <= 0 {
+		o.Cfg.BatchSize = defaultBatchSize
+	}
+	if o.Cfg.FlushTimer <= 0 {
+		o.Cfg.FlushTimer = defaultFlushTimer
+	}
+	return nil
+}
+
+func (o *OTLPOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+	switch rsp := rsp.(type) {
+	case *gnmi.SubscribeResponse:
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, o.evps...)
+		if err != nil {
+			o.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			o.WriteEvent(ctx, ev)
+		}
+	}
+}
+
+func (o *OTLPOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	case o.eventChan <- ev:
+	}
+}
+
+func (o *OTLPOutput) Close() error {
+	if o.cancelFn == nil {
+		return nil
+	}
+	o.closeOnce.Do(func() {
+		o.cancelFn()
+		o.wg.Wait()
+		if o.exporter != nil {
+			o.closeErr = o.exporter.close()
+		}
+	})
+	return o.closeErr
+}
+
+func (o *OTLPOutput) RegisterMetrics(reg *prometheus.Registry) {}
+
+func (o *OTLPOutput) SetName(name string)        {}
+func (o *OTLPOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (o *OTLPOutput) Depths() map[string]int {
+	return map[string]int{"event-chan": len(o.eventChan)}
+}
+
+// worker buffers the received events and exports them once the batch size is reached,
+// or when the flush timer ticks.
+func (o *OTLPOutput) worker(ctx context.Context) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.Cfg.FlushTimer)
+	defer ticker.Stop()
+	batch := make([]*formatters.EventMsg, 0, o.Cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// flush the remaining events, including the ones held by the processors,
+			// the output context is already done
+			batch = append(batch, formatters.FlushProcessors(o.evps)...)
+			o.flush(context.Background(), batch)
+			return
+		case ev := <-o.eventChan:
+			batch = append(batch, ev)
+			if len(batch) >= o.Cfg.BatchSize {
+				o.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			o.flush(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (o *OTLPOutput) flush(ctx context.Context, batch []*formatters.EventMsg) {
+	if len(batch) == 0 {
+		return
+	}
+	req := o.createRequest(batch)
+	if len(req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.Cfg.Timeout)
+	defer cancel()
+	err := o.exporter.export(ctx, req)
+	if err != nil {
+		o.logger.Printf("failed to export %d events: %v", len(batch), err)
+		return
+	}
+	if o.Cfg.Debug {
+		o.logger.Printf("exported %d events", len(batch))
+	}
+}
+
+// createRequest converts the events numeric values into metrics data points,
+// the data points of values with the same name are grouped under the same metric.
+func (o *OTLPOutput) createRequest(evs []*formatters.EventMsg) *colmetricspb.ExportMetricsServiceRequest {
+	metrics := make([]*metricspb.Metric, 0)
+	byName := make(map[string]*metricspb.Metric)
+	for _, ev := range evs {
+		if ev == nil {
+			continue
+		}
+		attrs := attributes(ev.Tags)
+		for vName, v := range ev.Values {
+			dp := &metricspb.NumberDataPoint{
+				Attributes:   attrs,
+				TimeUnixNano: uint64(ev.Timestamp),
+			}
+			if !setValue(dp, v) {
+				continue
+			}
+			name := o.metricName(vName)
+			m, ok := byName[name]
+			if !ok {
+				m = o.newMetric(name, vName)
+				byName[name] = m
+				metrics = append(metrics, m)
+			}
+			switch data := m.Data.(type) {
+			case *metricspb.Metric_Gauge:
+				data.Gauge.DataPoints = append(data.Gauge.DataPoints, dp)
+			case *metricspb.Metric_Sum:
+				// the counters are accumulated from an unknown time,
+				// the output start time is used, unless the data point is older.
+				dp.StartTimeUnixNano = uint64(o.startTime.UnixNano())
+				if dp.TimeUnixNano < dp.StartTimeUnixNano {
+					dp.StartTimeUnixNano = dp.TimeUnixNano
+				}
+				data.Sum.DataPoints = append(data.Sum.DataPoints, dp)
+			}
+		}
+	}
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: o.resource,
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+// newMetric creates a cumulative monotonic sum if the value is a counter per the YANG schema,
+// a gauge otherwise.
+func (o *OTLPOutput) newMetric(name, valueName string) *metricspb.Metric {
+	m := &metricspb.Metric{Name: name}
+	if o.Cfg.InferMetricTypes && o.metricTypes[outputs.SchemaPath(valueName)] == outputs.CounterMetric {
+		m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+		return m
+	}
+	m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+	return m
+}
+
+// metricName returns the value name without its leading "/",
+// prefixed with the configured metric prefix.
+func (o *OTLPOutput) metricName(valueName string) string {
+	name := strings.TrimPrefix(valueName, "/")
+	if o.Cfg.MetricPrefix == "" {
+		return name
+	}
+	return o.Cfg.MetricPrefix + "/" + name
+}
+
+// setValue sets the data point value from an event value,
+// it returns false if the value is not numeric.
+func setValue(dp *metricspb.NumberDataPoint, v interface{}) bool {
+	switch v := v.(type) {
+	case int:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int64:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+	case uint:
+		return setUint(dp, uint64(v))
+	case uint8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint64:
+		return setUint(dp, v)
+	case float32:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+	case float64:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+	case string:
+		// 64 bits integers are encoded as strings in JSON_IETF
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: i}
+			return true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: f}
+	default:
+		return false
+	}
+	return true
+}
+
+func setUint(dp *metricspb.NumberDataPoint, v uint64) bool {
+	if v > math.MaxInt64 {
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+		return true
+	}
+	dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	return true
+}
+
+// attributes converts the event tags into data point attributes, sorted by key
+func attributes(tags map[string]string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(tags))
+	for k, v := range tags {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+		})
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	return attrs
+}
+
+// newResource creates the resource describing gnmic,
+// its service.name attribute defaults to "gnmic".
+func newResource(attrs map[string]string) *resourcepb.Resource {
+	tags := map[string]string{"service.name": defaultServiceName}
+	for k, v := range attrs {
+		tags[k] = v
+	}
+	return &resourcepb.Resource{Attributes: attributes(tags)}
+}
@@ -0,0 +1,321 @@
+package otlp_output
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// received is an export request along with the value of its x-api-key header
+type received struct {
+	req    *colmetricspb.ExportMetricsServiceRequest
+	apiKey string
+}
+
+// mockReceiver is an OTLP gRPC metrics receiver
+type mockReceiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	reqs chan *received
+}
+
+func (r *mockReceiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	rcv := &received{req: req}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+		rcv.apiKey = md.Get("x-api-key")[0]
+	}
+	r.reqs <- rcv
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func startGRPCReceiver(t *testing.T, reqs chan *received) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(s, &mockReceiver{reqs: reqs})
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func startHTTPReceiver(t *testing.T, reqs chan *received) string {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != httpMetricsPath || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := new(colmetricspb.ExportMetricsServiceRequest)
+		err = proto.Unmarshal(b, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reqs <- &received{req: req, apiKey: r.Header.Get("x-api-key")}
+	}))
+	t.Cleanup(s.Close)
+	return s.URL
+}
+
+func attributesMap(kvs []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range kvs {
+		m[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	return m
+}
+
+func TestOTLPOutput(t *testing.T) {
+	for _, protocol := range []string{"grpc", "http"} {
+		t.Run(protocol, func(t *testing.T) {
+			reqs := make(chan *received, 1)
+			var endpoint string
+			switch protocol {
+			case "grpc":
+				endpoint = startGRPCReceiver(t, reqs)
+			case "http":
+				endpoint = startHTTPReceiver(t, reqs)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			o := outputs.Outputs["otlp"]()
+			err := o.Init(ctx, "otlp", map[string]interface{}{
+				"endpoint":            endpoint,
+				"protocol":            protocol,
+				"insecure":            true,
+				"batch-size":          2,
+				"flush-timer":         "1h",
+				"headers":             map[string]string{"x-api-key": "secret"},
+				"resource-attributes": map[string]string{"deployment.environment": "lab"},
+			})
+			if err != nil {
+				t.Fatalf("failed to init output: %v", err)
+			}
+			defer o.Close()
+			tags := map[string]string{"source": "router1", "subscription-name": "sub1"}
+			o.WriteEvent(ctx, &formatters.EventMsg{
+				Name:      "sub1",
+				Timestamp: 42,
+				Tags:      tags,
+				Values: map[string]interface{}{
+					"/interfaces/interface/state/counters/in-octets": 100,
+					"/interfaces/interface/state/oper-status":        "UP",
+				},
+			})
+			o.WriteEvent(ctx, &formatters.EventMsg{
+				Name:      "sub1",
+				Timestamp: 43,
+				Tags:      tags,
+				Values: map[string]interface{}{
+					"/interfaces/interface/state/counters/in-octets": "18446744073709551615",
+					"/interfaces/interface/state/mtu":                uint16(1500),
+				},
+			})
+
+			var rcv *received
+			select {
+			case rcv = <-reqs:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for the export request")
+			}
+			if rcv.apiKey != "secret" {
+				t.Errorf("expected header x-api-key=secret, got %q", rcv.apiKey)
+			}
+			rms := rcv.req.GetResourceMetrics()
+			if len(rms) != 1 || len(rms[0].GetScopeMetrics()) != 1 {
+				t.Fatalf("unexpected request: %v", rcv.req)
+			}
+			rattrs := attributesMap(rms[0].GetResource().GetAttributes())
+			if rattrs["service.name"] != "gnmic" || rattrs["deployment.environment"] != "lab" {
+				t.Errorf("unexpected resource attributes: %v", rattrs)
+			}
+			metrics := make(map[string]*metricspb.Metric)
+			for _, m := range rms[0].GetScopeMetrics()[0].GetMetrics() {
+				metrics[m.GetName()] = m
+			}
+			if len(metrics) != 2 {
+				t.Fatalf("expected 2 metrics, got %v", metrics)
+			}
+			dps := metrics["interfaces/interface/state/counters/in-octets"].GetGauge().GetDataPoints()
+			if len(dps) != 2 {
+				t.Fatalf("expected 2 in-octets data points, got %v", dps)
+			}
+			if dps[0].GetAsInt() != 100 || dps[0].GetTimeUnixNano() != 42 {
+				t.Errorf("unexpected data point: %v", dps[0])
+			}
+			if dps[1].GetAsDouble() != 18446744073709551615 || dps[1].GetTimeUnixNano() != 43 {
+				t.Errorf("unexpected data point: %v", dps[1])
+			}
+			if attrs := attributesMap(dps[0].GetAttributes()); len(attrs) != 2 || attrs["source"] != "router1" || attrs["subscription-name"] != "sub1" {
+				t.Errorf("unexpected data point attributes: %v", attrs)
+			}
+			dps = metrics["interfaces/interface/state/mtu"].GetGauge().GetDataPoints()
+			if len(dps) != 1 || dps[0].GetAsInt() != 1500 {
+				t.Errorf("unexpected mtu data points: %v", dps)
+			}
+		})
+	}
+}
+
+func TestOTLPOutputFlushProcessors(t *testing.T) {
+	reqs := make(chan *received, 2)
+	endpoint := startGRPCReceiver(t, reqs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the first merge window expires while the output runs, the second one is flushed on close
+	merge := formatters.EventProcessors["event-merge"]()
+	err := merge.Init(map[string]interface{}{
+		"key-tags": []string{"source"},
+		"window":   "100ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := outputs.Outputs["otlp"]().(*OTLPOutput)
+	o.evps = []formatters.EventProcessor{merge}
+	err = o.Init(ctx, "otlp", map[string]interface{}{
+		"endpoint":    endpoint,
+		"insecure":    true,
+		"batch-size":  1,
+		"flush-timer": "1h",
+	})
+	if err != nil {
+		t.Fatalf("failed to init output: %v", err)
+	}
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+	response := func(ts int64, name string, v int64) *gnmi.SubscribeResponse {
+		return &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Timestamp: ts,
+			Update: []*gnmi.Update{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: name}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: v}},
+			}},
+		}}}
+	}
+	o.Write(ctx, response(1, "cpu", 10), meta)
+	o.Write(ctx, response(1, "memory", 20), meta)
+	metricNames := func(rcv *received) []string {
+		names := make([]string, 0)
+		for _, m := range rcv.req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics() {
+			names = append(names, m.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+	select {
+	case rcv := <-reqs:
+		if names := metricNames(rcv); !reflect.DeepEqual(names, []string{"cpu", "memory"}) {
+			t.Errorf("expected the merged cpu and memory metrics, got %v", names)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the merge window to be exported")
+	}
+
+	o.Write(ctx, response(2, "temperature", 30), meta)
+	o.Close()
+	select {
+	case rcv := <-reqs:
+		if names := metricNames(rcv); !reflect.DeepEqual(names, []string{"temperature"}) {
+			t.Errorf("expected the pending temperature metric, got %v", names)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the pending merge window to be exported on close")
+	}
+}
+
+func TestCreateRequestMetricTypes(t *testing.T) {
+	start := time.Unix(0, 100)
+	o := &OTLPOutput{
+		Cfg:       &Config{InferMetricTypes: true, MetricPrefix: "gnmic"},
+		startTime: start,
+		metricTypes: map[string]outputs.MetricType{
+			"/interfaces/interface/state/counters/in-octets": outputs.CounterMetric,
+			"/interfaces/interface/state/mtu":                outputs.GaugeMetric,
+		},
+	}
+	req := o.createRequest([]*formatters.EventMsg{
+		{
+			Timestamp: 200,
+			Values: map[string]interface{}{
+				"/interfaces/interface/state/counters/in-octets": 1,
+				"/interfaces/interface/state/mtu":                1500,
+				"/interfaces/interface/state/unknown":            1.5,
+			},
+		},
+	})
+	metrics := make(map[string]*metricspb.Metric)
+	for _, m := range req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics() {
+		metrics[m.GetName()] = m
+	}
+	sum := metrics["gnmic/interfaces/interface/state/counters/in-octets"].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() ||
+		sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE ||
+		len(sum.GetDataPoints()) != 1 {
+		t.Errorf("expected in-octets to be a cumulative monotonic sum, got %v", metrics["gnmic/interfaces/interface/state/counters/in-octets"])
+	}
+	if dp := sum.GetDataPoints()[0]; dp.GetStartTimeUnixNano() != uint64(start.UnixNano()) || dp.GetTimeUnixNano() != 200 {
+		t.Errorf("expected the in-octets data point to start at the output start time, got %v", dp)
+	}
+	for _, name := range []string{"gnmic/interfaces/interface/state/mtu", "gnmic/interfaces/interface/state/unknown"} {
+		if metrics[name].GetGauge() == nil {
+			t.Errorf("expected %s to be a gauge, got %v", name, metrics[name])
+		}
+	}
+}
+
+// countingExporter counts the calls to close
+type countingExporter struct {
+	closed int32
+}
+
+func (e *countingExporter) export(context.Context, *colmetricspb.ExportMetricsServiceRequest) error {
+	return nil
+}
+
+func (e *countingExporter) close() error {
+	atomic.AddInt32(&e.closed, 1)
+	return nil
+}
+
+func TestOTLPOutputCloseOnce(t *testing.T) {
+	o := outputs.Outputs["otlp"]().(*OTLPOutput)
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancelFn = cancel
+	e := new(countingExporter)
+	o.exporter = e
+	// as started by Init
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		o.Close()
+		close(done)
+	}()
+	o.Close()
+	o.Close()
+	<-done
+	if c := atomic.LoadInt32(&e.closed); c != 1 {
+		t.Errorf("expected the exporter to be closed once, got %d", c)
+	}
+}
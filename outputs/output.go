@@ -3,6 +3,7 @@ package outputs
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/karimra/gnmic/formatters"
 	_ "github.com/karimra/gnmic/formatters/all"
@@ -25,15 +26,66 @@ type Output interface {
 	SetClusterName(string)
 }
 
+// DepthReporter is optionally implemented by outputs,
+// it reports the number of items buffered in the output internal channels and caches.
+type DepthReporter interface {
+	Depths() map[string]int
+}
+
+// ErrorReporter is optionally implemented by outputs,
+// it reports the last error that occurred while handling each target's messages.
+type ErrorReporter interface {
+	LastErrors() map[string]*TargetError
+}
+
+// TargetError is the last error reported by an output for a target.
+type TargetError struct {
+	Category string    `json:"category,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time,omitempty"`
+}
+
+// TargetStateHandler is optionally implemented by outputs,
+// it is notified when a target subscription fails.
+type TargetStateHandler interface {
+	TargetDown(name string)
+}
+
+// ConfigValidator is optionally implemented by outputs,
+// it decodes and validates the output config without opening any listener or connection.
+type ConfigValidator interface {
+	ValidateConfig(map[string]interface{}) error
+}
+
+// SetFlushHandlers sets the flush handlers of the event processors evps,
+// the events they flush on their own are written to the output o with WriteEvent.
+func SetFlushHandlers(ctx context.Context, o Output, evps []formatters.EventProcessor) {
+	formatters.SetFlushHandlers(evps, func(es []*formatters.EventMsg) {
+		for _, ev := range es {
+			o.WriteEvent(ctx, ev)
+		}
+	})
+}
+
+// FlushEventProcessors writes the events still held by the event processors evps to the output o,
+// it is called when the output closes.
+func FlushEventProcessors(ctx context.Context, o Output, evps []formatters.EventProcessor) {
+	for _, ev := range formatters.FlushProcessors(evps) {
+		o.WriteEvent(ctx, ev)
+	}
+}
+
 type Initializer func() Output
 
 var Outputs = map[string]Initializer{}
 
 var OutputTypes = []string{
 	"file",
+	"gnmi_server",
 	"influxdb",
 	"kafka",
 	"nats",
+	"otlp",
 	"prometheus",
 	"stan",
 	"tcp",
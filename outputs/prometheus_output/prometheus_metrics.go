@@ -0,0 +1,253 @@
+package prometheus_output
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// targetsUpCollector implements prometheus.Collector,
+// it exports a gauge per target set to 1 if events are received from the target,
+// and 0 if its subscription failed or if no events were received within the metrics expiration period.
+type targetsUpCollector struct {
+	p    *PrometheusOutput
+	desc *prometheus.Desc
+
+	m        *sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+func newTargetsUpCollector(p *PrometheusOutput) *targetsUpCollector {
+	return &targetsUpCollector{
+		p: p,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("gnmic", "prometheus", "target_up"),
+			"Whether the prometheus output is receiving events from the target",
+			[]string{"target"},
+			prometheus.Labels{"output": p.Cfg.Name},
+		),
+		m:        new(sync.Mutex),
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+func (c *targetsUpCollector) seen(target string, t time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.lastSeen[target] = t
+}
+
+func (c *targetsUpCollector) down(target string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.lastSeen[target] = time.Time{}
+}
+
+// Describe implements prometheus.Collector
+func (c *targetsUpCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *targetsUpCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	for target, t := range c.lastSeen {
+		up := 0.0
+		if !t.IsZero() && (!c.p.expires() || now.Sub(t) < c.p.Cfg.Expiration) {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, up, target)
+	}
+}
+
+// subscriptionSyncCollector implements prometheus.Collector,
+// it exports a gauge per target and subscription set to 1 once the target sent a sync response.
+type subscriptionSyncCollector struct {
+	desc *prometheus.Desc
+
+	m      *sync.Mutex
+	synced map[[2]string]struct{}
+}
+
+func newSubscriptionSyncCollector() *subscriptionSyncCollector {
+	return &subscriptionSyncCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("gnmic", "subscription", "synced"),
+			"Whether the target sent a sync response for the subscription",
+			[]string{"subscription", "target"},
+			nil,
+		),
+		m:      new(sync.Mutex),
+		synced: make(map[[2]string]struct{}),
+	}
+}
+
+func (c *subscriptionSyncCollector) sync(subscription, target string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.synced[[2]string{subscription, target}] = struct{}{}
+}
+
+// reset removes the subscriptions of target, they are synced again by the next sync response.
+func (c *subscriptionSyncCollector) reset(target string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for k := range c.synced {
+		if k[1] == target {
+			delete(c.synced, k)
+		}
+	}
+}
+
+func (c *subscriptionSyncCollector) isSynced(subscription, target string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	_, ok := c.synced[[2]string{subscription, target}]
+	return ok
+}
+
+// Describe implements prometheus.Collector
+func (c *subscriptionSyncCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *subscriptionSyncCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for k := range c.synced {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1, k[0], k[1])
+	}
+}
+
+// lastErrorsCollector implements prometheus.Collector,
+// it stores the last error per target and exports it as an info gauge
+// labeled with the target name and the error category.
+type lastErrorsCollector struct {
+	// desc is set when the collector is registered, nil if enable-metrics is false
+	desc *prometheus.Desc
+
+	m    *sync.Mutex
+	errs map[string]*outputs.TargetError
+}
+
+func newLastErrorsCollector() *lastErrorsCollector {
+	return &lastErrorsCollector{
+		m:    new(sync.Mutex),
+		errs: make(map[string]*outputs.TargetError),
+	}
+}
+
+func (c *lastErrorsCollector) setOutputName(name string) {
+	c.desc = prometheus.NewDesc(
+		prometheus.BuildFQName("gnmic", "prometheus", "last_error"),
+		"Last error that occurred while handling the target messages, labeled with its category",
+		[]string{"target", "category"},
+		prometheus.Labels{"output": name},
+	)
+}
+
+func (c *lastErrorsCollector) record(target, category string, err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.errs[target] = &outputs.TargetError{
+		Category: category,
+		Error:    err.Error(),
+		Time:     time.Now(),
+	}
+}
+
+// clear removes the error stored for target, if any.
+func (c *lastErrorsCollector) clear(target string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.errs, target)
+}
+
+// lastErrors returns a copy of the stored errors
+func (c *lastErrorsCollector) lastErrors() map[string]*outputs.TargetError {
+	c.m.Lock()
+	defer c.m.Unlock()
+	errs := make(map[string]*outputs.TargetError, len(c.errs))
+	for target, te := range c.errs {
+		cte := *te
+		errs[target] = &cte
+	}
+	return errs
+}
+
+// Describe implements prometheus.Collector
+func (c *lastErrorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *lastErrorsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for target, te := range c.errs {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1, target, te.Category)
+	}
+}
+
+// errorCategory returns the category of an error returned while converting a message to events
+func errorCategory(err error) string {
+	var serr *json.SyntaxError
+	var uerr *json.UnmarshalTypeError
+	if errors.As(err, &serr) || errors.As(err, &uerr) {
+		return "json_decode"
+	}
+	return "conversion"
+}
+
+func newProcessorErrors() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "event_processor",
+		Name:      "errors_total",
+		Help:      "Number of errors encountered by the event processors of the prometheus output",
+	}, []string{"processor", "type"})
+}
+
+// recoverProcessor wraps an event processor, it counts the processor panics as errors.
+// The events being processed when the processor panics are dropped.
+type recoverProcessor struct {
+	formatters.EventProcessor
+	name   string
+	errs   prometheus.Counter
+	logger *log.Logger
+}
+
+func (rp *recoverProcessor) Apply(es ...*formatters.EventMsg) (res []*formatters.EventMsg) {
+	defer func() {
+		if r := recover(); r != nil {
+			rp.errs.Inc()
+			rp.logger.Printf("event processor '%s' failed, dropping %d event(s): %v", rp.name, len(es), r)
+			res = nil
+		}
+	}()
+	return rp.EventProcessor.Apply(es...)
+}
+
+// WithFlushHandler implements formatters.Flusher, it is a no-op if the wrapped processor does not hold events.
+func (rp *recoverProcessor) WithFlushHandler(h formatters.FlushHandler) {
+	if f, ok := rp.EventProcessor.(formatters.Flusher); ok {
+		f.WithFlushHandler(h)
+	}
+}
+
+// Flush implements formatters.Flusher
+func (rp *recoverProcessor) Flush() []*formatters.EventMsg {
+	if f, ok := rp.EventProcessor.(formatters.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
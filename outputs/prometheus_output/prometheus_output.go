@@ -9,8 +9,11 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
+	"math/rand"
+	"mime"
 	"net"
 	"net/http"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -25,20 +28,44 @@ import (
 	"github.com/karimra/gnmic/outputs"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	defaultListen     = ":9804"
-	defaultPath       = "/metrics"
-	defaultExpiration = time.Minute
-	defaultMetricHelp = "gNMIc generated metric"
-	metricNameRegex   = "[^a-zA-Z0-9_]+"
-	loggingPrefix     = "[prometheus_output] "
+	defaultListen            = ":9804"
+	defaultPath              = "/metrics"
+	defaultExpiration        = time.Minute
+	defaultMetricHelp        = "gNMIc generated metric"
+	defaultInstanceLabelName = "gnmic_instance"
+	selfCheckTimeout         = 5 * time.Second
+	metricNameRegex          = "[^a-zA-Z0-9_]+"
+	// colons are valid in metric names, not in label names
+	metricNameColonsRegex = "[^a-zA-Z0-9_:]+"
+	loggingPrefix         = "[prometheus_output] "
 )
 
+// expire-on values
+const (
+	// the gNMI notification timestamp
+	expireOnTime = "time"
+	// the time the event was received
+	expireOnAdded = "added"
+	// the later of the two
+	expireOnMax = "max"
+)
+
+// max-labels-mode values
+const (
+	// the series with more than max-labels labels are not exported
+	maxLabelsModeDrop = "drop"
+	// only the first max-labels labels by name are exported
+	maxLabelsModeTruncate = "truncate"
+)
+
+var labelNameRegex = regexp.MustCompile(metricNameRegex)
+
 type labelPair struct {
 	Name  string
 	Value string
@@ -51,17 +78,32 @@ type promMetric struct {
 	// addedAt is used to expire metrics if the time field is not initialized
 	// this happens when ExportTimestamp == false
 	addedAt time.Time
+
+	// valueType is the exported metric type, untyped if not set
+	valueType prometheus.ValueType
+	// desc is set when the metric is stored, to avoid building it on each scrape
+	desc *prometheus.Desc
+	// sortLabels sorts the exposed labels by name
+	sortLabels bool
+	// valueName is the event value name the metric was built from,
+	// used to match the deleted paths
+	valueName string
+	// previous holds the samples replaced by this one, oldest first,
+	// up to samples-per-series - 1 of them
+	previous []*promMetric
 }
 
 func init() {
 	outputs.Register("prometheus", func() outputs.Output {
 		return &PrometheusOutput{
-			Cfg:         &Config{},
-			eventChan:   make(chan *formatters.EventMsg),
-			wg:          new(sync.WaitGroup),
-			entries:     make(map[uint64]*promMetric),
-			metricRegex: regexp.MustCompile(metricNameRegex),
-			logger:      log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
+			Cfg:             &Config{},
+			eventChan:       make(chan *formatters.EventMsg),
+			wg:              new(sync.WaitGroup),
+			entries:         make(map[uint64]*promMetric),
+			synced:          newSubscriptionSyncCollector(),
+			lastErrs:        newLastErrorsCollector(),
+			processorErrors: newProcessorErrors(),
+			logger:          log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds),
 		}
 	})
 }
@@ -73,29 +115,81 @@ type PrometheusOutput struct {
 
 	wg     *sync.WaitGroup
 	server *http.Server
+	// guards the entries and the lifecycle state
 	sync.Mutex
 	entries map[uint64]*promMetric
+	// set once the server is started, cancels the workers
+	cancelFn context.CancelFunc
+	// set by the first call to Close, the output cannot be started afterwards
+	closed bool
 
 	metricRegex  *regexp.Regexp
 	evps         []formatters.EventProcessor
 	consulClient *api.Client
+	targetsUp    *targetsUpCollector
+	// number of values per received event, nil if enable-metrics is false
+	valuesPerEvent prometheus.Histogram
+	// difference between the reception time and the timestamp of the last event per target, nil if enable-metrics is false
+	eventLag *prometheus.GaugeVec
+	synced   *subscriptionSyncCollector
+	// last conversion error per target
+	lastErrs *lastErrorsCollector
+	// consul service registration status, nil if enable-metrics is false or service-registration is not set
+	consulRegistered *prometheus.GaugeVec
+	// number of series dropped because of max-labels, nil if enable-metrics is false
+	maxLabelsDrops prometheus.Counter
+	// number of runtime errors per event processor, exported with the output metrics
+	processorErrors *prometheus.CounterVec
+	// schema leaves paths to metric types, used if InferMetricTypes is true
+	metricTypes map[string]outputs.MetricType
+	// label names not used to calculate the metrics key
+	identityExclude map[string]struct{}
 }
 type Config struct {
 	Name                   string               `mapstructure:"name,omitempty"`
 	Listen                 string               `mapstructure:"listen,omitempty"`
 	Path                   string               `mapstructure:"path,omitempty"`
 	Expiration             time.Duration        `mapstructure:"expiration,omitempty"`
+	ExpirationJitter       time.Duration        `mapstructure:"expiration-jitter,omitempty"`
+	ExpireOn               string               `mapstructure:"expire-on,omitempty"`
+	KeepLast               bool                 `mapstructure:"keep-last,omitempty"`
+	ClearOnScrape          bool                 `mapstructure:"clear-on-scrape,omitempty"`
+	SamplesPerSeries       int                  `mapstructure:"samples-per-series,omitempty"`
 	MetricPrefix           string               `mapstructure:"metric-prefix,omitempty"`
 	AppendSubscriptionName bool                 `mapstructure:"append-subscription-name,omitempty"`
 	ExportTimestamps       bool                 `mapstructure:"export-timestamps,omitempty"`
 	StringsAsLabels        bool                 `mapstructure:"strings-as-labels,omitempty"`
+	MaxLabels              int                  `mapstructure:"max-labels,omitempty"`
+	MaxLabelsMode          string               `mapstructure:"max-labels-mode,omitempty"`
+	LabelNameMap           map[string]string    `mapstructure:"label-name-map,omitempty"`
+	KeepFullPath           bool                 `mapstructure:"keep-full-path,omitempty"`
 	Debug                  bool                 `mapstructure:"debug,omitempty"`
+	EnableMetrics          bool                 `mapstructure:"enable-metrics,omitempty"`
+	InferMetricTypes       bool                 `mapstructure:"infer-metric-types,omitempty"`
+	CounterTotalSuffix     bool                 `mapstructure:"counter-total-suffix,omitempty"`
+	SkipInitialSync        bool                 `mapstructure:"skip-initial-sync,omitempty"`
+	YangFiles              []string             `mapstructure:"yang-files,omitempty"`
+	YangDirs               []string             `mapstructure:"yang-dirs,omitempty"`
+	IdentityExcludeLabels  []string             `mapstructure:"identity-exclude-labels,omitempty"`
+	MetricNameAllowColons  bool                 `mapstructure:"metric-name-allow-colons,omitempty"`
+	NoTrimUnderscores      bool                 `mapstructure:"no-trim-underscores,omitempty"`
+	SortLabels             bool                 `mapstructure:"sort-labels,omitempty"`
+	PrefixAsLabels         bool                 `mapstructure:"prefix-as-labels,omitempty"`
+	DropEmptyLabels        bool                 `mapstructure:"drop-empty-labels,omitempty"`
+	EmptyLabelPlaceholder  string               `mapstructure:"empty-label-placeholder,omitempty"`
+	EnableOpenMetrics      bool                 `mapstructure:"enable-openmetrics,omitempty"`
+	ForceContentType       string               `mapstructure:"force-content-type,omitempty"`
+	SelfCheck              bool                 `mapstructure:"self-check,omitempty"`
+	InstanceLabel          bool                 `mapstructure:"instance-label,omitempty"`
+	InstanceLabelName      string               `mapstructure:"instance-label-name,omitempty"`
 	EventProcessors        []string             `mapstructure:"event-processors,omitempty"`
 	ServiceRegistration    *ServiceRegistration `mapstructure:"service-registration,omitempty"`
+	Paths                  []*PathConfig        `mapstructure:"paths,omitempty"`
 
-	clusterName string
-	address     string
-	port        int
+	clusterName  string
+	instanceName string
+	address      string
+	port         int
 }
 
 func (p *PrometheusOutput) String() string {
@@ -123,12 +217,22 @@ func (p *PrometheusOutput) SetEventProcessors(ps map[string]map[string]interface
 			}
 			if in, ok := formatters.EventProcessors[epType]; ok {
 				ep := in()
-				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				errs := p.processorErrors.WithLabelValues(epName, epType)
+				err := ep.Init(epCfg[epType],
+					formatters.WithLogger(logger),
+					formatters.WithTargets(tcs),
+					formatters.WithErrorHandler(func(error) { errs.Inc() }),
+				)
 				if err != nil {
 					p.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
 				}
-				p.evps = append(p.evps, ep)
+				p.evps = append(p.evps, &recoverProcessor{
+					EventProcessor: ep,
+					name:           epName,
+					errs:           errs,
+					logger:         p.logger,
+				})
 				p.logger.Printf("added event processor '%s' of type=%s to prometheus output", epName, epType)
 			}
 		}
@@ -151,20 +255,18 @@ func (p *PrometheusOutput) Init(ctx context.Context, name string, cfg map[string
 	if err != nil {
 		return err
 	}
-	// create prometheus registry
-	registry := prometheus.NewRegistry()
-
-	err = registry.Register(p)
+	if p.Cfg.InferMetricTypes {
+		p.metricTypes, err = outputs.LoadMetricTypes(p.Cfg.YangFiles, p.Cfg.YangDirs)
+		if err != nil {
+			p.logger.Printf("failed to load YANG schema, metrics will be exported as gauges: %v", err)
+		}
+	}
+	// create http server
+	mux, err := p.createServeMux()
 	if err != nil {
 		return err
 	}
-	// create http server
-	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
-
-	mux := http.NewServeMux()
-	mux.Handle(p.Cfg.Path, promHandler)
-
-	p.server = &http.Server{
+	server := &http.Server{
 		Addr:    p.Cfg.Listen,
 		Handler: mux,
 	}
@@ -174,20 +276,56 @@ func (p *PrometheusOutput) Init(ctx context.Context, name string, cfg map[string
 	if err != nil {
 		return err
 	}
+	// the server is started only if the output was not closed meanwhile,
+	// a concurrent Close either stops it or happened before
+	p.Lock()
+	if p.closed || p.server != nil {
+		started := p.server != nil
+		p.Unlock()
+		listener.Close()
+		if started {
+			return errors.New("output already initialized")
+		}
+		return errors.New("output closed")
+	}
+	p.server = server
+	p.setListenAddress(listener.Addr())
+	p.logger.Printf("prometheus output listening on %s", p.Cfg.Listen)
 	// start worker
 	p.wg.Add(2)
 	wctx, wcancel := context.WithCancel(ctx)
+	p.cancelFn = wcancel
+	// the events held by the processors, e.g over a merge window, are stored once due
+	outputs.SetFlushHandlers(wctx, p, p.evps)
 	go p.worker(wctx)
 	go p.expireMetricsPeriodic(wctx)
 	go func() {
 		defer p.wg.Done()
-		err = p.server.Serve(listener)
+		err := server.Serve(listener)
 		if err != nil && err != http.ErrServerClosed {
 			p.logger.Printf("prometheus server error: %v", err)
 		}
 		wcancel()
 	}()
-	go p.registerService(wctx)
+	p.Unlock()
+	if p.Cfg.SelfCheck {
+		if err := p.selfCheck(); err != nil {
+			p.logger.Printf("self-check failed: %v", err)
+			p.Close()
+			return fmt.Errorf("self-check failed: %v", err)
+		}
+	}
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return errors.New("output closed")
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.registerService(wctx)
+	}()
+	p.Unlock()
 	p.logger.Printf("initialized prometheus output: %s", p.String())
 	go func() {
 		<-ctx.Done()
@@ -196,6 +334,52 @@ func (p *PrometheusOutput) Init(ctx context.Context, name string, cfg map[string
 	return nil
 }
 
+// selfCheck scrapes the output's own metrics endpoint
+// and checks that the exposition is served and can be parsed.
+// The body is parsed only if it is in the text format.
+func (p *PrometheusOutput) selfCheck() error {
+	host := p.Cfg.address
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	url := "http://" + net.JoinHostPort(host, strconv.Itoa(p.Cfg.port)) + p.Cfg.Path
+	client := &http.Client{Timeout: selfCheckTimeout}
+	rsp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(rsp.Body)
+		return fmt.Errorf("GET %s: unexpected status %q: %s", url, rsp.Status, strings.TrimSpace(string(body)))
+	}
+	if expfmt.ResponseFormat(rsp.Header) != expfmt.FmtText {
+		p.logger.Printf("self-check: skipping the parsing of the %q response", rsp.Header.Get("Content-Type"))
+		return nil
+	}
+	var parser expfmt.TextParser
+	_, err = parser.TextToMetricFamilies(rsp.Body)
+	if err != nil {
+		return fmt.Errorf("GET %s: malformed exposition: %v", url, err)
+	}
+	p.logger.Printf("self-check: %s OK", url)
+	return nil
+}
+
+// ValidateConfig implements outputs.ConfigValidator
+func (p *PrometheusOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, p.Cfg)
+	if err != nil {
+		return err
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	_, err = p.createServeMux()
+	return err
+}
+
 // Write implements the outputs.Output interface
 func (p *PrometheusOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
 	if rsp == nil {
@@ -207,11 +391,29 @@ func (p *PrometheusOutput) Write(ctx context.Context, rsp proto.Message, meta ou
 		if subName, ok := meta["subscription-name"]; ok {
 			measName = subName
 		}
-		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, p.evps...)
+		if _, ok := rsp.GetResponse().(*gnmi.SubscribeResponse_SyncResponse); ok {
+			if p.synced != nil {
+				p.synced.sync(measName, meta["source"])
+			}
+			return
+		}
+		if p.Cfg.SkipInitialSync && p.synced != nil && !p.synced.isSynced(measName, meta["source"]) {
+			if p.Cfg.Debug {
+				p.logger.Printf("skipping response received before the initial sync from target %q, subscription %q", meta["source"], measName)
+			}
+			return
+		}
+		if p.Cfg.PrefixAsLabels {
+			rsp, meta = prefixAsLabels(rsp, meta)
+		}
+		events, err := formatters.ResponseToEventMsgsSplitDeletes(measName, rsp, meta, p.evps...)
 		if err != nil {
 			p.logger.Printf("failed to convert message to event: %v", err)
+			p.lastErrs.record(meta["source"], errorCategory(err), err)
 			return
 		}
+		// the target's notifications are converted again, its last error is outdated
+		p.lastErrs.clear(meta["source"])
 		for _, ev := range events {
 			select {
 			case <-ctx.Done():
@@ -222,6 +424,41 @@ func (p *PrometheusOutput) Write(ctx context.Context, rsp proto.Message, meta ou
 	}
 }
 
+// prefixAsLabels moves the notification prefix elements and keys to the meta,
+// as a "prefix" path and "prefix_<elem>_<key>" entries.
+// It returns a copy of the response, with a prefix holding only the origin and target,
+// the values names are then built from the updates paths.
+func prefixAsLabels(rsp *gnmi.SubscribeResponse, meta outputs.Meta) (*gnmi.SubscribeResponse, outputs.Meta) {
+	n := rsp.GetUpdate()
+	if len(n.GetPrefix().GetElem()) == 0 {
+		return rsp, meta
+	}
+	name, keys := formatters.TagsFromGNMIPath(&gnmi.Path{Elem: n.Prefix.Elem})
+	m := make(outputs.Meta, len(meta)+len(keys)+1)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m["prefix"] = name
+	for k, v := range keys {
+		m["prefix_"+k] = v
+	}
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: n.Timestamp,
+				Prefix: &gnmi.Path{
+					Origin: n.Prefix.Origin,
+					Target: n.Prefix.Target,
+				},
+				Alias:  n.Alias,
+				Update: n.Update,
+				Delete: n.Delete,
+				Atomic: n.Atomic,
+			},
+		},
+	}, m
+}
+
 func (p *PrometheusOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	select {
 	case <-ctx.Done():
@@ -230,49 +467,165 @@ func (p *PrometheusOutput) WriteEvent(ctx context.Context, ev *formatters.EventM
 	}
 }
 
+// Close stops the output, it can be called before, during or after Init, and more than once.
+// The server is shut down and the service deregistered by the first call only.
 func (p *PrometheusOutput) Close() error {
-	var err error
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return nil
+	}
+	p.closed = true
+	server := p.server
+	cancel := p.cancelFn
+	p.Unlock()
+	if server == nil {
+		p.logger.Printf("closed before being started.")
+		return nil
+	}
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		p.logger.Printf("failed to shutdown http server: %v", err)
+	}
+	cancel()
+	p.wg.Wait()
+	// the service registration goroutine is done, the consul client can be used safely
 	if p.consulClient != nil {
 		err = p.consulClient.Agent().ServiceDeregister(p.Cfg.ServiceRegistration.Name)
 		if err != nil {
 			p.logger.Printf("failed to deregister consul service: %v", err)
 		}
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	err = p.server.Shutdown(ctx)
-	if err != nil {
-		p.logger.Printf("failed to shutdown http server: %v", err)
+		p.setConsulRegistered(false)
 	}
 	p.logger.Printf("closed.")
-	p.wg.Wait()
 	return nil
 }
 
-func (p *PrometheusOutput) RegisterMetrics(reg *prometheus.Registry) {}
+func (p *PrometheusOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !p.Cfg.EnableMetrics || reg == nil {
+		return
+	}
+	p.targetsUp = newTargetsUpCollector(p)
+	if err := reg.Register(p.targetsUp); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+	p.valuesPerEvent = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   "gnmic",
+		Subsystem:   "prometheus",
+		Name:        "values_per_event",
+		Help:        "Number of values per event received by the prometheus output",
+		ConstLabels: prometheus.Labels{"output": p.Cfg.Name},
+		Buckets:     prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	if err := reg.Register(p.valuesPerEvent); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+	p.eventLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "gnmic",
+		Subsystem:   "prometheus",
+		Name:        "event_lag_seconds",
+		Help:        "Difference between the reception time and the timestamp of the last event received from the target",
+		ConstLabels: prometheus.Labels{"output": p.Cfg.Name},
+	}, []string{"target"})
+	if err := reg.Register(p.eventLag); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+	p.maxLabelsDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   "gnmic",
+		Subsystem:   "prometheus",
+		Name:        "max_labels_dropped_series_total",
+		Help:        "Number of series not exported because they have more labels than max-labels",
+		ConstLabels: prometheus.Labels{"output": p.Cfg.Name},
+	})
+	if err := reg.Register(p.maxLabelsDrops); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+	p.lastErrs.setOutputName(p.Cfg.Name)
+	if err := reg.Register(p.lastErrs); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+	if p.Cfg.ServiceRegistration == nil {
+		return
+	}
+	p.consulRegistered = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "gnmic",
+		Subsystem:   "prometheus",
+		Name:        "consul_registered",
+		Help:        "1 if the prometheus output service is registered in consul and its TTL check is passing, 0 otherwise",
+		ConstLabels: prometheus.Labels{"output": p.Cfg.Name},
+	}, []string{"name"})
+	if err := reg.Register(p.consulRegistered); err != nil {
+		p.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+// TargetDown implements outputs.TargetStateHandler
+func (p *PrometheusOutput) TargetDown(name string) {
+	if p.targetsUp != nil {
+		p.targetsUp.down(name)
+	}
+	// the target re-subscribes after a failure, its subscriptions are
+	// not synced until it sends a new sync response
+	if p.synced != nil {
+		p.synced.reset(name)
+	}
+}
 
 // Describe implements prometheus.Collector
 func (p *PrometheusOutput) Describe(ch chan<- *prometheus.Desc) {}
 
 // Collect implements prometheus.Collector
 func (p *PrometheusOutput) Collect(ch chan<- prometheus.Metric) {
+	for _, entry := range p.snapshot(nil) {
+		ch <- entry
+	}
+	if p.synced != nil {
+		p.synced.Collect(ch)
+	}
+}
+
+// snapshot expires the stored metrics and returns a copy of the remaining ones
+// with a name matching match, or all of them if match is nil.
+// stored metrics are never modified, only replaced, so the returned entries
+// can be exported without holding the lock.
+// If clear-on-scrape is set, the returned entries are removed from the store.
+func (p *PrometheusOutput) snapshot(match func(name string) bool) []*promMetric {
 	p.Lock()
 	defer p.Unlock()
 	// run expire before exporting metrics
 	p.expireMetrics()
-	for _, entry := range p.entries {
-		ch <- entry
+	entries := make([]*promMetric, 0, len(p.entries))
+	for key, entry := range p.entries {
+		if match != nil && !match(entry.name) {
+			continue
+		}
+		entries = append(entries, entry)
+		if p.Cfg.ClearOnScrape {
+			delete(p.entries, key)
+		}
 	}
+	return entries
 }
 
 func (p *PrometheusOutput) getLabels(ev *formatters.EventMsg) []*labelPair {
-	labels := make([]*labelPair, 0, len(ev.Tags))
+	labels := make([]*labelPair, 0, len(ev.Tags)+1)
 	addedLabels := make(map[string]struct{})
+	// the instance label takes precedence over a tag with the same label name
+	if p.Cfg.InstanceLabel {
+		labels = append(labels, &labelPair{Name: p.Cfg.InstanceLabelName, Value: p.Cfg.instanceName})
+		addedLabels[p.Cfg.InstanceLabelName] = struct{}{}
+	}
 	for k, v := range ev.Tags {
-		labelName := p.metricRegex.ReplaceAllString(filepath.Base(k), "_")
+		labelName := p.labelName(k)
 		if _, ok := addedLabels[labelName]; ok {
 			continue
 		}
+		v, ok := p.labelValue(v)
+		if !ok {
+			continue
+		}
 		labels = append(labels, &labelPair{Name: labelName, Value: v})
 		addedLabels[labelName] = struct{}{}
 	}
@@ -287,16 +640,62 @@ func (p *PrometheusOutput) getLabels(ev *formatters.EventMsg) []*labelPair {
 			continue
 		}
 		if vs, ok := v.(string); ok {
-			labelName := p.metricRegex.ReplaceAllString(filepath.Base(k), "_")
+			labelName := p.labelName(k)
 			if _, ok := addedLabels[labelName]; ok {
 				continue
 			}
+			vs, ok := p.labelValue(vs)
+			if !ok {
+				continue
+			}
 			labels = append(labels, &labelPair{Name: labelName, Value: vs})
 		}
 	}
 	return labels
 }
 
+// limitLabels applies max-labels to labels, it returns false if the series must be dropped.
+// In truncate mode, the labels are sorted by name and the first max-labels ones are kept.
+func (p *PrometheusOutput) limitLabels(labels []*labelPair) ([]*labelPair, bool) {
+	if p.Cfg.MaxLabels <= 0 || len(labels) <= p.Cfg.MaxLabels {
+		return labels, true
+	}
+	if p.Cfg.MaxLabelsMode != maxLabelsModeTruncate {
+		return nil, false
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels[:p.Cfg.MaxLabels], true
+}
+
+// labelValue returns the label value to export for v and false if the label should be dropped.
+// An empty v is dropped if drop-empty-labels is set, or replaced with the empty-label-placeholder if configured.
+func (p *PrometheusOutput) labelValue(v string) (string, bool) {
+	if v != "" {
+		return v, true
+	}
+	if p.Cfg.DropEmptyLabels {
+		return "", false
+	}
+	if p.Cfg.EmptyLabelPlaceholder != "" {
+		return p.Cfg.EmptyLabelPlaceholder, true
+	}
+	return v, true
+}
+
+// labelName returns the label name built from the tag or value name k.
+// k is renamed if present in the label-name-map, otherwise its last path element is used,
+// unless keep-full-path is set. The result is then sanitized.
+func (p *PrometheusOutput) labelName(k string) string {
+	if n, ok := p.Cfg.LabelNameMap[k]; ok {
+		k = n
+	} else if !p.Cfg.KeepFullPath {
+		k = filepath.Base(k)
+	}
+	return labelNameRegex.ReplaceAllString(k, "_")
+}
+
 func (p *PrometheusOutput) worker(ctx context.Context) {
 	defer p.wg.Done()
 	for {
@@ -309,7 +708,21 @@ func (p *PrometheusOutput) worker(ctx context.Context) {
 			}
 			p.Lock()
 			now := time.Now()
-			labels := p.getLabels(ev)
+			if source, ok := ev.Tags["source"]; ok {
+				if p.targetsUp != nil {
+					p.targetsUp.seen(source, now)
+				}
+				if p.eventLag != nil && ev.Timestamp > 0 {
+					p.eventLag.WithLabelValues(source).Set(now.Sub(time.Unix(0, ev.Timestamp)).Seconds())
+				}
+			}
+			if len(ev.Deletes) > 0 {
+				p.deleteMetrics(ev)
+			}
+			if p.valuesPerEvent != nil && (len(ev.Values) > 0 || len(ev.Deletes) == 0) {
+				p.valuesPerEvent.Observe(float64(len(ev.Values)))
+			}
+			labels, keep := p.limitLabels(p.getLabels(ev))
 			for vName, val := range ev.Values {
 				v, err := getFloat(val)
 				if err != nil {
@@ -318,19 +731,45 @@ func (p *PrometheusOutput) worker(ctx context.Context) {
 					}
 					v = 1.0
 				}
+				if !keep {
+					if p.maxLabelsDrops != nil {
+						p.maxLabelsDrops.Inc()
+					}
+					if p.Cfg.Debug {
+						p.logger.Printf("dropped value %q of event %q: more than %d labels", vName, ev.Name, p.Cfg.MaxLabels)
+					}
+					continue
+				}
 				pm := &promMetric{
-					name:    p.metricName(ev.Name, vName),
-					labels:  labels,
-					value:   v,
-					addedAt: now,
+					name:       p.metricName(ev.Name, vName),
+					labels:     labels,
+					value:      v,
+					addedAt:    now,
+					sortLabels: p.Cfg.SortLabels,
+					valueName:  vName,
+				}
+				if p.Cfg.InferMetricTypes {
+					pm.valueType = p.metricType(vName)
+					if pm.valueType == prometheus.CounterValue && p.Cfg.CounterTotalSuffix && !strings.HasSuffix(pm.name, "_total") {
+						pm.name += "_total"
+					}
 				}
 				if p.Cfg.ExportTimestamps {
 					tm := time.Unix(0, ev.Timestamp)
 					pm.time = &tm
 				}
-				key := pm.calculateKey()
-				if e, ok := p.entries[key]; ok && pm.time != nil {
+				key := pm.calculateKey(p.identityExclude)
+				e, ok := p.entries[key]
+				if ok && e.sameDesc(pm) {
+					pm.desc = e.desc
+				} else {
+					pm.desc = pm.newDesc()
+				}
+				if ok && pm.time != nil {
 					if e.time.Before(*pm.time) {
+						if p.Cfg.SamplesPerSeries > 1 {
+							pm.keepPrevious(e, p.Cfg.SamplesPerSeries-1)
+						}
 						p.entries[key] = pm
 					}
 				} else {
@@ -345,42 +784,134 @@ func (p *PrometheusOutput) worker(ctx context.Context) {
 	}
 }
 
-func (p *PrometheusOutput) expireMetrics() {
-	if p.Cfg.Expiration <= 0 {
+// deleteMetrics removes the stored metrics built from a value under one of the event deleted paths,
+// and having all the event labels.
+// Metrics with a timestamp more recent than the event are kept.
+func (p *PrometheusOutput) deleteMetrics(ev *formatters.EventMsg) {
+	labels, keep := p.limitLabels(p.getLabels(ev))
+	if !keep {
+		// the series were not stored
 		return
 	}
-	expiry := time.Now().Add(-p.Cfg.Expiration)
 	for k, e := range p.entries {
-		if p.Cfg.ExportTimestamps {
-			if e.time.Before(expiry) {
-				delete(p.entries, k)
-			}
+		if p.Cfg.ExportTimestamps && e.time != nil && ev.Timestamp > 0 && e.time.After(time.Unix(0, ev.Timestamp)) {
 			continue
 		}
-		if e.addedAt.Before(expiry) {
+		if !underPaths(e.valueName, ev.Deletes) || !hasLabels(e.labels, labels) {
+			continue
+		}
+		delete(p.entries, k)
+		if p.Cfg.Debug {
+			p.logger.Printf("deleted key=%d, metric: %+v", k, e)
+		}
+	}
+}
+
+// underPaths returns true if valueName is one of the paths or one of their descendants
+func underPaths(valueName string, paths []string) bool {
+	for _, path := range paths {
+		path = strings.TrimRight(path, "/")
+		if valueName == path || strings.HasPrefix(valueName, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLabels returns true if labels includes all the subset labels
+func hasLabels(labels, subset []*labelPair) bool {
+OUTER:
+	for _, sl := range subset {
+		for _, l := range labels {
+			if l.Name == sl.Name && l.Value == sl.Value {
+				continue OUTER
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// metricType returns the type of the schema leaf matching the value name,
+// prometheus.GaugeValue if the schema is not loaded or does not have such leaf.
+func (p *PrometheusOutput) metricType(valueName string) prometheus.ValueType {
+	if p.metricTypes[outputs.SchemaPath(valueName)] == outputs.CounterMetric {
+		return prometheus.CounterValue
+	}
+	return prometheus.GaugeValue
+}
+
+// expires returns true if the stored metrics are subject to expiration,
+// false if the last seen value of each metric is kept forever.
+func (p *PrometheusOutput) expires() bool {
+	return !p.Cfg.KeepLast && p.Cfg.Expiration > 0
+}
+
+func (p *PrometheusOutput) expireMetrics() {
+	if !p.expires() {
+		return
+	}
+	expiry := time.Now().Add(-p.Cfg.Expiration)
+	for k, e := range p.entries {
+		if p.lastSeen(e).Before(expiry) {
 			delete(p.entries, k)
 		}
 	}
 }
 
+// lastSeen returns the time the expiration of a stored metric is calculated from, based on expire-on.
+// The reception time is used if the metric does not have a timestamp.
+func (p *PrometheusOutput) lastSeen(e *promMetric) time.Time {
+	if e.time == nil {
+		return e.addedAt
+	}
+	switch p.Cfg.ExpireOn {
+	case expireOnAdded:
+		return e.addedAt
+	case expireOnMax:
+		if e.addedAt.After(*e.time) {
+			return e.addedAt
+		}
+	}
+	return *e.time
+}
+
 func (p *PrometheusOutput) expireMetricsPeriodic(ctx context.Context) {
-	if p.Cfg.Expiration <= 0 {
+	if !p.expires() {
 		return
 	}
-	ticker := time.NewTicker(p.Cfg.Expiration)
-	defer ticker.Stop()
+	// each gnmic instance uses its own random sequence for the expiration jitter
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	timer := time.NewTimer(p.expirationInterval(rnd, true))
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			p.Lock()
 			p.expireMetrics()
 			p.Unlock()
+			timer.Reset(p.expirationInterval(rnd, false))
 		}
 	}
 }
 
+// expirationInterval returns the duration until the next metrics expiration run.
+// Without expiration-jitter, it is the expiration period.
+// Otherwise a random duration up to the jitter is added to the period,
+// and the first run happens after a random duration up to the period plus the jitter,
+// so that multiple gnmic instances started together do not run it at the same time.
+func (p *PrometheusOutput) expirationInterval(rnd *rand.Rand, first bool) time.Duration {
+	if p.Cfg.ExpirationJitter <= 0 {
+		return p.Cfg.Expiration
+	}
+	if first {
+		return time.Duration(rnd.Int63n(int64(p.Cfg.Expiration + p.Cfg.ExpirationJitter)))
+	}
+	return p.Cfg.Expiration + time.Duration(rnd.Int63n(int64(p.Cfg.ExpirationJitter)))
+}
+
 func (p *PrometheusOutput) setDefaults() error {
 	if p.Cfg.Listen == "" {
 		p.Cfg.Listen = defaultListen
@@ -391,7 +922,69 @@ func (p *PrometheusOutput) setDefaults() error {
 	if p.Cfg.Expiration == 0 {
 		p.Cfg.Expiration = defaultExpiration
 	}
+	switch p.Cfg.ExpireOn {
+	case "":
+		if p.Cfg.ExportTimestamps {
+			p.Cfg.ExpireOn = expireOnTime
+		} else {
+			p.Cfg.ExpireOn = expireOnAdded
+		}
+	case expireOnTime, expireOnAdded, expireOnMax:
+	default:
+		return fmt.Errorf("unknown expire-on %q, must be one of %s, %s or %s", p.Cfg.ExpireOn, expireOnTime, expireOnAdded, expireOnMax)
+	}
+	switch p.Cfg.MaxLabelsMode {
+	case "":
+		p.Cfg.MaxLabelsMode = maxLabelsModeDrop
+	case maxLabelsModeDrop, maxLabelsModeTruncate:
+	default:
+		return fmt.Errorf("unknown max-labels-mode %q, must be one of %s or %s", p.Cfg.MaxLabelsMode, maxLabelsModeDrop, maxLabelsModeTruncate)
+	}
+	if p.Cfg.SamplesPerSeries <= 0 {
+		p.Cfg.SamplesPerSeries = 1
+	}
+	if p.Cfg.SamplesPerSeries > 1 {
+		if !p.Cfg.ExportTimestamps {
+			return fmt.Errorf("samples-per-series requires export-timestamps")
+		}
+		if p.Cfg.ClearOnScrape {
+			return fmt.Errorf("samples-per-series and clear-on-scrape are mutually exclusive")
+		}
+	}
+	if p.Cfg.DropEmptyLabels && p.Cfg.EmptyLabelPlaceholder != "" {
+		return fmt.Errorf("drop-empty-labels and empty-label-placeholder are mutually exclusive")
+	}
+	if p.Cfg.ForceContentType != "" {
+		mt, _, err := mime.ParseMediaType(p.Cfg.ForceContentType)
+		if err != nil {
+			return fmt.Errorf("invalid force-content-type %q: %v", p.Cfg.ForceContentType, err)
+		}
+		// the OpenMetrics encoder is only used if enabled
+		if mt == expfmt.OpenMetricsType && !p.Cfg.EnableOpenMetrics {
+			return fmt.Errorf("force-content-type %q requires enable-openmetrics", p.Cfg.ForceContentType)
+		}
+	}
+	if p.Cfg.InstanceLabel {
+		if p.Cfg.InstanceLabelName == "" {
+			p.Cfg.InstanceLabelName = defaultInstanceLabelName
+		}
+		p.Cfg.InstanceLabelName = labelNameRegex.ReplaceAllString(p.Cfg.InstanceLabelName, "_")
+		if p.Cfg.instanceName == "" {
+			p.Cfg.instanceName, _ = os.Hostname()
+		}
+	}
 	p.setServiceRegistrationDefaults()
+	if p.Cfg.MetricNameAllowColons {
+		p.metricRegex = regexp.MustCompile(metricNameColonsRegex)
+	} else {
+		p.metricRegex = regexp.MustCompile(metricNameRegex)
+	}
+	if len(p.Cfg.IdentityExcludeLabels) > 0 {
+		p.identityExclude = make(map[string]struct{}, len(p.Cfg.IdentityExcludeLabels))
+		for _, l := range p.Cfg.IdentityExcludeLabels {
+			p.identityExclude[l] = struct{}{}
+		}
+	}
 	var err error
 	var port string
 	p.Cfg.address, port, err = net.SplitHostPort(p.Cfg.Listen)
@@ -408,8 +1001,25 @@ func (p *PrometheusOutput) setDefaults() error {
 	return nil
 }
 
+// setListenAddress stores the address the server is listening on,
+// it differs from the configured one if it uses port 0, in which case a free port is picked.
+func (p *PrometheusOutput) setListenAddress(addr net.Addr) {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok || p.Cfg.port != 0 {
+		return
+	}
+	p.Cfg.port = tcpAddr.Port
+	p.Cfg.Listen = net.JoinHostPort(p.Cfg.address, strconv.Itoa(p.Cfg.port))
+	p.server.Addr = p.Cfg.Listen
+	// update the HTTP check address derived from the listen address
+	p.setServiceRegistrationDefaults()
+}
+
 // Metric
-func (p *promMetric) calculateKey() uint64 {
+
+// calculateKey returns the metric identity key, a hash of its name and labels,
+// the labels listed in exclude are not included.
+func (p *promMetric) calculateKey(exclude map[string]struct{}) uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(p.name))
 	if len(p.labels) > 0 {
@@ -418,6 +1028,9 @@ func (p *promMetric) calculateKey() uint64 {
 			return p.labels[i].Name < p.labels[j].Name
 		})
 		for _, label := range p.labels {
+			if _, ok := exclude[label.Name]; ok {
+				continue
+			}
 			h.Write([]byte(label.Name))
 			h.Write([]byte(":"))
 			h.Write([]byte(label.Value))
@@ -462,6 +1075,13 @@ func (p *promMetric) String() string {
 
 // Desc implements prometheus.Metric
 func (p *promMetric) Desc() *prometheus.Desc {
+	if p.desc != nil {
+		return p.desc
+	}
+	return p.newDesc()
+}
+
+func (p *promMetric) newDesc() *prometheus.Desc {
 	labelNames := make([]string, 0, len(p.labels))
 	for _, label := range p.labels {
 		labelNames = append(labelNames, label.Name)
@@ -470,15 +1090,41 @@ func (p *promMetric) Desc() *prometheus.Desc {
 	return prometheus.NewDesc(p.name, defaultMetricHelp, labelNames, nil)
 }
 
+// sameDesc returns true if pm has the same name and label names as p,
+// in which case the desc of p can be reused for pm.
+func (p *promMetric) sameDesc(pm *promMetric) bool {
+	if p.desc == nil || p.name != pm.name || len(p.labels) != len(pm.labels) {
+		return false
+	}
+	for i := range p.labels {
+		if p.labels[i].Name != pm.labels[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
 // Write implements prometheus.Metric
 func (p *promMetric) Write(out *dto.Metric) error {
-	out.Untyped = &dto.Untyped{
-		Value: &p.value,
+	switch p.valueType {
+	case prometheus.CounterValue:
+		out.Counter = &dto.Counter{Value: &p.value}
+	case prometheus.GaugeValue:
+		out.Gauge = &dto.Gauge{Value: &p.value}
+	default:
+		out.Untyped = &dto.Untyped{
+			Value: &p.value,
+		}
 	}
 	out.Label = make([]*dto.LabelPair, 0, len(p.labels))
 	for _, lb := range p.labels {
 		out.Label = append(out.Label, &dto.LabelPair{Name: &lb.Name, Value: &lb.Value})
 	}
+	if p.sortLabels {
+		sort.Slice(out.Label, func(i, j int) bool {
+			return out.Label[i].GetName() < out.Label[j].GetName()
+		})
+	}
 	if p.time == nil {
 		return nil
 	}
@@ -534,14 +1180,23 @@ func (p *PrometheusOutput) metricName(measName, valueName string) string {
 		sb.WriteString("_")
 	}
 	if p.Cfg.AppendSubscriptionName {
-		sb.WriteString(strings.TrimRight(p.metricRegex.ReplaceAllString(measName, "_"), "_"))
+		measName = p.metricRegex.ReplaceAllString(measName, "_")
+		if !p.Cfg.NoTrimUnderscores {
+			measName = strings.TrimRight(measName, "_")
+		}
+		sb.WriteString(measName)
 		sb.WriteString("_")
 	}
-	sb.WriteString(strings.TrimLeft(p.metricRegex.ReplaceAllString(valueName, "_"), "_"))
+	valueName = p.metricRegex.ReplaceAllString(valueName, "_")
+	if !p.Cfg.NoTrimUnderscores {
+		valueName = strings.TrimLeft(valueName, "_")
+	}
+	sb.WriteString(valueName)
 	return sb.String()
 }
 
 func (p *PrometheusOutput) SetName(name string) {
+	p.Cfg.instanceName = name
 	sb := strings.Builder{}
 	if name != "" {
 		sb.WriteString(name)
@@ -570,3 +1225,18 @@ func (p *PrometheusOutput) SetClusterName(name string) {
 		p.Cfg.ServiceRegistration.Tags = append(p.Cfg.ServiceRegistration.Tags, fmt.Sprintf("gnmic-cluster=%s", name))
 	}
 }
+
+// LastErrors implements outputs.ErrorReporter
+func (p *PrometheusOutput) LastErrors() map[string]*outputs.TargetError {
+	return p.lastErrs.lastErrors()
+}
+
+// Depths implements outputs.DepthReporter
+func (p *PrometheusOutput) Depths() map[string]int {
+	p.Lock()
+	defer p.Unlock()
+	return map[string]int{
+		"event-chan": len(p.eventChan),
+		"entries":    len(p.entries),
+	}
+}
@@ -1,8 +1,32 @@
 package prometheus_output
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/karimra/gnmic/formatters"
+	_ "github.com/karimra/gnmic/formatters/event_strings"
+	"github.com/karimra/gnmic/logging"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var metricNameSet = map[string]struct {
@@ -82,6 +106,71 @@ func TestMetricName(t *testing.T) {
 	}
 }
 
+func TestMetricNameAllowColons(t *testing.T) {
+	tests := map[string]struct {
+		allowColons bool
+		want        string
+	}{
+		"colons_stripped": {
+			allowColons: false,
+			want:        "job_rate5m_sub_instance_in_octets",
+		},
+		"colons_allowed": {
+			allowColons: true,
+			want:        "job:rate5m_sub_instance:in_octets",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &PrometheusOutput{Cfg: &Config{
+				MetricPrefix:           "job:rate5m",
+				AppendSubscriptionName: true,
+				MetricNameAllowColons:  tc.allowColons,
+			}}
+			err := p.setDefaults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.metricName("sub", "instance:in-octets")
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMetricNameNoTrimUnderscores(t *testing.T) {
+	tests := map[string]struct {
+		noTrim bool
+		want   string
+	}{
+		"trimmed": {
+			noTrim: false,
+			want:   "sub_interfaces_interface_state_counters",
+		},
+		"not_trimmed": {
+			noTrim: true,
+			want:   "sub___interfaces_interface_state_counters",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &PrometheusOutput{Cfg: &Config{
+				AppendSubscriptionName: true,
+				NoTrimUnderscores:      tc.noTrim,
+			}}
+			err := p.setDefaults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.metricName("sub-", "/interfaces/interface/state/counters")
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
 func BenchmarkMetricName(b *testing.B) {
 	for name, tc := range metricNameSet {
 		b.Run(name, func(b *testing.B) {
@@ -92,3 +181,1771 @@ func BenchmarkMetricName(b *testing.B) {
 		})
 	}
 }
+
+func TestExpireMetrics(t *testing.T) {
+	tests := map[string]struct {
+		cfg     *Config
+		age     time.Duration
+		wantLen int
+	}{
+		"default_expiration": {
+			cfg:     &Config{Expiration: defaultExpiration},
+			age:     2 * defaultExpiration,
+			wantLen: 0,
+		},
+		"not_expired_yet": {
+			cfg:     &Config{Expiration: defaultExpiration},
+			age:     defaultExpiration / 2,
+			wantLen: 1,
+		},
+		"negative_expiration": {
+			cfg:     &Config{Expiration: -1},
+			age:     24 * time.Hour,
+			wantLen: 1,
+		},
+		"keep_last": {
+			cfg:     &Config{Expiration: defaultExpiration, KeepLast: true},
+			age:     24 * time.Hour,
+			wantLen: 1,
+		},
+		"keep_last_with_timestamps": {
+			cfg:     &Config{Expiration: defaultExpiration, KeepLast: true, ExportTimestamps: true},
+			age:     24 * time.Hour,
+			wantLen: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(tc.cfg)
+			then := time.Now().Add(-tc.age)
+			pm := &promMetric{
+				name:    "metric",
+				value:   1,
+				time:    &then,
+				addedAt: then,
+			}
+			p.entries[pm.calculateKey(nil)] = pm
+			p.expireMetrics()
+			if len(p.entries) != tc.wantLen {
+				t.Errorf("expected %d entries, got %d", tc.wantLen, len(p.entries))
+			}
+		})
+	}
+}
+
+func TestExpireOn(t *testing.T) {
+	tests := map[string]struct {
+		expireOn string
+		wantLen  int
+	}{
+		"default": {expireOn: "", wantLen: 0},
+		"time":    {expireOn: "time", wantLen: 0},
+		"added":   {expireOn: "added", wantLen: 1},
+		"max":     {expireOn: "max", wantLen: 1},
+		"unknown": {expireOn: "scrape", wantLen: -1},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(&Config{Expiration: time.Minute, ExportTimestamps: true, ExpireOn: tc.expireOn})
+			err := p.setDefaults()
+			if tc.wantLen < 0 {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			stop := startTestWorker(p)
+			defer stop()
+			// a fresh event with a timestamp set by a target clock running 2 hours late
+			p.eventChan <- &formatters.EventMsg{
+				Name:      "sub",
+				Timestamp: time.Now().Add(-2 * time.Hour).UnixNano(),
+				Tags:      map[string]string{"source": "router1"},
+				Values:    map[string]interface{}{"counter": 1},
+			}
+			// wait for the event to be processed
+			p.eventChan <- &formatters.EventMsg{}
+
+			p.Lock()
+			p.expireMetrics()
+			got := len(p.entries)
+			p.Unlock()
+			if got != tc.wantLen {
+				t.Errorf("expected %d entries, got %d", tc.wantLen, got)
+			}
+		})
+	}
+}
+
+// startTestWorker starts the prometheus output worker and returns a function stopping it
+func startTestWorker(p *PrometheusOutput) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.eventChan = make(chan *formatters.EventMsg)
+	p.wg = new(sync.WaitGroup)
+	p.wg.Add(1)
+	go p.worker(ctx)
+	return func() {
+		cancel()
+		p.wg.Wait()
+	}
+}
+
+func testEvent(i int) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub",
+		Timestamp: time.Now().UnixNano(),
+		Tags:      map[string]string{"source": "router1", "index": fmt.Sprintf("%d", i%1000)},
+		Values:    map[string]interface{}{"counter": i},
+	}
+}
+
+// collect drains the metrics exported by p.Collect,
+// waiting d for each metric to simulate a slow scrape.
+func collect(p *PrometheusOutput, d time.Duration) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		p.Collect(ch)
+		close(ch)
+	}()
+	count := 0
+	for range ch {
+		count++
+		if d > 0 {
+			time.Sleep(d)
+		}
+	}
+	return count
+}
+
+func TestExpirationInterval(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	p := newTestOutput(&Config{Expiration: time.Minute})
+	for i := 0; i < 10; i++ {
+		if d := p.expirationInterval(rnd, i == 0); d != time.Minute {
+			t.Fatalf("expected a fixed interval without jitter, got %s", d)
+		}
+	}
+
+	p = newTestOutput(&Config{Expiration: time.Minute, ExpirationJitter: 10 * time.Second})
+	first := p.expirationInterval(rnd, true)
+	if first < 0 || first >= 70*time.Second {
+		t.Errorf("expected a first interval in [0s, 70s), got %s", first)
+	}
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		d := p.expirationInterval(rnd, false)
+		if d < time.Minute || d >= 70*time.Second {
+			t.Fatalf("expected an interval in [60s, 70s), got %s", d)
+		}
+		seen[d] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected varying intervals, got %v", seen)
+	}
+}
+
+func TestCollectConcurrentWrites(t *testing.T) {
+	p := newTestOutput(&Config{Expiration: time.Minute})
+	stop := startTestWorker(p)
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5000; i++ {
+			p.eventChan <- testEvent(i)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			if n := collect(p, 0); n != 1000 {
+				t.Errorf("expected 1000 metrics, got %d", n)
+			}
+			return
+		default:
+			if n := collect(p, 0); n > 1000 {
+				t.Fatalf("expected at most 1000 metrics, got %d", n)
+			}
+		}
+	}
+}
+
+// BenchmarkWriteDuringCollect measures the time to store events
+// while a slow scrape is in progress.
+func BenchmarkWriteDuringCollect(b *testing.B) {
+	p := newTestOutput(&Config{Expiration: time.Minute})
+	stop := startTestWorker(p)
+	defer stop()
+	for i := 0; i < 1000; i++ {
+		p.eventChan <- testEvent(i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			collect(p, time.Microsecond)
+		}
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.eventChan <- testEvent(i)
+	}
+}
+
+func TestCachedDesc(t *testing.T) {
+	p := newTestOutput(&Config{Expiration: time.Minute})
+	stop := startTestWorker(p)
+	defer stop()
+	for i := 0; i < 10; i++ {
+		p.eventChan <- testEvent(i)
+	}
+	p.eventChan <- &formatters.EventMsg{}
+	descs := make(map[uint64]*prometheus.Desc)
+	for key, pm := range p.snapshotEntries() {
+		if pm.desc == nil {
+			t.Fatalf("metric %s stored without a desc", pm)
+		}
+		if got, want := pm.Desc().String(), pm.newDesc().String(); got != want {
+			t.Errorf("expected desc %s, got %s", want, got)
+		}
+		descs[key] = pm.desc
+	}
+	// updates of the same series reuse the stored desc
+	for i := 0; i < 10; i++ {
+		p.eventChan <- testEvent(i)
+	}
+	p.eventChan <- &formatters.EventMsg{}
+	for key, pm := range p.snapshotEntries() {
+		if pm.desc != descs[key] {
+			t.Errorf("metric %s: expected the desc to be reused", pm)
+		}
+	}
+}
+
+// snapshotEntries returns a copy of the stored metrics map
+func (p *PrometheusOutput) snapshotEntries() map[uint64]*promMetric {
+	p.Lock()
+	defer p.Unlock()
+	entries := make(map[uint64]*promMetric, len(p.entries))
+	for k, v := range p.entries {
+		entries[k] = v
+	}
+	return entries
+}
+
+// BenchmarkCollectDesc measures a scrape of 1000 metrics,
+// with the descs cached when the metrics are stored, or built during the scrape.
+func BenchmarkCollectDesc(b *testing.B) {
+	for _, cached := range []bool{true, false} {
+		b.Run(fmt.Sprintf("cached=%t", cached), func(b *testing.B) {
+			p := newTestOutput(&Config{Expiration: time.Minute})
+			stop := startTestWorker(p)
+			for i := 0; i < 1000; i++ {
+				p.eventChan <- testEvent(i)
+			}
+			p.eventChan <- &formatters.EventMsg{}
+			stop()
+			if !cached {
+				for _, pm := range p.entries {
+					pm.desc = nil
+				}
+			}
+			ch := make(chan prometheus.Metric, len(p.entries))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Collect(ch)
+				for len(ch) > 0 {
+					(<-ch).Desc()
+				}
+			}
+		})
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	tests := map[string]struct {
+		cfg  *Config
+		in   string
+		want string
+	}{
+		"default": {
+			cfg:  &Config{},
+			in:   "/interfaces/interface/name",
+			want: "name",
+		},
+		"sanitized": {
+			cfg:  &Config{},
+			in:   "port-id",
+			want: "port_id",
+		},
+		"mapped": {
+			cfg:  &Config{LabelNameMap: map[string]string{"/interfaces/interface/name": "interface"}},
+			in:   "/interfaces/interface/name",
+			want: "interface",
+		},
+		"mapped_sanitized": {
+			cfg:  &Config{LabelNameMap: map[string]string{"source": "target-address"}},
+			in:   "source",
+			want: "target_address",
+		},
+		"keep_full_path": {
+			cfg:  &Config{KeepFullPath: true},
+			in:   "interfaces/interface/name",
+			want: "interfaces_interface_name",
+		},
+		"keep_full_path_not_mapped": {
+			cfg:  &Config{KeepFullPath: true, LabelNameMap: map[string]string{"other": "x"}},
+			in:   "/system/name",
+			want: "_system_name",
+		},
+		"colons_allowed_in_metric_names_only": {
+			cfg:  &Config{MetricNameAllowColons: true, LabelNameMap: map[string]string{"source": "job:target"}},
+			in:   "source",
+			want: "job_target",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(tc.cfg)
+			if got := p.labelName(tc.in); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestListenRandomPort(t *testing.T) {
+	// fake consul agent recording the registered services
+	registered := make(chan *api.AgentServiceRegistration, 1)
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/agent/self":
+			w.Write([]byte("{}"))
+		case "/v1/agent/service/register":
+			service := new(api.AgentServiceRegistration)
+			if err := json.NewDecoder(r.Body).Decode(service); err == nil {
+				select {
+				case registered <- service:
+				default:
+				}
+			}
+		}
+	}))
+	defer consul.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := outputs.Outputs["prometheus"]().(*PrometheusOutput)
+	err := p.Init(ctx, "prom", map[string]interface{}{
+		"listen": "127.0.0.1:0",
+		"service-registration": map[string]interface{}{
+			"address":           strings.TrimPrefix(consul.URL, "http://"),
+			"name":              "gnmic-prom",
+			"enable-http-check": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Cfg.port == 0 || p.Cfg.Listen != fmt.Sprintf("127.0.0.1:%d", p.Cfg.port) {
+		t.Fatalf("expected the listen address to be resolved, got %q, port %d", p.Cfg.Listen, p.Cfg.port)
+	}
+	rsp, err := http.Get("http://" + p.Cfg.Listen + p.Cfg.Path)
+	if err != nil {
+		t.Fatalf("failed to scrape %s: %v", p.Cfg.Listen, err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rsp.StatusCode)
+	}
+	select {
+	case service := <-registered:
+		if service.Port != p.Cfg.port || service.Address != "127.0.0.1" {
+			t.Errorf("expected the service to be registered with address 127.0.0.1:%d, got %s:%d", p.Cfg.port, service.Address, service.Port)
+		}
+		if len(service.Checks) != 2 || service.Checks[1].HTTP != "http://"+p.Cfg.Listen+"/metrics" {
+			t.Errorf("expected an HTTP check on the resolved address, got %+v", service.Checks)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the service registration")
+	}
+}
+
+// retryConsul is a fake consul agent rejecting the first service registrations
+type retryConsul struct {
+	m              *sync.Mutex
+	rejects        int
+	registrations  int
+	services       map[string]*api.AgentService
+	registeredChan chan struct{}
+	// if true, the registrations and TTL checks updates fail
+	fail bool
+}
+
+func (c *retryConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	switch {
+	case r.URL.Path == "/v1/agent/self":
+		w.Write([]byte("{}"))
+	case r.URL.Path == "/v1/agent/service/register":
+		c.registrations++
+		if c.registrations <= c.rejects || c.fail {
+			http.Error(w, "agent not ready", http.StatusInternalServerError)
+			return
+		}
+		service := new(api.AgentServiceRegistration)
+		if err := json.NewDecoder(r.Body).Decode(service); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.services[service.ID] = &api.AgentService{ID: service.ID, Service: service.Name}
+		c.registeredChan <- struct{}{}
+	case r.URL.Path == "/v1/agent/services":
+		json.NewEncoder(w).Encode(c.services)
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/check/update/"):
+		if len(c.services) == 0 || c.fail {
+			http.Error(w, "unknown check", http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestRegisterServiceRetry(t *testing.T) {
+	minRegistrationRetryDelay = 10 * time.Millisecond
+	defer func() { minRegistrationRetryDelay = time.Second }()
+
+	c := &retryConsul{
+		m:              new(sync.Mutex),
+		rejects:        3,
+		services:       make(map[string]*api.AgentService),
+		registeredChan: make(chan struct{}, 2),
+	}
+	consul := httptest.NewServer(c)
+	defer consul.Close()
+
+	p := newTestOutput(&Config{
+		address: "127.0.0.1",
+		port:    9804,
+		ServiceRegistration: &ServiceRegistration{
+			Address:         strings.TrimPrefix(consul.URL, "http://"),
+			Name:            "gnmic-prom",
+			CheckInterval:   100 * time.Millisecond,
+			id:              "gnmic-prom-1",
+			deregisterAfter: "1s",
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.registerService(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case <-c.registeredChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the service registration")
+	}
+	c.m.Lock()
+	if c.registrations != c.rejects+1 {
+		t.Errorf("expected the service to be registered after %d attempts, got %d", c.rejects+1, c.registrations)
+	}
+	// the service vanishes from consul
+	delete(c.services, "gnmic-prom-1")
+	c.m.Unlock()
+
+	select {
+	case <-c.registeredChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the service to be registered again")
+	}
+}
+
+func TestConsulRegisteredMetric(t *testing.T) {
+	minRegistrationRetryDelay = 10 * time.Millisecond
+	defer func() { minRegistrationRetryDelay = time.Second }()
+
+	c := &retryConsul{
+		m:              new(sync.Mutex),
+		services:       make(map[string]*api.AgentService),
+		registeredChan: make(chan struct{}, 2),
+	}
+	consul := httptest.NewServer(c)
+	defer consul.Close()
+
+	p := newTestOutput(&Config{
+		Name:          "prom",
+		EnableMetrics: true,
+		address:       "127.0.0.1",
+		port:          9804,
+		ServiceRegistration: &ServiceRegistration{
+			Address:         strings.TrimPrefix(consul.URL, "http://"),
+			Name:            "gnmic-prom",
+			CheckInterval:   100 * time.Millisecond,
+			id:              "gnmic-prom-1",
+			deregisterAfter: "1s",
+		},
+	})
+	reg := prometheus.NewRegistry()
+	p.RegisterMetrics(reg)
+
+	registered := func() (float64, bool) {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() != "gnmic_prometheus_consul_registered" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "name" && l.GetValue() == "gnmic-prom" {
+						return m.GetGauge().GetValue(), true
+					}
+				}
+			}
+		}
+		return 0, false
+	}
+	waitFor := func(want float64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if v, ok := registered(); ok && v == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		v, ok := registered()
+		t.Fatalf("expected gnmic_prometheus_consul_registered=%v, got %v (found=%v)", want, v, ok)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.registerService(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor(1)
+	// the consul agent starts failing
+	c.m.Lock()
+	c.fail = true
+	delete(c.services, "gnmic-prom-1")
+	c.m.Unlock()
+	waitFor(0)
+	// the consul agent recovers
+	c.m.Lock()
+	c.fail = false
+	c.m.Unlock()
+	waitFor(1)
+}
+
+func TestRegistrationRetryDelay(t *testing.T) {
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		if got := registrationRetryDelay(attempt); got != want {
+			t.Errorf("attempt %d: expected delay %s, got %s", attempt, want, got)
+		}
+	}
+}
+
+func TestTargetUp(t *testing.T) {
+	p := newTestOutput(&Config{Name: "prom", Expiration: time.Minute, EnableMetrics: true})
+	reg := prometheus.NewRegistry()
+	p.RegisterMetrics(reg)
+	stop := startTestWorker(p)
+	defer stop()
+
+	targetUp := func() map[string]float64 {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := make(map[string]float64)
+		for _, mf := range mfs {
+			if mf.GetName() != "gnmic_prometheus_target_up" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "target" {
+						up[l.GetValue()] = m.GetGauge().GetValue()
+					}
+				}
+			}
+		}
+		return up
+	}
+
+	p.eventChan <- &formatters.EventMsg{Name: "sub", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}}
+	p.eventChan <- &formatters.EventMsg{Name: "sub", Tags: map[string]string{"source": "router2"}, Values: map[string]interface{}{"v": 1}}
+	// wait for the events to be processed
+	p.eventChan <- &formatters.EventMsg{}
+	if up := targetUp(); up["router1"] != 1 || up["router2"] != 1 {
+		t.Fatalf("expected both targets to be up, got %v", up)
+	}
+	// router1 goes stale
+	p.targetsUp.seen("router1", time.Now().Add(-2*time.Minute))
+	// router2 subscription fails
+	p.TargetDown("router2")
+	if up := targetUp(); up["router1"] != 0 || up["router2"] != 0 {
+		t.Fatalf("expected both targets to be down, got %v", up)
+	}
+	// router2 events flow again
+	p.eventChan <- &formatters.EventMsg{Name: "sub", Tags: map[string]string{"source": "router2"}, Values: map[string]interface{}{"v": 1}}
+	p.eventChan <- &formatters.EventMsg{}
+	if up := targetUp(); up["router1"] != 0 || up["router2"] != 1 {
+		t.Fatalf("expected router2 to be up, got %v", up)
+	}
+}
+
+func TestValuesPerEvent(t *testing.T) {
+	p := newTestOutput(&Config{Name: "prom", Expiration: time.Minute, EnableMetrics: true})
+	reg := prometheus.NewRegistry()
+	p.RegisterMetrics(reg)
+	stop := startTestWorker(p)
+	defer stop()
+
+	numValues := []int{1, 3, 10}
+	for i, n := range numValues {
+		values := make(map[string]interface{}, n)
+		for j := 0; j < n; j++ {
+			values[fmt.Sprintf("counter%d", j)] = j
+		}
+		p.eventChan <- &formatters.EventMsg{
+			Name:   "sub",
+			Tags:   map[string]string{"source": fmt.Sprintf("router%d", i)},
+			Values: values,
+		}
+	}
+	// wait for the events to be processed, the empty event is observed as well
+	p.eventChan <- &formatters.EventMsg{}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "gnmic_prometheus_values_per_event" {
+			continue
+		}
+		h := mf.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != uint64(len(numValues)+1) {
+			t.Errorf("expected %d observations, got %d", len(numValues)+1, h.GetSampleCount())
+		}
+		if h.GetSampleSum() != 14 {
+			t.Errorf("expected a sum of 14 values, got %v", h.GetSampleSum())
+		}
+		return
+	}
+	t.Errorf("metric gnmic_prometheus_values_per_event not found")
+}
+
+func TestEventLag(t *testing.T) {
+	p := newTestOutput(&Config{Name: "prom", Expiration: time.Minute, EnableMetrics: true})
+	reg := prometheus.NewRegistry()
+	p.RegisterMetrics(reg)
+	stop := startTestWorker(p)
+	defer stop()
+
+	p.eventChan <- &formatters.EventMsg{
+		Name:      "sub",
+		Timestamp: time.Now().Add(-time.Minute).UnixNano(),
+		Tags:      map[string]string{"source": "router1"},
+		Values:    map[string]interface{}{"v": 1},
+	}
+	// events without a timestamp are ignored
+	p.eventChan <- &formatters.EventMsg{
+		Name:   "sub",
+		Tags:   map[string]string{"source": "router2"},
+		Values: map[string]interface{}{"v": 1},
+	}
+	p.eventChan <- &formatters.EventMsg{}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lags := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() != "gnmic_prometheus_event_lag_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "target" {
+					lags[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	if len(lags) != 1 {
+		t.Fatalf("expected a single event lag series, got %v", lags)
+	}
+	if lag := lags["router1"]; lag < 60 || lag > 70 {
+		t.Errorf("expected router1 event lag to be about 60s, got %v", lag)
+	}
+}
+
+func TestLastErrors(t *testing.T) {
+	p := newTestOutput(&Config{Name: "prom", Expiration: time.Minute, EnableMetrics: true})
+	reg := prometheus.NewRegistry()
+	p.RegisterMetrics(reg)
+
+	update := func(val *gnmi.TypedValue) *gnmi.SubscribeResponse {
+		return &gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: 42,
+					Update: []*gnmi.Update{{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+						Val:  val,
+					}},
+				},
+			},
+		}
+	}
+	// the response with an invalid JSON value fails the conversion to events
+	p.Write(context.Background(),
+		update(&gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`{"mtu":`)}}),
+		outputs.Meta{"source": "router1", "subscription-name": "sub1"},
+	)
+	errs := p.LastErrors()
+	if len(errs) != 1 {
+		t.Fatalf("expected a single target error, got %v", errs)
+	}
+	te, ok := errs["router1"]
+	if !ok {
+		t.Fatalf("missing router1 last error: %v", errs)
+	}
+	if te.Category != "json_decode" || te.Error == "" || te.Time.IsZero() {
+		t.Errorf("unexpected router1 last error: %+v", te)
+	}
+	// the returned errors are copies
+	te.Category = "changed"
+	if p.LastErrors()["router1"].Category != "json_decode" {
+		t.Errorf("last errors modified through the returned map")
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]map[string]string, 0)
+	for _, mf := range mfs {
+		if mf.GetName() != "gnmic_prometheus_last_error" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() != 1 {
+				t.Errorf("expected last error value to be 1, got %v", m.GetGauge().GetValue())
+			}
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			got = append(got, labels)
+		}
+	}
+	want := []map[string]string{{"output": "prom", "target": "router1", "category": "json_decode"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected gnmic_prometheus_last_error series: got %v, want %v", got, want)
+	}
+
+	// a successfully converted response clears the target's last error
+	p.eventChan = make(chan *formatters.EventMsg, 1)
+	p.Write(context.Background(),
+		update(&gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1500}}),
+		outputs.Meta{"source": "router1", "subscription-name": "sub1"},
+	)
+	if errs := p.LastErrors(); len(errs) != 0 {
+		t.Errorf("expected the router1 last error to be cleared, got %v", errs)
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	serr := json.Unmarshal([]byte(`{"mtu":`), new(interface{}))
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"syntax":         {err: serr, want: "json_decode"},
+		"wrapped_syntax": {err: fmt.Errorf("failed to decode value: %w", serr), want: "json_decode"},
+		"wrapped_type": {
+			err:  fmt.Errorf("failed to decode value: %w", &json.UnmarshalTypeError{Value: "string"}),
+			want: "json_decode",
+		},
+		"other": {err: errors.New("unknown value type"), want: "conversion"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := errorCategory(tt.err); got != tt.want {
+				t.Errorf("got category %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterMetricsMultipleOutputs(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	outs := make([]*PrometheusOutput, 0, 2)
+	for _, name := range []string{"prom1", "prom2"} {
+		buf := new(bytes.Buffer)
+		p := newTestOutput(&Config{Name: name, Expiration: time.Minute, EnableMetrics: true})
+		p.logger = log.New(buf, "", 0)
+		p.RegisterMetrics(reg)
+		if buf.Len() != 0 {
+			t.Fatalf("output %q failed to register its metrics: %s", name, buf.String())
+		}
+		p.targetsUp.seen("router1", time.Now())
+		outs = append(outs, p)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() != "gnmic_prometheus_target_up" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "output" {
+					got[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	if len(got) != len(outs) || got["prom1"] != 1 || got["prom2"] != 1 {
+		t.Errorf("expected a target_up series per output, got %v", got)
+	}
+	// an output reusing a name fails to register its metrics without panicking
+	buf := new(bytes.Buffer)
+	p := newTestOutput(&Config{Name: "prom1", Expiration: time.Minute, EnableMetrics: true})
+	p.logger = log.New(buf, "", 0)
+	p.RegisterMetrics(reg)
+	if !strings.Contains(buf.String(), "failed to register metric") {
+		t.Errorf("expected a registration failure for a duplicate output name, got %q", buf.String())
+	}
+}
+
+func TestIdentityExcludeLabels(t *testing.T) {
+	p := newTestOutput(&Config{Expiration: time.Minute, IdentityExcludeLabels: []string{"request_id"}})
+	if err := p.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	stop := startTestWorker(p)
+	defer stop()
+	for i, rid := range []string{"a1", "b2"} {
+		p.eventChan <- &formatters.EventMsg{
+			Name:   "sub",
+			Tags:   map[string]string{"source": "router1", "request_id": rid},
+			Values: map[string]interface{}{"counter": i + 1},
+		}
+	}
+	// wait for the events to be processed
+	p.eventChan <- &formatters.EventMsg{}
+
+	entries := p.snapshot(nil)
+	if len(entries) != 1 {
+		t.Fatalf("expected a single series, got %d: %v", len(entries), entries)
+	}
+	m := new(dto.Metric)
+	if err := entries[0].Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetUntyped().GetValue() != 2 {
+		t.Errorf("expected the latest value 2, got %v", m)
+	}
+	labels := make(map[string]string)
+	for _, lp := range m.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	if labels["request_id"] != "b2" || labels["source"] != "router1" {
+		t.Errorf("expected the latest labels, got %v", labels)
+	}
+}
+
+func TestEmptyLabelValues(t *testing.T) {
+	tests := map[string]struct {
+		cfg  *Config
+		want map[string]string
+	}{
+		"default": {
+			cfg:  &Config{},
+			want: map[string]string{"source": "router1", "description": ""},
+		},
+		"drop_empty_labels": {
+			cfg:  &Config{DropEmptyLabels: true},
+			want: map[string]string{"source": "router1"},
+		},
+		"empty_label_placeholder": {
+			cfg:  &Config{EmptyLabelPlaceholder: "none"},
+			want: map[string]string{"source": "router1", "description": "none"},
+		},
+	}
+	ev := &formatters.EventMsg{
+		Name:   "sub",
+		Tags:   map[string]string{"source": "router1", "description": ""},
+		Values: map[string]interface{}{"counter": 1},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(tc.cfg)
+			labels := p.getLabels(ev)
+			got := make(map[string]string, len(labels))
+			for _, lp := range labels {
+				got[lp.Name] = lp.Value
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected labels %v, got %v", tc.want, got)
+			}
+			// the series key is built from the exported labels only
+			want := make([]*labelPair, 0, len(tc.want))
+			for k, v := range tc.want {
+				want = append(want, &labelPair{Name: k, Value: v})
+			}
+			gotKey := (&promMetric{name: "sub_counter", labels: labels}).calculateKey(nil)
+			wantKey := (&promMetric{name: "sub_counter", labels: want}).calculateKey(nil)
+			if gotKey != wantKey {
+				t.Errorf("expected key %d, got %d", wantKey, gotKey)
+			}
+		})
+	}
+}
+
+func TestEmptyLabelOptionsExclusive(t *testing.T) {
+	p := newTestOutput(&Config{DropEmptyLabels: true, EmptyLabelPlaceholder: "none"})
+	if err := p.setDefaults(); err == nil {
+		t.Error("expected an error when both drop-empty-labels and empty-label-placeholder are set")
+	}
+}
+
+func TestForceContentTypeOpenMetricsDisabled(t *testing.T) {
+	p := newTestOutput(&Config{ForceContentType: "application/openmetrics-text; version=1.0.0; charset=utf-8"})
+	if err := p.setDefaults(); err == nil {
+		t.Error("expected an error when force-content-type is OpenMetrics and enable-openmetrics is false")
+	}
+	p = newTestOutput(&Config{
+		Listen:            ":9804",
+		EnableOpenMetrics: true,
+		ForceContentType:  "application/openmetrics-text; version=1.0.0; charset=utf-8",
+	})
+	if err := p.setDefaults(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetLoggerJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	appLogger := log.New(buf, "[gnmic] ", log.LstdFlags|log.Lmicroseconds)
+	logging.SetFormat(appLogger, logging.FormatJSON)
+
+	p := newTestOutput(&Config{})
+	p.logger = log.New(ioutil.Discard, loggingPrefix, log.LstdFlags|log.Lmicroseconds)
+	p.SetLogger(appLogger)
+	logging.NewLogger(p.logger).Errorf("failed to write metric: %v", "bad value")
+
+	ev := make(map[string]interface{})
+	err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &ev)
+	if err != nil {
+		t.Fatalf("log line %q is not JSON: %v", buf.String(), err)
+	}
+	for _, k := range []string{"level", "ts", "component", "message", "fields"} {
+		if _, ok := ev[k]; !ok {
+			t.Errorf("missing key %q in log line: %v", k, ev)
+		}
+	}
+	if ev["component"] != "prometheus_output" || ev["level"] != "error" ||
+		ev["message"] != "failed to write metric: bad value" {
+		t.Errorf("unexpected log line: %v", ev)
+	}
+}
+
+func TestSubscriptionSynced(t *testing.T) {
+	p := newTestOutput(&Config{Name: "prom"})
+	p.synced = newSubscriptionSyncCollector()
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(p); err != nil {
+		t.Fatal(err)
+	}
+	synced := func() map[string]float64 {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := make(map[string]float64)
+		for _, mf := range mfs {
+			if mf.GetName() != "gnmic_subscription_synced" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := make(map[string]string)
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				res[labels["target"]+"/"+labels["subscription"]] = m.GetGauge().GetValue()
+			}
+		}
+		return res
+	}
+	if s := synced(); len(s) != 0 {
+		t.Fatalf("expected no synced subscriptions, got %v", s)
+	}
+	syncRsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}
+	p.Write(context.Background(), syncRsp, outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+	p.Write(context.Background(), syncRsp, outputs.Meta{"source": "router2", "subscription-name": "sub2"})
+	s := synced()
+	if len(s) != 2 || s["router1/sub1"] != 1 || s["router2/sub2"] != 1 {
+		t.Fatalf("expected router1/sub1 and router2/sub2 to be synced, got %v", s)
+	}
+	// router1 re-subscribes after a failure
+	p.TargetDown("router1")
+	s = synced()
+	if len(s) != 1 || s["router2/sub2"] != 1 {
+		t.Fatalf("expected only router2/sub2 to be synced, got %v", s)
+	}
+	if p.synced.isSynced("sub1", "router1") {
+		t.Fatal("expected router1/sub1 not to be synced")
+	}
+	p.Write(context.Background(), syncRsp, outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+	s = synced()
+	if len(s) != 2 || s["router1/sub1"] != 1 {
+		t.Fatalf("expected router1/sub1 to be synced again, got %v", s)
+	}
+}
+
+func TestSkipInitialSync(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip-initial-sync=%t", skip), func(t *testing.T) {
+			p := newTestOutput(&Config{Expiration: time.Minute, SkipInitialSync: skip})
+			p.synced = newSubscriptionSyncCollector()
+			stop := startTestWorker(p)
+			defer stop()
+			update := func(name string, v int64) *gnmi.SubscribeResponse {
+				return &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+					Timestamp: time.Now().UnixNano(),
+					Update: []*gnmi.Update{{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: name}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: v}},
+					}},
+				}}}
+			}
+			meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+			other := outputs.Meta{"source": "router2", "subscription-name": "sub1"}
+			ctx := context.Background()
+			p.Write(ctx, update("pre_sync", 1), meta)
+			p.Write(ctx, update("other_target", 1), other)
+			p.Write(ctx, &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}, meta)
+			p.Write(ctx, update("post_sync", 2), meta)
+			// wait for the events to be processed
+			p.eventChan <- &formatters.EventMsg{}
+
+			got := make(map[string]float64)
+			for _, pm := range p.snapshot(nil) {
+				got[pm.name] = pm.value
+			}
+			want := map[string]float64{"post_sync": 2}
+			if !skip {
+				want["pre_sync"] = 1
+				want["other_target"] = 1
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected metrics: %v, expected: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInferMetricTypes(t *testing.T) {
+	p := newTestOutput(&Config{Expiration: time.Minute, InferMetricTypes: true})
+	p.metricTypes = map[string]outputs.MetricType{
+		"/interfaces/interface/state/counters/in-octets": outputs.CounterMetric,
+		"/interfaces/interface/state/mtu":                outputs.GaugeMetric,
+	}
+	stop := startTestWorker(p)
+	defer stop()
+	p.eventChan <- &formatters.EventMsg{
+		Name: "sub",
+		Tags: map[string]string{"source": "router1", "interface_name": "eth0"},
+		Values: map[string]interface{}{
+			"/interfaces/interface/state/counters/in-octets": 42,
+			"/interfaces/interface/state/mtu":                1500,
+		},
+	}
+	// wait for the event to be processed
+	p.eventChan <- &formatters.EventMsg{}
+
+	got := make(map[string]*dto.Metric)
+	for _, pm := range p.snapshot(nil) {
+		m := new(dto.Metric)
+		if err := pm.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		got[pm.name] = m
+	}
+	if m, ok := got["interfaces_interface_state_counters_in_octets"]; !ok || m.GetCounter().GetValue() != 42 {
+		t.Errorf("expected interfaces_interface_state_counters_in_octets to be a counter, got %v", m)
+	}
+	if m, ok := got["interfaces_interface_state_mtu"]; !ok || m.GetGauge().GetValue() != 1500 {
+		t.Errorf("expected interfaces_interface_state_mtu to be a gauge, got %v", m)
+	}
+}
+
+func TestCounterTotalSuffix(t *testing.T) {
+	p := newTestOutput(&Config{Expiration: time.Minute, InferMetricTypes: true, CounterTotalSuffix: true})
+	p.metricTypes = map[string]outputs.MetricType{
+		"/interfaces/interface/state/counters/in-octets": outputs.CounterMetric,
+		"/interfaces/interface/state/counters/in-total":  outputs.CounterMetric,
+		"/interfaces/interface/state/mtu":                outputs.GaugeMetric,
+	}
+	stop := startTestWorker(p)
+	defer stop()
+	p.eventChan <- &formatters.EventMsg{
+		Name: "sub",
+		Tags: map[string]string{"source": "router1", "interface_name": "eth0"},
+		Values: map[string]interface{}{
+			"/interfaces/interface/state/counters/in-octets": 42,
+			"/interfaces/interface/state/counters/in-total":  43,
+			"/interfaces/interface/state/mtu":                1500,
+		},
+	}
+	// wait for the event to be processed
+	p.eventChan <- &formatters.EventMsg{}
+
+	got := make(map[string]float64)
+	for _, pm := range p.snapshot(nil) {
+		got[pm.name] = pm.value
+	}
+	want := map[string]float64{
+		"interfaces_interface_state_counters_in_octets_total": 42,
+		"interfaces_interface_state_counters_in_total":        43,
+		"interfaces_interface_state_mtu":                      1500,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected metrics: %v, expected: %v", got, want)
+	}
+}
+
+func TestClearOnScrape(t *testing.T) {
+	p := newTestOutput(&Config{
+		Path:          defaultPath,
+		Expiration:    time.Minute,
+		ClearOnScrape: true,
+		Paths:         []*PathConfig{{Path: "/octets", Allowlist: []string{"octets"}}},
+	})
+	stop := startTestWorker(p)
+	defer stop()
+
+	p.eventChan <- &formatters.EventMsg{
+		Name:   "sub",
+		Tags:   map[string]string{"source": "router1"},
+		Values: map[string]interface{}{"in_octets": 1, "in_errors": 2},
+	}
+	p.eventChan <- &formatters.EventMsg{}
+
+	// the filtered path only clears the metrics it exported
+	if body := scrape(t, p, "/octets"); !strings.Contains(body, "in_octets") {
+		t.Fatalf("expected in_octets in the first scrape, got: %s", body)
+	}
+	body := scrape(t, p, defaultPath)
+	if strings.Contains(body, "in_octets") || !strings.Contains(body, "in_errors") {
+		t.Fatalf("expected only in_errors in the second scrape, got: %s", body)
+	}
+	if body := scrape(t, p, defaultPath); strings.Contains(body, "in_errors") {
+		t.Fatalf("expected in_errors to be removed after being scraped, got: %s", body)
+	}
+	// a new write is exported again
+	p.eventChan <- &formatters.EventMsg{
+		Name:   "sub",
+		Tags:   map[string]string{"source": "router1"},
+		Values: map[string]interface{}{"in_errors": 3},
+	}
+	p.eventChan <- &formatters.EventMsg{}
+	if body := scrape(t, p, defaultPath); !strings.Contains(body, "in_errors") {
+		t.Fatalf("expected in_errors after a new write, got: %s", body)
+	}
+}
+
+func TestSortLabels(t *testing.T) {
+	p := newTestOutput(&Config{
+		Path:       defaultPath,
+		Expiration: time.Minute,
+		SortLabels: true,
+	})
+	stop := startTestWorker(p)
+	defer stop()
+
+	p.eventChan <- &formatters.EventMsg{
+		Name: "sub",
+		Tags: map[string]string{
+			"source":         "router1",
+			"interface_name": "ethernet-1/1",
+			"subinterface":   "0",
+			"afi":            "ipv4",
+			"network":        "default",
+		},
+		Values: map[string]interface{}{"in_octets": 1, "out_octets": 2},
+	}
+	p.eventChan <- &formatters.EventMsg{}
+
+	first := scrape(t, p, defaultPath)
+	if !strings.Contains(first, `in_octets{afi="ipv4",interface_name="ethernet-1/1",network="default",source="router1",subinterface="0"} 1`) {
+		t.Fatalf("unexpected exposition: %s", first)
+	}
+	if second := scrape(t, p, defaultPath); second != first {
+		t.Fatalf("expected identical scrapes, got:\n%s\nand:\n%s", first, second)
+	}
+
+	// labels are written sorted, regardless of the stored order
+	pm := &promMetric{
+		name: "sub_in_octets",
+		labels: []*labelPair{
+			{Name: "source", Value: "router1"},
+			{Name: "afi", Value: "ipv4"},
+			{Name: "network", Value: "default"},
+		},
+		sortLabels: true,
+	}
+	out := &dto.Metric{}
+	if err := pm.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(out.Label))
+	for _, lb := range out.Label {
+		got = append(got, lb.GetName())
+	}
+	if want := []string{"afi", "network", "source"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected labels order: got %v, want %v", got, want)
+	}
+}
+
+func TestPrefixAsLabels(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Prefix: &gnmi.Path{
+					Elem: []*gnmi.PathElem{
+						{Name: "components"},
+						{Name: "component", Key: map[string]string{"name": "Slot 1"}},
+					},
+				},
+				Update: []*gnmi.Update{{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "state"}, {Name: "temperature"}, {Name: "instant"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 45}},
+				}},
+			},
+		},
+	}
+	tests := map[string]struct {
+		prefixAsLabels bool
+		want           string
+	}{
+		"disabled": {
+			want: `sub1_components_component_state_temperature_instant{component_name="Slot 1",source="router1",subscription_name="sub1"} 45`,
+		},
+		"enabled": {
+			prefixAsLabels: true,
+			want:           `sub1_state_temperature_instant{prefix="/components/component",prefix_component_name="Slot 1",source="router1",subscription_name="sub1"} 45`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(&Config{
+				Path:                   defaultPath,
+				Expiration:             time.Minute,
+				AppendSubscriptionName: true,
+				PrefixAsLabels:         tc.prefixAsLabels,
+			})
+			stop := startTestWorker(p)
+			defer stop()
+
+			p.Write(context.Background(), rsp, outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+			p.eventChan <- &formatters.EventMsg{}
+
+			if body := scrape(t, p, defaultPath); !strings.Contains(body, tc.want) {
+				t.Fatalf("expected %q in the exposition, got: %s", tc.want, body)
+			}
+		})
+	}
+	// the response is not modified
+	if len(rsp.GetUpdate().GetPrefix().GetElem()) != 2 {
+		t.Fatalf("unexpected response prefix change: %v", rsp.GetUpdate().GetPrefix())
+	}
+}
+
+func TestDeletes(t *testing.T) {
+	p := newTestOutput(&Config{Path: defaultPath, Expiration: time.Hour})
+	stop := startTestWorker(p)
+	defer stop()
+
+	ifPath := func(name string, elems ...string) *gnmi.Path {
+		path := &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": name}}}}
+		for _, e := range elems {
+			path.Elem = append(path.Elem, &gnmi.PathElem{Name: e})
+		}
+		return path
+	}
+	notification := func(n *gnmi.Notification) *gnmi.SubscribeResponse {
+		n.Prefix = &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}}
+		return &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}}
+	}
+	val := &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 42}}
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+
+	for _, name := range []string{"ethernet-1/1", "ethernet-1/2"} {
+		p.Write(context.Background(), notification(&gnmi.Notification{
+			Timestamp: 1,
+			Update: []*gnmi.Update{
+				{Path: ifPath(name, "state", "counters", "in-octets"), Val: val},
+				{Path: ifPath(name, "mtu"), Val: val},
+			},
+		}), meta)
+	}
+	p.eventChan <- &formatters.EventMsg{}
+	body := scrape(t, p, defaultPath)
+	for _, name := range []string{"ethernet-1/1", "ethernet-1/2"} {
+		for _, m := range []string{"interfaces_interface_state_counters_in_octets", "interfaces_interface_mtu"} {
+			if series := fmt.Sprintf(`%s{interface_name=%q,source="router1",subscription_name="sub1"} 42`, m, name); !strings.Contains(body, series) {
+				t.Fatalf("expected %q in the exposition, got: %s", series, body)
+			}
+		}
+	}
+
+	// deleting ethernet-1/1 state removes its counters only
+	p.Write(context.Background(), notification(&gnmi.Notification{
+		Timestamp: 2,
+		Delete:    []*gnmi.Path{ifPath("ethernet-1/1", "state")},
+	}), meta)
+	p.eventChan <- &formatters.EventMsg{}
+	body = scrape(t, p, defaultPath)
+	if strings.Contains(body, `interfaces_interface_state_counters_in_octets{interface_name="ethernet-1/1"`) {
+		t.Fatalf("expected ethernet-1/1 counters to be deleted, got: %s", body)
+	}
+	for _, series := range []string{
+		`interfaces_interface_state_counters_in_octets{interface_name="ethernet-1/2"`,
+		`interfaces_interface_mtu{interface_name="ethernet-1/1"`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Fatalf("expected %q in the exposition, got: %s", series, body)
+		}
+	}
+
+	// a delete from another target is ignored
+	p.Write(context.Background(), notification(&gnmi.Notification{
+		Timestamp: 3,
+		Delete:    []*gnmi.Path{ifPath("ethernet-1/2")},
+	}), outputs.Meta{"source": "router2", "subscription-name": "sub1"})
+	p.eventChan <- &formatters.EventMsg{}
+	if n := len(p.snapshot(nil)); n != 3 {
+		t.Fatalf("expected 3 metrics, got %d", n)
+	}
+
+	// deleting ethernet-1/2 removes all its metrics
+	p.Write(context.Background(), notification(&gnmi.Notification{
+		Timestamp: 3,
+		Delete:    []*gnmi.Path{ifPath("ethernet-1/2")},
+	}), meta)
+	p.eventChan <- &formatters.EventMsg{}
+	body = scrape(t, p, defaultPath)
+	if strings.Contains(body, `interface_name="ethernet-1/2"`) {
+		t.Fatalf("expected ethernet-1/2 metrics to be deleted, got: %s", body)
+	}
+	if n := len(p.snapshot(nil)); n != 1 {
+		t.Fatalf("expected a single metric left, got %d", n)
+	}
+}
+
+func TestDeletesRenamedValues(t *testing.T) {
+	p := newTestOutput(&Config{Path: defaultPath, Expiration: time.Hour})
+	ep := formatters.EventProcessors["event-strings"]()
+	err := ep.Init(map[string]interface{}{
+		"value-names": []string{"^/interfaces/"},
+		"transforms": []map[string]interface{}{
+			{"trim-prefix": map[string]interface{}{"apply-on": "name", "prefix": "/interfaces/"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.evps = []formatters.EventProcessor{ep}
+	stop := startTestWorker(p)
+	defer stop()
+
+	ifPath := func(name string, elems ...string) *gnmi.Path {
+		path := &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": name}}}}
+		for _, e := range elems {
+			path.Elem = append(path.Elem, &gnmi.PathElem{Name: e})
+		}
+		return path
+	}
+	notification := func(n *gnmi.Notification) *gnmi.SubscribeResponse {
+		n.Prefix = &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}}
+		return &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}}
+	}
+	val := &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 42}}
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+
+	p.Write(context.Background(), notification(&gnmi.Notification{
+		Timestamp: 1,
+		Update: []*gnmi.Update{
+			{Path: ifPath("ethernet-1/1", "state", "counters", "in-octets"), Val: val},
+			{Path: ifPath("ethernet-1/1", "mtu"), Val: val},
+		},
+	}), meta)
+	p.eventChan <- &formatters.EventMsg{}
+	body := scrape(t, p, defaultPath)
+	for _, m := range []string{"interface_state_counters_in_octets", "interface_mtu"} {
+		if series := fmt.Sprintf(`%s{interface_name="ethernet-1/1",source="router1",subscription_name="sub1"} 42`, m); !strings.Contains(body, series) {
+			t.Fatalf("expected %q in the exposition, got: %s", series, body)
+		}
+	}
+
+	// the deleted path is renamed by the processor like the values
+	p.Write(context.Background(), notification(&gnmi.Notification{
+		Timestamp: 2,
+		Delete:    []*gnmi.Path{ifPath("ethernet-1/1", "state")},
+	}), meta)
+	p.eventChan <- &formatters.EventMsg{}
+	body = scrape(t, p, defaultPath)
+	if strings.Contains(body, "interface_state_counters_in_octets") {
+		t.Fatalf("expected the renamed counters to be deleted, got: %s", body)
+	}
+	if !strings.Contains(body, `interface_mtu{interface_name="ethernet-1/1"`) {
+		t.Fatalf("expected the mtu to be kept, got: %s", body)
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	tests := map[string]struct {
+		metricName string
+		wantErr    bool
+	}{
+		"valid_metric": {
+			metricName: "interfaces_in_octets",
+		},
+		"broken_metric_name": {
+			metricName: "interfaces-in-octets",
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := outputs.Outputs["prometheus"]().(*PrometheusOutput)
+			// the metric name is not sanitized when added directly to the entries
+			p.addTestMetric(tt.metricName, 1)
+			err := p.Init(ctx, "prom", map[string]interface{}{
+				"listen":     "127.0.0.1:0",
+				"self-check": true,
+			})
+			if tt.wantErr {
+				if err == nil {
+					p.Close()
+					t.Fatal("expected the self-check to fail")
+				}
+				t.Logf("self-check error: %v", err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p.Close()
+		})
+	}
+}
+
+func TestInstanceLabel(t *testing.T) {
+	tests := map[string]struct {
+		cfg  *Config
+		want map[string]string
+	}{
+		"disabled": {
+			cfg:  &Config{},
+			want: map[string]string{"source": "router1"},
+		},
+		"default_label_name": {
+			cfg:  &Config{InstanceLabel: true},
+			want: map[string]string{"source": "router1", "gnmic_instance": "gnmic-1"},
+		},
+		"custom_label_name": {
+			cfg:  &Config{InstanceLabel: true, InstanceLabelName: "collector"},
+			want: map[string]string{"source": "router1", "collector": "gnmic-1"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.cfg.Expiration = time.Minute
+			p := newTestOutput(tc.cfg)
+			p.SetName("gnmic-1")
+			if err := p.setDefaults(); err != nil {
+				t.Fatal(err)
+			}
+			stop := startTestWorker(p)
+			defer stop()
+			p.eventChan <- &formatters.EventMsg{
+				Name:   "sub",
+				Tags:   map[string]string{"source": "router1"},
+				Values: map[string]interface{}{"counter": 1},
+			}
+			// wait for the event to be processed
+			p.eventChan <- &formatters.EventMsg{}
+
+			entries := p.snapshot(nil)
+			if len(entries) != 1 {
+				t.Fatalf("expected a single series, got %d: %v", len(entries), entries)
+			}
+			m := new(dto.Metric)
+			if err := entries[0].Write(m); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, lp := range m.GetLabel() {
+				got[lp.GetName()] = lp.GetValue()
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected labels %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCloseBeforeInit(t *testing.T) {
+	p := outputs.Outputs["prometheus"]().(*PrometheusOutput)
+	if err := p.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	err := p.Init(context.Background(), "prom", map[string]interface{}{"listen": "127.0.0.1:0"})
+	if err == nil {
+		t.Fatal("expected the initialization of a closed output to fail")
+	}
+	if p.server != nil {
+		t.Error("expected the server not to be started")
+	}
+}
+
+func TestInitCloseConcurrent(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		p := outputs.Outputs["prometheus"]().(*PrometheusOutput)
+		ctx, cancel := context.WithCancel(context.Background())
+		var initErr error
+		wg := new(sync.WaitGroup)
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			initErr = p.Init(ctx, "prom", map[string]interface{}{"listen": "127.0.0.1:0"})
+		}()
+		for j := 0; j < 2; j++ {
+			go func() {
+				defer wg.Done()
+				if err := p.Close(); err != nil {
+					t.Errorf("close failed: %v", err)
+				}
+			}()
+		}
+		// the context watcher closes the output as well
+		go func() {
+			defer wg.Done()
+			cancel()
+		}()
+		wg.Wait()
+		p.Close()
+		if initErr != nil {
+			continue
+		}
+		// the output was started then closed, its server is stopped
+		rsp, err := http.Get("http://" + p.Cfg.Listen + p.Cfg.Path)
+		if err == nil {
+			rsp.Body.Close()
+			t.Fatalf("iteration %d: expected the server to be stopped", i)
+		}
+	}
+}
+
+func TestMaxLabels(t *testing.T) {
+	tests := map[string]struct {
+		mode        string
+		wantSeries  int
+		wantLabels  []string
+		wantDropped float64
+	}{
+		"drop": {
+			mode:        "",
+			wantSeries:  1,
+			wantLabels:  []string{"source"},
+			wantDropped: 3,
+		},
+		"truncate": {
+			mode:       "truncate",
+			wantSeries: 4,
+			wantLabels: []string{"description", "interface_name", "mtu"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(&Config{Name: "prom", Expiration: time.Minute, EnableMetrics: true, StringsAsLabels: true, MaxLabels: 3, MaxLabelsMode: tc.mode})
+			if err := p.setDefaults(); err != nil {
+				t.Fatal(err)
+			}
+			reg := prometheus.NewRegistry()
+			p.RegisterMetrics(reg)
+			stop := startTestWorker(p)
+			defer stop()
+			// 4 labels: 3 tags and 1 string value, each value is a series, each value is a series
+			p.eventChan <- &formatters.EventMsg{
+				Name: "sub",
+				Tags: map[string]string{"source": "router1", "interface_name": "ethernet-1/1", "mtu": "1500"},
+				Values: map[string]interface{}{
+					"in-octets":   1,
+					"out-octets":  2,
+					"description": "uplink",
+				},
+			}
+			// under the limit
+			p.eventChan <- &formatters.EventMsg{
+				Name:   "sub",
+				Tags:   map[string]string{"source": "router1"},
+				Values: map[string]interface{}{"cpu": 1},
+			}
+			// wait for the events to be processed
+			p.eventChan <- &formatters.EventMsg{}
+
+			entries := p.snapshot(nil)
+			if len(entries) != tc.wantSeries {
+				t.Fatalf("expected %d series, got %d: %v", tc.wantSeries, len(entries), entries)
+			}
+			for _, e := range entries {
+				if len(e.labels) > 3 {
+					t.Errorf("series %s has more than 3 labels", e)
+				}
+				if e.valueName == "cpu" {
+					continue
+				}
+				got := make([]string, 0, len(e.labels))
+				for _, l := range e.labels {
+					got = append(got, l.Name)
+				}
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, tc.wantLabels) {
+					t.Errorf("expected labels %v, got %v", tc.wantLabels, got)
+				}
+			}
+			mfs, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, mf := range mfs {
+				if mf.GetName() != "gnmic_prometheus_max_labels_dropped_series_total" {
+					continue
+				}
+				if v := mf.GetMetric()[0].GetCounter().GetValue(); v != tc.wantDropped {
+					t.Errorf("expected %v dropped series, got %v", tc.wantDropped, v)
+				}
+				return
+			}
+			t.Errorf("metric gnmic_prometheus_max_labels_dropped_series_total not found")
+		})
+	}
+}
+
+func TestMaxLabelsModeInvalid(t *testing.T) {
+	p := newTestOutput(&Config{MaxLabels: 3, MaxLabelsMode: "keep"})
+	if err := p.setDefaults(); err == nil {
+		t.Errorf("expected an error with an unknown max-labels-mode")
+	}
+}
+
+// failingProcessor reports an error for each event, or panics if panics is set.
+type failingProcessor struct {
+	formatters.EventProcessor
+	formatters.ErrorReporter
+	panics bool
+}
+
+func (fp *failingProcessor) Init(_ interface{}, opts ...formatters.Option) error {
+	for _, opt := range opts {
+		opt(fp)
+	}
+	return nil
+}
+
+func (fp *failingProcessor) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	if fp.panics {
+		panic("processor failure")
+	}
+	for range es {
+		fp.ReportError(errors.New("processor failure"))
+	}
+	return es
+}
+
+func (fp *failingProcessor) WithLogger(*log.Logger) {}
+
+func (fp *failingProcessor) WithTargets(map[string]interface{}) {}
+
+func TestEventProcessorErrors(t *testing.T) {
+	formatters.Register("test-failing", func() formatters.EventProcessor { return &failingProcessor{} })
+	formatters.Register("test-panic", func() formatters.EventProcessor { return &failingProcessor{panics: true} })
+
+	p := outputs.Outputs["prometheus"]().(*PrometheusOutput)
+	p.Cfg = &Config{Name: "prom", Path: "/metrics", EventProcessors: []string{"fail", "panic"}}
+	p.SetEventProcessors(map[string]map[string]interface{}{
+		"fail":  {"test-failing": map[string]interface{}{}},
+		"panic": {"test-panic": map[string]interface{}{}},
+	}, nil, nil)
+	if len(p.evps) != 2 {
+		t.Fatalf("expected 2 event processors, got %d", len(p.evps))
+	}
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "in-octets"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "out-octets"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 2}},
+					},
+				},
+			},
+		},
+	}
+	events, err := formatters.ResponseToEventMsgsSplitDeletes("sub", rsp, nil, p.evps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the events are dropped by the panicking processor
+	if len(events) != 0 {
+		t.Errorf("expected the events to be dropped, got %v", events)
+	}
+	body := scrape(t, p, "/metrics")
+	for _, want := range []string{
+		`gnmic_event_processor_errors_total{processor="fail",type="test-failing"} 2`,
+		`gnmic_event_processor_errors_total{processor="panic",type="test-panic"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in scraped metrics, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package prometheus_output
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// PathConfig defines an additional HTTP path serving a filtered view of the stored metrics
+type PathConfig struct {
+	Path      string   `mapstructure:"path,omitempty"`
+	Allowlist []string `mapstructure:"allowlist,omitempty"`
+	Denylist  []string `mapstructure:"denylist,omitempty"`
+}
+
+// filteredCollector implements prometheus.Collector,
+// it exports the subset of the prometheus output entries matching its allow and deny lists.
+type filteredCollector struct {
+	p     *PrometheusOutput
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+func newFilteredCollector(p *PrometheusOutput, pc *PathConfig) (*filteredCollector, error) {
+	fc := &filteredCollector{
+		p:     p,
+		allow: make([]*regexp.Regexp, 0, len(pc.Allowlist)),
+		deny:  make([]*regexp.Regexp, 0, len(pc.Denylist)),
+	}
+	for _, reg := range pc.Allowlist {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %v", pc.Path, err)
+		}
+		fc.allow = append(fc.allow, re)
+	}
+	for _, reg := range pc.Denylist {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %v", pc.Path, err)
+		}
+		fc.deny = append(fc.deny, re)
+	}
+	return fc, nil
+}
+
+// Describe implements prometheus.Collector
+func (fc *filteredCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (fc *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, entry := range fc.p.snapshot(fc.match) {
+		ch <- entry
+	}
+}
+
+// match returns true if the metric name is not denied,
+// and is allowed by at least one allowlist entry (if any).
+func (fc *filteredCollector) match(name string) bool {
+	for _, re := range fc.deny {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(fc.allow) == 0 {
+		return true
+	}
+	for _, re := range fc.allow {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// createServeMux creates the http handler serving the default path
+// as well as a handler per configured path.
+func (p *PrometheusOutput) createServeMux() (*http.ServeMux, error) {
+	registry := prometheus.NewRegistry()
+	err := registry.Register(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.processorErrors != nil {
+		err = registry.Register(p.processorErrors)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle(p.Cfg.Path, p.metricsHandler(p.samplesGatherer(registry, nil)))
+
+	paths := map[string]struct{}{p.Cfg.Path: {}}
+	for _, pc := range p.Cfg.Paths {
+		if pc == nil {
+			continue
+		}
+		if pc.Path == "" {
+			return nil, fmt.Errorf("missing 'path' field in paths definition")
+		}
+		if _, ok := paths[pc.Path]; ok {
+			return nil, fmt.Errorf("duplicate path %q", pc.Path)
+		}
+		paths[pc.Path] = struct{}{}
+		fc, err := newFilteredCollector(p, pc)
+		if err != nil {
+			return nil, err
+		}
+		reg := prometheus.NewRegistry()
+		err = reg.Register(fc)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle(pc.Path, p.metricsHandler(p.samplesGatherer(reg, fc.match)))
+	}
+	return mux, nil
+}
+
+// metricsHandler returns the handler exposing the metrics gathered by g.
+// The exposition format is negotiated based on the request Accept header,
+// unless force-content-type is set.
+func (p *PrometheusOutput) metricsHandler(g prometheus.Gatherer) http.Handler {
+	h := promhttp.HandlerFor(g, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: p.Cfg.EnableOpenMetrics,
+	})
+	if p.Cfg.ForceContentType == "" {
+		return h
+	}
+	accept := p.Cfg.ForceContentType
+	// encode the body in the forced format if it is a known one,
+	// the text format is used otherwise.
+	// OpenMetrics is accepted in any version.
+	if mt, _, _ := mime.ParseMediaType(accept); mt == expfmt.OpenMetricsType {
+		accept = string(expfmt.FmtOpenMetrics)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Accept", accept)
+		h.ServeHTTP(&contentTypeWriter{ResponseWriter: w, contentType: p.Cfg.ForceContentType}, r)
+	})
+}
+
+// contentTypeWriter overrides the Content-Type header set by the wrapped handler.
+type contentTypeWriter struct {
+	http.ResponseWriter
+	contentType string
+	wroteHeader bool
+}
+
+func (w *contentTypeWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Content-Type", w.contentType)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *contentTypeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,172 @@
+package prometheus_output
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOutput(cfg *Config) *PrometheusOutput {
+	return &PrometheusOutput{
+		Cfg:         cfg,
+		entries:     make(map[uint64]*promMetric),
+		metricRegex: regexp.MustCompile(metricNameRegex),
+		lastErrs:    newLastErrorsCollector(),
+		logger:      log.New(ioutil.Discard, "", 0),
+	}
+}
+
+func (p *PrometheusOutput) addTestMetric(name string, value float64) {
+	pm := &promMetric{
+		name:    name,
+		labels:  []*labelPair{{Name: "source", Value: "router1"}},
+		value:   value,
+		addedAt: time.Now(),
+	}
+	p.entries[pm.calculateKey(nil)] = pm
+}
+
+func scrape(t *testing.T, p *PrometheusOutput, path string) string {
+	mux, err := p.createServeMux()
+	if err != nil {
+		t.Fatalf("failed to create serve mux: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code scraping %q: %d", path, rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestPaths(t *testing.T) {
+	p := newTestOutput(&Config{
+		Path:       defaultPath,
+		Expiration: time.Minute,
+		Paths: []*PathConfig{
+			{
+				Path:      "/slow",
+				Allowlist: []string{"^inventory_"},
+			},
+			{
+				Path:     "/fast",
+				Denylist: []string{"^inventory_"},
+			},
+		},
+	})
+	p.addTestMetric("inventory_serial", 1)
+	p.addTestMetric("interface_in_octets", 42)
+
+	all := scrape(t, p, defaultPath)
+	if !strings.Contains(all, "inventory_serial") || !strings.Contains(all, "interface_in_octets") {
+		t.Errorf("path %q expected all metrics, got:\n%s", defaultPath, all)
+	}
+	slow := scrape(t, p, "/slow")
+	if !strings.Contains(slow, "inventory_serial") {
+		t.Errorf("path %q missing allowed metric, got:\n%s", "/slow", slow)
+	}
+	if strings.Contains(slow, "interface_in_octets") {
+		t.Errorf("path %q returned a metric not in allowlist, got:\n%s", "/slow", slow)
+	}
+	fast := scrape(t, p, "/fast")
+	if strings.Contains(fast, "inventory_serial") {
+		t.Errorf("path %q returned a denied metric, got:\n%s", "/fast", fast)
+	}
+	if !strings.Contains(fast, "interface_in_octets") {
+		t.Errorf("path %q missing metric, got:\n%s", "/fast", fast)
+	}
+}
+
+func TestPathsDuplicate(t *testing.T) {
+	p := newTestOutput(&Config{
+		Path:  defaultPath,
+		Paths: []*PathConfig{{Path: defaultPath}},
+	})
+	_, err := p.createServeMux()
+	if err == nil {
+		t.Errorf("expected an error for a duplicate path")
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *Config
+		accept     string
+		want       string
+		wantSuffix string
+	}{
+		{
+			name: "default",
+			cfg:  &Config{Path: "/metrics"},
+			want: "text/plain; version=0.0.4; charset=utf-8",
+		},
+		{
+			name:   "openmetrics_disabled",
+			cfg:    &Config{Path: "/metrics"},
+			accept: "application/openmetrics-text; version=0.0.1",
+			want:   "text/plain; version=0.0.4; charset=utf-8",
+		},
+		{
+			name:       "openmetrics_enabled",
+			cfg:        &Config{Path: "/metrics", EnableOpenMetrics: true},
+			accept:     "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5",
+			want:       "application/openmetrics-text; version=0.0.1; charset=utf-8",
+			wantSuffix: "# EOF\n",
+		},
+		{
+			name:   "openmetrics_enabled_text_accepted",
+			cfg:    &Config{Path: "/metrics", EnableOpenMetrics: true},
+			accept: "text/plain",
+			want:   "text/plain; version=0.0.4; charset=utf-8",
+		},
+		{
+			name:   "protobuf",
+			cfg:    &Config{Path: "/metrics"},
+			accept: "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited",
+			want:   "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited",
+		},
+		{
+			name:       "forced",
+			cfg:        &Config{Path: "/metrics", EnableOpenMetrics: true, ForceContentType: "application/openmetrics-text; version=1.0.0; charset=utf-8"},
+			accept:     "text/plain",
+			want:       "application/openmetrics-text; version=1.0.0; charset=utf-8",
+			wantSuffix: "# EOF\n",
+		},
+		{
+			name:   "forced_unknown",
+			cfg:    &Config{Path: "/metrics", ForceContentType: "text/plain"},
+			accept: "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited",
+			want:   "text/plain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestOutput(tt.cfg)
+			p.addTestMetric("metric_one", 1)
+			mux, err := p.createServeMux()
+			if err != nil {
+				t.Fatalf("failed to create serve mux: %v", err)
+			}
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != 200 {
+				t.Fatalf("unexpected status code: %d", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("expected Content-Type %q, got %q", tt.want, got)
+			}
+			if !strings.HasSuffix(rec.Body.String(), tt.wantSuffix) {
+				t.Errorf("expected body ending with %q, got %q", tt.wantSuffix, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package prometheus_output
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// samplesGatherer implements prometheus.Gatherer,
+// it adds the previous samples of the stored metrics to the metric families gathered by g.
+// The registries reject a series collected more than once,
+// so the previous samples are appended after gathering.
+type samplesGatherer struct {
+	g     prometheus.Gatherer
+	p     *PrometheusOutput
+	match func(name string) bool
+}
+
+// samplesGatherer returns g if a single sample is kept per series,
+// otherwise it wraps it to expose the previous samples of the metrics with a name matching match.
+func (p *PrometheusOutput) samplesGatherer(g prometheus.Gatherer, match func(name string) bool) prometheus.Gatherer {
+	if p.Cfg.SamplesPerSeries <= 1 {
+		return g
+	}
+	return &samplesGatherer{g: g, p: p, match: match}
+}
+
+// Gather implements prometheus.Gatherer
+func (sg *samplesGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := sg.g.Gather()
+	if len(mfs) == 0 {
+		return mfs, err
+	}
+	families := make(map[string]*dto.MetricFamily, len(mfs))
+	// gathered samples, used to skip a previous sample exported as the latest one
+	seen := make(map[string]struct{})
+	for _, mf := range mfs {
+		families[mf.GetName()] = mf
+		for _, m := range mf.Metric {
+			seen[sampleKey(mf.GetName(), m)] = struct{}{}
+		}
+	}
+	updated := make(map[string]*dto.MetricFamily)
+	for _, pm := range sg.p.previousSamples(sg.match) {
+		mf, ok := families[pm.name]
+		if !ok {
+			continue
+		}
+		m := new(dto.Metric)
+		if err := pm.Write(m); err != nil {
+			continue
+		}
+		key := sampleKey(pm.name, m)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		mf.Metric = append(mf.Metric, m)
+		updated[pm.name] = mf
+	}
+	// the samples of a series are exported together, oldest first
+	for _, mf := range updated {
+		sort.SliceStable(mf.Metric, func(i, j int) bool {
+			li, lj := labelsString(mf.Metric[i]), labelsString(mf.Metric[j])
+			if li != lj {
+				return li < lj
+			}
+			return mf.Metric[i].GetTimestampMs() < mf.Metric[j].GetTimestampMs()
+		})
+	}
+	return mfs, err
+}
+
+// previousSamples returns the non expired previous samples of the stored metrics
+// with a name matching match, or of all of them if match is nil.
+func (p *PrometheusOutput) previousSamples(match func(name string) bool) []*promMetric {
+	p.Lock()
+	defer p.Unlock()
+	expiry := time.Now().Add(-p.Cfg.Expiration)
+	samples := make([]*promMetric, 0)
+	for _, entry := range p.entries {
+		if match != nil && !match(entry.name) {
+			continue
+		}
+		for _, pm := range entry.previous {
+			if p.expires() && pm.time != nil && pm.time.Before(expiry) {
+				continue
+			}
+			samples = append(samples, pm)
+		}
+	}
+	return samples
+}
+
+// keepPrevious sets the previous samples of p to the n most recent samples of e,
+// e included.
+// The samples are copied without their own previous samples, so that they are not chained.
+func (p *promMetric) keepPrevious(e *promMetric, n int) {
+	last := *e
+	last.previous = nil
+	samples := make([]*promMetric, 0, len(e.previous)+1)
+	samples = append(samples, e.previous...)
+	samples = append(samples, &last)
+	if len(samples) > n {
+		samples = samples[len(samples)-n:]
+	}
+	p.previous = samples
+}
+
+func labelsString(m *dto.Metric) string {
+	sb := strings.Builder{}
+	for _, lb := range m.Label {
+		sb.WriteString(lb.GetName())
+		sb.WriteString("=")
+		sb.WriteString(lb.GetValue())
+		sb.WriteString(",")
+	}
+	return sb.String()
+}
+
+func sampleKey(name string, m *dto.Metric) string {
+	return name + "{" + labelsString(m) + "}" + strconv.FormatInt(m.GetTimestampMs(), 10)
+}
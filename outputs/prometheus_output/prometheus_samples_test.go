@@ -0,0 +1,105 @@
+package prometheus_output
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+func TestSamplesPerSeries(t *testing.T) {
+	p := newTestOutput(&Config{
+		Path:             defaultPath,
+		Expiration:       time.Minute,
+		ExportTimestamps: true,
+		SamplesPerSeries: 2,
+		Paths:            []*PathConfig{{Path: "/octets", Allowlist: []string{"octets"}}},
+	})
+	stop := startTestWorker(p)
+	defer stop()
+
+	now := time.Now().Truncate(time.Millisecond)
+	timestamps := []time.Time{now.Add(-3 * time.Second), now.Add(-2 * time.Second), now.Add(-time.Second)}
+	for i, ts := range timestamps {
+		p.eventChan <- &formatters.EventMsg{
+			Name:      "sub",
+			Timestamp: ts.UnixNano(),
+			Tags:      map[string]string{"source": "router1"},
+			Values:    map[string]interface{}{"in_octets": i + 1},
+		}
+	}
+	// a series with a single sample
+	p.eventChan <- &formatters.EventMsg{
+		Name:      "sub",
+		Timestamp: now.UnixNano(),
+		Tags:      map[string]string{"source": "router2"},
+		Values:    map[string]interface{}{"in_octets": 42},
+	}
+	p.eventChan <- &formatters.EventMsg{}
+
+	want := fmt.Sprintf(`in_octets{source="router1"} 2 %d
+in_octets{source="router1"} 3 %d
+in_octets{source="router2"} 42 %d
+`,
+		timestamps[1].UnixNano()/int64(time.Millisecond),
+		timestamps[2].UnixNano()/int64(time.Millisecond),
+		now.UnixNano()/int64(time.Millisecond),
+	)
+	for _, path := range []string{defaultPath, "/octets"} {
+		body := scrape(t, p, path)
+		if !strings.HasSuffix(body, want) {
+			t.Fatalf("path %q: expected the last two samples of router1 in the exposition:\n%s\ngot:\n%s", path, want, body)
+		}
+		// the samples are kept across scrapes
+		if second := scrape(t, p, path); second != body {
+			t.Fatalf("path %q: expected identical scrapes, got:\n%s\nand:\n%s", path, body, second)
+		}
+	}
+	// previous samples are not chained
+	for _, e := range p.snapshot(nil) {
+		for _, prev := range e.previous {
+			if len(prev.previous) != 0 {
+				t.Fatalf("unexpected chained samples: %v", prev.previous)
+			}
+		}
+	}
+}
+
+func TestSamplesPerSeriesConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg   *Config
+		valid bool
+	}{
+		"default": {
+			cfg:   &Config{},
+			valid: true,
+		},
+		"with_timestamps": {
+			cfg:   &Config{SamplesPerSeries: 3, ExportTimestamps: true},
+			valid: true,
+		},
+		"without_timestamps": {
+			cfg: &Config{SamplesPerSeries: 3},
+		},
+		"clear_on_scrape": {
+			cfg: &Config{SamplesPerSeries: 3, ExportTimestamps: true, ClearOnScrape: true},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestOutput(tc.cfg)
+			err := p.setDefaults()
+			if tc.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if tc.valid && p.Cfg.SamplesPerSeries < 1 {
+				t.Fatalf("expected samples-per-series to default to 1, got %d", p.Cfg.SamplesPerSeries)
+			}
+		})
+	}
+}
@@ -18,6 +18,12 @@ const (
 	defaultMaxServiceFail             = 3
 )
 
+// bounds of the delay between consul connection or service registration attempts
+var (
+	minRegistrationRetryDelay = time.Second
+	maxRegistrationRetryDelay = 30 * time.Second
+)
+
 type ServiceRegistration struct {
 	Address    string `mapstructure:"address,omitempty"`
 	Datacenter string `mapstructure:"datacenter,omitempty"`
@@ -55,19 +61,27 @@ func (p *PrometheusOutput) registerService(ctx context.Context) {
 			Password: p.Cfg.ServiceRegistration.Password,
 		}
 	}
+	attempt := 0
 INITCONSUL:
 	p.consulClient, err = api.NewClient(clientConfig)
 	if err != nil {
 		p.logger.Printf("failed to connect to consul: %v", err)
-		time.Sleep(1 * time.Second)
+		if !waitRegistrationRetry(ctx, attempt) {
+			return
+		}
+		attempt++
 		goto INITCONSUL
 	}
 	self, err := p.consulClient.Agent().Self()
 	if err != nil {
 		p.logger.Printf("failed to connect to consul: %v", err)
-		time.Sleep(1 * time.Second)
+		if !waitRegistrationRetry(ctx, attempt) {
+			return
+		}
+		attempt++
 		goto INITCONSUL
 	}
+	attempt = 0
 	if cfg, ok := self["Config"]; ok {
 		b, _ := json.Marshal(cfg)
 		p.logger.Printf("consul agent config: %s", string(b))
@@ -80,7 +94,10 @@ INITCONSUL:
 		doneCh, err = p.acquireAndKeepLock(ctx, "gnmic/"+p.Cfg.clusterName+"/prometheus-output", []byte(p.Cfg.ServiceRegistration.id))
 		if err != nil {
 			p.logger.Printf("failed to acquire lock: %v", err)
-			time.Sleep(1 * time.Second)
+			if !waitRegistrationRetry(ctx, attempt) {
+				return
+			}
+			attempt++
 			goto INITCONSUL
 		}
 	}
@@ -109,13 +126,29 @@ INITCONSUL:
 		})
 		ttlCheckID = ttlCheckID + ":1"
 	}
+REGISTER:
 	b, _ := json.Marshal(service)
 	p.logger.Printf("registering service: %s", string(b))
-	err = p.consulClient.Agent().ServiceRegister(service)
-	if err != nil {
-		p.logger.Printf("failed to register service in consul: %v", err)
-		return
+	for attempt = 0; ; attempt++ {
+		err = p.consulClient.Agent().ServiceRegister(service)
+		if err == nil {
+			break
+		}
+		p.logger.Printf("failed to register service in consul: %v, retrying in %s", err, registrationRetryDelay(attempt))
+		p.setConsulRegistered(false)
+		select {
+		case <-doneCh:
+			attempt = 0
+			goto INITCONSUL
+		default:
+		}
+		if !waitRegistrationRetry(ctx, attempt) {
+			return
+		}
 	}
+	attempt = 0
+	p.logger.Printf("service %q registered", service.ID)
+	p.setConsulRegistered(true)
 
 	err = p.consulClient.Agent().UpdateTTL(ttlCheckID, "", api.HealthPassing)
 	if err != nil {
@@ -126,19 +159,79 @@ INITCONSUL:
 		select {
 		case <-ticker.C:
 			err = p.consulClient.Agent().UpdateTTL(ttlCheckID, "", api.HealthPassing)
+			p.setConsulRegistered(err == nil)
 			if err != nil {
 				p.logger.Printf("failed to pass TTL check: %v", err)
+				// the service is removed by consul if its check stays critical longer than deregisterAfter,
+				// or if the agent lost its state.
+				if !p.serviceRegistered(service.ID) {
+					p.logger.Printf("service %q not found in consul, registering it again", service.ID)
+					ticker.Stop()
+					goto REGISTER
+				}
 			}
 		case <-ctx.Done():
 			p.consulClient.Agent().UpdateTTL(ttlCheckID, ctx.Err().Error(), api.HealthCritical)
+			p.setConsulRegistered(false)
 			ticker.Stop()
-			goto INITCONSUL
+			return
 		case <-doneCh:
+			p.setConsulRegistered(false)
 			goto INITCONSUL
 		}
 	}
 }
 
+// setConsulRegistered sets the consul registration status metric, if enabled
+func (p *PrometheusOutput) setConsulRegistered(registered bool) {
+	if p.consulRegistered == nil {
+		return
+	}
+	v := 0.0
+	if registered {
+		v = 1
+	}
+	p.consulRegistered.WithLabelValues(p.Cfg.ServiceRegistration.Name).Set(v)
+}
+
+// serviceRegistered returns false if the consul agent does not know the service id.
+// It returns true if the agent cannot be reached, since the registration state is unknown.
+func (p *PrometheusOutput) serviceRegistered(id string) bool {
+	services, err := p.consulClient.Agent().Services()
+	if err != nil {
+		p.logger.Printf("failed to list consul services: %v", err)
+		return true
+	}
+	_, ok := services[id]
+	return ok
+}
+
+// registrationRetryDelay returns the delay before the registration attempt following attempt,
+// it doubles after each failed attempt, up to maxRegistrationRetryDelay.
+func registrationRetryDelay(attempt int) time.Duration {
+	d := minRegistrationRetryDelay
+	for i := 0; i < attempt && d < maxRegistrationRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxRegistrationRetryDelay {
+		d = maxRegistrationRetryDelay
+	}
+	return d
+}
+
+// waitRegistrationRetry waits for the delay following a failed attempt,
+// it returns false if ctx is done before.
+func waitRegistrationRetry(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(registrationRetryDelay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
 func (p *PrometheusOutput) setServiceRegistrationDefaults() {
 	if p.Cfg.ServiceRegistration == nil {
 		return
@@ -47,11 +47,14 @@ func init() {
 type protoMsg struct {
 	m    proto.Message
 	meta outputs.Meta
+	// ev is set instead of m by WriteEvent
+	ev *formatters.EventMsg
 }
 
 // StanOutput //
 type StanOutput struct {
 	Cfg      *Config
+	ctx      context.Context
 	cancelFn context.CancelFunc
 	logger   *log.Logger
 	msgChan  chan *protoMsg
@@ -137,6 +140,8 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 
 	s.mo = &formatters.MarshalOptions{Format: s.Cfg.Format}
 	ctx, s.cancelFn = context.WithCancel(ctx)
+	s.ctx = ctx
+	outputs.SetFlushHandlers(ctx, s, s.evps)
 	s.wg.Add(s.Cfg.NumWorkers)
 	for i := 0; i < s.Cfg.NumWorkers; i++ {
 		cfg := *s.Cfg
@@ -152,6 +157,15 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (s *StanOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, s.Cfg)
+	if err != nil {
+		return err
+	}
+	return s.setDefaults()
+}
+
 func (s *StanOutput) setDefaults() error {
 	if s.Cfg.Format == "" {
 		s.Cfg.Format = defaultFormat
@@ -194,14 +208,25 @@ func (s *StanOutput) Write(ctx context.Context, rsp protoreflect.ProtoMessage, m
 	if rsp == nil || s.mo == nil {
 		return
 	}
+	s.send(ctx, &protoMsg{m: rsp, meta: meta})
+}
 
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (s *StanOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil || s.mo == nil {
+		return
+	}
+	s.send(ctx, &protoMsg{meta: ev.Tags, ev: ev})
+}
+
+func (s *StanOutput) send(ctx context.Context, m *protoMsg) {
 	wctx, cancel := context.WithTimeout(ctx, s.Cfg.WriteTimeout)
 	defer cancel()
 
 	select {
 	case <-ctx.Done():
 		return
-	case s.msgChan <- &protoMsg{m: rsp, meta: meta}:
+	case s.msgChan <- m:
 	case <-wctx.Done():
 		if s.Cfg.Debug {
 			s.logger.Printf("writing expired after %s, STAN output might not be initialized", s.Cfg.WriteTimeout)
@@ -213,8 +238,6 @@ func (s *StanOutput) Write(ctx context.Context, rsp protoreflect.ProtoMessage, m
 	}
 }
 
-func (s *StanOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
-
 // Metrics //
 func (s *StanOutput) RegisterMetrics(reg *prometheus.Registry) {
 	if !s.Cfg.EnableMetrics {
@@ -227,6 +250,7 @@ func (s *StanOutput) RegisterMetrics(reg *prometheus.Registry) {
 
 // Close //
 func (s *StanOutput) Close() error {
+	outputs.FlushEventProcessors(s.ctx, s, s.evps)
 	s.cancelFn()
 	s.wg.Wait()
 	return nil
@@ -286,7 +310,7 @@ CRCONN:
 			s.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-s.msgChan:
-			b, err := s.mo.Marshal(m.m, m.meta, s.evps...)
+			b, err := s.marshal(m)
 			if err != nil {
 				if s.Cfg.Debug {
 					s.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -320,6 +344,14 @@ CRCONN:
 	}
 }
 
+// marshal marshals the message m, or its event in format 'event'.
+func (s *StanOutput) marshal(m *protoMsg) ([]byte, error) {
+	if m.ev != nil {
+		return s.mo.MarshalEvents([]*formatters.EventMsg{m.ev})
+	}
+	return s.mo.Marshal(m.m, m.meta, s.evps...)
+}
+
 func (s *StanOutput) subjectName(c *Config, meta outputs.Meta) string {
 	if c.SubjectPrefix != "" {
 		ssb := strings.Builder{}
@@ -351,3 +383,8 @@ func (s *StanOutput) SetName(name string) {
 }
 
 func (s *StanOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (s *StanOutput) Depths() map[string]int {
+	return map[string]int{"msg-chan": len(s.msgChan)}
+}
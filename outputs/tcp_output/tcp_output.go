@@ -33,6 +33,7 @@ func init() {
 type TCPOutput struct {
 	Cfg *Config
 
+	ctx      context.Context
 	cancelFn context.CancelFunc
 	buffer   chan []byte
 	limiter  *time.Ticker
@@ -111,12 +112,27 @@ func (t *TCPOutput) Init(ctx context.Context, name string, cfg map[string]interf
 	}()
 
 	ctx, t.cancelFn = context.WithCancel(ctx)
+	t.ctx = ctx
+	outputs.SetFlushHandlers(ctx, t, t.evps)
 	for i := 0; i < t.Cfg.NumWorkers; i++ {
 		go t.start(ctx, i)
 	}
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (t *TCPOutput) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, t.Cfg)
+	if err != nil {
+		return err
+	}
+	_, _, err = net.SplitHostPort(t.Cfg.Address)
+	if err != nil {
+		return fmt.Errorf("wrong address format: %v", err)
+	}
+	return nil
+}
+
 func (t *TCPOutput) Write(ctx context.Context, m proto.Message, meta outputs.Meta) {
 	if m == nil {
 		return
@@ -134,9 +150,26 @@ func (t *TCPOutput) Write(ctx context.Context, m proto.Message, meta outputs.Met
 	}
 }
 
-func (t *TCPOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (t *TCPOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		b, err := t.mo.MarshalEvents([]*formatters.EventMsg{ev})
+		if err != nil {
+			t.logger.Printf("failed marshaling event: %v", err)
+			return
+		}
+		t.buffer <- b
+	}
+}
 
 func (t *TCPOutput) Close() error {
+	outputs.FlushEventProcessors(t.ctx, t, t.evps)
 	t.cancelFn()
 	if t.limiter != nil {
 		t.limiter.Stop()
@@ -195,3 +228,8 @@ START:
 
 func (t *TCPOutput) SetName(name string)        {}
 func (t *TCPOutput) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (t *TCPOutput) Depths() map[string]int {
+	return map[string]int{"buffer": len(t.buffer)}
+}
@@ -33,6 +33,7 @@ type UDPSock struct {
 	Cfg *Config
 
 	conn     *net.UDPConn
+	ctx      context.Context
 	cancelFn context.CancelFunc
 	buffer   chan []byte
 	limiter  *time.Ticker
@@ -105,11 +106,26 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 		u.Close()
 	}()
 	ctx, u.cancelFn = context.WithCancel(ctx)
+	u.ctx = ctx
 	u.mo = &formatters.MarshalOptions{Format: u.Cfg.Format}
+	outputs.SetFlushHandlers(ctx, u, u.evps)
 	go u.start(ctx)
 	return nil
 }
 
+// ValidateConfig implements outputs.ConfigValidator
+func (u *UDPSock) ValidateConfig(cfg map[string]interface{}) error {
+	err := outputs.DecodeConfig(cfg, u.Cfg)
+	if err != nil {
+		return err
+	}
+	_, _, err = net.SplitHostPort(u.Cfg.Address)
+	if err != nil {
+		return fmt.Errorf("wrong address format: %v", err)
+	}
+	return nil
+}
+
 func (u *UDPSock) Write(ctx context.Context, m proto.Message, meta outputs.Meta) {
 	if m == nil {
 		return
@@ -122,9 +138,24 @@ func (u *UDPSock) Write(ctx context.Context, m proto.Message, meta outputs.Meta)
 	u.buffer <- b
 }
 
-func (u *UDPSock) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
+// WriteEvent writes ev in format 'event', e.g the events held and flushed by the event processors.
+func (u *UDPSock) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	b, err := u.mo.MarshalEvents([]*formatters.EventMsg{ev})
+	if err != nil {
+		u.logger.Printf("failed marshaling event: %v", err)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case u.buffer <- b:
+	}
+}
 
 func (u *UDPSock) Close() error {
+	outputs.FlushEventProcessors(u.ctx, u, u.evps)
 	u.cancelFn()
 	if u.limiter != nil {
 		u.limiter.Stop()
@@ -183,3 +214,8 @@ DIAL:
 
 func (u *UDPSock) SetName(name string)        {}
 func (u *UDPSock) SetClusterName(name string) {}
+
+// Depths implements outputs.DepthReporter
+func (u *UDPSock) Depths() map[string]int {
+	return map[string]int{"buffer": len(u.buffer)}
+}
@@ -34,7 +34,8 @@ func CompareGetRequests(req1, req2 *gnmi.GetRequest) bool {
 		return false
 	}
 	for i := range req1.UseModels {
-		if req1.UseModels[i].Name != req2.UseModels[i].Name {
+		if req1.UseModels[i].Name != req2.UseModels[i].Name ||
+			req1.UseModels[i].Version != req2.UseModels[i].Version {
 			return false
 		}
 	}